@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"span-links-signoz-demo/linkguard"
+	"span-links-signoz-demo/linkprune"
+	"span-links-signoz-demo/pkg/queue"
+	"span-links-signoz-demo/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ackEntry is a processed order waiting to be acknowledged
+type ackEntry struct {
+	orderID string
+	spanCtx trace.SpanContext
+}
+
+// AckBatcher models Kafka-style batched acknowledgment: each worker acknowledges its
+// processed orders K at a time. Every batch emits a BrokerAck span (the broker handling
+// the offset commit) and an AckBatch span in its own trace linking to each acknowledged
+// ProcessOrder span (link.type=acked_order) and to the BrokerAck span (link.type=broker_ack).
+type AckBatcher struct {
+	tracer   trace.Tracer
+	size     int
+	maxLinks int
+	prune    linkprune.Strategy
+	guard    *linkguard.Guard
+
+	mu      sync.Mutex
+	pending map[string][]ackEntry // worker ID -> processed, unacknowledged orders
+	batches map[string]int        // worker ID -> batches acknowledged so far
+}
+
+// NewAckBatcher creates a batcher acknowledging every size processed orders per worker
+func NewAckBatcher(size int) *AckBatcher {
+	return &AckBatcher{
+		tracer:   otel.Tracer("ack-batcher"),
+		size:     size,
+		maxLinks: linkprune.DefaultMaxLinks,
+		prune:    linkprune.KeepFirst,
+		pending:  make(map[string][]ackEntry),
+		batches:  make(map[string]int),
+	}
+}
+
+// SetLinkPruning caps the acked_order links of each AckBatch span at maxLinks (the
+// broker_ack link is always kept), choosing survivors with strategy
+func (a *AckBatcher) SetLinkPruning(maxLinks int, strategy linkprune.Strategy) {
+	a.maxLinks = maxLinks
+	a.prune = strategy
+}
+
+// SetAttributeGuard caps the distinct order.id values recorded on acked_order links
+func (a *AckBatcher) SetAttributeGuard(guard *linkguard.Guard) {
+	a.guard = guard
+}
+
+// Record adds a processed order to its worker's pending batch, acknowledging the batch
+// once it is full
+func (a *AckBatcher) Record(workerID string, order queue.Order, spanCtx trace.SpanContext) {
+	a.mu.Lock()
+	a.pending[workerID] = append(a.pending[workerID], ackEntry{orderID: order.ID, spanCtx: spanCtx})
+	var batch []ackEntry
+	if len(a.pending[workerID]) >= a.size {
+		batch = a.pending[workerID]
+		delete(a.pending, workerID)
+	}
+	a.mu.Unlock()
+
+	if batch != nil {
+		a.ack(workerID, batch, false)
+	}
+}
+
+// Flush acknowledges every worker's partial batch (at shutdown)
+func (a *AckBatcher) Flush() {
+	a.mu.Lock()
+	pending := a.pending
+	a.pending = make(map[string][]ackEntry)
+	a.mu.Unlock()
+
+	workers := make([]string, 0, len(pending))
+	for id := range pending {
+		workers = append(workers, id)
+	}
+	sort.Strings(workers)
+	for _, id := range workers {
+		a.ack(id, pending[id], true)
+	}
+}
+
+func (a *AckBatcher) ack(workerID string, batch []ackEntry, partial bool) {
+	a.mu.Lock()
+	a.batches[workerID]++
+	seq := a.batches[workerID]
+	a.mu.Unlock()
+
+	// The broker's side of the commit, in its own trace
+	_, brokerSpan := a.tracer.Start(context.Background(), "BrokerAck",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", queue.MessagingSystem),
+			attribute.String("messaging.operation.type", "settle"),
+			attribute.String("messaging.consumer.group.name", ConsumerGroupName),
+			attribute.Int("messaging.batch.message_count", len(batch)),
+		),
+	)
+	time.Sleep(5 * time.Millisecond)
+	telemetry.EndWithStatus(brokerSpan, nil)
+
+	links := make([]trace.Link, 0, len(batch)+1)
+	for i, e := range batch {
+		links = append(links, trace.Link{
+			SpanContext: e.spanCtx,
+			Attributes: a.guard.Attributes([]attribute.KeyValue{
+				attribute.String("link.type", "acked_order"),
+				attribute.String("order.id", e.orderID),
+				attribute.Int("ack.index", i),
+			}),
+		})
+	}
+	limit := a.maxLinks
+	if limit > 1 {
+		limit-- // leave room for the broker_ack link
+	}
+	links, omitted := linkprune.Prune(links, limit, a.prune)
+	kept := len(links)
+	links = append(links, trace.Link{
+		SpanContext: brokerSpan.SpanContext(),
+		Attributes: []attribute.KeyValue{
+			attribute.String("link.type", "broker_ack"),
+		},
+	})
+
+	_, span := a.tracer.Start(context.Background(), "AckBatch",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithLinks(links...),
+		trace.WithAttributes(append(linkprune.Attributes(a.prune, kept, omitted),
+			attribute.String("worker.id", workerID),
+			attribute.Int("ack.batch.sequence", seq),
+			attribute.Int("ack.batch.size", len(batch)),
+			attribute.Int("ack.batch.target_size", a.size),
+			attribute.Bool("ack.batch.partial", partial),
+		)...),
+	)
+	telemetry.EndWithStatus(span, nil)
+
+	log.Printf("Acknowledged order batch (worker=%s seq=%d size=%d partial=%t)", workerID, seq, len(batch), partial)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"span-links-signoz-demo/pkg/queue"
+	"span-links-signoz-demo/pkg/worker"
+)
+
+// WorkerState is the per-worker section of the /debug/state response
+type WorkerState struct {
+	WorkerID       string `json:"worker_id"`
+	ActiveOrders   int    `json:"active_orders"`
+	CurrentOrderID string `json:"current_order_id,omitempty"`
+	CurrentTraceID string `json:"current_trace_id,omitempty"`
+	Processed      int64  `json:"processed"`
+}
+
+// DebugState is the /debug/state response
+type DebugState struct {
+	QueueLength  int                   `json:"queue_length"`
+	Published    int64                 `json:"published"`
+	Processed    int64                 `json:"processed"`
+	Failed       int64                 `json:"failed"`
+	ActiveOrders int64                 `json:"active_orders"`
+	Workers      []WorkerState         `json:"workers"`
+	RecentTraces []worker.TraceSummary `json:"recent_traces"`
+}
+
+// AdminServer serves run state for debugging demo runs
+type AdminServer struct {
+	stats      *RunStats
+	worker     *worker.Service
+	queue      *queue.SimpleQueue
+	queueDepth func() int
+	reconfig   *Reconfigurer
+	audit      *AuditLog
+}
+
+// NewAdminServer creates an admin server over the run's stats, worker, queue, and queue depth
+func NewAdminServer(stats *RunStats, worker *worker.Service, orders *queue.SimpleQueue, queueDepth func() int) *AdminServer {
+	return &AdminServer{
+		stats:      stats,
+		worker:     worker,
+		queue:      orders,
+		queueDepth: queueDepth,
+	}
+}
+
+// SetReconfigurer also serves GET and POST /admin/config, reading and changing the
+// runtime configuration
+func (a *AdminServer) SetReconfigurer(r *Reconfigurer) {
+	a.reconfig = r
+}
+
+// SetAuditLog also serves GET /audit/orders/{id}, the state transitions of an order
+func (a *AdminServer) SetAuditLog(audit *AuditLog) {
+	a.audit = audit
+}
+
+// State builds the current debug state snapshot
+func (a *AdminServer) State() DebugState {
+	workers := make(map[string]*WorkerState)
+	for id, processed := range a.stats.ProcessedByWorker() {
+		workers[id] = &WorkerState{WorkerID: id, Processed: processed}
+	}
+	for id, current := range a.worker.InFlightOrders() {
+		ws, ok := workers[id]
+		if !ok {
+			ws = &WorkerState{WorkerID: id}
+			workers[id] = ws
+		}
+		ws.ActiveOrders = 1
+		ws.CurrentOrderID = current.OrderID
+		ws.CurrentTraceID = current.SpanCtx.TraceID().String()
+	}
+
+	state := DebugState{
+		QueueLength:  a.queueDepth(),
+		Published:    a.stats.Published(),
+		Processed:    a.stats.Processed(),
+		Failed:       a.stats.Failed(),
+		ActiveOrders: a.worker.ActiveOrders(),
+		Workers:      make([]WorkerState, 0, len(workers)),
+		RecentTraces: a.stats.RecentTraces(),
+	}
+	for _, ws := range workers {
+		state.Workers = append(state.Workers, *ws)
+	}
+	sort.Slice(state.Workers, func(i, j int) bool { return state.Workers[i].WorkerID < state.Workers[j].WorkerID })
+	return state
+}
+
+// Start serves GET /debug/state and GET /debug/queue (the messages pending in the queue's
+// topics; orders already dispatched to consumer-group partitions are not listed) on addr
+// and returns a function that stops the server
+func (a *AdminServer) Start(addr string) func() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /debug/state", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, a.State(), "debug state")
+	})
+	mux.HandleFunc("GET /debug/queue", func(w http.ResponseWriter, r *http.Request) {
+		pending := a.queue.Pending()
+		if pending == nil {
+			pending = []queue.PendingMessage{}
+		}
+		writeJSON(w, pending, "pending messages")
+	})
+	if a.reconfig != nil {
+		mux.HandleFunc("GET /admin/config", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, a.reconfig.Current(), "runtime config")
+		})
+		mux.HandleFunc("POST /admin/config", func(w http.ResponseWriter, r *http.Request) {
+			var u ConfigUpdate
+			dec := json.NewDecoder(r.Body)
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(&u); err != nil {
+				http.Error(w, fmt.Sprintf("invalid config update: %v", err), http.StatusBadRequest)
+				return
+			}
+			cfg, err := a.reconfig.Apply("admin", u)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeJSON(w, cfg, "runtime config")
+		})
+	}
+
+	if a.audit != nil {
+		mux.HandleFunc("GET /audit/orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, a.audit.Trail(r.PathValue("id")), "audit trail")
+		})
+	}
+
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Admin server stopped: %v", err)
+		}
+	}()
+	log.Printf("Admin endpoint listening on http://%s/debug/state", addr)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	}
+}
+
+// writeJSON writes v as indented JSON, logging encoding failures as what
+func writeJSON(w http.ResponseWriter, v any, what string) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Printf("Failed to encode %s: %v", what, err)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"span-links-signoz-demo/pkg/queue"
+	"span-links-signoz-demo/spanstore"
+
+	"go.opentelemetry.io/otel/attribute"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/global"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AuditEventName is the event name of every audit log record
+const AuditEventName = "order.state_change"
+
+// Attributes of the audit trail entries that are not span context
+const (
+	auditStateKey         = attribute.Key("audit.state")
+	auditProducerTraceKey = attribute.Key("link.producer.trace_id")
+	auditProducerSpanKey  = attribute.Key("link.producer.span_id")
+)
+
+// AuditEntry is one state transition of an order, as served by GET /audit/orders/{id}
+type AuditEntry struct {
+	State           string    `json:"state"`
+	Time            time.Time `json:"time"`
+	TraceID         string    `json:"trace_id"` // the span that caused the transition
+	SpanID          string    `json:"span_id"`
+	ProducerTraceID string    `json:"producer_trace_id,omitempty"` // the PublishOrder span the processing links to
+	ProducerSpanID  string    `json:"producer_span_id,omitempty"`
+}
+
+// AuditLog records order state transitions (published, validated, paid, shipped) as OTLP
+// log records. Each record carries the causing span's context as its trace context, so the
+// backend shows it on that span, and the linked producer span as link.producer.trace_id
+// and link.producer.span_id, so one query on order.id (or on the producer trace ID)
+// returns an order's trail across its publish and consumer traces. The most recent
+// AuditTrailCapacity transitions are also kept in memory.
+type AuditLog struct {
+	logger otellog.Logger
+	trail  *spanstore.Store
+}
+
+// NewAuditLog creates an audit log emitting through the global logger provider
+func NewAuditLog() *AuditLog {
+	return &AuditLog{
+		logger: global.GetLoggerProvider().Logger("order-audit"),
+		trail:  spanstore.New(AuditTrailCapacity, 0),
+	}
+}
+
+// RecordTransition emits the audit record of order reaching state
+func (a *AuditLog) RecordTransition(ctx context.Context, state string, order queue.Order, producer trace.SpanContext) {
+	now := time.Now()
+	attrs := []otellog.KeyValue{
+		otellog.String(string(auditStateKey), state),
+		otellog.String("order.id", order.ID),
+		otellog.String("customer.id", order.CustomerID),
+		otellog.String("messaging.destination.name", order.Topic),
+		otellog.String(string(runIDKey), runID),
+	}
+	trailAttrs := []attribute.KeyValue{auditStateKey.String(state)}
+	if producer.IsValid() {
+		attrs = append(attrs,
+			otellog.String(string(auditProducerTraceKey), producer.TraceID().String()),
+			otellog.String(string(auditProducerSpanKey), producer.SpanID().String()),
+		)
+		trailAttrs = append(trailAttrs,
+			auditProducerTraceKey.String(producer.TraceID().String()),
+			auditProducerSpanKey.String(producer.SpanID().String()),
+		)
+	}
+
+	var rec otellog.Record
+	rec.SetEventName(AuditEventName)
+	rec.SetTimestamp(now)
+	rec.SetObservedTimestamp(now)
+	rec.SetSeverity(otellog.SeverityInfo)
+	rec.SetSeverityText("INFO")
+	rec.SetBody(otellog.StringValue(fmt.Sprintf("order %s %s", order.ID, state)))
+	rec.AddAttributes(attrs...)
+	a.logger.Emit(ctx, rec)
+
+	a.trail.Add(order.ID, trace.SpanContextFromContext(ctx), trailAttrs...)
+}
+
+// Trail returns the recorded transitions of orderID, oldest first
+func (a *AuditLog) Trail(orderID string) []AuditEntry {
+	entries := a.trail.Lookup(orderID)
+	trail := make([]AuditEntry, 0, len(entries))
+	for _, e := range entries {
+		entry := AuditEntry{
+			Time:    e.Recorded,
+			TraceID: e.SpanContext.TraceID().String(),
+			SpanID:  e.SpanContext.SpanID().String(),
+		}
+		for _, kv := range e.Attributes {
+			switch kv.Key {
+			case auditStateKey:
+				entry.State = kv.Value.AsString()
+			case auditProducerTraceKey:
+				entry.ProducerTraceID = kv.Value.AsString()
+			case auditProducerSpanKey:
+				entry.ProducerSpanID = kv.Value.AsString()
+			}
+		}
+		trail = append(trail, entry)
+	}
+	return trail
+}
+
+// auditLogEnabled reports whether order state transitions are exported as audit logs
+// (AUDIT_LOG)
+func auditLogEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("AUDIT_LOG"))
+	return err == nil && enabled
+}
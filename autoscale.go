@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"span-links-signoz-demo/spanstore"
+	"span-links-signoz-demo/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Keys of the evidence the autoscaler keeps in its span store
+const (
+	evidenceDepth   = "queue_depth"
+	evidenceBlocked = "blocked_publish"
+)
+
+// Autoscaler resizes the worker pool from the queue depth. Every AutoscaleInterval it
+// wants ceil(depth / target) workers, within [min, max], and one more than it has while
+// publishes are blocking on a full queue. A sample that disagrees with the current size is
+// recorded as a QueueDepthSample span (own trace): the depth at that moment. Scaling up
+// acts on the first such sample, scaling down only after AutoscaleScaleDownSamples in a
+// row. Every resize emits a ScaleDecision span (own trace) linking to the depth samples
+// and blocked PublishOrder spans that drove it, and to the previous decision.
+type Autoscaler struct {
+	pool     *WorkerPool
+	depth    func() int
+	min, max int
+	target   int // queue depth one worker is expected to keep up with
+	tracer   trace.Tracer
+
+	evidence  *spanstore.Store
+	streak    int // consecutive samples disagreeing with the current size, in one direction
+	decisions int
+	last      trace.SpanContext
+}
+
+// NewAutoscaler scales pool between min and max workers, aiming for at most target
+// orders of depth per worker
+func NewAutoscaler(pool *WorkerPool, depth func() int, minWorkers, maxWorkers, target int) *Autoscaler {
+	return &Autoscaler{
+		pool:     pool,
+		depth:    depth,
+		min:      minWorkers,
+		max:      maxWorkers,
+		target:   target,
+		tracer:   otel.Tracer("autoscaler"),
+		evidence: spanstore.New(AutoscaleEvidenceCapacity, AutoscaleEvidenceTTL),
+	}
+}
+
+// RecordBlockedPublish keeps a blocked PublishOrder span as scale-up evidence
+func (a *Autoscaler) RecordBlockedPublish(pubSpan trace.SpanContext, wait time.Duration) {
+	a.evidence.Add(evidenceBlocked, pubSpan, attribute.Int64("messaging.publish.blocked_ms", wait.Milliseconds()))
+}
+
+// Run samples the queue depth every AutoscaleInterval until ctx is done
+func (a *Autoscaler) Run(ctx context.Context) {
+	ticker := time.NewTicker(AutoscaleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.sample()
+		}
+	}
+}
+
+// sample compares the wanted worker count with the current one and resizes when the
+// evidence is strong enough
+func (a *Autoscaler) sample() {
+	depth := a.depth()
+	current := a.pool.Size()
+	want := min(max((depth+a.target-1)/a.target, a.min), a.max)
+	reason := "queue_depth"
+	blocked := len(a.evidence.Lookup(evidenceBlocked))
+	if blocked > 0 && want <= current && current < a.max {
+		want, reason = current+1, "blocked_publish"
+	}
+
+	if want == current {
+		a.streak = 0
+		a.evidence.Take(evidenceDepth) // stale: the pool caught up without a resize
+		return
+	}
+	if (want > current) != (a.streak > 0) {
+		a.streak = 0
+	}
+	if want > current {
+		a.streak++
+	} else {
+		a.streak--
+	}
+	a.recordDepth(depth, current, want)
+	if want < current && -a.streak < AutoscaleScaleDownSamples {
+		return
+	}
+	a.decide(depth, current, want, blocked, reason)
+	a.streak = 0
+}
+
+// recordDepth emits a QueueDepthSample span for the depth observed now
+func (a *Autoscaler) recordDepth(depth, current, want int) {
+	_, span := a.tracer.Start(context.Background(), "QueueDepthSample",
+		trace.WithNewRoot(),
+		trace.WithAttributes(
+			runIDKey.String(runID),
+			attribute.String("metric.name", telemetry.MetricOrdersQueueDepth),
+			attribute.Int("queue.depth", depth),
+			attribute.Int("workers.current", current),
+			attribute.Int("workers.wanted", want),
+		),
+	)
+	telemetry.EndWithStatus(span, nil)
+	a.evidence.Add(evidenceDepth, span.SpanContext(), attribute.Int("queue.depth", depth))
+}
+
+// decide resizes the pool from current to want workers under a ScaleDecision span
+func (a *Autoscaler) decide(depth, current, want, blocked int, reason string) {
+	direction := "up"
+	if want < current {
+		direction = "down"
+	}
+
+	var links []trace.Link
+	for _, e := range a.evidence.Take(evidenceDepth) {
+		links = append(links, e.Link(attribute.String("link.type", "queue_depth_sample")))
+	}
+	for _, e := range a.evidence.Take(evidenceBlocked) {
+		links = append(links, e.Link(attribute.String("link.type", "blocked_publish")))
+	}
+	if a.last.IsValid() {
+		links = append(links, trace.Link{
+			SpanContext: a.last,
+			Attributes:  []attribute.KeyValue{attribute.String("link.type", "previous_decision")},
+		})
+	}
+
+	a.decisions++
+	_, span := a.tracer.Start(context.Background(), "ScaleDecision",
+		trace.WithNewRoot(),
+		trace.WithLinks(links...),
+		trace.WithAttributes(
+			runIDKey.String(runID),
+			attribute.String("scale.direction", direction),
+			attribute.String("scale.reason", reason),
+			attribute.Int("scale.generation", a.decisions),
+			attribute.Int("workers.from", current),
+			attribute.Int("workers.to", want),
+			attribute.Int("queue.depth", depth),
+			attribute.Int("autoscale.blocked_publishes", blocked),
+			attribute.Int("autoscale.target_depth_per_worker", a.target),
+			attribute.Int("autoscale.min_workers", a.min),
+			attribute.Int("autoscale.max_workers", a.max),
+		),
+	)
+	a.pool.Resize(want)
+	telemetry.EndWithStatus(span, nil)
+	a.last = span.SpanContext()
+
+	log.Printf("Scaled workers %s (from=%d to=%d queue_depth=%d blocked_publishes=%d reason=%s)",
+		direction, current, want, depth, blocked, reason)
+}
+
+// autoscaleFromEnv reads AUTOSCALE_WORKERS ("min-max", e.g. "1-8"; unset disables the
+// autoscaler) and AUTOSCALE_TARGET_DEPTH (queued orders per worker, default
+// DefaultAutoscaleTargetDepth)
+func autoscaleFromEnv() (minWorkers, maxWorkers, target int, ok bool) {
+	val := os.Getenv("AUTOSCALE_WORKERS")
+	if val == "" {
+		return 0, 0, 0, false
+	}
+	lo, hi, found := strings.Cut(val, "-")
+	minWorkers, errMin := strconv.Atoi(strings.TrimSpace(lo))
+	maxWorkers, errMax := strconv.Atoi(strings.TrimSpace(hi))
+	if !found || errMin != nil || errMax != nil || minWorkers < 1 || maxWorkers < minWorkers {
+		log.Printf("Ignoring invalid AUTOSCALE_WORKERS=%q", val)
+		return 0, 0, 0, false
+	}
+
+	target = intAtLeastFromEnv("AUTOSCALE_TARGET_DEPTH", DefaultAutoscaleTargetDepth, 1)
+	return minWorkers, maxWorkers, target, true
+}
+
+// autoscaleRange formats the AUTOSCALE_WORKERS range for the run attributes ("" when off)
+func autoscaleRange() string {
+	minWorkers, maxWorkers, _, ok := autoscaleFromEnv()
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", minWorkers, maxWorkers)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"span-links-signoz-demo/pkg/queue"
+
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	"github.com/redis/go-redis/v9"
+)
+
+// Process roles (PRODUCER_ONLY / CONSUMER_ONLY split the pipeline across processes)
+const (
+	roleCombined = "combined" // producer and workers in one process (default)
+	roleProducer = "producer" // publish and forward to the broker; no workers
+	roleConsumer = "consumer" // pull from the broker and process; no publishing
+)
+
+// brokerKeyPrefix namespaces the Redis lists holding each topic's messages
+const brokerKeyPrefix = "spanlinks:orders:"
+
+// RedisBroker carries orders between a producer-only and a consumer-only process. Each
+// side keeps its in-process queue; the broker moves messages between that queue and a
+// Redis list per topic. Orders travel as JSON with their headers, so the traceparent the
+// producer injected reaches the consumer unchanged and its ProcessOrder span links back
+// across processes (and containers) exactly as it does in a single process.
+type RedisBroker struct {
+	client *redis.Client
+
+	forwarded atomic.Int64
+	inFlight  atomic.Int64
+}
+
+// NewRedisBroker connects to the Redis broker at addr
+func NewRedisBroker(ctx context.Context, addr string) (*RedisBroker, error) {
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	if err := redisotel.InstrumentTracing(client); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to instrument broker client: %w", err)
+	}
+	pingCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if err := client.Ping(pingCtx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("broker at %s unreachable: %w", addr, err)
+	}
+	return &RedisBroker{client: client}, nil
+}
+
+// Close releases the broker connection
+func (b *RedisBroker) Close() error {
+	return b.client.Close()
+}
+
+// Forward moves orders published to the local queue's topics onto the broker until ctx
+// is done (producer side)
+func (b *RedisBroker) Forward(ctx context.Context, orders *queue.SimpleQueue, topics ...string) {
+	for {
+		order, err := orders.Consume(ctx, topics...)
+		if err != nil {
+			return
+		}
+		b.inFlight.Add(1)
+		if err := b.push(order); err != nil {
+			log.Printf("Failed to forward order %s to broker: %v", order.ID, err)
+		} else {
+			b.forwarded.Add(1)
+		}
+		b.inFlight.Add(-1)
+	}
+}
+
+// push appends order to its topic's list. It deliberately ignores the caller's context:
+// an order taken off the local queue must reach the broker even during shutdown.
+func (b *RedisBroker) push(order queue.Order) error {
+	data, err := json.Marshal(order)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return b.client.RPush(ctx, brokerKeyPrefix+order.Topic, data).Err()
+}
+
+// Drained reports whether every order published locally has been forwarded
+func (b *RedisBroker) Drained(orders *queue.SimpleQueue) bool {
+	return orders.Length() == 0 && b.inFlight.Load() == 0
+}
+
+// Forwarded returns how many orders were forwarded to the broker
+func (b *RedisBroker) Forwarded() int64 {
+	return b.forwarded.Load()
+}
+
+// Pull moves orders from the broker onto the local queue until ctx is done (consumer
+// side). The pull context carries no span, so publishing locally keeps the producer's
+// traceparent header.
+func (b *RedisBroker) Pull(ctx context.Context, orders *queue.SimpleQueue, topics ...string) {
+	keys := make([]string, len(topics))
+	for i, topic := range topics {
+		keys[i] = brokerKeyPrefix + topic
+	}
+	for ctx.Err() == nil {
+		res, err := b.client.BLPop(ctx, time.Second, keys...).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Failed to pull from broker: %v", err)
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+
+		var order queue.Order
+		if err := json.Unmarshal([]byte(res[1]), &order); err != nil {
+			log.Printf("Dropping undecodable message from %s: %v", strings.TrimPrefix(res[0], brokerKeyPrefix), err)
+			continue
+		}
+		if err := orders.Publish(ctx, order); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"span-links-signoz-demo/telemetry"
+
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// cacheBackend is the storage behind CustomerCache
+type cacheBackend interface {
+	get(ctx context.Context, key string) (string, bool, error)
+	set(ctx context.Context, key, value string, ttl time.Duration) error
+	close() error
+	system() string
+}
+
+// CustomerCache caches customer profiles looked up while processing orders. It uses Redis
+// (instrumented with redisotel) when REDIS_ADDR is set and reachable, otherwise an
+// in-memory map that emits equivalent client spans, so cache hits and misses show up in
+// the consumer trace either way.
+type CustomerCache struct {
+	backend cacheBackend
+	ttl     time.Duration
+}
+
+// NewCustomerCache connects to Redis at addr, falling back to memory when addr is empty
+// or Redis is unreachable
+func NewCustomerCache(ctx context.Context, addr string, ttl time.Duration) *CustomerCache {
+	if addr != "" {
+		backend, err := newRedisBackend(ctx, addr)
+		if err == nil {
+			log.Printf("Customer cache using Redis at %s", addr)
+			return &CustomerCache{backend: backend, ttl: ttl}
+		}
+		log.Printf("Customer cache falling back to memory: %v", err)
+	}
+	return &CustomerCache{backend: newMemoryBackend(), ttl: ttl}
+}
+
+// Close releases the cache backend
+func (c *CustomerCache) Close() error {
+	return c.backend.close()
+}
+
+// System returns the cache.system attribute value of the backend
+func (c *CustomerCache) System() string {
+	return c.backend.system()
+}
+
+// Lookup returns the cached profile for customerID, loading and caching it on a miss
+func (c *CustomerCache) Lookup(ctx context.Context, customerID string) (profile string, hit bool, err error) {
+	key := "customer:" + customerID
+	profile, hit, err = c.backend.get(ctx, key)
+	if err != nil || hit {
+		return profile, hit, err
+	}
+
+	// Simulate loading the profile from the customer service
+	time.Sleep(20 * time.Millisecond)
+	profile = fmt.Sprintf(`{"id":%q,"tier":"standard"}`, customerID)
+	return profile, false, c.backend.set(ctx, key, profile, c.ttl)
+}
+
+// redisBackend stores entries in Redis; commands are traced by the redisotel hook
+type redisBackend struct {
+	client *redis.Client
+}
+
+func newRedisBackend(ctx context.Context, addr string) (*redisBackend, error) {
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	if err := redisotel.InstrumentTracing(client); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to instrument redis client: %w", err)
+	}
+	pingCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := client.Ping(pingCtx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("redis at %s unreachable: %w", addr, err)
+	}
+	return &redisBackend{client: client}, nil
+}
+
+func (b *redisBackend) get(ctx context.Context, key string) (string, bool, error) {
+	val, err := b.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return val, true, nil
+}
+
+func (b *redisBackend) set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return b.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (b *redisBackend) close() error { return b.client.Close() }
+
+func (b *redisBackend) system() string { return "redis" }
+
+// memoryBackend stores entries in a map, emitting a client span per command shaped like
+// the redisotel ones
+type memoryBackend struct {
+	tracer  trace.Tracer
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	value   string
+	expires time.Time
+}
+
+func newMemoryBackend() *memoryBackend {
+	return &memoryBackend{
+		tracer:  otel.Tracer("customer-cache"),
+		entries: make(map[string]memoryEntry),
+	}
+}
+
+func (b *memoryBackend) get(ctx context.Context, key string) (string, bool, error) {
+	_, span := b.command(ctx, "get", key)
+	defer telemetry.EndWithStatus(span, nil)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry, ok := b.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return "", false, nil
+	}
+	return entry.value, true, nil
+}
+
+func (b *memoryBackend) set(ctx context.Context, key, value string, ttl time.Duration) error {
+	_, span := b.command(ctx, "set", key)
+	defer telemetry.EndWithStatus(span, nil)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries[key] = memoryEntry{value: value, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+func (b *memoryBackend) command(ctx context.Context, op, key string) (context.Context, trace.Span) {
+	return b.tracer.Start(ctx, op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", b.system()),
+			attribute.String("db.operation", op),
+			attribute.String("db.statement", op+" "+key),
+		),
+	)
+}
+
+func (b *memoryBackend) close() error { return nil }
+
+func (b *memoryBackend) system() string { return "in-memory" }
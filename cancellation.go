@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"span-links-signoz-demo/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CancellationService handles user-initiated order cancellations. Each cancellation is
+// a new trace that links back to the original publish and processing spans of the order.
+type CancellationService struct {
+	registry *OrderRegistry
+	tracer   trace.Tracer
+}
+
+// NewCancellationService creates a new cancellation service
+func NewCancellationService(registry *OrderRegistry) *CancellationService {
+	return &CancellationService{
+		registry: registry,
+		tracer:   otel.Tracer("cancellation-service"),
+	}
+}
+
+// CancelOrder cancels a previously published order
+func (c *CancellationService) CancelOrder(ctx context.Context, orderID, reason string) (err error) {
+	original, ok := c.registry.Lookup(orderID)
+
+	var links []trace.Link
+	if original.Publish.IsValid() {
+		links = append(links, trace.Link{
+			SpanContext: original.Publish,
+			Attributes: []attribute.KeyValue{
+				attribute.String("link.type", "cancellation_of_publish"),
+				attribute.String("order.id", orderID),
+			},
+		})
+	}
+	if original.Process.IsValid() {
+		links = append(links, trace.Link{
+			SpanContext: original.Process,
+			Attributes: []attribute.KeyValue{
+				attribute.String("link.type", "cancellation_of_processing"),
+				attribute.String("order.id", orderID),
+			},
+		})
+	}
+
+	// Cancellation is a follow-up action with its own lifecycle: new trace, linked back
+	_, span := c.tracer.Start(ctx, "CancelOrder",
+		trace.WithNewRoot(),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithLinks(links...),
+		trace.WithAttributes(
+			attribute.String("order.id", orderID),
+			attribute.String("cancellation.reason", reason),
+			attribute.Bool("order.was_processed", original.Process.IsValid()),
+		),
+	)
+	defer func() { telemetry.EndWithStatus(span, err) }()
+
+	if !ok {
+		return fmt.Errorf("order %s not found", orderID)
+	}
+
+	span.AddEvent("Order cancelled")
+	log.Printf("Order cancelled (order=%s reason=%s links=%d)", orderID, reason, len(links))
+	return nil
+}
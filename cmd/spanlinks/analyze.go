@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"span-links-signoz-demo/otlpjson"
+)
+
+// latencyPercentiles are the percentiles analyze reports
+var latencyPercentiles = []float64{50, 90, 99}
+
+// deliveryLatency is one consumer delivery joined to its producer span through the
+// queue_consumption link
+type deliveryLatency struct {
+	queueWait  time.Duration // producer span end -> consumer span start
+	processing time.Duration // ProcessOrder duration
+	endToEnd   time.Duration // producer span start -> ProcessOrder end
+}
+
+// LatencyStats summarizes one latency series of a run (milliseconds)
+type LatencyStats struct {
+	Count       int                `json:"count"`
+	Percentiles map[string]float64 `json:"percentiles_ms"`
+	Max         float64            `json:"max_ms"`
+}
+
+// RunLatency is the analyze report for one run.id
+type RunLatency struct {
+	RunID      string       `json:"run_id"`
+	Deliveries int          `json:"deliveries"`
+	Orders     int          `json:"orders"`
+	Dangling   int          `json:"dangling_links"`
+	QueueWait  LatencyStats `json:"queue_wait"`
+	Processing LatencyStats `json:"processing"`
+	EndToEnd   LatencyStats `json:"end_to_end"`
+}
+
+// runAnalyze joins consumer spans to the producer spans their queue_consumption links
+// point at, in collector file-exporter output, and reports queue-wait, processing and
+// end-to-end latency percentiles per run.id. In the semconv form the link sits on
+// ReceiveOrder and processing is measured on its ProcessOrder child.
+func runAnalyze(args []string) error {
+	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
+	runFilter := fs.String("run", "", "only report this run.id")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("usage: spanlinks analyze [-run ID] [-json] <traces.json>...")
+	}
+
+	var spans []otlpjson.Span
+	for _, path := range fs.Args() {
+		s, err := otlpjson.LoadFile(path)
+		if err != nil {
+			return err
+		}
+		spans = append(spans, s...)
+	}
+	index := otlpjson.Index(spans)
+	children := make(map[string][]otlpjson.Span)
+	for _, s := range spans {
+		if s.ParentSpanID != "" {
+			parent := s.TraceID + "/" + s.ParentSpanID
+			children[parent] = append(children[parent], s)
+		}
+	}
+
+	deliveries := make(map[string][]deliveryLatency)
+	orders := make(map[string]map[string]bool)
+	dangling := make(map[string]int)
+	for _, s := range spans {
+		for _, l := range s.Links {
+			if l.Attributes["link.type"] != "queue_consumption" {
+				continue
+			}
+			run := spanRunID(s, l)
+			if *runFilter != "" && run != *runFilter {
+				continue
+			}
+			producer, ok := index[l.Key()]
+			if !ok {
+				dangling[run]++
+				continue
+			}
+			processing := s
+			for _, c := range children[s.Key()] {
+				if strings.Contains(c.Name, "ProcessOrder") {
+					processing = c
+				}
+			}
+			deliveries[run] = append(deliveries[run], deliveryLatency{
+				queueWait:  max(s.Start.Sub(producer.End), 0),
+				processing: processing.Duration(),
+				endToEnd:   processing.End.Sub(producer.Start),
+			})
+			if orders[run] == nil {
+				orders[run] = make(map[string]bool)
+			}
+			orders[run][orderID(s)] = true
+		}
+	}
+
+	reports := make([]RunLatency, 0, len(deliveries))
+	for run, ds := range deliveries {
+		reports = append(reports, RunLatency{
+			RunID:      run,
+			Deliveries: len(ds),
+			Orders:     len(orders[run]),
+			Dangling:   dangling[run],
+			QueueWait:  latencyStats(ds, func(d deliveryLatency) time.Duration { return d.queueWait }),
+			Processing: latencyStats(ds, func(d deliveryLatency) time.Duration { return d.processing }),
+			EndToEnd:   latencyStats(ds, func(d deliveryLatency) time.Duration { return d.endToEnd }),
+		})
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].RunID < reports[j].RunID })
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(reports)
+	}
+
+	fmt.Printf("%d spans read from %s\n", len(spans), strings.Join(fs.Args(), ", "))
+	if len(reports) == 0 {
+		return errors.New("no queue_consumption links resolved to a producer span")
+	}
+	for _, r := range reports {
+		fmt.Printf("\nrun.id=%s: %d deliveries of %d orders", r.RunID, r.Deliveries, r.Orders)
+		if r.Dangling > 0 {
+			fmt.Printf(" (%d links to unexported producer spans skipped)", r.Dangling)
+		}
+		fmt.Println()
+		fmt.Printf("  %-12s", "")
+		for _, p := range latencyPercentiles {
+			fmt.Printf(" %9s", fmt.Sprintf("p%g", p))
+		}
+		fmt.Printf(" %9s\n", "max")
+		printLatencyRow("queue wait", r.QueueWait)
+		printLatencyRow("processing", r.Processing)
+		printLatencyRow("end-to-end", r.EndToEnd)
+	}
+	return nil
+}
+
+// spanRunID returns the run.id of a consumer span: its resource's, else its link's
+func spanRunID(s otlpjson.Span, l otlpjson.Link) string {
+	if id := s.Resource["run.id"]; id != "" {
+		return id
+	}
+	if id := l.Attributes["run.id"]; id != "" {
+		return id
+	}
+	return "(none)"
+}
+
+// latencyStats computes nearest-rank percentiles of one series of ds
+func latencyStats(ds []deliveryLatency, series func(deliveryLatency) time.Duration) LatencyStats {
+	values := make([]float64, len(ds))
+	for i, d := range ds {
+		values[i] = float64(series(d)) / float64(time.Millisecond)
+	}
+	sort.Float64s(values)
+
+	stats := LatencyStats{Count: len(values), Percentiles: make(map[string]float64, len(latencyPercentiles))}
+	if len(values) == 0 {
+		return stats
+	}
+	for _, p := range latencyPercentiles {
+		rank := int(math.Ceil(p / 100 * float64(len(values))))
+		stats.Percentiles[fmt.Sprintf("p%g", p)] = values[max(rank, 1)-1]
+	}
+	stats.Max = values[len(values)-1]
+	return stats
+}
+
+func printLatencyRow(name string, stats LatencyStats) {
+	fmt.Printf("  %-12s", name)
+	for _, p := range latencyPercentiles {
+		fmt.Printf(" %7.1fms", stats.Percentiles[fmt.Sprintf("p%g", p)])
+	}
+	fmt.Printf(" %7.1fms\n", stats.Max)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"span-links-signoz-demo/otlpjson"
+)
+
+// linkPair is one producer/consumer pair seen from either side
+type linkPair struct {
+	producer string // producer span key
+	consumer string // consumer span key
+	orderID  string
+}
+
+// runConsistency checks the dual-link approach in a forward-link run's file-exporter
+// output: every backward (queue_consumption) link from a consumer must be matched by a
+// forward (forward_to_consumer) link from the same producer span, and vice versa. In the
+// semconv form the backward link sits on ReceiveOrder and the forward link targets its
+// ProcessOrder child; both count as the same consumer. Forward links from a PublishOutcome
+// span count as its PublishOrder parent's.
+func runConsistency(args []string) error {
+	fs := flag.NewFlagSet("consistency", flag.ExitOnError)
+	show := fs.Int("show", 10, "asymmetries to list per direction")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: spanlinks consistency [-show N] <traces.json>")
+	}
+
+	spans, err := otlpjson.LoadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	index := otlpjson.Index(spans)
+
+	// backward[consumer][producer] / forward[producer][consumer]
+	backward := make(map[string]map[string]bool)
+	forward := make(map[string]map[string]bool)
+	var backwardPairs, forwardPairs []linkPair
+	for _, s := range spans {
+		for _, l := range s.Links {
+			switch {
+			case l.Attributes["link.type"] == "queue_consumption":
+				addPair(backward, s.Key(), l.Key())
+				backwardPairs = append(backwardPairs, linkPair{producer: l.Key(), consumer: s.Key(), orderID: orderID(s)})
+			case l.Attributes["link.type"] == "forward_to_consumer":
+				producer := forwardSource(s)
+				addPair(forward, producer, l.Key())
+				forwardPairs = append(forwardPairs, linkPair{producer: producer, consumer: l.Key(), orderID: orderID(s)})
+			}
+		}
+	}
+
+	fmt.Printf("%d spans read from %s (%d backward links, %d forward links)\n\n", len(spans), fs.Arg(0), len(backwardPairs), len(forwardPairs))
+	if len(forwardPairs) == 0 {
+		return errors.New("no forward links found; run the app with ENABLE_FORWARD_LINKS_TO_PRODUCER=true")
+	}
+
+	// consumers returns the spans that may hold the backward link for a forward link target:
+	// the target itself and its parent (ReceiveOrder in the semconv form)
+	consumers := func(key string) []string {
+		keys := []string{key}
+		if s, ok := index[key]; ok && s.ParentSpanID != "" {
+			keys = append(keys, s.TraceID+"/"+s.ParentSpanID)
+		}
+		return keys
+	}
+
+	var missingForward []linkPair
+	for _, p := range backwardPairs {
+		matched := false
+		for c := range forward[p.producer] {
+			for _, holder := range consumers(c) {
+				if holder == p.consumer {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			missingForward = append(missingForward, p)
+		}
+	}
+
+	var missingBackward, dangling []linkPair
+	for _, p := range forwardPairs {
+		if _, ok := index[p.consumer]; !ok {
+			dangling = append(dangling, p)
+		}
+		matched := false
+		for _, holder := range consumers(p.consumer) {
+			if backward[holder][p.producer] {
+				matched = true
+			}
+		}
+		if !matched {
+			missingBackward = append(missingBackward, p)
+		}
+	}
+
+	var failures int
+	check := func(ok bool, format string, a ...any) {
+		status := "PASS"
+		if !ok {
+			status = "FAIL"
+			failures++
+		}
+		fmt.Printf("%s  %s\n", status, fmt.Sprintf(format, a...))
+	}
+	check(len(missingForward) == 0, "every backward link has a matching forward link (%d without)", len(missingForward))
+	listPairs(missingForward, *show)
+	check(len(missingBackward) == 0, "every forward link has a matching backward link (%d without)", len(missingBackward))
+	listPairs(missingBackward, *show)
+	check(len(dangling) == 0, "every forward link resolves to an exported consumer span (%d dangling)", len(dangling))
+	listPairs(dangling, *show)
+
+	if failures > 0 {
+		return fmt.Errorf("%d check(s) failed", failures)
+	}
+	return nil
+}
+
+// forwardSource returns the producer span a forward link stands for: the span holding it,
+// or the PublishOrder parent of a PublishOutcome span
+func forwardSource(s otlpjson.Span) string {
+	if s.Name == "PublishOutcome" && s.ParentSpanID != "" {
+		return s.TraceID + "/" + s.ParentSpanID
+	}
+	return s.Key()
+}
+
+func addPair(m map[string]map[string]bool, from, to string) {
+	if m[from] == nil {
+		m[from] = make(map[string]bool)
+	}
+	m[from][to] = true
+}
+
+// listPairs prints up to max asymmetric pairs under a check line
+func listPairs(pairs []linkPair, max int) {
+	for i, p := range pairs {
+		if i == max {
+			fmt.Printf("      ... and %d more\n", len(pairs)-max)
+			return
+		}
+		fmt.Printf("      order=%s producer=%s consumer=%s\n", p.orderID, p.producer, p.consumer)
+	}
+}
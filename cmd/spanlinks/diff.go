@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"span-links-signoz-demo/otlpjson"
+)
+
+// unexportedSpan stands for a link target missing from the file
+const unexportedSpan = "(not exported)"
+
+// EdgeDiff is one link edge kind and how often each run has it. Trace and span IDs differ
+// between runs, so edges are compared by shape: source span name, link.type, and target
+// span name (plus the -key attribute when set).
+type EdgeDiff struct {
+	From     string `json:"from"`
+	LinkType string `json:"link_type"`
+	To       string `json:"to"`
+	Key      string `json:"key,omitempty"`
+	Run1     int    `json:"run1"`
+	Run2     int    `json:"run2"`
+}
+
+// LinkGraphDiff is the diff report
+type LinkGraphDiff struct {
+	Added     []EdgeDiff `json:"added"`   // edges only run 2 has
+	Removed   []EdgeDiff `json:"removed"` // edges only run 1 has
+	Changed   []EdgeDiff `json:"changed"` // edges both have, in different numbers
+	Unchanged int        `json:"unchanged"`
+}
+
+// edgeShape identifies an edge across runs
+type edgeShape struct {
+	from, linkType, to, key string
+}
+
+// runDiff compares the link graphs of two recorded runs in collector file-exporter output
+// (two files, or one file holding both with -run1 and -run2) and reports the edge kinds
+// one run has and the other does not, and those whose number changed. Use it to document
+// what a configuration change does to the links, e.g. a backward-only run against one
+// with ENABLE_FORWARD_LINKS_TO_PRODUCER.
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	run1 := fs.String("run1", "", "only read spans of this run.id from the first file")
+	run2 := fs.String("run2", "", "only read spans of this run.id from the second file")
+	key := fs.String("key", "", "also tell edges apart by this link (else source span) attribute, e.g. order.id for runs with reproducible IDs")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	paths := fs.Args()
+	if len(paths) == 1 && *run1 != "" && *run2 != "" {
+		paths = append(paths, paths[0])
+	}
+	if len(paths) != 2 {
+		return errors.New("usage: spanlinks diff [-run1 ID] [-run2 ID] [-key ATTR] [-json] <run1.jsonl> <run2.jsonl>")
+	}
+
+	var edges [2]map[edgeShape]int
+	var spans, links [2]int
+	for i, run := range []string{*run1, *run2} {
+		s, err := otlpjson.LoadFile(paths[i])
+		if err != nil {
+			return err
+		}
+		if run != "" {
+			s = spansOfRun(s, run)
+		}
+		edges[i], links[i] = linkEdges(s, *key)
+		spans[i] = len(s)
+		if spans[i] == 0 {
+			return fmt.Errorf("no spans of run %d in %s", i+1, paths[i])
+		}
+	}
+
+	diff := diffEdges(edges[0], edges[1])
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(diff)
+	}
+
+	for i, run := range []string{*run1, *run2} {
+		fmt.Printf("run %d: %d spans, %d links, %d edge kinds (%s", i+1, spans[i], links[i], len(edges[i]), paths[i])
+		if run != "" {
+			fmt.Printf(" run.id=%s", run)
+		}
+		fmt.Println(")")
+	}
+	fmt.Println()
+	printEdges("Added (run 2 only)", "+", diff.Added)
+	printEdges("Removed (run 1 only)", "-", diff.Removed)
+	printEdges("Changed", "~", diff.Changed)
+	fmt.Printf("%d edge kinds unchanged\n", diff.Unchanged)
+	return nil
+}
+
+// spansOfRun keeps the spans whose resource (or, failing that, first link) has run.id run
+func spansOfRun(spans []otlpjson.Span, run string) []otlpjson.Span {
+	var out []otlpjson.Span
+	for _, s := range spans {
+		id := s.Resource["run.id"]
+		if id == "" && len(s.Links) > 0 {
+			id = s.Links[0].Attributes["run.id"]
+		}
+		if id == run {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// linkEdges counts the links of spans by shape, returning the counts and the link total
+func linkEdges(spans []otlpjson.Span, key string) (map[edgeShape]int, int) {
+	index := otlpjson.Index(spans)
+	edges := make(map[edgeShape]int)
+	var total int
+	for _, s := range spans {
+		for _, l := range s.Links {
+			shape := edgeShape{from: s.Name, linkType: l.Attributes["link.type"], to: unexportedSpan}
+			if shape.linkType == "" {
+				shape.linkType = "(none)"
+			}
+			if target, ok := index[l.Key()]; ok {
+				shape.to = target.Name
+			}
+			if key != "" {
+				shape.key = l.Attributes[key]
+				if shape.key == "" {
+					shape.key = s.Attributes[key]
+				}
+			}
+			edges[shape]++
+			total++
+		}
+	}
+	return edges, total
+}
+
+// diffEdges compares the edge counts of two runs
+func diffEdges(run1, run2 map[edgeShape]int) LinkGraphDiff {
+	diff := LinkGraphDiff{Added: []EdgeDiff{}, Removed: []EdgeDiff{}, Changed: []EdgeDiff{}}
+	for shape, n1 := range run1 {
+		n2 := run2[shape]
+		e := EdgeDiff{From: shape.from, LinkType: shape.linkType, To: shape.to, Key: shape.key, Run1: n1, Run2: n2}
+		switch {
+		case n2 == 0:
+			diff.Removed = append(diff.Removed, e)
+		case n1 != n2:
+			diff.Changed = append(diff.Changed, e)
+		default:
+			diff.Unchanged++
+		}
+	}
+	for shape, n2 := range run2 {
+		if run1[shape] == 0 {
+			diff.Added = append(diff.Added, EdgeDiff{From: shape.from, LinkType: shape.linkType, To: shape.to, Key: shape.key, Run2: n2})
+		}
+	}
+	for _, list := range [][]EdgeDiff{diff.Added, diff.Removed, diff.Changed} {
+		sort.Slice(list, func(i, j int) bool {
+			a, b := list[i], list[j]
+			if a.From != b.From {
+				return a.From < b.From
+			}
+			if a.LinkType != b.LinkType {
+				return a.LinkType < b.LinkType
+			}
+			if a.To != b.To {
+				return a.To < b.To
+			}
+			return a.Key < b.Key
+		})
+	}
+	return diff
+}
+
+// printEdges prints one section of the diff
+func printEdges(title, mark string, edges []EdgeDiff) {
+	fmt.Printf("%s: %d\n", title, len(edges))
+	for _, e := range edges {
+		edge := fmt.Sprintf("%s -[%s]-> %s", e.From, e.LinkType, e.To)
+		if e.Key != "" {
+			edge += " (" + e.Key + ")"
+		}
+		fmt.Printf("  %s %-70s %d -> %d\n", mark, edge, e.Run1, e.Run2)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// checkStatus is the outcome of one doctor check
+type checkStatus string
+
+const (
+	statusOK   checkStatus = " OK "
+	statusWarn checkStatus = "WARN"
+	statusFail checkStatus = "FAIL"
+)
+
+// OTLP transport protocols and their conventional ports, resolved the same way as the
+// demo's resolveOTLPTarget
+const (
+	protocolHTTP = "http/protobuf"
+	protocolGRPC = "grpc"
+	grpcPort     = "4317"
+	httpPort     = "4318"
+)
+
+// doctorReport collects check results and prints them as they arrive
+type doctorReport struct {
+	out      io.Writer
+	failures int
+}
+
+func (r *doctorReport) add(status checkStatus, check, detail, fix string) {
+	fmt.Fprintf(r.out, "[%s] %-28s %s\n", status, check, detail)
+	if fix != "" {
+		fmt.Fprintf(r.out, "       %-28s fix: %s\n", "", fix)
+	}
+	if status == statusFail {
+		r.failures++
+	}
+}
+
+// envKind is how a demo environment variable is parsed
+type envKind int
+
+const (
+	envBool envKind = iota
+	envNonNegativeInt
+	envInt
+	envPercent
+	envNonNegativeFloat
+	envPruneStrategy
+)
+
+// demoEnv lists the root app's tunables and how each must parse
+var demoEnv = []struct {
+	name string
+	kind envKind
+}{
+	{"ENABLE_FORWARD_LINKS_TO_PRODUCER", envBool},
+	{"ENABLE_TOPIC_ROUTING", envBool},
+	{"ENABLE_CONSUMER_GROUP", envBool},
+	{"KEY_AFFINITY", envBool},
+	{"STRICT_TRACEPARENT", envBool},
+	{"SEMCONV_SPAN_KINDS", envBool},
+	{"CONTINUOUS_RUN", envBool},
+	{"DASHBOARD", envBool},
+	{"ORDER_LATENCY_BUDGET_MS", envNonNegativeInt},
+	{"ORDER_CANCELLATIONS", envNonNegativeInt},
+	{"WORKER_HEARTBEAT_INTERVAL_MS", envNonNegativeInt},
+	{"BACKFILL_QUEUE_WAIT_MS", envNonNegativeInt},
+	{"CONSUMER_LAG_ALERT_MS", envNonNegativeInt},
+	{"ORDERING_WINDOW_MS", envNonNegativeInt},
+	{"ACK_BATCH_SIZE", envNonNegativeInt},
+	{"MAX_AGGREGATE_LINKS", envNonNegativeInt},
+	{"PUBLISH_RATE_BURST", envNonNegativeInt},
+	{"CLOCK_SKEW_MS", envInt},
+	{"WORKER_PANIC_PERCENT", envPercent},
+	{"WORKER_FAILURE_PERCENT", envPercent},
+	{"ORDER_SCHEMA_V2_PERCENT", envPercent},
+	{"PUBLISH_RATE_LIMIT", envNonNegativeFloat},
+	{"LINK_PRUNE_STRATEGY", envPruneStrategy},
+}
+
+// runDoctor validates the environment the demo would run with and sends a test span,
+// metric and log record to the configured OTLP endpoint, explaining any failure
+func runDoctor(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	timeout := fs.Duration("timeout", 5*time.Second, "timeout per export attempt")
+	skipExport := fs.Bool("offline", false, "only validate configuration, do not contact the endpoint")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Same .env handling as the demo app
+	_ = godotenv.Load()
+
+	r := &doctorReport{out: os.Stdout}
+	endpoint, protocol, ok := checkEndpoint(r, !*skipExport)
+	checkDemoEnv(r)
+	if ok && !*skipExport {
+		for _, signal := range []string{"traces", "metrics", "logs"} {
+			if protocol == protocolGRPC {
+				checkGRPCExport(r, endpoint, signal, *timeout)
+			} else {
+				checkExport(r, endpoint, signal, *timeout)
+			}
+		}
+	}
+
+	if r.failures > 0 {
+		return fmt.Errorf("%d check(s) failed", r.failures)
+	}
+	fmt.Println("\nAll checks passed.")
+	return nil
+}
+
+// checkEndpoint validates OTEL_EXPORTER_OTLP_ENDPOINT, OTEL_EXPORTER_OTLP_PROTOCOL and
+// the ingestion headers, and returns the endpoint and protocol the demo would export with.
+// Without an endpoint the local ports are probed like the demo does, unless probe is false.
+func checkEndpoint(r *doctorReport, probe bool) (*url.URL, string, bool) {
+	protocol := checkProtocol(r)
+	raw := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	if raw == "" {
+		raw = defaultEndpoint(protocol, probe)
+		r.add(statusWarn, "OTEL_EXPORTER_OTLP_ENDPOINT", "unset, checking "+raw,
+			"set it to your collector (http://localhost:4318) or SigNoz Cloud (https://ingest.<region>.signoz.cloud:443)")
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		r.add(statusFail, "OTEL_EXPORTER_OTLP_ENDPOINT", fmt.Sprintf("%q is not a URL", raw),
+			"use scheme://host:port, e.g. http://localhost:4318")
+		return nil, "", false
+	}
+	if strings.Contains(u.Host, "<") || strings.Contains(u.Host, ">") {
+		r.add(statusFail, "OTEL_EXPORTER_OTLP_ENDPOINT", fmt.Sprintf("%q still contains a placeholder", raw),
+			"replace <region> with your SigNoz Cloud region (us, in, eu)")
+		return nil, "", false
+	}
+	r.add(statusOK, "OTEL_EXPORTER_OTLP_ENDPOINT", u.String(), "")
+
+	switch {
+	case protocol == "" && u.Port() == grpcPort:
+		protocol = protocolGRPC
+		r.add(statusOK, "export protocol", "OTLP/gRPC (inferred from port "+grpcPort+")", "")
+	case protocol == "":
+		protocol = protocolHTTP
+		r.add(statusOK, "export protocol", "OTLP/HTTP", "")
+	case protocol == protocolHTTP && u.Port() == grpcPort:
+		r.add(statusWarn, "endpoint port", grpcPort+" is the OTLP/gRPC port, but the protocol is "+protocolHTTP,
+			fmt.Sprintf("use port %s, or OTEL_EXPORTER_OTLP_PROTOCOL=%s", httpPort, protocolGRPC))
+	case protocol == protocolGRPC && u.Port() == httpPort:
+		r.add(statusWarn, "endpoint port", httpPort+" is the OTLP/HTTP port, but the protocol is "+protocolGRPC,
+			fmt.Sprintf("use port %s, or OTEL_EXPORTER_OTLP_PROTOCOL=%s", grpcPort, protocolHTTP))
+	}
+	if u.Scheme == "http" && u.Port() == "443" {
+		r.add(statusWarn, "endpoint scheme", "plain http on port 443", "use https:// for port 443")
+	}
+
+	cloud := strings.HasSuffix(u.Hostname(), "signoz.cloud")
+	if cloud && u.Scheme != "https" {
+		r.add(statusFail, "endpoint scheme", "SigNoz Cloud requires TLS", "use https://ingest.<region>.signoz.cloud:443")
+	}
+
+	for _, signal := range []string{"", "TRACES_", "METRICS_"} {
+		name := "OTEL_EXPORTER_OTLP_" + signal + "HEADERS"
+		val := os.Getenv(name)
+		if val == "" {
+			continue
+		}
+		if strings.Contains(val, "YOUR_INGESTION_KEY") {
+			r.add(statusFail, name, "still contains the .env.example placeholder key",
+				"paste the key from SigNoz Cloud: Settings → Ingestion Settings")
+			continue
+		}
+		if len(parseHeaders(val)) == 0 {
+			r.add(statusFail, name, "no key=value pairs found", "use key=value pairs separated by commas; URL-encode ',' and '=' in values")
+			continue
+		}
+		r.add(statusOK, name, fmt.Sprintf("%d header(s)", len(parseHeaders(val))), "")
+	}
+
+	if cloud {
+		if _, ok := exportHeaders("traces")["signoz-ingestion-key"]; !ok {
+			r.add(statusFail, "ingestion key", "no signoz-ingestion-key header for SigNoz Cloud",
+				"set OTEL_EXPORTER_OTLP_HEADERS=signoz-ingestion-key=<your key>")
+		}
+	}
+	return u, protocol, true
+}
+
+// checkProtocol validates OTEL_EXPORTER_OTLP_PROTOCOL; "" means the protocol follows the
+// endpoint's port, as in the demo
+func checkProtocol(r *doctorReport) string {
+	const name = "OTEL_EXPORTER_OTLP_PROTOCOL"
+	protocol := strings.ToLower(os.Getenv(name))
+	switch protocol {
+	case "":
+		return ""
+	case protocolHTTP, protocolGRPC:
+		r.add(statusOK, name, protocol, "")
+	case "http", "http/json":
+		r.add(statusWarn, name, fmt.Sprintf("%q is not supported, the demo uses %s", protocol, protocolHTTP),
+			"set it to "+protocolHTTP+" or "+protocolGRPC)
+		protocol = protocolHTTP
+	default:
+		r.add(statusWarn, name, fmt.Sprintf("unknown value %q is ignored", protocol),
+			"set it to "+protocolHTTP+" or "+protocolGRPC)
+		protocol = ""
+	}
+	return protocol
+}
+
+// defaultEndpoint is the local collector the demo falls back to without an endpoint:
+// the protocol's port, or whichever of 4318 and 4317 answers first when probing
+func defaultEndpoint(protocol string, probe bool) string {
+	switch {
+	case protocol == protocolGRPC:
+		return "http://localhost:" + grpcPort
+	case protocol == "" && probe && !portOpen("localhost:"+httpPort) && portOpen("localhost:"+grpcPort):
+		return "http://localhost:" + grpcPort
+	}
+	return "http://localhost:" + httpPort
+}
+
+// portOpen reports whether a TCP connection to addr succeeds
+func portOpen(addr string) bool {
+	conn, err := net.DialTimeout("tcp", addr, 300*time.Millisecond)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// checkDemoEnv validates the demo's own tunables
+func checkDemoEnv(r *doctorReport) {
+	var invalid int
+	for _, v := range demoEnv {
+		val := os.Getenv(v.name)
+		if val == "" {
+			continue
+		}
+		if msg := validateEnv(v.kind, val); msg != "" {
+			r.add(statusWarn, v.name, fmt.Sprintf("%q is %s; the demo will ignore it", val, msg), "")
+			invalid++
+		}
+	}
+	if invalid == 0 {
+		r.add(statusOK, "demo settings", "all set values parse", "")
+	}
+}
+
+func validateEnv(kind envKind, val string) string {
+	switch kind {
+	case envBool:
+		if _, err := strconv.ParseBool(val); err != nil {
+			return "not a boolean (true/false)"
+		}
+	case envNonNegativeInt:
+		if n, err := strconv.Atoi(val); err != nil || n < 0 {
+			return "not a non-negative integer"
+		}
+	case envInt:
+		if _, err := strconv.Atoi(val); err != nil {
+			return "not an integer"
+		}
+	case envPercent:
+		if p, err := strconv.ParseFloat(val, 64); err != nil || p < 0 || p > 100 {
+			return "not a percentage (0-100)"
+		}
+	case envNonNegativeFloat:
+		if f, err := strconv.ParseFloat(val, 64); err != nil || f < 0 {
+			return "not a non-negative number"
+		}
+	case envPruneStrategy:
+		switch val {
+		case "first", "last", "reservoir":
+		default:
+			return "not first, last or reservoir"
+		}
+	}
+	return ""
+}
+
+// exportHeaders returns the headers sent with signal ("traces", "metrics", "logs")
+func exportHeaders(signal string) map[string]string {
+	headers := parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"))
+	for k, v := range parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_" + strings.ToUpper(signal) + "_HEADERS")) {
+		headers[k] = v
+	}
+	return headers
+}
+
+// checkExport posts a minimal OTLP/JSON payload for signal and explains the response
+func checkExport(r *doctorReport, endpoint *url.URL, signal string, timeout time.Duration) {
+	check := "export " + signal
+	target := strings.TrimSuffix(endpoint.String(), "/") + "/v1/" + signal
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(testPayload(signal)))
+	if err != nil {
+		r.add(statusFail, check, err.Error(), "")
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range exportHeaders(signal) {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		status, detail, fix := explainTransportError(err, endpoint)
+		r.add(status, check, detail, fix)
+		return
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		r.add(statusOK, check, fmt.Sprintf("HTTP %d from %s", resp.StatusCode, target), "")
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		r.add(statusFail, check, fmt.Sprintf("HTTP %d: authentication rejected", resp.StatusCode),
+			"check the signoz-ingestion-key header value (and its region)")
+	case resp.StatusCode == http.StatusNotFound:
+		r.add(statusFail, check, fmt.Sprintf("HTTP 404 for %s", target),
+			"the endpoint does not serve OTLP/HTTP here; use the collector's "+httpPort+" port and no extra path")
+	case resp.StatusCode == http.StatusUnsupportedMediaType:
+		r.add(statusWarn, check, "HTTP 415: endpoint rejects OTLP/JSON (the demo itself sends protobuf)", "")
+	default:
+		r.add(statusFail, check, fmt.Sprintf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body))), "")
+	}
+}
+
+// explainTransportError turns a failed request into a diagnosis and suggested fix
+func explainTransportError(err error, endpoint *url.URL) (checkStatus, string, string) {
+	var dnsErr *net.DNSError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return statusFail, "timed out", "check network access to " + endpoint.Host + " (firewall, proxy, VPN)"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		fix := "start the collector / SigNoz, or fix the host and port"
+		if endpoint.Port() == grpcPort {
+			fix = fmt.Sprintf("nothing listens on %s; OTLP/HTTP is usually on %s", grpcPort, httpPort)
+		}
+		return statusFail, "connection refused by " + endpoint.Host, fix
+	case errors.As(err, &dnsErr):
+		return statusFail, "cannot resolve " + endpoint.Hostname(), "check the hostname (and the region for SigNoz Cloud)"
+	case strings.Contains(err.Error(), "malformed HTTP response"):
+		return statusFail, "endpoint answered with HTTP/2 frames (a gRPC port)",
+			fmt.Sprintf("use port %s for OTLP/HTTP, or OTEL_EXPORTER_OTLP_PROTOCOL=%s", httpPort, protocolGRPC)
+	case strings.Contains(err.Error(), "server gave HTTP response to HTTPS client"):
+		return statusFail, "TLS requested but the endpoint speaks plain HTTP", "use http:// for this endpoint"
+	case strings.Contains(err.Error(), "tls:") || strings.Contains(err.Error(), "x509"):
+		return statusFail, "TLS handshake failed: " + err.Error(), "use http:// for a local collector, https:// for SigNoz Cloud"
+	default:
+		return statusFail, err.Error(), ""
+	}
+}
+
+// testPayload returns a one-item OTLP/JSON export request for signal
+func testPayload(signal string) []byte {
+	now := strconv.FormatInt(time.Now().UnixNano(), 10)
+	resource := `{"attributes":[{"key":"service.name","value":{"stringValue":"spanlinks-doctor"}}]}`
+	switch signal {
+	case "metrics":
+		return []byte(`{"resourceMetrics":[{"resource":` + resource + `,"scopeMetrics":[{"scope":{"name":"spanlinks-doctor"},"metrics":[{"name":"spanlinks.doctor.check","sum":{"dataPoints":[{"asInt":"1","timeUnixNano":"` + now + `"}],"aggregationTemporality":2,"isMonotonic":true}}]}]}]}`)
+	case "logs":
+		return []byte(`{"resourceLogs":[{"resource":` + resource + `,"scopeLogs":[{"scope":{"name":"spanlinks-doctor"},"logRecords":[{"timeUnixNano":"` + now + `","severityText":"INFO","body":{"stringValue":"spanlinks doctor test log"}}]}]}]}`)
+	default:
+		traceID, spanID := make([]byte, 16), make([]byte, 8)
+		_, _ = rand.Read(traceID)
+		_, _ = rand.Read(spanID)
+		return []byte(`{"resourceSpans":[{"resource":` + resource + `,"scopeSpans":[{"scope":{"name":"spanlinks-doctor"},"spans":[{"traceId":"` + hex.EncodeToString(traceID) + `","spanId":"` + hex.EncodeToString(spanID) + `","name":"DoctorCheck","kind":1,"startTimeUnixNano":"` + now + `","endTimeUnixNano":"` + now + `"}]}]}]}`)
+	}
+}
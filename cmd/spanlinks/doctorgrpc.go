@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	collectorlogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// checkGRPCExport sends a one-item OTLP/gRPC export request for signal and explains the
+// response, as checkExport does for OTLP/HTTP
+func checkGRPCExport(r *doctorReport, endpoint *url.URL, signal string, timeout time.Duration) {
+	check := "export " + signal
+
+	creds := insecure.NewCredentials()
+	if endpoint.Scheme == "https" {
+		creds = credentials.NewTLS(&tls.Config{})
+	}
+	conn, err := grpc.NewClient(endpoint.Host, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		r.add(statusFail, check, err.Error(), "")
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	ctx = metadata.NewOutgoingContext(ctx, metadata.New(exportHeaders(signal)))
+
+	resource := &resourcepb.Resource{Attributes: []*commonpb.KeyValue{{
+		Key:   "service.name",
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "spanlinks-doctor"}},
+	}}}
+	scope := &commonpb.InstrumentationScope{Name: "spanlinks-doctor"}
+	now := uint64(time.Now().UnixNano())
+	switch signal {
+	case "metrics":
+		_, err = collectormetrics.NewMetricsServiceClient(conn).Export(ctx, &collectormetrics.ExportMetricsServiceRequest{
+			ResourceMetrics: []*metricspb.ResourceMetrics{{
+				Resource: resource,
+				ScopeMetrics: []*metricspb.ScopeMetrics{{Scope: scope, Metrics: []*metricspb.Metric{{
+					Name: "spanlinks.doctor.check",
+					Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+						DataPoints:             []*metricspb.NumberDataPoint{{TimeUnixNano: now, Value: &metricspb.NumberDataPoint_AsInt{AsInt: 1}}},
+						AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+						IsMonotonic:            true,
+					}},
+				}}}},
+			}},
+		})
+	case "logs":
+		_, err = collectorlogs.NewLogsServiceClient(conn).Export(ctx, &collectorlogs.ExportLogsServiceRequest{
+			ResourceLogs: []*logspb.ResourceLogs{{
+				Resource: resource,
+				ScopeLogs: []*logspb.ScopeLogs{{Scope: scope, LogRecords: []*logspb.LogRecord{{
+					TimeUnixNano: now,
+					SeverityText: "INFO",
+					Body:         &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "spanlinks doctor test log"}},
+				}}}},
+			}},
+		})
+	default:
+		traceID, spanID := make([]byte, 16), make([]byte, 8)
+		_, _ = rand.Read(traceID)
+		_, _ = rand.Read(spanID)
+		_, err = collectortrace.NewTraceServiceClient(conn).Export(ctx, &collectortrace.ExportTraceServiceRequest{
+			ResourceSpans: []*tracepb.ResourceSpans{{
+				Resource: resource,
+				ScopeSpans: []*tracepb.ScopeSpans{{Scope: scope, Spans: []*tracepb.Span{{
+					TraceId:           traceID,
+					SpanId:            spanID,
+					Name:              "DoctorCheck",
+					Kind:              tracepb.Span_SPAN_KIND_INTERNAL,
+					StartTimeUnixNano: now,
+					EndTimeUnixNano:   now,
+				}}}},
+			}},
+		})
+	}
+	if err != nil {
+		status, detail, fix := explainGRPCError(err, endpoint)
+		r.add(status, check, detail, fix)
+		return
+	}
+	r.add(statusOK, check, "OTLP/gRPC export accepted by "+endpoint.Host, "")
+}
+
+// explainGRPCError turns a failed gRPC export into a diagnosis and suggested fix
+func explainGRPCError(err error, endpoint *url.URL) (checkStatus, string, string) {
+	st := status.Convert(err)
+	msg := st.Message()
+	switch st.Code() {
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return statusFail, fmt.Sprintf("%s: authentication rejected", st.Code()),
+			"check the signoz-ingestion-key header value (and its region)"
+	case codes.Unimplemented:
+		return statusFail, "endpoint does not serve OTLP/gRPC: " + msg,
+			fmt.Sprintf("use the collector's %s port, or OTEL_EXPORTER_OTLP_PROTOCOL=%s for an OTLP/HTTP endpoint", grpcPort, protocolHTTP)
+	case codes.DeadlineExceeded:
+		return statusFail, "timed out", "check network access to " + endpoint.Host + " (firewall, proxy, VPN)"
+	}
+	switch {
+	case strings.Contains(msg, "connection refused"):
+		fix := "start the collector / SigNoz, or fix the host and port"
+		if endpoint.Port() == httpPort {
+			fix = fmt.Sprintf("nothing listens on %s; OTLP/gRPC is usually on %s", httpPort, grpcPort)
+		}
+		return statusFail, "connection refused by " + endpoint.Host, fix
+	case strings.Contains(msg, "no such host"):
+		return statusFail, "cannot resolve " + endpoint.Hostname(), "check the hostname (and the region for SigNoz Cloud)"
+	case strings.Contains(msg, "tls:") || strings.Contains(msg, "x509") || strings.Contains(msg, "handshake"):
+		return statusFail, "TLS handshake failed: " + msg, "use http:// for a local collector, https:// for SigNoz Cloud"
+	case strings.Contains(msg, "HTTP/1") || strings.Contains(msg, "frame") || strings.Contains(msg, "malformed"):
+		return statusFail, "endpoint does not speak HTTP/2 (an OTLP/HTTP port?): " + msg,
+			fmt.Sprintf("use port %s for OTLP/gRPC, or OTEL_EXPORTER_OTLP_PROTOCOL=%s", grpcPort, protocolHTTP)
+	default:
+		return statusFail, fmt.Sprintf("%s: %s", st.Code(), msg), ""
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"span-links-signoz-demo/telemetry"
+)
+
+// alertQuery is a query of an alert rule, optionally filtered by attribute values
+type alertQuery struct {
+	panelQuery
+	where map[string]string
+}
+
+// alertRule is a threshold alert over one query or a formula of several
+type alertRule struct {
+	name      string
+	summary   string
+	severity  string
+	unit      string
+	queries   []alertQuery
+	formula   string // over the query names A, B, ...; empty alerts on A
+	threshold float64
+	once      bool // fire when the condition holds at any point of the window
+}
+
+// SigNoz rule condition operators and match types
+const (
+	signozOpAbove          = "1"
+	signozMatchAtLeastOnce = "1" // the condition held at some point of the evaluation window
+	signozMatchAllTime     = "2" // the condition held for the whole evaluation window
+)
+
+// runGenAlerts writes SigNoz alert rule payloads for SLO breaches of the demo: processing
+// p99 over budget, order error rate over a percentage, consumer lag p95 over a threshold
+// and any publish → processed latency budget breach
+func runGenAlerts(args []string) error {
+	fs := flag.NewFlagSet("gen-alerts", flag.ExitOnError)
+	processingBudget := fs.Duration("processing-budget", 500*time.Millisecond, "alert when processing p99 exceeds this")
+	errorRate := fs.Float64("error-rate", 1, "alert when more than this percentage of orders fail")
+	lag := fs.Duration("lag", time.Second, "alert when consumer lag p95 exceeds this (0 disables the rule)")
+	window := fs.Duration("window", 5*time.Minute, "evaluation window; conditions must hold for all of it (latency budget breaches: any point)")
+	service := fs.String("service", "", "only evaluate metrics of this service.name (empty = all)")
+	out := fs.String("o", "", "output file (default stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *errorRate <= 0 || *errorRate > 100 {
+		return fmt.Errorf("-error-rate must be in (0, 100], got %g", *errorRate)
+	}
+
+	rules := []alertRule{
+		{
+			name:     "Order processing p99 over budget",
+			summary:  fmt.Sprintf("Order processing p99 is above %s", *processingBudget),
+			severity: "warning",
+			unit:     "ms",
+			queries: []alertQuery{
+				{panelQuery: panelQuery{metric: telemetry.MetricOrdersProcessingDuration, agg: "p99"}},
+			},
+			threshold: float64(processingBudget.Milliseconds()),
+		},
+		{
+			name:     "Order error rate high",
+			summary:  fmt.Sprintf("More than %g%% of orders fail processing", *errorRate),
+			severity: "critical",
+			unit:     "percent",
+			queries: []alertQuery{
+				{panelQuery: panelQuery{metric: telemetry.MetricOrdersProcessed, agg: "rate"}, where: map[string]string{telemetry.OutcomeKey: telemetry.OutcomeFailure}},
+				{panelQuery: panelQuery{metric: telemetry.MetricOrdersProcessed, agg: "rate"}},
+			},
+			formula:   "A / B * 100",
+			threshold: *errorRate,
+		},
+		{
+			name:     "Order latency budget breached",
+			summary:  "Orders exceed the publish → processed latency budget (LATENCY_BUDGET_MS); SLOBreach spans link to them",
+			severity: "warning",
+			unit:     "ops",
+			queries: []alertQuery{
+				{panelQuery: panelQuery{metric: telemetry.MetricOrdersSLOBreaches, agg: "rate"}},
+			},
+			once: true,
+		},
+	}
+	if *lag > 0 {
+		rules = append(rules, alertRule{
+			name:     "Consumer lag high",
+			summary:  fmt.Sprintf("Consumer lag p95 is above %s", *lag),
+			severity: "warning",
+			unit:     "ms",
+			queries: []alertQuery{
+				{panelQuery: panelQuery{metric: telemetry.MetricOrdersConsumerLag, agg: "p95"}},
+			},
+			threshold: float64(lag.Milliseconds()),
+		})
+	}
+
+	payloads := make([]map[string]any, 0, len(rules))
+	for _, r := range rules {
+		for _, q := range r.queries {
+			m, ok := telemetry.LookupMetric(q.metric)
+			if !ok || !aggregationFits(m.Kind, q.agg) {
+				return fmt.Errorf("rule %q: invalid query %s(%s)", r.name, q.agg, q.metric)
+			}
+		}
+		payloads = append(payloads, signozAlertRule(r, *service, *window))
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(payloads); err != nil {
+		return err
+	}
+	if *out != "" {
+		fmt.Fprintf(os.Stderr, "Wrote %d alert rules to %s\n", len(payloads), *out)
+	}
+	return nil
+}
+
+// signozAlertRule renders r as a SigNoz threshold rule payload (POST /api/v1/rules)
+func signozAlertRule(r alertRule, service string, window time.Duration) map[string]any {
+	queries := make(map[string]any, len(r.queries)+1)
+	selected := "A"
+	for i, q := range r.queries {
+		name := string(rune('A' + i))
+		query := signozQuery(q.panelQuery, name, service, q.where)
+		query["disabled"] = r.formula != "" // only the formula is evaluated
+		queries[name] = query
+	}
+	if r.formula != "" {
+		selected = "F1"
+		queries[selected] = map[string]any{"queryName": selected, "expression": r.formula, "disabled": false}
+	}
+
+	matchType := signozMatchAllTime
+	if r.once {
+		matchType = signozMatchAtLeastOnce
+	}
+
+	return map[string]any{
+		"alert":       r.name,
+		"alertType":   "METRIC_BASED_ALERT",
+		"ruleType":    "threshold_rule",
+		"version":     "v4",
+		"description": r.summary,
+		"evalWindow":  window.String(),
+		"frequency":   "1m0s",
+		"condition": map[string]any{
+			"compositeQuery": map[string]any{
+				"queryType":      "builder",
+				"panelType":      "graph",
+				"unit":           r.unit,
+				"builderQueries": queries,
+			},
+			"op":                signozOpAbove,
+			"matchType":         matchType,
+			"target":            r.threshold,
+			"targetUnit":        r.unit,
+			"selectedQueryName": selected,
+		},
+		"labels": map[string]string{"severity": r.severity, "app": "span-links-demo"},
+		"annotations": map[string]string{
+			"summary":     r.summary,
+			"description": fmt.Sprintf("%s ({{$value}} against a threshold of {{$threshold}})", r.summary),
+		},
+		"preferredChannels": []string{},
+		"disabled":          false,
+	}
+}
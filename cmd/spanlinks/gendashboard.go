@@ -0,0 +1,390 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"span-links-signoz-demo/telemetry"
+)
+
+// panelQuery is one series query of a dashboard panel
+type panelQuery struct {
+	metric  string
+	agg     string // rate (counters), p50/p95/p99 (histograms), avg or max (gauges)
+	groupBy []string
+	legend  string
+}
+
+// dashboardPanel is one time-series panel
+type dashboardPanel struct {
+	title   string
+	unit    string // Grafana unit id; SigNoz accepts the same ids
+	queries []panelQuery
+}
+
+// dashboardRow is a titled group of panels
+type dashboardRow struct {
+	title  string
+	panels []dashboardPanel
+}
+
+// demoDashboard lays out the demo's metrics (see telemetry.Metrics)
+var demoDashboard = []dashboardRow{
+	{"Queue", []dashboardPanel{
+		{"Queue depth", "short", []panelQuery{{telemetry.MetricOrdersQueueDepth, "max", nil, "pending orders"}}},
+		{"Consumer lag p95", "ms", []panelQuery{{telemetry.MetricOrdersConsumerLag, "p95", []string{"messaging.destination.name"}, "{{messaging.destination.name}}"}}},
+		{"Queue messages / s by topic", "ops", []panelQuery{
+			{telemetry.MetricQueuePublished, "rate", []string{"messaging.destination.name"}, "published {{messaging.destination.name}}"},
+			{telemetry.MetricQueueConsumed, "rate", []string{"messaging.destination.name"}, "consumed {{messaging.destination.name}}"},
+			{telemetry.MetricQueueAcked, "rate", []string{"messaging.destination.name"}, "acked {{messaging.destination.name}}"},
+		}},
+		{"Publish to consume p95 by link direction", "ms", []panelQuery{{telemetry.MetricQueuePublishToConsume, "p95", []string{"link.direction", "messaging.destination.name"}, "{{link.direction}} {{messaging.destination.name}}"}}},
+	}},
+	{"Throughput", []dashboardPanel{
+		{"Orders published / s", "ops", []panelQuery{{telemetry.MetricOrdersPublished, "rate", []string{"messaging.destination.name"}, "{{messaging.destination.name}}"}}},
+		{"Orders processed / s", "ops", []panelQuery{{telemetry.MetricOrdersProcessed, "rate", []string{telemetry.OutcomeKey}, "{{" + telemetry.OutcomeKey + "}}"}}},
+	}},
+	{"Latency", []dashboardPanel{
+		{"Processing duration", "ms", []panelQuery{
+			{telemetry.MetricOrdersProcessingDuration, "p50", nil, "p50"},
+			{telemetry.MetricOrdersProcessingDuration, "p95", nil, "p95"},
+			{telemetry.MetricOrdersProcessingDuration, "p99", nil, "p99"},
+		}},
+		{"SLO breaches / s", "ops", []panelQuery{{telemetry.MetricOrdersSLOBreaches, "rate", nil, "breaches"}}},
+	}},
+	{"Links", []dashboardPanel{
+		{"Links / s by type", "ops", []panelQuery{{telemetry.MetricSpanLinks, "rate", []string{"link.type"}, "{{link.type}}"}}},
+		{"Linked traces per span p95", "short", []panelQuery{{telemetry.MetricSpanLinkedTraces, "p95", []string{"span.name"}, "{{span.name}}"}}},
+		{"Link attribute values suppressed / s", "ops", []panelQuery{{telemetry.MetricLinkAttributeSuppressed, "rate", []string{"attribute.key"}, "{{attribute.key}}"}}},
+	}},
+	{"Run health", []dashboardPanel{
+		{"Error budget", "percentunit", []panelQuery{
+			{telemetry.MetricErrorBudgetSuccessRate, "max", nil, "success rate"},
+			{telemetry.MetricErrorBudgetConsumed, "max", nil, "budget consumed"},
+		}},
+		{"Span export / s", "ops", []panelQuery{
+			{telemetry.MetricSpansSpilled, "rate", nil, "spilled"},
+			{telemetry.MetricSpansRecovered, "rate", nil, "recovered"},
+			{telemetry.MetricSpansDropped, "rate", nil, "dropped"},
+		}},
+	}},
+}
+
+// runGenDashboard writes a dashboard JSON for the demo's metrics, in SigNoz or Grafana
+// (Prometheus data source) form
+func runGenDashboard(args []string) error {
+	fs := flag.NewFlagSet("gen-dashboard", flag.ExitOnError)
+	format := fs.String("format", "signoz", "dashboard format: signoz or grafana")
+	service := fs.String("service", "", "only show metrics of this service.name (empty = all)")
+	title := fs.String("title", "Span Links Demo", "dashboard title")
+	out := fs.String("o", "", "output file (default stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := validateDashboard(demoDashboard); err != nil {
+		return err
+	}
+
+	var dashboard any
+	switch *format {
+	case "signoz":
+		dashboard = signozDashboard(*title, *service, demoDashboard)
+	case "grafana":
+		dashboard = grafanaDashboard(*title, *service, demoDashboard)
+	default:
+		return fmt.Errorf("unknown format %q (expected signoz or grafana)", *format)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(dashboard); err != nil {
+		return err
+	}
+	if *out != "" {
+		fmt.Fprintf(os.Stderr, "Wrote %s dashboard to %s\n", *format, *out)
+	}
+	return nil
+}
+
+// validateDashboard checks that every query names an emitted metric with an aggregation
+// that fits its kind
+func validateDashboard(rows []dashboardRow) error {
+	var errs []error
+	for _, row := range rows {
+		for _, p := range row.panels {
+			for _, q := range p.queries {
+				m, ok := telemetry.LookupMetric(q.metric)
+				if !ok {
+					errs = append(errs, fmt.Errorf("panel %q: unknown metric %s", p.title, q.metric))
+					continue
+				}
+				if !aggregationFits(m.Kind, q.agg) {
+					errs = append(errs, fmt.Errorf("panel %q: %s cannot be applied to %s %s", p.title, q.agg, m.Kind, m.Name))
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func aggregationFits(kind telemetry.MetricKind, agg string) bool {
+	switch kind {
+	case telemetry.Counter:
+		return agg == "rate"
+	case telemetry.Histogram:
+		return quantile(agg) > 0
+	default:
+		return agg == "avg" || agg == "max"
+	}
+}
+
+// quantile returns the quantile of a pNN aggregation (0 if agg is not one)
+func quantile(agg string) float64 {
+	var p float64
+	if _, err := fmt.Sscanf(agg, "p%g", &p); err != nil || p <= 0 || p >= 100 {
+		return 0
+	}
+	return p / 100
+}
+
+// gridPos returns the column, width and height of panel i of a row on a 24-column grid,
+// up to three panels per line
+func gridPos(i, count int) (x, w, h int) {
+	perLine := min(count, 3)
+	w = 24 / perLine
+	return (i % perLine) * w, w, 8
+}
+
+// --- Grafana (Prometheus data source, metrics exported through the collector) ---
+
+// promName returns the Prometheus name the collector's Prometheus exporter gives m: dots
+// become underscores, the unit is appended as a suffix and counters end in _total
+func promName(m telemetry.Metric) string {
+	name := promLabel(m.Name)
+	switch m.Unit {
+	case "ms":
+		name += "_milliseconds"
+	case "s":
+		name += "_seconds"
+	}
+	if m.Kind == telemetry.Counter {
+		name += "_total"
+	}
+	return name
+}
+
+// promLabel returns the Prometheus form of an attribute or metric name
+func promLabel(s string) string {
+	return strings.NewReplacer(".", "_", "-", "_").Replace(s)
+}
+
+func promExpr(q panelQuery, service string) string {
+	m, _ := telemetry.LookupMetric(q.metric)
+	selector := ""
+	if service != "" {
+		selector = fmt.Sprintf(`{job=%q}`, service)
+	}
+	by := make([]string, len(q.groupBy))
+	for i, g := range q.groupBy {
+		by[i] = promLabel(g)
+	}
+	switch {
+	case q.agg == "rate":
+		return fmt.Sprintf("sum by (%s) (rate(%s%s[$__rate_interval]))", strings.Join(by, ", "), promName(m), selector)
+	case quantile(q.agg) > 0:
+		return fmt.Sprintf("histogram_quantile(%g, sum by (%s) (rate(%s_bucket%s[$__rate_interval])))",
+			quantile(q.agg), strings.Join(append([]string{"le"}, by...), ", "), promName(m), selector)
+	default:
+		return fmt.Sprintf("%s by (%s) (%s%s)", q.agg, strings.Join(by, ", "), promName(m), selector)
+	}
+}
+
+func grafanaDashboard(title, service string, rows []dashboardRow) map[string]any {
+	datasource := map[string]any{"type": "prometheus", "uid": "${datasource}"}
+	var panels []map[string]any
+	id, y := 1, 0
+	for _, row := range rows {
+		panels = append(panels, map[string]any{
+			"id": id, "type": "row", "title": row.title, "collapsed": false,
+			"gridPos": map[string]int{"x": 0, "y": y, "w": 24, "h": 1},
+		})
+		id++
+		y++
+		for i, p := range row.panels {
+			x, w, h := gridPos(i, len(row.panels))
+			targets := make([]map[string]any, len(p.queries))
+			for j, q := range p.queries {
+				targets[j] = map[string]any{
+					"refId":        string(rune('A' + j)),
+					"datasource":   datasource,
+					"expr":         promExpr(q, service),
+					"legendFormat": promLegend(q.legend),
+				}
+			}
+			panels = append(panels, map[string]any{
+				"id": id, "type": "timeseries", "title": p.title, "datasource": datasource,
+				"gridPos":     map[string]int{"x": x, "y": y + (i/3)*h, "w": w, "h": h},
+				"fieldConfig": map[string]any{"defaults": map[string]any{"unit": p.unit}, "overrides": []any{}},
+				"targets":     targets,
+			})
+			id++
+		}
+		y += ((len(row.panels) + 2) / 3) * 8
+	}
+	return map[string]any{
+		"title":         title,
+		"uid":           "span-links-demo",
+		"tags":          []string{"span-links", "demo"},
+		"schemaVersion": 39,
+		"time":          map[string]string{"from": "now-30m", "to": "now"},
+		"refresh":       "10s",
+		"templating": map[string]any{"list": []map[string]any{
+			{"name": "datasource", "type": "datasource", "query": "prometheus", "label": "Data source"},
+		}},
+		"panels": panels,
+	}
+}
+
+// promLegend rewrites {{attr.name}} legends to Prometheus label names
+func promLegend(legend string) string {
+	return strings.ReplaceAll(legend, ".", "_")
+}
+
+// --- SigNoz (query builder over the OTLP metrics SigNoz stores) ---
+
+func signozDashboard(title, service string, rows []dashboardRow) map[string]any {
+	var layout, widgets []map[string]any
+	y := 0
+	for _, row := range rows {
+		rowID := "row-" + promLabel(strings.ToLower(row.title))
+		layout = append(layout, map[string]any{"i": rowID, "x": 0, "y": y, "w": 12, "h": 1, "static": true})
+		widgets = append(widgets, map[string]any{"id": rowID, "panelTypes": "row", "title": row.title, "description": ""})
+		y++
+		for i, p := range row.panels {
+			// SigNoz layouts are 12 columns wide
+			x, w, h := gridPos(i, len(row.panels))
+			id := promLabel(strings.ToLower(p.title))
+			id = strings.NewReplacer(" ", "-", "/", "per").Replace(id)
+			layout = append(layout, map[string]any{"i": id, "x": x / 2, "y": y + (i/3)*h, "w": w / 2, "h": h})
+			widgets = append(widgets, map[string]any{
+				"id":          id,
+				"title":       p.title,
+				"description": panelDescription(p),
+				"panelTypes":  "graph",
+				"yAxisUnit":   p.unit,
+				"query": map[string]any{
+					"queryType": "builder",
+					"builder": map[string]any{
+						"queryData":     signozQueries(p.queries, service),
+						"queryFormulas": []any{},
+					},
+				},
+			})
+		}
+		y += ((len(row.panels) + 2) / 3) * 8
+	}
+	return map[string]any{
+		"title":       title,
+		"description": "Queue, throughput, latency and link metrics of the span links demo (generated by spanlinks gen-dashboard)",
+		"tags":        []string{"span-links", "demo"},
+		"version":     "v4",
+		"layout":      layout,
+		"widgets":     widgets,
+	}
+}
+
+func signozQueries(queries []panelQuery, service string) []map[string]any {
+	out := make([]map[string]any, len(queries))
+	for i, q := range queries {
+		out[i] = signozQuery(q, string(rune('A'+i)), service, nil)
+	}
+	return out
+}
+
+// signozQuery builds one query builder query named name; where adds attribute = value
+// filters on top of the service filter
+func signozQuery(q panelQuery, name, service string, where map[string]string) map[string]any {
+	m, _ := telemetry.LookupMetric(q.metric)
+
+	metricType, timeAgg, spaceAgg := "Gauge", "latest", q.agg
+	switch m.Kind {
+	case telemetry.Counter:
+		metricType, timeAgg, spaceAgg = "Sum", "rate", "sum"
+	case telemetry.Histogram:
+		metricType, timeAgg = "Histogram", ""
+	}
+
+	groupBy := make([]map[string]any, len(q.groupBy))
+	for j, g := range q.groupBy {
+		groupBy[j] = map[string]any{"key": g, "dataType": "string", "type": "tag", "isColumn": false}
+	}
+	filters := []map[string]any{}
+	if service != "" {
+		filters = append(filters, signozFilter("service.name", "resource", service))
+	}
+	for _, key := range sortedKeys(where) {
+		filters = append(filters, signozFilter(key, "tag", where[key]))
+	}
+
+	return map[string]any{
+		"dataSource":         "metrics",
+		"queryName":          name,
+		"expression":         name,
+		"aggregateOperator":  spaceAgg,
+		"aggregateAttribute": map[string]any{"key": m.Name, "dataType": "float64", "type": metricType, "isColumn": true},
+		"timeAggregation":    timeAgg,
+		"spaceAggregation":   spaceAgg,
+		"groupBy":            groupBy,
+		"filters":            map[string]any{"op": "AND", "items": filters},
+		"legend":             q.legend,
+		"disabled":           false,
+		"reduceTo":           "avg",
+	}
+}
+
+func signozFilter(key, keyType, value string) map[string]any {
+	return map[string]any{
+		"key":   map[string]any{"key": key, "dataType": "string", "type": keyType, "isColumn": false},
+		"op":    "=",
+		"value": value,
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// panelDescription lists the metrics behind a panel with their descriptions
+func panelDescription(p dashboardPanel) string {
+	seen := make(map[string]bool)
+	var parts []string
+	for _, q := range p.queries {
+		if seen[q.metric] {
+			continue
+		}
+		seen[q.metric] = true
+		m, _ := telemetry.LookupMetric(q.metric)
+		parts = append(parts, fmt.Sprintf("%s: %s", m.Name, m.Description))
+	}
+	return strings.Join(parts, "; ")
+}
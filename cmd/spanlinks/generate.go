@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"span-links-signoz-demo/scenario"
+
+	"go.opentelemetry.io/otel"
+)
+
+// runGenerate produces synthetic traces with a configurable shape and link density
+func runGenerate(args []string) error {
+	defaults := scenario.DefaultGenerateOptions()
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	traces := fs.Int("traces", defaults.Traces, "number of traces")
+	depth := fs.Int("depth", defaults.Depth, "levels below the root span")
+	fanout := fs.Int("fanout", defaults.Fanout, "children per span")
+	links := fs.Int("links", defaults.LinksPerSpan, "links per non-root span")
+	pool := fs.Int("link-pool", defaults.LinkPool, "recent spans link targets are drawn from")
+	duration := fs.Duration("span-duration", defaults.SpanDuration, "leaf span duration")
+	rate := fs.Float64("rate", defaults.Rate, "traces per second (0 = as fast as possible)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	tp, err := initTracing(ctx, "synthetic", nil)
+	if err != nil {
+		return fmt.Errorf("failed to init tracing: %w", err)
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := tp.Shutdown(shutdownCtx); err != nil {
+			log.Printf("shutdown tracer provider: %v", err)
+		}
+	}()
+
+	started := time.Now()
+	r := scenario.Generate(ctx, otel.Tracer("synthetic"), scenario.GenerateOptions{
+		Traces:       *traces,
+		Depth:        *depth,
+		Fanout:       *fanout,
+		LinksPerSpan: *links,
+		LinkPool:     *pool,
+		SpanDuration: *duration,
+		Rate:         *rate,
+	})
+
+	fmt.Printf("traces=%d spans=%d links=%d elapsed=%s\n", r.Traces, r.Spans, r.Links, time.Since(started).Round(time.Millisecond))
+	return nil
+}
@@ -0,0 +1,186 @@
+// Command spanlinks is the unified CLI for the span link examples.
+//
+//	spanlinks run-all             run every example with a shared run.id and print a summary
+//	spanlinks run <example>       run a single example (fanout, retry, ...)
+//	spanlinks scenario file.yaml  generate the link topology described by a YAML scenario
+//	spanlinks generate            generate synthetic traces with configurable breadth/depth/link density
+//	spanlinks verify traces.json  assert the producer/worker link structure in file-exporter output
+//	spanlinks consistency traces.json  check backward/forward link symmetry in a forward-link run
+//	spanlinks analyze traces.json      queue-wait and processing latency percentiles per run
+//	spanlinks diff run1.jsonl run2.jsonl  link edges added/removed between two recorded runs
+//	spanlinks gen-dashboard       write a SigNoz or Grafana dashboard JSON for the demo's metrics
+//	spanlinks gen-alerts          write SigNoz alert rules for the demo's SLO metrics
+//	spanlinks navigate -trace ID  follow a span's links and list the linked span's parent and siblings
+//	spanlinks queue inspect       list a running app's pending messages with their trace context
+//	spanlinks fanout / fanin      run the fan-out / fan-in example with a custom shape
+//	spanlinks retry               run the retry example with a custom retry policy
+//	spanlinks doctor              validate the environment and test-export to the OTLP endpoint
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"span-links-signoz-demo/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// command is a spanlinks subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{name: "run-all", summary: "run every example with a shared run.id and print a summary table", run: runAll},
+	{name: "run", summary: "run a single example by name (see run -list)", run: runOne},
+	{name: "scenario", summary: "generate spans and links from a YAML scenario file", run: runScenario},
+	{name: "generate", summary: "generate synthetic traces with configurable shape and link density", run: runGenerate},
+	{name: "fanout", summary: "run the fan-out example (-items, -delay, -failure-percent, -same-trace)", run: runFanOut},
+	{name: "fanin", summary: "run the fan-in example (-producers, -delay, -failure-percent, -same-trace)", run: runFanIn},
+	{name: "retry", summary: "run the retry example (-max-retries, -base-delay, -jitter, -script)", run: runRetry},
+	{name: "doctor", summary: "validate env configuration and test span/metric/log export to the endpoint", run: runDoctor},
+	{name: "verify", summary: "assert the producer/worker link structure in collector file-exporter output", run: runVerify},
+	{name: "consistency", summary: "check that backward and forward links pair up in forward-link run output", run: runConsistency},
+	{name: "analyze", summary: "report queue-wait/processing latency percentiles per run from file-exporter output", run: runAnalyze},
+	{name: "diff", summary: "compare the link graphs of two recorded runs (-run1/-run2 ID, -key ATTR, -json)", run: runDiff},
+	{name: "gen-dashboard", summary: "write a SigNoz (-format signoz) or Grafana (-format grafana) dashboard for the demo's metrics", run: runGenDashboard},
+	{name: "gen-alerts", summary: "write SigNoz alert rules (-processing-budget, -error-rate, -lag) for the demo's SLO metrics", run: runGenAlerts},
+	{name: "navigate", summary: "follow a span's links via the SigNoz API (or -file) and list the linked span's siblings", run: runNavigate},
+	{name: "queue", summary: "inspect pending messages of a running app (queue inspect -addr, -min-age, -json)", run: runQueue},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "spanlinks %s: %v\n", cmd.name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "spanlinks: unknown command %q\n\n", os.Args[1])
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: spanlinks <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+// initTracing sets up OTLP/HTTP trace export with the given extra resource attributes
+// and span processors registered ahead of the batcher. OTEL_SERVICE_NAME overrides
+// defaultServiceName.
+func initTracing(ctx context.Context, defaultServiceName string, attrs []attribute.KeyValue, processors ...sdktrace.SpanProcessor) (*sdktrace.TracerProvider, error) {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	if endpoint == "" {
+		endpoint = "http://localhost:4318"
+	}
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+	headers := parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"))
+	for k, v := range parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_TRACES_HEADERS")) {
+		headers[k] = v
+	}
+
+	res, err := telemetry.ServiceResource(ctx, serviceName, attrs...)
+	if err != nil {
+		return nil, err
+	}
+
+	host, insecure := parseEndpoint(endpoint)
+	if strings.HasSuffix(host, ":4317") {
+		fmt.Fprintf(os.Stderr, "WARNING: %s is the OTLP/gRPC port but spans are exported over OTLP/HTTP; use port 4318\n", host)
+	}
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(host),
+		otlptracehttp.WithURLPath("/v1/traces"),
+	}
+	if insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	if len(headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(headers))
+	}
+
+	exp, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	tpOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+	}
+	for _, p := range processors {
+		tpOpts = append(tpOpts, sdktrace.WithSpanProcessor(p))
+	}
+	tpOpts = append(tpOpts, sdktrace.WithBatcher(exp))
+	tp := sdktrace.NewTracerProvider(tpOpts...)
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	return tp, nil
+}
+
+func parseEndpoint(endpoint string) (string, bool) {
+	if strings.HasPrefix(endpoint, "https://") {
+		return strings.TrimPrefix(endpoint, "https://"), false
+	}
+	if strings.HasPrefix(endpoint, "http://") {
+		return strings.TrimPrefix(endpoint, "http://"), true
+	}
+	return endpoint, true
+}
+
+func parseHeaders(headersStr string) map[string]string {
+	headers := make(map[string]string)
+	if headersStr == "" {
+		return headers
+	}
+	for _, pair := range strings.Split(headersStr, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) == 2 {
+			headers[unescapeHeader(strings.TrimSpace(parts[0]))] = unescapeHeader(strings.TrimSpace(parts[1]))
+		}
+	}
+	return headers
+}
+
+// unescapeHeader percent-decodes s (OTLP header values may be URL-encoded)
+func unescapeHeader(s string) string {
+	if decoded, err := url.PathUnescape(s); err == nil {
+		return decoded
+	}
+	return s
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"span-links-signoz-demo/otlpjson"
+)
+
+// runNavigate follows the links of a consumer span across traces: for each link it
+// fetches the linked trace, finds the linked span, and prints its parent and siblings —
+// for a ProcessOrder span, the PublishOrderBatch its order was published in and the
+// other orders of that batch. Traces come from the SigNoz API or a file-exporter file.
+func runNavigate(args []string) error {
+	fs := flag.NewFlagSet("navigate", flag.ExitOnError)
+	traceID := fs.String("trace", "", "trace ID of the consumer span (required)")
+	spanID := fs.String("span", "", "span ID of the consumer span (default: every span of the trace with links)")
+	linkType := fs.String("link-type", "", "only follow links with this link.type")
+	addr := fs.String("signoz", envOr("SIGNOZ_URL", "http://localhost:3301"), "SigNoz base URL (env SIGNOZ_URL)")
+	apiKey := fs.String("api-key", os.Getenv("SIGNOZ_API_KEY"), "SigNoz API key (env SIGNOZ_API_KEY)")
+	file := fs.String("file", "", "read traces from collector file-exporter output instead of SigNoz")
+	show := fs.Int("show", 20, "siblings to list per linked span")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *traceID == "" {
+		return errors.New("usage: spanlinks navigate -trace ID [-span ID] [-link-type T] [-signoz URL | -file traces.json]")
+	}
+
+	var src traceSource = newSignozTraceSource(*addr, *apiKey)
+	if *file != "" {
+		fileSrc, err := newFileTraceSource(*file)
+		if err != nil {
+			return err
+		}
+		src = fileSrc
+	}
+
+	ctx := context.Background()
+	spans, err := src.Trace(ctx, *traceID)
+	if err != nil {
+		return err
+	}
+	var consumers []otlpjson.Span
+	for _, s := range spans {
+		if (*spanID == "" && len(s.Links) > 0) || s.SpanID == *spanID {
+			consumers = append(consumers, s)
+		}
+	}
+	if len(consumers) == 0 {
+		if *spanID != "" {
+			return fmt.Errorf("span %s not found in trace %s", *spanID, *traceID)
+		}
+		return fmt.Errorf("no span of trace %s has links", *traceID)
+	}
+
+	// Linked traces are fetched once even when several links point into them
+	linked := make(map[string][]otlpjson.Span)
+	for _, c := range consumers {
+		fmt.Printf("%s (%s) %s/%s  %s\n", c.Name, c.Service, c.TraceID, c.SpanID, c.Duration().Round(time.Microsecond))
+		followed := 0
+		for _, l := range c.Links {
+			if *linkType != "" && l.Attributes["link.type"] != "" && l.Attributes["link.type"] != *linkType {
+				continue
+			}
+			followed++
+			fmt.Printf("  link %s -> %s\n", describeLinkType(l), l.Key())
+
+			target, ok := linked[l.TraceID]
+			if !ok {
+				target, err = src.Trace(ctx, l.TraceID)
+				if err != nil {
+					fmt.Printf("    linked trace unavailable: %v\n", err)
+					continue
+				}
+				linked[l.TraceID] = target
+			}
+			printLinkedNeighborhood(target, l, *show)
+		}
+		if followed == 0 {
+			fmt.Println("  (no links to follow)")
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// printLinkedNeighborhood prints the linked span, its parent and its siblings in trace
+func printLinkedNeighborhood(trace []otlpjson.Span, l otlpjson.Link, show int) {
+	index := otlpjson.Index(trace)
+	span, ok := index[l.Key()]
+	if !ok {
+		fmt.Printf("    linked span %s not in its trace (not exported yet, or dropped)\n", l.SpanID)
+		return
+	}
+	fmt.Printf("    linked span: %s\n", describeSpan(span))
+	if span.ParentSpanID == "" {
+		fmt.Println("    (root span: no siblings)")
+		return
+	}
+	if parent, ok := index[span.TraceID+"/"+span.ParentSpanID]; ok {
+		fmt.Printf("    parent:      %s\n", describeSpan(parent))
+	} else {
+		fmt.Printf("    parent:      %s (not exported)\n", span.ParentSpanID)
+	}
+
+	var siblings []otlpjson.Span
+	for _, s := range trace {
+		if s.ParentSpanID == span.ParentSpanID && s.SpanID != span.SpanID {
+			siblings = append(siblings, s)
+		}
+	}
+	sort.Slice(siblings, func(i, j int) bool { return siblings[i].Start.Before(siblings[j].Start) })
+	fmt.Printf("    siblings (%d):\n", len(siblings))
+	for i, s := range siblings {
+		if i == show {
+			fmt.Printf("      ... and %d more\n", len(siblings)-show)
+			break
+		}
+		fmt.Printf("      %s\n", describeSpan(s))
+	}
+}
+
+func describeSpan(s otlpjson.Span) string {
+	desc := fmt.Sprintf("%s %s", s.Name, s.SpanID)
+	if id := orderID(s); id != "" {
+		desc += " order=" + id
+	}
+	return fmt.Sprintf("%s  %s", desc, s.Duration().Round(time.Microsecond))
+}
+
+// describeLinkType returns the link's link.type, if the source recorded link attributes
+func describeLinkType(l otlpjson.Link) string {
+	if t := l.Attributes["link.type"]; t != "" {
+		return t
+	}
+	return "link"
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
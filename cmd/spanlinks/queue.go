@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"span-links-signoz-demo/pkg/queue"
+)
+
+// runQueue dispatches the queue subcommands
+func runQueue(args []string) error {
+	if len(args) == 0 || args[0] != "inspect" {
+		return errors.New("usage: spanlinks queue inspect [-addr host:port] [-min-age d] [-json]")
+	}
+	return runQueueInspect(args[1:])
+}
+
+// runQueueInspect lists the messages pending in a running app's queue (served by its
+// admin endpoint, ADMIN_ADDR) with the traceparent each carries, its age and how often
+// it was redelivered. A consumer link pointing at an old trace usually means an old or
+// redelivered message: its traceparent is still the original publish.
+func runQueueInspect(args []string) error {
+	defaultAddr := os.Getenv("ADMIN_ADDR")
+	if defaultAddr == "" {
+		defaultAddr = "localhost:8081"
+	}
+
+	fs := flag.NewFlagSet("queue inspect", flag.ExitOnError)
+	addr := fs.String("addr", defaultAddr, "admin endpoint of the running app")
+	minAge := fs.Duration("min-age", 0, "only list messages at least this old")
+	asJSON := fs.Bool("json", false, "print the messages as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	url := *addr
+	if !strings.Contains(url, "://") {
+		url = "http://" + url
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(strings.TrimSuffix(url, "/") + "/debug/queue")
+	if err != nil {
+		return fmt.Errorf("failed to reach the admin endpoint (is the app running with ADMIN_ADDR=%s?): %w", *addr, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("admin endpoint returned %s", resp.Status)
+	}
+
+	var pending []queue.PendingMessage
+	if err := json.NewDecoder(resp.Body).Decode(&pending); err != nil {
+		return fmt.Errorf("failed to decode pending messages: %w", err)
+	}
+	shown := pending[:0]
+	for _, m := range pending {
+		if time.Duration(m.AgeMs)*time.Millisecond >= *minAge {
+			shown = append(shown, m)
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(shown)
+	}
+
+	fmt.Printf("%d messages pending (%d shown)\n\n", len(pending), len(shown))
+	if len(shown) == 0 {
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ORDER\tTOPIC\tAGE\tREDELIVERIES\tTRACEPARENT")
+	for _, m := range shown {
+		traceParent := m.TraceParent
+		if traceParent == "" {
+			traceParent = "(none)"
+		}
+		age := (time.Duration(m.AgeMs) * time.Millisecond).Round(time.Millisecond)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", m.OrderID, m.Topic, age, m.RedeliveryCount, traceParent)
+	}
+	return tw.Flush()
+}
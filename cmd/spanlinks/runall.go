@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"span-links-signoz-demo/examples"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// example is one runnable example
+type example struct {
+	name string
+	run  func(context.Context)
+}
+
+// allExamples lists every example in the order run-all executes them
+var allExamples = []example{
+	{name: "same-trace-span-links", run: examples.SameTraceSpanLinks},
+	{name: "fanout", run: examples.FanOutExample},
+	{name: "fanin", run: examples.FanInExample},
+	{name: "retry", run: examples.RetryExample},
+	{name: "remote-parent-gap", run: examples.RemoteParentGapExample},
+	{name: "context-cancellation", run: examples.ContextCancellationExample},
+	{name: "chained-queue", run: examples.ChainedQueueExample},
+	{name: "schema-migration", run: examples.SchemaMigrationExample},
+	{name: "chunked-upload", run: examples.ChunkedUploadExample},
+	{name: "foreign-correlation", run: examples.ForeignCorrelationExample},
+	{name: "time-window", run: examples.TimeWindowExample},
+	{name: "batch-hierarchy", run: examples.BatchHierarchyExample},
+}
+
+// exampleStats counts what one example produced
+type exampleStats struct {
+	name   string
+	traces map[trace.TraceID]struct{}
+	spans  int
+	links  int
+	errors int
+}
+
+// exampleRecorder is a span processor that tags spans with the running example's name
+// and attributes finished spans to it. Examples run one at a time, so every span ended
+// between a call to begin and the next begin or end belongs to that example.
+type exampleRecorder struct {
+	mu      sync.Mutex
+	current *exampleStats
+}
+
+// begin starts attributing spans to a new example and returns its stats
+func (r *exampleRecorder) begin(name string) *exampleStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.current = &exampleStats{name: name, traces: make(map[trace.TraceID]struct{})}
+	return r.current
+}
+
+// end stops attributing spans to an example; spans ended afterwards are not counted
+func (r *exampleRecorder) end() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.current = nil
+}
+
+func (r *exampleRecorder) OnStart(_ context.Context, s sdktrace.ReadWriteSpan) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.current != nil {
+		s.SetAttributes(attribute.String("example.name", r.current.name))
+	}
+}
+
+func (r *exampleRecorder) OnEnd(s sdktrace.ReadOnlySpan) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.current == nil {
+		return
+	}
+	r.current.traces[s.SpanContext().TraceID()] = struct{}{}
+	r.current.spans++
+	r.current.links += len(s.Links())
+	if s.Status().Code == codes.Error {
+		r.current.errors++
+	}
+}
+
+func (r *exampleRecorder) Shutdown(context.Context) error   { return nil }
+func (r *exampleRecorder) ForceFlush(context.Context) error { return nil }
+
+// runOne runs a single example under its own service name, like the examples/cmd runners
+func runOne(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	list := fs.Bool("list", false, "list example names")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *list || fs.NArg() != 1 {
+		for _, ex := range allExamples {
+			fmt.Println(ex.name)
+		}
+		if *list {
+			return nil
+		}
+		return errors.New("usage: spanlinks run <example>")
+	}
+
+	for _, ex := range allExamples {
+		if ex.name == fs.Arg(0) {
+			return withTracing(ex.name, ex.run)
+		}
+	}
+	return fmt.Errorf("unknown example %q (see spanlinks run -list)", fs.Arg(0))
+}
+
+// runAll executes every example sequentially under one tracer provider whose resource
+// carries a shared run.id, then prints a per-example summary table.
+func runAll(args []string) error {
+	fs := flag.NewFlagSet("run-all", flag.ExitOnError)
+	runID := fs.String("run-id", uuid.New().String(), "value of the run.id resource attribute shared by all examples")
+	timeout := fs.Duration("timeout", 30*time.Second, "per-example timeout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	recorder := &exampleRecorder{}
+	tp, err := initTracing(ctx, "spanlinks", []attribute.KeyValue{attribute.String("run.id", *runID)}, recorder)
+	if err != nil {
+		return fmt.Errorf("failed to init tracing: %w", err)
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tp.Shutdown(shutdownCtx); err != nil {
+			log.Printf("shutdown tracer provider: %v", err)
+		}
+	}()
+
+	results := make([]*exampleStats, 0, len(allExamples))
+	for _, ex := range allExamples {
+		log.Printf("=== %s (run.id=%s) ===", ex.name, *runID)
+		stats := recorder.begin(ex.name)
+		exCtx, cancel := context.WithTimeout(ctx, *timeout)
+		ex.run(exCtx)
+		cancel()
+		results = append(results, stats)
+	}
+	recorder.end() // stray spans ending after the last example are not counted
+
+	fmt.Printf("\nrun.id=%s\n\n", *runID)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "EXAMPLE\tTRACES\tSPANS\tLINKS\tERRORS")
+	var totalTraces, totalSpans, totalLinks, totalErrors int
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\n", r.name, len(r.traces), r.spans, r.links, r.errors)
+		totalTraces += len(r.traces)
+		totalSpans += r.spans
+		totalLinks += r.links
+		totalErrors += r.errors
+	}
+	fmt.Fprintf(tw, "TOTAL\t%d\t%d\t%d\t%d\n", totalTraces, totalSpans, totalLinks, totalErrors)
+	return tw.Flush()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"span-links-signoz-demo/scenario"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// runScenario generates the spans and links described by a YAML scenario file
+func runScenario(args []string) error {
+	fs := flag.NewFlagSet("scenario", flag.ExitOnError)
+	repeat := fs.Int("repeat", 1, "number of times to generate the scenario")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: spanlinks scenario [-repeat N] <file.yaml>")
+	}
+
+	s, err := scenario.Load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	tp, err := initTracing(ctx, "scenario", []attribute.KeyValue{attribute.String("scenario.name", s.Name)})
+	if err != nil {
+		return fmt.Errorf("failed to init tracing: %w", err)
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tp.Shutdown(shutdownCtx); err != nil {
+			log.Printf("shutdown tracer provider: %v", err)
+		}
+	}()
+
+	tracer := otel.Tracer("scenario")
+	var total scenario.Result
+	for i := 0; i < *repeat; i++ {
+		r := s.Run(ctx, tracer)
+		total.Traces += r.Traces
+		total.Spans += r.Spans
+		total.Links += r.Links
+		total.Failures += r.Failures
+	}
+
+	fmt.Printf("scenario=%s runs=%d traces=%d spans=%d links=%d failures=%d\n",
+		s.Name, *repeat, total.Traces, total.Spans, total.Links, total.Failures)
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"span-links-signoz-demo/examples"
+	"span-links-signoz-demo/linkprune"
+)
+
+// runFanOut runs the fan-out example with a shape taken from flags
+func runFanOut(args []string) error {
+	opts := examples.DefaultFanOutOptions()
+	fs := flag.NewFlagSet("fanout", flag.ExitOnError)
+	fs.IntVar(&opts.Items, "items", opts.Items, "items in the batch")
+	fs.DurationVar(&opts.WorkerDelay, "delay", opts.WorkerDelay, "processing time per item")
+	fs.Float64Var(&opts.FailurePercent, "failure-percent", opts.FailurePercent, "0-100 chance each item fails")
+	fs.BoolVar(&opts.SameTrace, "same-trace", opts.SameTrace, "process items in the batch's trace instead of new traces")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	return withTracing("fanout", func(ctx context.Context) {
+		examples.FanOutExampleWithOptions(ctx, opts)
+	})
+}
+
+// runFanIn runs the fan-in example with a shape taken from flags
+func runFanIn(args []string) error {
+	opts := examples.DefaultFanInOptions()
+	fs := flag.NewFlagSet("fanin", flag.ExitOnError)
+	fs.IntVar(&opts.Producers, "producers", opts.Producers, "number of producers")
+	fs.DurationVar(&opts.ProducerDelay, "delay", opts.ProducerDelay, "production time per producer")
+	fs.Float64Var(&opts.FailurePercent, "failure-percent", opts.FailurePercent, "0-100 chance each producer fails")
+	fs.BoolVar(&opts.SameTrace, "same-trace", opts.SameTrace, "run producers and aggregator in one trace")
+	fs.IntVar(&opts.MaxLinks, "max-links", opts.MaxLinks, "aggregator link cap (0 = no cap)")
+	prune := fs.String("prune", string(opts.PruneStrategy), "which links to keep over the cap: first, last, reservoir")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	strategy, err := linkprune.ParseStrategy(*prune)
+	if err != nil {
+		return err
+	}
+	opts.PruneStrategy = strategy
+
+	return withTracing("fanin", func(ctx context.Context) {
+		examples.FanInExampleWithOptions(ctx, opts)
+	})
+}
+
+// runRetry runs the retry example with a retry policy taken from flags
+func runRetry(args []string) error {
+	opts := examples.DefaultRetryOptions()
+	fs := flag.NewFlagSet("retry", flag.ExitOnError)
+	fs.IntVar(&opts.MaxRetries, "max-retries", opts.MaxRetries, "retries after the original attempt")
+	fs.DurationVar(&opts.BaseDelay, "base-delay", opts.BaseDelay, "backoff before the first retry")
+	fs.DurationVar(&opts.MaxDelay, "max-delay", opts.MaxDelay, "backoff cap")
+	fs.StringVar(&opts.Jitter, "jitter", opts.Jitter, "jitter strategy: none, full, equal, decorrelated")
+	fs.StringVar(&opts.FailureScript, "script", opts.FailureScript, "per-attempt outcomes, e.g. FFS (F=fail, S=succeed)")
+	fs.Float64Var(&opts.SuccessRate, "success-rate", opts.SuccessRate, "0..1 chance an unscripted attempt succeeds")
+	fs.BoolVar(&opts.LinkPrevious, "link-previous", opts.LinkPrevious, "also link each retry to the attempt before it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	switch opts.Jitter {
+	case examples.JitterNone, examples.JitterFull, examples.JitterEqual, examples.JitterDecorrelated:
+	default:
+		return fmt.Errorf("unknown jitter strategy %q", opts.Jitter)
+	}
+
+	return withTracing("retry", func(ctx context.Context) {
+		examples.RetryExampleWithOptions(ctx, opts)
+	})
+}
+
+// withTracing runs fn with tracing initialised (service name defaults to name), flushing
+// spans afterwards
+func withTracing(name string, fn func(context.Context)) error {
+	ctx := context.Background()
+	tp, err := initTracing(ctx, name, nil)
+	if err != nil {
+		return fmt.Errorf("failed to init tracing: %w", err)
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tp.Shutdown(shutdownCtx); err != nil {
+			log.Printf("shutdown tracer provider: %v", err)
+		}
+	}()
+
+	log.Printf("=== %s ===", name)
+	fn(ctx)
+	return nil
+}
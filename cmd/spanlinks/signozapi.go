@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"span-links-signoz-demo/otlpjson"
+)
+
+// traceSource returns every span of a trace
+type traceSource interface {
+	Trace(ctx context.Context, traceID string) ([]otlpjson.Span, error)
+}
+
+// fileTraceSource serves traces from collector file-exporter output
+type fileTraceSource struct {
+	byTrace map[string][]otlpjson.Span
+}
+
+func newFileTraceSource(path string) (*fileTraceSource, error) {
+	spans, err := otlpjson.LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	src := &fileTraceSource{byTrace: make(map[string][]otlpjson.Span)}
+	for _, s := range spans {
+		src.byTrace[s.TraceID] = append(src.byTrace[s.TraceID], s)
+	}
+	return src, nil
+}
+
+func (f *fileTraceSource) Trace(_ context.Context, traceID string) ([]otlpjson.Span, error) {
+	spans, ok := f.byTrace[traceID]
+	if !ok {
+		return nil, fmt.Errorf("trace %s not found", traceID)
+	}
+	return spans, nil
+}
+
+// signozTraceSource fetches traces from the SigNoz query service (GET /api/v1/traces/{id}).
+// SigNoz stores a span's parent and its links as references: CHILD_OF is the parent,
+// FOLLOWS_FROM entries are links.
+type signozTraceSource struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+func newSignozTraceSource(baseURL, apiKey string) *signozTraceSource {
+	return &signozTraceSource{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// signozTraceResponse is the table form the v1 trace endpoint returns
+type signozTraceResponse []struct {
+	Columns []string `json:"columns"`
+	Events  [][]any  `json:"events"`
+}
+
+func (s *signozTraceSource) Trace(ctx context.Context, traceID string) ([]otlpjson.Span, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/api/v1/traces/"+traceID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.apiKey != "" {
+		req.Header.Set("SIGNOZ-API-KEY", s.apiKey)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query SigNoz: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("SigNoz returned %s for trace %s: %s", resp.Status, traceID, strings.TrimSpace(string(body)))
+	}
+
+	var tables signozTraceResponse
+	if err := json.Unmarshal(body, &tables); err != nil {
+		return nil, fmt.Errorf("unexpected SigNoz trace response: %w", err)
+	}
+	var spans []otlpjson.Span
+	for _, t := range tables {
+		col := make(map[string]int, len(t.Columns))
+		for i, c := range t.Columns {
+			col[c] = i
+		}
+		for _, row := range t.Events {
+			spans = append(spans, signozSpan(col, row))
+		}
+	}
+	if len(spans) == 0 {
+		return nil, fmt.Errorf("trace %s not found", traceID)
+	}
+	return spans, nil
+}
+
+// signozSpan converts one row of the trace table to a span
+func signozSpan(col map[string]int, row []any) otlpjson.Span {
+	get := func(name string) any {
+		if i, ok := col[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return nil
+	}
+	str := func(name string) string {
+		if v := get(name); v != nil {
+			return fmt.Sprint(v)
+		}
+		return ""
+	}
+	num := func(name string) int64 {
+		switch v := get(name).(type) {
+		case float64:
+			return int64(v)
+		case string:
+			var n int64
+			fmt.Sscan(v, &n)
+			return n
+		}
+		return 0
+	}
+
+	start := time.UnixMilli(num("__time"))
+	span := otlpjson.Span{
+		TraceID:    str("TraceId"),
+		SpanID:     str("SpanId"),
+		Name:       str("Name"),
+		Service:    str("ServiceName"),
+		Start:      start,
+		End:        start.Add(time.Duration(num("DurationNano"))),
+		Attributes: make(map[string]string),
+	}
+	keys, _ := get("TagsKeys").([]any)
+	values, _ := get("TagsValues").([]any)
+	for i := range min(len(keys), len(values)) {
+		span.Attributes[fmt.Sprint(keys[i])] = fmt.Sprint(values[i])
+	}
+	refs, _ := get("References").([]any)
+	for _, r := range refs {
+		ref := parseSignozReference(r)
+		if ref.SpanID == "" {
+			continue
+		}
+		if ref.RefType == "CHILD_OF" && ref.TraceID == span.TraceID {
+			span.ParentSpanID = ref.SpanID
+			continue
+		}
+		span.Links = append(span.Links, otlpjson.Link{TraceID: ref.TraceID, SpanID: ref.SpanID, Attributes: map[string]string{}})
+	}
+	return span
+}
+
+type signozReference struct {
+	TraceID string `json:"TraceId"`
+	SpanID  string `json:"SpanId"`
+	RefType string `json:"RefType"`
+}
+
+// parseSignozReference reads a reference given as a JSON object or as the
+// "{TraceId=..., SpanId=..., RefType=...}" string older query services return
+func parseSignozReference(r any) signozReference {
+	var ref signozReference
+	switch v := r.(type) {
+	case map[string]any:
+		ref.TraceID, _ = v["TraceId"].(string)
+		ref.SpanID, _ = v["SpanId"].(string)
+		ref.RefType, _ = v["RefType"].(string)
+	case string:
+		if json.Unmarshal([]byte(v), &ref) == nil {
+			return ref
+		}
+		for _, field := range strings.Split(strings.Trim(v, "{}"), ",") {
+			key, val, _ := strings.Cut(strings.TrimSpace(field), "=")
+			switch key {
+			case "TraceId":
+				ref.TraceID = val
+			case "SpanId":
+				ref.SpanID = val
+			case "RefType":
+				ref.RefType = val
+			}
+		}
+	}
+	return ref
+}
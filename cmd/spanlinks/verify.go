@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"span-links-signoz-demo/otlpjson"
+)
+
+// runVerify asserts the producer/worker link structure in a collector file-exporter output
+// file: every consumer link resolves to an exported producer span for the same order in a
+// different trace, every order was consumed, and the RunSummary links resolve.
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	expectOrders := fs.Int("expect-orders", 0, "number of distinct orders that must have been consumed (0 = at least one)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: spanlinks verify [-expect-orders N] <traces.json>")
+	}
+
+	spans, err := otlpjson.LoadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	index := otlpjson.Index(spans)
+
+	var failures int
+	check := func(ok bool, format string, a ...any) {
+		status := "PASS"
+		if !ok {
+			status = "FAIL"
+			failures++
+		}
+		fmt.Printf("%s  %s\n", status, fmt.Sprintf(format, a...))
+	}
+
+	var consumerLinks, dangling, sameTrace, mismatched int
+	consumed := make(map[string]bool)
+	var summaries, summaryLinks, summaryDangling int
+	for _, s := range spans {
+		if s.Name == "RunSummary" || s.Name == "RunSummaryPage" {
+			if s.Name == "RunSummary" {
+				summaries++
+			}
+			for _, l := range s.Links {
+				summaryLinks++
+				if _, ok := index[l.Key()]; !ok {
+					summaryDangling++
+				}
+			}
+		}
+
+		for _, l := range s.Links {
+			if l.Attributes["link.type"] != "queue_consumption" {
+				continue
+			}
+			consumerLinks++
+			target, ok := index[l.Key()]
+			if !ok {
+				dangling++
+				continue
+			}
+			if target.TraceID == s.TraceID {
+				sameTrace++
+			}
+			if orderID(target) != orderID(s) {
+				mismatched++
+				continue
+			}
+			consumed[orderID(s)] = true
+		}
+	}
+
+	fmt.Printf("%d spans read from %s\n\n", len(spans), fs.Arg(0))
+	check(consumerLinks > 0, "consumer spans carry queue_consumption links (%d)", consumerLinks)
+	check(dangling == 0, "every consumer link resolves to an exported span (%d dangling)", dangling)
+	check(sameTrace == 0, "consumer and producer spans are in different traces (%d in the same trace)", sameTrace)
+	check(mismatched == 0, "linked producer span is for the same order (%d mismatched)", mismatched)
+	if *expectOrders > 0 {
+		check(len(consumed) == *expectOrders, "distinct orders consumed: %d (expected %d)", len(consumed), *expectOrders)
+	} else {
+		check(len(consumed) > 0, "distinct orders consumed: %d", len(consumed))
+	}
+	check(summaries == 1, "exactly one RunSummary span (%d)", summaries)
+	check(summaryDangling == 0, "every RunSummary link resolves (%d of %d dangling)", summaryDangling, summaryLinks)
+
+	if failures > 0 {
+		return fmt.Errorf("%d check(s) failed", failures)
+	}
+	return nil
+}
+
+// orderID returns the order a producer or consumer span is about
+func orderID(s otlpjson.Span) string {
+	if id := s.Attributes["order.id"]; id != "" {
+		return id
+	}
+	return s.Attributes["messaging.message.id"]
+}
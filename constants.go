@@ -2,17 +2,110 @@ package main
 
 import "time"
 
-// Processing timeouts for order processing steps
+// Pipeline defaults
 const (
-	ValidationTimeout = 100 * time.Millisecond
-	PaymentTimeout    = 150 * time.Millisecond
-	ShippingTimeout   = 120 * time.Millisecond
+	// DefaultLatencyBudget is the publish → processed budget per order (0 disables SLO breach detection)
+	DefaultLatencyBudget = 0
+
+	// DefaultHeartbeatInterval is the WorkerHeartbeat span interval (0 disables heartbeats)
+	DefaultHeartbeatInterval = 0
+
+	// DefaultBackfillQueueWait is the simulated queue wait for backfilled consumer spans (0 disables backfill)
+	DefaultBackfillQueueWait = 0
+
+	// DefaultOrderingWindow is the OrderingWindow span interval in key-affinity mode
+	DefaultOrderingWindow = 1 * time.Second
+
+	// DefaultLagAlertThreshold is the consumer lag above which LagAlert spans are emitted (0 disables them)
+	DefaultLagAlertThreshold = 0
+
+	// DefaultCustomerCacheTTL is how long LookupCustomer caches a customer profile
+	DefaultCustomerCacheTTL = 30 * time.Second
+
+	// DefaultPublishBurst is the token bucket burst when PUBLISH_RATE_LIMIT is set
+	DefaultPublishBurst = 5
+
+	// DefaultClockSkew is the simulated consumer host clock skew (0 disables it)
+	DefaultClockSkew = 0
+
+	// DefaultConcurrentWorkers is the worker count of the concurrent side in ordering comparison mode
+	DefaultConcurrentWorkers = 5
 )
 
 // Queue configuration
 const (
-	DefaultQueueCapacity = 100
-	DefaultBatchSize     = 10
-	DefaultWorkerCount   = 2
-	BatchPublishInterval = 2 * time.Second
+	DefaultBatchSize      = 10
+	DefaultForwardBatches = 1
+	DefaultWorkerCount    = 2
+	BatchPublishInterval  = 2 * time.Second
+
+	// DrainTimeout bounds the wait for consumers to finish once publishing is done
+	// (topology and ordering comparison modes)
+	DrainTimeout = 60 * time.Second
+)
+
+// Forward-link collection: each order waits for its consumer to report until its own
+// deadline, ForwardLinkDeadlineSlack times the expected processing time of the order and
+// those published before it (spread over the workers), at most FORWARD_LINK_MAX_WAIT_MS.
+// The expected processing time of an order starts at ForwardLinkExpectedProcessing and
+// follows the durations consumers report.
+const (
+	DefaultForwardLinkMaxWait     = 30 * time.Second
+	ForwardLinkExpectedProcessing = 1 * time.Second
+	ForwardLinkDeadlineSlack      = 3
+)
+
+// Worker autoscaling (AUTOSCALE_WORKERS): sampling interval, samples in a row needed to
+// scale down, default queued orders per worker, and the bound on kept evidence spans
+const (
+	AutoscaleInterval           = 500 * time.Millisecond
+	AutoscaleScaleDownSamples   = 3
+	DefaultAutoscaleTargetDepth = 3
+	AutoscaleEvidenceCapacity   = 64
+	AutoscaleEvidenceTTL        = 10 * time.Second
+)
+
+// AuditTrailCapacity bounds the state transitions (AUDIT_LOG) kept in memory for
+// GET /audit/orders/{id}; the OTLP log records are the durable trail
+const AuditTrailCapacity = 4096
+
+// MaxQueueHTTPWait caps the long-poll wait of GET /queue/messages (QUEUE_HTTP_ADDR)
+const MaxQueueHTTPWait = 60 * time.Second
+
+// Consumer group configuration
+const (
+	ConsumerGroupName          = "order-processors"
+	ConsumerGroupPartitions    = 4
+	ConsumerGroupLateJoinDelay = 500 * time.Millisecond
+	ConsumerGroupLateMemberTTL = 1500 * time.Millisecond
+)
+
+// Error budget report: success-rate target and how many slowest / failed orders it links to
+const (
+	DefaultErrorBudgetTarget = 0.99
+	ErrorBudgetReportSize    = 5
+)
+
+// Shutdown: per-hook timeout, and how long the queue drain hook lets workers finish what
+// was already published
+const (
+	ShutdownHookTimeout = 5 * time.Second
+	QueueDrainTimeout   = 15 * time.Second
+)
+
+// RunSummaryMaxLinks matches the SDK's default span link count limit
+const RunSummaryMaxLinks = 128
+
+// Dashboard configuration
+const (
+	DashboardRefreshInterval = 500 * time.Millisecond
+	RecentTraceCapacity      = 10
+)
+
+// Metrics configuration
+const (
+	DefaultExportTimeout       = 10 * time.Second
+	MetricExportInterval       = 10 * time.Second
+	RuntimeMetricsReadInterval = time.Second
+	SpillRetryInterval         = 5 * time.Second // how often spilled span batches are re-exported
 )
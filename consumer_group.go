@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"reflect"
+	"slices"
+	"sync"
+
+	"span-links-signoz-demo/pkg/queue"
+	"span-links-signoz-demo/pkg/worker"
+	"span-links-signoz-demo/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ConsumerGroup adds Kafka-style consumer-group semantics on top of SimpleQueue:
+// orders are dispatched into a fixed set of partitions (keyed by customer), and
+// partitions are reassigned across members whenever a member joins or leaves.
+// Each rebalance gets its own span linking to the in-flight processing spans of
+// the members whose assignment changed.
+type ConsumerGroup struct {
+	name       string
+	tracer     trace.Tracer
+	partitions []chan queue.Order
+
+	mu          sync.Mutex
+	members     []string
+	assignments map[string][]int
+	generation  int
+	changed     chan struct{}
+	inFlight    func(memberID string) (trace.SpanContext, bool)
+}
+
+// NewConsumerGroup creates a consumer group with the given number of partitions
+func NewConsumerGroup(name string, partitionCount int) *ConsumerGroup {
+	partitions := make([]chan queue.Order, partitionCount)
+	for i := range partitions {
+		partitions[i] = make(chan queue.Order, queue.DefaultCapacity)
+	}
+	return &ConsumerGroup{
+		name:        name,
+		tracer:      otel.Tracer("consumer-group"),
+		partitions:  partitions,
+		assignments: make(map[string][]int),
+		changed:     make(chan struct{}),
+	}
+}
+
+// SetInFlightResolver sets the function used to look up the processing span a member
+// is currently working on, so rebalance spans can link to affected in-flight work.
+func (g *ConsumerGroup) SetInFlightResolver(resolver func(memberID string) (trace.SpanContext, bool)) {
+	g.inFlight = resolver
+}
+
+// Dispatch moves orders from the queue topics into partitions until ctx is done
+func (g *ConsumerGroup) Dispatch(ctx context.Context, queue *queue.SimpleQueue, topics ...string) {
+	for {
+		order, err := queue.Consume(ctx, topics...)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		select {
+		case g.partitions[g.partitionFor(order)] <- order:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// partitionFor keys orders by customer so one customer's orders stay on one partition
+func (g *ConsumerGroup) partitionFor(order queue.Order) int {
+	h := fnv.New32a()
+	h.Write([]byte(order.CustomerID))
+	return int(h.Sum32() % uint32(len(g.partitions)))
+}
+
+// Length returns the number of orders waiting in partitions
+func (g *ConsumerGroup) Length() int {
+	var n int
+	for _, p := range g.partitions {
+		n += len(p)
+	}
+	return n
+}
+
+// Join adds a member to the group and triggers a rebalance
+func (g *ConsumerGroup) Join(ctx context.Context, memberID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if slices.Contains(g.members, memberID) {
+		return
+	}
+	g.members = append(g.members, memberID)
+	g.rebalance(ctx, "member_joined", memberID)
+}
+
+// Leave removes a member from the group and triggers a rebalance
+func (g *ConsumerGroup) Leave(ctx context.Context, memberID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	idx := slices.Index(g.members, memberID)
+	if idx < 0 {
+		return
+	}
+	g.members = slices.Delete(g.members, idx, idx+1)
+	g.rebalance(ctx, "member_left", memberID)
+}
+
+// rebalance reassigns partitions round-robin across members. Caller must hold g.mu.
+func (g *ConsumerGroup) rebalance(ctx context.Context, reason, memberID string) {
+	previous := g.assignments
+	next := make(map[string][]int, len(g.members))
+	for p := range g.partitions {
+		if len(g.members) == 0 {
+			break
+		}
+		m := g.members[p%len(g.members)]
+		next[m] = append(next[m], p)
+	}
+	g.generation++
+
+	// Members whose assignment changed (including the one that left)
+	affected := make(map[string]bool)
+	for m, parts := range previous {
+		if !slices.Equal(parts, next[m]) {
+			affected[m] = true
+		}
+	}
+	for m, parts := range next {
+		if !slices.Equal(parts, previous[m]) {
+			affected[m] = true
+		}
+	}
+
+	var links []trace.Link
+	if g.inFlight != nil {
+		for m := range affected {
+			sc, ok := g.inFlight(m)
+			if !ok || !sc.IsValid() {
+				continue
+			}
+			links = append(links, trace.Link{
+				SpanContext: sc,
+				Attributes: []attribute.KeyValue{
+					attribute.String("link.type", "rebalance_in_flight"),
+					attribute.String("worker.id", m),
+					attribute.String("partitions.before", fmt.Sprint(previous[m])),
+					attribute.String("partitions.after", fmt.Sprint(next[m])),
+				},
+			})
+		}
+	}
+
+	_, span := g.tracer.Start(context.WithoutCancel(ctx), "ConsumerGroupRebalance",
+		trace.WithNewRoot(),
+		trace.WithLinks(links...),
+		trace.WithAttributes(
+			attribute.String("messaging.consumer.group.name", g.name),
+			attribute.String("rebalance.reason", reason),
+			attribute.String("rebalance.member.id", memberID),
+			attribute.Int("rebalance.generation", g.generation),
+			attribute.Int("rebalance.members", len(g.members)),
+			attribute.Int("rebalance.partitions", len(g.partitions)),
+			attribute.Int("rebalance.affected_members", len(affected)),
+			attribute.Int("rebalance.in_flight_links", len(links)),
+		),
+	)
+	telemetry.EndWithStatus(span, nil)
+
+	g.assignments = next
+	close(g.changed)
+	g.changed = make(chan struct{})
+
+	log.Printf("Consumer group rebalanced (group=%s reason=%s member=%s generation=%d members=%d in_flight_links=%d)",
+		g.name, reason, memberID, g.generation, len(g.members), len(links))
+}
+
+// Process joins the group as memberID and has consumer process the orders of the
+// partitions assigned to it, leaving the group (and triggering a rebalance) on return.
+func (g *ConsumerGroup) Process(ctx context.Context, consumer *worker.Service, memberID string) {
+	g.Join(ctx, memberID)
+	defer g.Leave(ctx, memberID)
+
+	consumer.ProcessFrom(ctx, memberID, func(ctx context.Context) (queue.Order, error) {
+		return g.Consume(ctx, memberID)
+	})
+}
+
+// Consume retrieves the next order from the partitions currently assigned to memberID,
+// picking up new assignments as rebalances happen.
+func (g *ConsumerGroup) Consume(ctx context.Context, memberID string) (queue.Order, error) {
+	for {
+		g.mu.Lock()
+		assigned := g.assignments[memberID]
+		changed := g.changed
+		g.mu.Unlock()
+
+		cases := make([]reflect.SelectCase, 0, len(assigned)+2)
+		cases = append(cases,
+			reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+			reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(changed)},
+		)
+		for _, p := range assigned {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(g.partitions[p])})
+		}
+
+		chosen, value, _ := reflect.Select(cases)
+		switch chosen {
+		case 0:
+			return queue.Order{}, ctx.Err()
+		case 1:
+			continue // assignment changed; re-read it
+		default:
+			return value.Interface().(queue.Order), nil
+		}
+	}
+}
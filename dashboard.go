@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"span-links-signoz-demo/pkg/queue"
+)
+
+// RunDashboard renders a live terminal dashboard (published/processed counts, queue
+// depth, errors, recent traces with link counts) until ctx is done.
+func RunDashboard(ctx context.Context, out io.Writer, stats *RunStats, queueDepth func() int, activeOrders func() int64) {
+	ticker := time.NewTicker(DashboardRefreshInterval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	for {
+		renderDashboard(out, stats, queueDepth(), activeOrders(), time.Since(start))
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func renderDashboard(out io.Writer, stats *RunStats, queueDepth int, activeOrders int64, uptime time.Duration) {
+	var b strings.Builder
+
+	// Clear screen and move cursor home
+	b.WriteString("\033[H\033[2J")
+	fmt.Fprintf(&b, "\033[1mSpan Links Demo — %s\033[0m  (uptime %s, Ctrl+C to stop)\n\n", serviceName(), uptime.Round(time.Second))
+	fmt.Fprintf(&b, "  Published   %6d\n", stats.Published())
+	fmt.Fprintf(&b, "  Processed   %6d\n", stats.Processed())
+	fmt.Fprintf(&b, "  Errors      %6d\n", stats.Failed())
+	fmt.Fprintf(&b, "  In flight   %6d\n", activeOrders)
+	fmt.Fprintf(&b, "  Queue depth %6d  %s\n\n", queueDepth, bar(queueDepth, queue.DefaultCapacity, 30))
+
+	fmt.Fprintf(&b, "\033[1m  %-16s %-10s %-32s %s\033[0m\n", "ORDER", "WORKER", "TRACE ID", "LINKS")
+	recent := stats.RecentTraces()
+	for i := len(recent) - 1; i >= 0; i-- {
+		t := recent[i]
+		fmt.Fprintf(&b, "  %-16s %-10s %-32s %d\n", t.OrderID, t.WorkerID, t.TraceID, len(t.LinkTargets))
+	}
+	if len(recent) == 0 {
+		b.WriteString("  (no orders processed yet)\n")
+	}
+
+	fmt.Fprint(out, b.String())
+}
+
+// bar renders value/max as a fixed-width ASCII bar
+func bar(value, max, width int) string {
+	if max <= 0 {
+		return ""
+	}
+	filled := value * width / max
+	if filled > width {
+		filled = width
+	}
+	return "[" + strings.Repeat("#", filled) + strings.Repeat(".", width-filled) + "]"
+}
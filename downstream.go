@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"span-links-signoz-demo/pkg/queue"
+	"span-links-signoz-demo/pkg/worker"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// downstreamRequest is the body sent to the fake payment and shipping services
+type downstreamRequest struct {
+	OrderID    string  `json:"order_id"`
+	CustomerID string  `json:"customer_id"`
+	Amount     float64 `json:"amount"`
+}
+
+// Downstream is an embedded pair of fake payment and shipping services served over
+// loopback HTTP. Both sides are instrumented with otelhttp, so a worker's step spans get
+// realistic client and server spans underneath them.
+type Downstream struct {
+	baseURL string
+	client  *http.Client
+	srv     *http.Server
+}
+
+// StartDownstream starts the fake services on a random loopback port
+func StartDownstream() (*Downstream, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for downstream services: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /payments", otelhttp.NewHandler(fakeService(worker.PaymentTimeout, "payment.authorized"), "payment-service"))
+	mux.Handle("POST /shipments", otelhttp.NewHandler(fakeService(worker.ShippingTimeout, "shipment.created"), "shipping-service"))
+
+	d := &Downstream{
+		baseURL: "http://" + ln.Addr().String(),
+		client:  &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)},
+		srv:     &http.Server{Handler: mux},
+	}
+	go func() {
+		if err := d.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Downstream services stopped: %v", err)
+		}
+	}()
+	log.Printf("Fake payment/shipping services listening on %s", d.baseURL)
+	return d, nil
+}
+
+// Stop shuts the fake services down
+func (d *Downstream) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_ = d.srv.Shutdown(ctx)
+}
+
+// Charge calls the payment service for order
+func (d *Downstream) Charge(ctx context.Context, order queue.Order) error {
+	return d.call(ctx, "/payments", order)
+}
+
+// Ship calls the shipping service for order
+func (d *Downstream) Ship(ctx context.Context, order queue.Order) error {
+	return d.call(ctx, "/shipments", order)
+}
+
+func (d *Downstream) call(ctx context.Context, path string, order queue.Order) error {
+	body, err := json.Marshal(downstreamRequest{OrderID: order.ID, CustomerID: order.CustomerID, Amount: order.Amount})
+	if err != nil {
+		return err
+	}
+	// Orders already in flight finish after shutdown is signalled, as the simulated sleeps did
+	req, err := http.NewRequestWithContext(context.WithoutCancel(ctx), http.MethodPost, d.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("downstream call %s failed: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("downstream call %s returned %s", path, resp.Status)
+	}
+	return nil
+}
+
+// fakeService simulates a downstream service taking latency to handle a request and
+// records the outcome as an event on its server span
+func fakeService(latency time.Duration, event string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req downstreamRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+
+		trace.SpanFromContext(r.Context()).AddEvent(event,
+			trace.WithAttributes(attribute.String("order.id", req.OrderID)),
+		)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
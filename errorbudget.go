@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"span-links-signoz-demo/pkg/worker"
+	"span-links-signoz-demo/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrorBudget tracks how every order of the run ended and, at the end, reports the run
+// against a success-rate target: a log table plus an ErrorBudgetReport span (own trace)
+// linking to the slowest and the failed ProcessOrder traces, and run.error_budget.*
+// gauges. Redelivered orders count once, with the outcome of their last delivery.
+type ErrorBudget struct {
+	target float64 // required success rate (0..1)
+
+	mu       sync.Mutex
+	outcomes map[string]worker.OrderOutcome // order ID -> last outcome
+}
+
+var _ worker.OutcomeRecorder = (*ErrorBudget)(nil)
+
+// NewErrorBudget creates a tracker for the given success-rate target (0..1)
+func NewErrorBudget(target float64) *ErrorBudget {
+	return &ErrorBudget{
+		target:   target,
+		outcomes: make(map[string]worker.OrderOutcome),
+	}
+}
+
+// RecordOutcome remembers the outcome of an order, replacing that of an earlier delivery
+func (b *ErrorBudget) RecordOutcome(outcome worker.OrderOutcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.outcomes[outcome.OrderID] = outcome
+}
+
+// Report prints the error-budget summary and emits its span and gauges
+func (b *ErrorBudget) Report() {
+	b.mu.Lock()
+	outcomes := make([]worker.OrderOutcome, 0, len(b.outcomes))
+	for _, o := range b.outcomes {
+		outcomes = append(outcomes, o)
+	}
+	b.mu.Unlock()
+	if len(outcomes) == 0 {
+		log.Printf("Error budget: no orders processed")
+		return
+	}
+
+	// Slowest first; failed orders keep that order
+	sort.Slice(outcomes, func(i, j int) bool { return outcomes[i].Duration > outcomes[j].Duration })
+	var failed []worker.OrderOutcome
+	for _, o := range outcomes {
+		if o.Err != nil {
+			failed = append(failed, o)
+		}
+	}
+	slowest := outcomes[:min(ErrorBudgetReportSize, len(outcomes))]
+	failedShown := failed[:min(ErrorBudgetReportSize, len(failed))]
+
+	total := len(outcomes)
+	successRate := float64(total-len(failed)) / float64(total)
+	allowed := (1 - b.target) * float64(total)
+	consumed := 1.0 // a zero budget is used up by the first failure
+	if allowed > 0 {
+		consumed = float64(len(failed)) / allowed
+	} else if len(failed) == 0 {
+		consumed = 0
+	}
+	met := successRate >= b.target
+
+	b.print(total, len(failed), successRate, allowed, consumed, met, slowest, failedShown)
+	traceID := b.emitSpan(total, len(failed), successRate, allowed, consumed, met, slowest, failedShown)
+	b.recordMetrics(successRate, consumed)
+	log.Printf("Error budget report emitted (trace=%s)", traceID)
+}
+
+func (b *ErrorBudget) print(total, failed int, successRate, allowed, consumed float64, met bool, slowest, failedShown []worker.OrderOutcome) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Error budget (target %.2f%% success): %d orders, %d failed, %.2f%% succeeded\n",
+		b.target*100, total, failed, successRate*100)
+	fmt.Fprintf(&sb, "  allowed failures %.1f, budget consumed %.0f%%, target met: %t\n", allowed, consumed*100, met)
+	fmt.Fprintf(&sb, "  %d slowest orders:\n", len(slowest))
+	for _, o := range slowest {
+		fmt.Fprintf(&sb, "    %-16s %8s  trace=%s\n", o.OrderID, o.Duration.Round(time.Millisecond), o.SpanCtx.TraceID())
+	}
+	if len(failedShown) > 0 {
+		fmt.Fprintf(&sb, "  failed orders (%d of %d):\n", len(failedShown), failed)
+		for _, o := range failedShown {
+			fmt.Fprintf(&sb, "    %-16s trace=%s  %v\n", o.OrderID, o.SpanCtx.TraceID(), o.Err)
+		}
+	}
+	log.Print(sb.String())
+}
+
+func (b *ErrorBudget) emitSpan(total, failed int, successRate, allowed, consumed float64, met bool, slowest, failedShown []worker.OrderOutcome) trace.TraceID {
+	links := make([]trace.Link, 0, len(slowest)+len(failedShown))
+	for i, o := range slowest {
+		links = append(links, outcomeLink(o, "slowest_order", i))
+	}
+	for i, o := range failedShown {
+		links = append(links, outcomeLink(o, "failed_order", i))
+	}
+
+	_, span := otel.Tracer("error-budget").Start(context.Background(), "ErrorBudgetReport",
+		trace.WithNewRoot(),
+		trace.WithLinks(links...),
+		trace.WithAttributes(
+			runIDKey.String(runID),
+			attribute.Float64("error_budget.target", b.target),
+			attribute.Int("error_budget.orders", total),
+			attribute.Int("error_budget.failed", failed),
+			attribute.Float64("error_budget.success_rate", successRate),
+			attribute.Float64("error_budget.allowed_failures", allowed),
+			attribute.Float64("error_budget.consumed", consumed),
+			attribute.Bool("error_budget.met", met),
+		),
+	)
+	var err error
+	if !met {
+		err = fmt.Errorf("error budget exceeded: %d failed orders, %.1f allowed", failed, allowed)
+	}
+	telemetry.EndWithStatus(span, err)
+	return span.SpanContext().TraceID()
+}
+
+func outcomeLink(o worker.OrderOutcome, linkType string, rank int) trace.Link {
+	attrs := []attribute.KeyValue{
+		attribute.String("link.type", linkType),
+		attribute.String("order.id", o.OrderID),
+		attribute.String("worker.id", o.WorkerID),
+		attribute.Int64("order.duration_ms", o.Duration.Milliseconds()),
+		attribute.Int("error_budget.rank", rank),
+	}
+	if o.Err != nil {
+		attrs = append(attrs, attribute.String("exception.message", o.Err.Error()))
+	}
+	return trace.Link{SpanContext: o.SpanCtx, Attributes: attrs}
+}
+
+func (b *ErrorBudget) recordMetrics(successRate, consumed float64) {
+	meter := otel.Meter("error-budget")
+	attrs := metric.WithAttributes(runIDKey.String(runID))
+	if g, err := meter.Float64Gauge(telemetry.MetricErrorBudgetSuccessRate,
+		metric.WithDescription("Share of the run's orders processed successfully"),
+	); err == nil {
+		g.Record(context.Background(), successRate, attrs)
+	}
+	if g, err := meter.Float64Gauge(telemetry.MetricErrorBudgetConsumed,
+		metric.WithDescription("Share of the run's error budget used up by failed orders"),
+	); err == nil {
+		g.Record(context.Background(), consumed, attrs)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"span-links-signoz-demo/pkg/queue"
+
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/global"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Event names of the order business events
+const (
+	OrderPublishedEvent = "order.published"
+	OrderShippedEvent   = "order.shipped"
+)
+
+// orderEventNames maps the transitions that are business events to their event names;
+// validation and payment are steps of processing, not events of their own
+var orderEventNames = map[string]string{
+	queue.OrderPublished: OrderPublishedEvent,
+	queue.OrderShipped:   OrderShippedEvent,
+}
+
+// OrderEvents emits the business events of an order's life (order.published when the
+// producer hands it to the queue, order.shipped when the worker ships it) as OTLP log
+// records with event.name set, for SigNoz's logs and events views. Each record's trace
+// context is the span that produced the event (PublishOrder, ShipOrder), and an event
+// raised in a consumer trace also carries the span that trace links to as
+// link.producer.trace_id and link.producer.span_id, so an event leads to both sides of
+// the link. With payload sealing on, neither event carries order.amount, which sealing
+// keeps off the queue.
+type OrderEvents struct {
+	logger otellog.Logger
+	sealed bool
+}
+
+// NewOrderEvents creates an emitter of order events through the global logger provider;
+// sealed leaves the order amount out of the events
+func NewOrderEvents(sealed bool) *OrderEvents {
+	return &OrderEvents{logger: global.GetLoggerProvider().Logger("order-events"), sealed: sealed}
+}
+
+// RecordTransition emits the event of order reaching state, if the state is a business
+// event
+func (e *OrderEvents) RecordTransition(ctx context.Context, state string, order queue.Order, producer trace.SpanContext) {
+	name, ok := orderEventNames[state]
+	if !ok {
+		return
+	}
+	now := time.Now()
+	attrs := []otellog.KeyValue{
+		otellog.String("order.id", order.ID),
+		otellog.String("customer.id", order.CustomerID),
+		otellog.String("messaging.destination.name", order.Topic),
+		otellog.String(string(runIDKey), runID),
+	}
+	// A sealed order has no amount in clear when published; leave it out of both events
+	if !e.sealed && !order.IsSealed() {
+		attrs = append(attrs, otellog.Float64("order.amount", order.Amount))
+	}
+	// The published event is raised by the producer span itself
+	if producer.IsValid() && !producer.Equal(trace.SpanContextFromContext(ctx)) {
+		attrs = append(attrs,
+			otellog.String(string(auditProducerTraceKey), producer.TraceID().String()),
+			otellog.String(string(auditProducerSpanKey), producer.SpanID().String()),
+		)
+	}
+
+	var rec otellog.Record
+	rec.SetEventName(name)
+	rec.SetTimestamp(now)
+	rec.SetObservedTimestamp(now)
+	rec.SetSeverity(otellog.SeverityInfo)
+	rec.SetSeverityText("INFO")
+	rec.SetBody(otellog.StringValue(fmt.Sprintf("%s %s", name, order.ID)))
+	rec.AddAttributes(attrs...)
+	e.logger.Emit(ctx, rec)
+}
+
+// orderEventsEnabled reports whether order business events are exported (ORDER_EVENTS)
+func orderEventsEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("ORDER_EVENTS"))
+	return err == nil && enabled
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"span-links-signoz-demo/pkg/queue"
+
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/embedded"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordingLogger keeps the records emitted through it
+type recordingLogger struct {
+	embedded.Logger
+	records []otellog.Record
+}
+
+func (l *recordingLogger) Emit(_ context.Context, rec otellog.Record) {
+	l.records = append(l.records, rec)
+}
+
+func (l *recordingLogger) Enabled(context.Context, otellog.EnabledParameters) bool { return true }
+
+// TestOrderEventsSealedAmount checks order.amount is left out of both events when payload
+// sealing is on, whether the order passed in is still sealed (published) or was opened by
+// the worker (shipped)
+func TestOrderEventsSealedAmount(t *testing.T) {
+	s, err := queue.NewSealer("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := queue.Order{ID: "ORDER-1", CustomerID: "CUST-1", Amount: 12.5, Topic: queue.DefaultTopic}
+	sealed, err := s.Encrypt(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed = s.Sign(sealed)
+
+	for _, tc := range []struct {
+		name       string
+		sealing    bool
+		state      string
+		order      queue.Order
+		wantAmount bool
+	}{
+		{name: "published without sealing", state: queue.OrderPublished, order: plain, wantAmount: true},
+		{name: "shipped without sealing", state: queue.OrderShipped, order: plain, wantAmount: true},
+		{name: "published sealed", sealing: true, state: queue.OrderPublished, order: sealed},
+		{name: "shipped after decrypting", sealing: true, state: queue.OrderShipped, order: plain},
+		{name: "sealed order from another producer", state: queue.OrderPublished, order: sealed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			logger := &recordingLogger{}
+			events := &OrderEvents{logger: logger, sealed: tc.sealing}
+			events.RecordTransition(context.Background(), tc.state, tc.order, trace.SpanContext{})
+			if len(logger.records) != 1 {
+				t.Fatalf("emitted %d records, want 1", len(logger.records))
+			}
+			rec := logger.records[0]
+			if want := orderEventNames[tc.state]; rec.EventName() != want {
+				t.Errorf("event name %q, want %q", rec.EventName(), want)
+			}
+			var hasAmount bool
+			rec.WalkAttributes(func(kv otellog.KeyValue) bool {
+				if kv.Key == "order.amount" {
+					hasAmount = true
+				}
+				return true
+			})
+			if hasAmount != tc.wantAmount {
+				t.Errorf("order.amount present = %t, want %t", hasAmount, tc.wantAmount)
+			}
+		})
+	}
+}
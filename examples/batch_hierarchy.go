@@ -0,0 +1,183 @@
+package examples
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"span-links-signoz-demo/spanbuild"
+	"span-links-signoz-demo/spanstore"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Virtual durations of the batch hierarchy example's spans
+const (
+	hierarchyPublishTime = 2 * time.Millisecond
+	hierarchyProcessTime = 30 * time.Millisecond
+	hierarchySummaryTime = 10 * time.Millisecond
+	hierarchyWorkers     = 2
+)
+
+// hierarchyOrder is one order published as part of a sub-batch
+type hierarchyOrder struct {
+	orderID    string
+	subBatchID string
+	publish    trace.SpanContext
+	published  time.Time // when the order was on the queue
+}
+
+// BatchHierarchyExample demonstrates a three-level link hierarchy, on a timeline starting now
+func BatchHierarchyExample(ctx context.Context) {
+	BatchHierarchyExampleOn(ctx, spanbuild.New(otel.Tracer("batch-hierarchy-example"), time.Now()))
+}
+
+// BatchHierarchyExampleOn runs the batch hierarchy example on tl. A PublishSuperBatch span
+// spawns PublishSubBatch child spans, each publishing its orders under PublishOrder spans.
+// Consumers process every order in its own trace, linked to its PublishOrder
+// (link.type=queue_consumption). Once a sub-batch is fully processed, a SubBatchSummary
+// span (new trace) links to its ProcessOrder spans (link.type=sub_batch_member) and to the
+// PublishSubBatch span it summarizes; a SuperBatchSummary span (new trace) then links to
+// every SubBatchSummary (link.type=super_batch_member). Following links down from the
+// super-batch summary reaches every order, and from any order back up via its publish trace.
+// Spans are placed on the timeline rather than timed with sleeps, and orders are handed
+// to the workers round-robin, so every run has the same structure and relative timing.
+func BatchHierarchyExampleOn(ctx context.Context, tl *spanbuild.Timeline) {
+	const (
+		subBatches        = 3
+		ordersPerSubBatch = 4
+	)
+	superBatchID := "super-1"
+
+	// Publishing: super-batch -> sub-batches -> orders, all in one trace
+	superCtx, superSpan := tl.Span("PublishSuperBatch").
+		Kind(trace.SpanKindProducer).
+		Attrs(
+			attribute.String("super_batch.id", superBatchID),
+			attribute.Int("super_batch.sub_batches", subBatches),
+		).
+		Start(ctx)
+	queue := make([]hierarchyOrder, 0, subBatches*ordersPerSubBatch)
+	subBatchSpans := make(map[string]trace.SpanContext, subBatches)
+	subBatchIDs := make([]string, 0, subBatches)
+	for s := 0; s < subBatches; s++ {
+		subBatchID := fmt.Sprintf("%s-sub-%d", superBatchID, s)
+		subCtx, subSpan := tl.Span("PublishSubBatch").
+			Kind(trace.SpanKindProducer).
+			Attrs(
+				attribute.String("super_batch.id", superBatchID),
+				attribute.String("sub_batch.id", subBatchID),
+				attribute.Int("sub_batch.orders", ordersPerSubBatch),
+			).
+			Start(superCtx)
+		for o := 0; o < ordersPerSubBatch; o++ {
+			orderID := fmt.Sprintf("%s-order-%d", subBatchID, o)
+			pub := tl.Span("PublishOrder").
+				Kind(trace.SpanKindProducer).
+				Attrs(
+					attribute.String("order.id", orderID),
+					attribute.String("sub_batch.id", subBatchID),
+				).
+				Lasting(hierarchyPublishTime).
+				Emit(subCtx)
+			queue = append(queue, hierarchyOrder{orderID: orderID, subBatchID: subBatchID, publish: pub, published: tl.Now()})
+		}
+		subSpan.End()
+		subBatchSpans[subBatchID] = subSpan.SpanContext()
+		subBatchIDs = append(subBatchIDs, subBatchID)
+	}
+	superSpan.End()
+	log.Printf("Super-batch published (super_batch.id=%s sub_batches=%d orders=%d trace_id=%s)",
+		superBatchID, subBatches, subBatches*ordersPerSubBatch, superSpan.SpanContext().TraceID())
+
+	// Consumers record each ProcessOrder under its sub-batch for the summaries. Each
+	// worker is a lane of its own on the timeline, taking orders round-robin.
+	processed := spanstore.New(subBatches*ordersPerSubBatch, 0)
+	workers := make([]*spanbuild.Timeline, hierarchyWorkers)
+	for w := range workers {
+		workers[w] = tl.Fork()
+	}
+	for i, msg := range queue {
+		workerID := i % hierarchyWorkers
+		processed.Add(msg.subBatchID, processHierarchyOrder(ctx, workers[workerID], workerID, msg),
+			attribute.String("order.id", msg.orderID))
+	}
+	tl.Join(workers...)
+
+	// Level 2: one summary per sub-batch, linked to its orders
+	summaries := make([]trace.Link, 0, subBatches)
+	for _, subBatchID := range subBatchIDs {
+		members := processed.Take(subBatchID)
+		links := make([]trace.Link, 0, len(members)+1)
+		for i, m := range members {
+			links = append(links, m.Link(
+				attribute.String("link.type", "sub_batch_member"),
+				attribute.Int("member.index", i),
+			))
+		}
+
+		summary := tl.Span("SubBatchSummary").
+			NewRoot().
+			Links(links...).
+			Link(subBatchSpans[subBatchID], attribute.String("link.type", "summarizes")).
+			Attrs(
+				attribute.String("super_batch.id", superBatchID),
+				attribute.String("sub_batch.id", subBatchID),
+				attribute.Int("sub_batch.processed", len(members)),
+			).
+			Lasting(hierarchySummaryTime).
+			Emit(ctx)
+		summaries = append(summaries, trace.Link{
+			SpanContext: summary,
+			Attributes: []attribute.KeyValue{
+				attribute.String("link.type", "super_batch_member"),
+				attribute.String("sub_batch.id", subBatchID),
+				attribute.Int("sub_batch.processed", len(members)),
+			},
+		})
+		log.Printf("Sub-batch summarized (sub_batch.id=%s processed=%d trace_id=%s)",
+			subBatchID, len(members), summary.TraceID())
+	}
+
+	// Level 3: the super-batch summary, linked to the sub-batch summaries
+	superSummary := tl.Span("SuperBatchSummary").
+		NewRoot().
+		Links(summaries...).
+		Link(superSpan.SpanContext(), attribute.String("link.type", "summarizes")).
+		Attrs(
+			attribute.String("super_batch.id", superBatchID),
+			attribute.Int("super_batch.sub_batches", len(summaries)),
+		).
+		Lasting(hierarchySummaryTime).
+		Emit(ctx)
+	log.Printf("Super-batch summarized (super_batch.id=%s sub_batches=%d trace_id=%s)",
+		superBatchID, len(summaries), superSummary.TraceID())
+}
+
+// processHierarchyOrder handles one order on a worker's lane, in a new trace linked to its
+// PublishOrder span, and returns the consumer span context. The worker picks the order
+// up once it is free and the order has been published.
+func processHierarchyOrder(ctx context.Context, lane *spanbuild.Timeline, workerID int, msg hierarchyOrder) trace.SpanContext {
+	start := lane.Now()
+	if msg.published.After(start) {
+		start = msg.published
+	}
+	return lane.Span("ProcessOrder").
+		NewRoot().
+		Kind(trace.SpanKindConsumer).
+		Link(msg.publish,
+			attribute.String("link.type", "queue_consumption"),
+			attribute.String("order.id", msg.orderID),
+		).
+		Attrs(
+			attribute.String("order.id", msg.orderID),
+			attribute.String("sub_batch.id", msg.subBatchID),
+			attribute.Int("worker.id", workerID),
+		).
+		At(start).
+		Lasting(hierarchyProcessTime).
+		Emit(ctx)
+}
@@ -0,0 +1,139 @@
+package examples
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// originTraceParentHeader carries the first producer's context across later hops
+const originTraceParentHeader = "origin-traceparent"
+
+// hopMessage is a queued message with its propagation headers
+type hopMessage struct {
+	orderID  string
+	strategy string
+	headers  propagation.MapCarrier
+}
+
+// ChainedQueueExample demonstrates multi-hop link chains:
+//
+//	PlaceOrder → queue A → EnrichOrder → queue B → FulfillOrder
+//
+// Every hop starts a new trace. EnrichOrder links one hop back to PlaceOrder. FulfillOrder
+// always links one hop back to EnrichOrder; with the "all_hops" strategy it also links
+// all the way back to PlaceOrder, using an origin traceparent header that EnrichOrder
+// forwards unchanged. With "previous_hop" the origin is only reachable by following the
+// chain link by link.
+func ChainedQueueExample(ctx context.Context) {
+	tracer := otel.Tracer("chained-queue-example")
+	propagator := propagation.TraceContext{}
+
+	strategies := []string{"previous_hop", "all_hops"}
+	queueA := make(chan hopMessage, len(strategies))
+	queueB := make(chan hopMessage, len(strategies))
+
+	// Hop 1: PlaceOrder publishes to queue A
+	for i, strategy := range strategies {
+		orderID := fmt.Sprintf("order-%d", i+1)
+		pubCtx, span := tracer.Start(context.Background(), "PlaceOrder",
+			trace.WithSpanKind(trace.SpanKindProducer),
+			trace.WithAttributes(
+				attribute.String("order.id", orderID),
+				attribute.String("messaging.destination.name", "queue-a"),
+				attribute.String("link.strategy", strategy),
+			),
+		)
+		headers := propagation.MapCarrier{}
+		propagator.Inject(pubCtx, headers)
+		headers.Set(originTraceParentHeader, headers.Get("traceparent"))
+		span.End()
+
+		log.Printf("Order placed (order.id=%s strategy=%s)", orderID, strategy)
+		queueA <- hopMessage{orderID: orderID, strategy: strategy, headers: headers}
+	}
+	close(queueA)
+
+	var wg sync.WaitGroup
+
+	// Hop 2: EnrichOrder consumes queue A, links one hop back, publishes to queue B
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(queueB)
+		for msg := range queueA {
+			upstream := trace.SpanContextFromContext(propagator.Extract(context.Background(), msg.headers))
+
+			consumeCtx, span := tracer.Start(context.Background(), "EnrichOrder",
+				trace.WithSpanKind(trace.SpanKindConsumer),
+				trace.WithLinks(hopLink(upstream, "previous_hop", 1)),
+				trace.WithAttributes(
+					attribute.String("order.id", msg.orderID),
+					attribute.String("messaging.source.name", "queue-a"),
+					attribute.String("messaging.destination.name", "queue-b"),
+				),
+			)
+			time.Sleep(50 * time.Millisecond)
+
+			headers := propagation.MapCarrier{}
+			propagator.Inject(consumeCtx, headers)
+			headers.Set(originTraceParentHeader, msg.headers.Get(originTraceParentHeader))
+			span.End()
+
+			queueB <- hopMessage{orderID: msg.orderID, strategy: msg.strategy, headers: headers}
+		}
+	}()
+
+	// Hop 3: FulfillOrder consumes queue B and links one hop or all hops back
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for msg := range queueB {
+			previous := trace.SpanContextFromContext(propagator.Extract(context.Background(), msg.headers))
+			links := []trace.Link{hopLink(previous, "previous_hop", 1)}
+
+			if msg.strategy == "all_hops" {
+				originCarrier := propagation.MapCarrier{"traceparent": msg.headers.Get(originTraceParentHeader)}
+				origin := trace.SpanContextFromContext(propagator.Extract(context.Background(), originCarrier))
+				if origin.IsValid() {
+					links = append(links, hopLink(origin, "origin", 2))
+				}
+			}
+
+			_, span := tracer.Start(context.Background(), "FulfillOrder",
+				trace.WithSpanKind(trace.SpanKindConsumer),
+				trace.WithLinks(links...),
+				trace.WithAttributes(
+					attribute.String("order.id", msg.orderID),
+					attribute.String("messaging.source.name", "queue-b"),
+					attribute.String("link.strategy", msg.strategy),
+					attribute.Int("links.count", len(links)),
+				),
+			)
+			time.Sleep(50 * time.Millisecond)
+			span.End()
+
+			log.Printf("Order fulfilled (order.id=%s strategy=%s links=%d)", msg.orderID, msg.strategy, len(links))
+		}
+	}()
+
+	wg.Wait()
+}
+
+// hopLink links to an upstream span the given number of hops back
+func hopLink(sc trace.SpanContext, linkType string, distance int) trace.Link {
+	return trace.Link{
+		SpanContext: sc,
+		Attributes: []attribute.KeyValue{
+			attribute.String("link.type", linkType),
+			attribute.Int("hop.distance", distance),
+		},
+	}
+}
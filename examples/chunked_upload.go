@@ -0,0 +1,160 @@
+package examples
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// uploadChunk is one published piece of a large payload
+type uploadChunk struct {
+	uploadID string
+	index    int
+	total    int
+	data     []byte
+	upload   trace.SpanContext
+}
+
+// processedChunk is what a chunk consumer reports to the reassembler
+type processedChunk struct {
+	index   int
+	data    []byte
+	spanCtx trace.SpanContext
+}
+
+// ChunkedUploadExample demonstrates chunked-transfer telemetry with links. An UploadPayload
+// span splits a large payload into chunks and publishes each separately. Every
+// ProcessChunk span starts its own trace and links back to the upload span
+// (link.type=chunk_of); a ReassemblePayload span then links to all chunk spans
+// (link.type=reassembled_from), so the whole transfer is navigable from either end.
+func ChunkedUploadExample(ctx context.Context) {
+	tracer := otel.Tracer("chunked-upload-example")
+
+	const (
+		payloadSize = 1 << 20 // 1 MiB
+		chunkSize   = 256 << 10
+	)
+	payload := make([]byte, payloadSize)
+	for i := range payload {
+		payload[i] = byte(i % 251)
+	}
+	uploadID := "upload-1"
+	total := (len(payload) + chunkSize - 1) / chunkSize
+
+	// Upload splits the payload and publishes each chunk
+	_, uploadSpan := tracer.Start(ctx, "UploadPayload",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("upload.id", uploadID),
+			attribute.Int("upload.size_bytes", len(payload)),
+			attribute.Int("upload.chunk_size_bytes", chunkSize),
+			attribute.Int("upload.chunks", total),
+		),
+	)
+	chunks := make(chan uploadChunk, total)
+	for i := 0; i < total; i++ {
+		end := min((i+1)*chunkSize, len(payload))
+		chunks <- uploadChunk{
+			uploadID: uploadID,
+			index:    i,
+			total:    total,
+			data:     payload[i*chunkSize : end],
+			upload:   uploadSpan.SpanContext(),
+		}
+	}
+	close(chunks)
+	uploadSpan.End()
+	log.Printf("Payload uploaded (upload.id=%s size_bytes=%d chunks=%d)", uploadID, len(payload), total)
+
+	// Chunk consumers process chunks concurrently, each in a new trace
+	processed := make(chan processedChunk, total)
+	var wg sync.WaitGroup
+	for w := 0; w < 2; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range chunks {
+				processed <- processChunk(tracer, c)
+			}
+		}()
+	}
+	wg.Wait()
+	close(processed)
+
+	// Reassembly orders the chunks and links to every chunk span
+	parts := make([]processedChunk, total)
+	links := make([]trace.Link, 0, total)
+	for p := range processed {
+		parts[p.index] = p
+	}
+	for _, p := range parts {
+		links = append(links, trace.Link{
+			SpanContext: p.spanCtx,
+			Attributes: []attribute.KeyValue{
+				attribute.String("link.type", "reassembled_from"),
+				attribute.Int("chunk.index", p.index),
+				attribute.Int("chunk.size_bytes", len(p.data)),
+			},
+		})
+	}
+
+	_, reassembleSpan := tracer.Start(context.Background(), "ReassemblePayload",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithLinks(links...),
+		trace.WithAttributes(
+			attribute.String("upload.id", uploadID),
+			attribute.Int("upload.chunks", total),
+		),
+	)
+	defer reassembleSpan.End()
+
+	reassembled := make([]byte, 0, len(payload))
+	for _, p := range parts {
+		reassembled = append(reassembled, p.data...)
+	}
+	want, got := sha256.Sum256(payload), sha256.Sum256(reassembled)
+	reassembleSpan.SetAttributes(
+		attribute.Int("upload.size_bytes", len(reassembled)),
+		attribute.String("upload.sha256", hex.EncodeToString(got[:])),
+		attribute.Bool("upload.checksum_ok", want == got),
+	)
+
+	log.Printf("Payload reassembled (upload.id=%s size_bytes=%d checksum_ok=%t)", uploadID, len(reassembled), want == got)
+}
+
+// processChunk handles one chunk in a new trace linked to the upload span
+func processChunk(tracer trace.Tracer, c uploadChunk) processedChunk {
+	_, span := tracer.Start(context.Background(), "ProcessChunk",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithLinks(trace.Link{
+			SpanContext: c.upload,
+			Attributes: []attribute.KeyValue{
+				attribute.String("link.type", "chunk_of"),
+				attribute.Int("chunk.index", c.index),
+				attribute.Int("chunk.total", c.total),
+			},
+		}),
+		trace.WithAttributes(
+			attribute.String("upload.id", c.uploadID),
+			attribute.Int("chunk.index", c.index),
+			attribute.Int("chunk.total", c.total),
+			attribute.Int("chunk.size_bytes", len(c.data)),
+		),
+	)
+	defer span.End()
+
+	// Simulate per-chunk work (virus scan, checksum, storage write)
+	time.Sleep(40 * time.Millisecond)
+	sum := sha256.Sum256(c.data)
+	span.SetAttributes(attribute.String("chunk.sha256", hex.EncodeToString(sum[:8])))
+	log.Printf("Chunk processed (upload.id=%s chunk=%d/%d)", c.uploadID, c.index+1, c.total)
+
+	return processedChunk{index: c.index, data: c.data, spanCtx: span.SpanContext()}
+}
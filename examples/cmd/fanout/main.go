@@ -3,19 +3,18 @@ package main
 import (
 	"context"
 	"log"
+	"net/url"
 	"os"
 	"strings"
 	"time"
 
 	"span-links-signoz-demo/examples"
+	"span-links-signoz-demo/telemetry"
 
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/propagation"
-	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
 )
 
 func main() {
@@ -38,26 +37,26 @@ func main() {
 func initTracing(ctx context.Context) (*sdktrace.TracerProvider, error) {
 	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
 	if endpoint == "" {
-		endpoint = "http://localhost:4317"
+		endpoint = "http://localhost:4318"
 	}
 	serviceName := os.Getenv("OTEL_SERVICE_NAME")
 	if serviceName == "" {
 		serviceName = "fanout"
 	}
 	headers := parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"))
+	for k, v := range parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_TRACES_HEADERS")) {
+		headers[k] = v
+	}
 
-	res, err := resource.New(ctx,
-		resource.WithAttributes(
-			semconv.ServiceName(serviceName),
-			semconv.ServiceVersion("1.0.0"),
-			attribute.String("environment", "demo"),
-		),
-	)
+	res, err := telemetry.ServiceResource(ctx, serviceName)
 	if err != nil {
 		return nil, err
 	}
 
 	host, insecure := parseEndpoint(endpoint)
+	if strings.HasSuffix(host, ":4317") {
+		log.Printf("WARNING: %s is the OTLP/gRPC port but spans are exported over OTLP/HTTP; use port 4318", host)
+	}
 	opts := []otlptracehttp.Option{
 		otlptracehttp.WithEndpoint(host),
 		otlptracehttp.WithURLPath("/v1/traces"),
@@ -110,10 +109,16 @@ func parseHeaders(headersStr string) map[string]string {
 		}
 		parts := strings.SplitN(pair, "=", 2)
 		if len(parts) == 2 {
-			headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+			headers[unescapeHeader(strings.TrimSpace(parts[0]))] = unescapeHeader(strings.TrimSpace(parts[1]))
 		}
 	}
 	return headers
 }
 
-
+// unescapeHeader percent-decodes s (OTLP header values may be URL-encoded)
+func unescapeHeader(s string) string {
+	if decoded, err := url.PathUnescape(s); err == nil {
+		return decoded
+	}
+	return s
+}
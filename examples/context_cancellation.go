@@ -0,0 +1,145 @@
+package examples
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// cancellableMessage is a queued message plus what the producer knows about it
+type cancellableMessage struct {
+	itemID       string
+	publishSpan  trace.SpanContext
+	acknowledged bool // false when the producer was cancelled before the broker ack
+}
+
+// ContextCancellationExample demonstrates what happens to span links when the producer
+// context is cancelled mid-batch:
+//   - items acknowledged before the deadline: normal backward links
+//   - the item in flight at cancellation: the message reached the queue, but its publish
+//     span ends with an Error status ("context canceled") — the consumer still links to it
+//   - items after cancellation: never published, so nothing links to them
+//
+// A CancellationSummary span links to the consumer spans of orphaned messages
+// (delivered, but the producer believes the publish failed).
+func ContextCancellationExample(ctx context.Context) {
+	tracer := otel.Tracer("context-cancellation-example")
+
+	const itemCount = 10
+	ackDelay := 50 * time.Millisecond
+	cancelAfter := 5*ackDelay + ackDelay/2
+
+	queue := make(chan cancellableMessage, itemCount)
+
+	// Producer: cancelled part-way through the batch
+	producerCtx, cancel := context.WithTimeout(ctx, cancelAfter)
+	defer cancel()
+
+	producerCtx, batchSpan := tracer.Start(producerCtx, "PublishBatch",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.Int("batch.size", itemCount),
+			attribute.Int64("batch.cancel_after_ms", cancelAfter.Milliseconds()),
+		),
+	)
+
+	var acknowledged, notPublished int
+	for i := 0; i < itemCount; i++ {
+		itemID := fmt.Sprintf("item-%d", i+1)
+
+		if producerCtx.Err() != nil {
+			notPublished++
+			continue
+		}
+
+		_, pubSpan := tracer.Start(producerCtx, "PublishItem",
+			trace.WithAttributes(attribute.String("item.id", itemID)),
+		)
+
+		// The message reaches the queue before the broker acknowledges it
+		msg := cancellableMessage{itemID: itemID, publishSpan: pubSpan.SpanContext()}
+
+		select {
+		case <-time.After(ackDelay):
+			msg.acknowledged = true
+			acknowledged++
+			pubSpan.SetStatus(codes.Ok, "acknowledged")
+		case <-producerCtx.Done():
+			pubSpan.RecordError(producerCtx.Err())
+			pubSpan.SetStatus(codes.Error, "context canceled before broker ack")
+		}
+		pubSpan.SetAttributes(attribute.Bool("publish.acknowledged", msg.acknowledged))
+		pubSpan.End()
+		queue <- msg
+	}
+
+	batchSpan.SetAttributes(
+		attribute.Int("batch.acknowledged", acknowledged),
+		attribute.Int("batch.not_published", notPublished),
+	)
+	if err := producerCtx.Err(); err != nil {
+		batchSpan.RecordError(err)
+		batchSpan.SetStatus(codes.Error, "batch cancelled")
+	}
+	batchSpan.End()
+	close(queue)
+
+	// Consumer: processes everything that reached the queue, linking back regardless
+	var orphanLinks []trace.Link
+	var delivered int
+	for msg := range queue {
+		delivered++
+		targetStatus := "ok"
+		if !msg.acknowledged {
+			targetStatus = "cancelled"
+		}
+
+		_, consumeSpan := tracer.Start(context.Background(), "ProcessItem",
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithLinks(trace.Link{
+				SpanContext: msg.publishSpan,
+				Attributes: []attribute.KeyValue{
+					attribute.String("link.type", "queue_consumption"),
+					attribute.String("link.target_status", targetStatus),
+				},
+			}),
+			trace.WithAttributes(
+				attribute.String("item.id", msg.itemID),
+				attribute.Bool("item.orphaned", !msg.acknowledged),
+			),
+		)
+		time.Sleep(20 * time.Millisecond)
+		consumeSpan.End()
+
+		if !msg.acknowledged {
+			orphanLinks = append(orphanLinks, trace.Link{
+				SpanContext: consumeSpan.SpanContext(),
+				Attributes: []attribute.KeyValue{
+					attribute.String("link.type", "orphaned_message"),
+					attribute.String("item.id", msg.itemID),
+				},
+			})
+		}
+	}
+
+	_, summary := tracer.Start(context.Background(), "CancellationSummary",
+		trace.WithLinks(orphanLinks...),
+		trace.WithAttributes(
+			attribute.Int("items.total", itemCount),
+			attribute.Int("items.acknowledged", acknowledged),
+			attribute.Int("items.delivered", delivered),
+			attribute.Int("items.orphaned", len(orphanLinks)),
+			attribute.Int("items.not_published", notPublished),
+		),
+	)
+	summary.End()
+
+	log.Printf("Cancellation summary: total=%d acknowledged=%d delivered=%d orphaned=%d not_published=%d",
+		itemCount, acknowledged, delivered, len(orphanLinks), notPublished)
+}
@@ -2,98 +2,155 @@ package examples
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"sync"
+	"math/rand"
 	"time"
 
+	"span-links-signoz-demo/linkbag"
+	"span-links-signoz-demo/linkprune"
+	"span-links-signoz-demo/spanbuild"
+
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
 
+// FanInOptions shapes the fan-in example
+type FanInOptions struct {
+	Producers      int           // number of producers
+	ProducerDelay  time.Duration // simulated production time per producer
+	FailurePercent float64       // 0-100 chance each producer fails
+	SameTrace      bool          // run producers and aggregator under one FanInRound span instead of separate traces
+	MaxLinks       int           // aggregator link cap; producers beyond it are pruned (0 = no cap)
+	PruneStrategy  linkprune.Strategy
+}
+
+// DefaultFanInOptions returns the classic shape: 3 producers, 150ms each, no failures, new
+// traces, links capped at the SDK limit keeping the first ones
+func DefaultFanInOptions() FanInOptions {
+	return FanInOptions{
+		Producers:     3,
+		ProducerDelay: 150 * time.Millisecond,
+		MaxLinks:      linkprune.DefaultMaxLinks,
+		PruneStrategy: linkprune.KeepFirst,
+	}
+}
+
 // FanInExample demonstrates many-to-one pattern with Span Links
 // Multiple producers create items, one aggregator collects them
 func FanInExample(ctx context.Context) {
-	tracer := otel.Tracer("fanin-example")
+	FanInExampleWithOptions(ctx, DefaultFanInOptions())
+}
+
+// FanInExampleWithOptions runs the fan-in example with a custom shape, on a timeline
+// starting now
+func FanInExampleWithOptions(ctx context.Context, opts FanInOptions) {
+	FanInExampleOn(ctx, spanbuild.New(otel.Tracer("fanin-example"), time.Now()), opts)
+}
+
+// FanInExampleOn runs the fan-in example on tl. Each producer works on a lane of its own,
+// so they overlap as concurrent producers would, and the aggregator starts once the last
+// of them is done. Without failures every run has the same structure and relative timing.
+func FanInExampleOn(ctx context.Context, tl *spanbuild.Timeline, opts FanInOptions) {
+	// Producers start their own traces, or (same-trace) share a FanInRound parent
+	producerParent := context.Background()
+	if opts.SameTrace {
+		var round *spanbuild.Span
+		ctx, round = tl.Span("FanInRound").
+			Attrs(attribute.Int("producers.count", opts.Producers)).
+			Start(ctx)
+		defer round.End()
+		producerParent = ctx
+	}
+
+	// Producers add their spans to a link bag carried in the context instead of
+	// reporting span contexts back
+	producerParent, bag := linkbag.NewContext(producerParent)
 
 	// Simulate multiple producers creating items
-	numProducers := 3
-	results := make(chan string, numProducers)
-	producerSpansChan := make(chan trace.SpanContext, numProducers)
-
-	var wg sync.WaitGroup
-	for i := 0; i < numProducers; i++ {
-		wg.Add(1)
-		go func(producerID int) {
-			defer wg.Done()
-
-			// Each producer creates its own span
-			_, producerSpan := tracer.Start(context.Background(), "ProduceItem",
-				trace.WithAttributes(
-					attribute.Int("producer.id", producerID),
-					attribute.String("item.value", fmt.Sprintf("value-%d", producerID)),
-				),
-			)
-			defer producerSpan.End()
-
-			// Send span context to channel (thread-safe)
-			producerSpansChan <- producerSpan.SpanContext()
-
-			// Simulate production
-			log.Printf("Producer creating item (producer.id=%d)", producerID)
-			time.Sleep(150 * time.Millisecond)
-
-			results <- fmt.Sprintf("item-from-producer-%d", producerID)
-		}(i)
+	var results []string
+	lanes := make([]*spanbuild.Timeline, opts.Producers)
+	for i := range lanes {
+		lanes[i] = tl.Fork()
+		if item, err := produceItem(producerParent, lanes[i], i, opts); err == nil {
+			results = append(results, item)
+		}
 	}
 
 	// Wait for all producers to finish
-	wg.Wait()
-	close(results)
-	close(producerSpansChan)
-
-	// Collect all producer span contexts
-	var producerSpans []trace.SpanContext
-	for spanCtx := range producerSpansChan {
-		producerSpans = append(producerSpans, spanCtx)
-	}
+	tl.Join(lanes...)
 
-	// Create links from aggregator to all producer spans
-	links := make([]trace.Link, 0, len(producerSpans))
-	for i, producerSpanCtx := range producerSpans {
-		links = append(links, trace.Link{
-			SpanContext: producerSpanCtx,
-			Attributes: []attribute.KeyValue{
-				attribute.String("link.type", "fan_in"),
-				attribute.Int("producer.index", i),
-			},
-		})
+	// Number the collected producer links (failed ones included, flagged) in arrival order
+	links := bag.Links()
+	for i := range links {
+		links[i].Attributes = append(links[i].Attributes, attribute.Int("producer.index", i))
 	}
-
-	// Create aggregator span with links to all producers
-	ctx, aggregatorSpan := tracer.Start(ctx, "AggregateResults",
-		trace.WithLinks(links...),
-		trace.WithAttributes(
+	produced := len(links)
+
+	// Create aggregator span with links to all producers, pruned to the link cap
+	links, omitted := linkprune.Prune(links, opts.MaxLinks, opts.PruneStrategy)
+	_, aggregatorSpan := tl.Span("AggregateResults").
+		Links(links...).
+		Attrs(linkprune.Attributes(opts.PruneStrategy, len(links), omitted)...).
+		Attrs(
 			attribute.String("aggregation.id", uuid.New().String()),
-			attribute.Int("items.count", len(producerSpans)),
-		),
-	)
+			attribute.Int("items.count", produced),
+			attribute.Bool("fanin.same_trace", opts.SameTrace),
+		).
+		Start(ctx)
 	defer aggregatorSpan.End()
 
 	// Aggregate results
 	aggregated := []string{}
-	for result := range results {
+	for _, result := range results {
 		aggregated = append(aggregated, result)
 		log.Printf("Aggregated item (item=%s)", result)
 	}
 
 	aggregatorSpan.AddEvent("Aggregation completed",
+		trace.WithTimestamp(tl.Now()),
 		trace.WithAttributes(
 			attribute.Int("aggregated.count", len(aggregated)),
+			attribute.Int("failed.count", produced-len(aggregated)),
 		),
 	)
 
-	log.Printf("Aggregation completed (items.count=%d)", len(aggregated))
+	log.Printf("Aggregation completed (items.count=%d failed.count=%d)", len(aggregated), produced-len(aggregated))
+}
+
+// produceItem creates one item under a ProduceItem span on the producer's lane and adds
+// that span to the link bag in ctx (link.type=fan_in), whether or not production fails
+func produceItem(ctx context.Context, lane *spanbuild.Timeline, producerID int, opts FanInOptions) (string, error) {
+	ctx, producerSpan := lane.Span("ProduceItem").
+		Attrs(
+			attribute.Int("producer.id", producerID),
+			attribute.String("item.value", fmt.Sprintf("value-%d", producerID)),
+		).
+		Lasting(opts.ProducerDelay).
+		Start(ctx)
+	defer producerSpan.End()
+
+	// Simulate production
+	log.Printf("Producer creating item (producer.id=%d)", producerID)
+
+	var err error
+	if rand.Float64()*100 < opts.FailurePercent {
+		err = errors.New("producer failed to create item")
+		producerSpan.RecordError(err)
+		producerSpan.SetStatus(codes.Error, err.Error())
+	}
+
+	linkbag.Add(ctx, producerSpan.SpanContext(),
+		attribute.String("link.type", "fan_in"),
+		attribute.Int("producer.id", producerID),
+		attribute.Bool("producer.failed", err != nil),
+	)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("item-from-producer-%d", producerID), nil
 }
@@ -2,38 +2,73 @@ package examples
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"math/rand"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
 
+// FanOutOptions shapes the fan-out example
+type FanOutOptions struct {
+	Items          int           // items in the batch
+	WorkerDelay    time.Duration // simulated processing time per item
+	FailurePercent float64       // 0-100 chance each item fails
+	SameTrace      bool          // process items as children of the batch span (same trace) instead of new traces
+}
+
+// DefaultFanOutOptions returns the classic shape: 5 items, 200ms each, no failures, new traces
+func DefaultFanOutOptions() FanOutOptions {
+	return FanOutOptions{Items: 5, WorkerDelay: 200 * time.Millisecond}
+}
+
 // FanOutExample demonstrates one-to-many pattern with Span Links
 // One producer creates a batch, multiple workers process items in parallel
 func FanOutExample(ctx context.Context) {
+	FanOutExampleWithOptions(ctx, DefaultFanOutOptions())
+}
+
+// FanOutExampleWithOptions runs the fan-out example with a custom shape
+func FanOutExampleWithOptions(ctx context.Context, opts FanOutOptions) {
 	tracer := otel.Tracer("fanout-example")
 
 	// Create a root span for the batch operation
 	ctx, rootSpan := tracer.Start(ctx, "CreateBatch",
 		trace.WithAttributes(
 			attribute.String("batch.id", uuid.New().String()),
-			attribute.Int("batch.size", 5),
+			attribute.Int("batch.size", opts.Items),
+			attribute.Bool("fanout.same_trace", opts.SameTrace),
+			attribute.Float64("fanout.failure_percent", opts.FailurePercent),
 		),
 	)
 	defer rootSpan.End()
 
 	rootSpanCtx := rootSpan.SpanContext()
 	batchID := uuid.New().String()
-	items := []string{"item-1", "item-2", "item-3", "item-4", "item-5"}
+	items := make([]string, opts.Items)
+	for i := range items {
+		items[i] = fmt.Sprintf("item-%d", i+1)
+	}
 
 	log.Printf("Creating batch (batch.id=%s items.count=%d)", batchID, len(items))
 
+	// Items start new traces linked to the batch, or (same-trace) are its children
+	itemParent := context.Background()
+	if opts.SameTrace {
+		itemParent = ctx
+	}
+
 	// Fan-out: Process each item in parallel with Span Links
 	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failed int
 	for i, item := range items {
 		wg.Add(1)
 		go func(idx int, itemID string) {
@@ -49,8 +84,8 @@ func FanOutExample(ctx context.Context) {
 				},
 			}
 
-			// Create a new span with link (new trace, but linked to batch)
-			_, itemSpan := tracer.Start(context.Background(), "ProcessItem",
+			// Create a new span with link (new trace unless same-trace, but linked to batch)
+			_, itemSpan := tracer.Start(itemParent, "ProcessItem",
 				trace.WithLinks(link),
 				trace.WithAttributes(
 					attribute.String("item.id", itemID),
@@ -62,7 +97,17 @@ func FanOutExample(ctx context.Context) {
 
 			// Simulate processing
 			log.Printf("Processing item (item.id=%s batch.id=%s)", itemID, batchID)
-			time.Sleep(200 * time.Millisecond)
+			time.Sleep(opts.WorkerDelay)
+
+			if rand.Float64()*100 < opts.FailurePercent {
+				err := errors.New("item processing failed")
+				itemSpan.RecordError(err)
+				itemSpan.SetStatus(codes.Error, err.Error())
+				mu.Lock()
+				failed++
+				mu.Unlock()
+				return
+			}
 
 			itemSpan.AddEvent("Item processed",
 				trace.WithAttributes(
@@ -77,9 +122,10 @@ func FanOutExample(ctx context.Context) {
 
 	rootSpan.AddEvent("Batch processing completed",
 		trace.WithAttributes(
-			attribute.Int("processed.count", len(items)),
+			attribute.Int("processed.count", len(items)-failed),
+			attribute.Int("failed.count", failed),
 		),
 	)
 
-	log.Printf("Batch processing completed (batch.id=%s processed.count=%d)", batchID, len(items))
+	log.Printf("Batch processing completed (batch.id=%s processed.count=%d failed.count=%d)", batchID, len(items)-failed, failed)
 }
@@ -0,0 +1,113 @@
+package examples
+
+import (
+	"context"
+	"crypto/sha256"
+	"log"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// legacyMessage is a message from a producer without OpenTelemetry: no traceparent, only
+// the correlation ID the legacy system uses in its own logs
+type legacyMessage struct {
+	correlationID string
+	orderID       string
+	publishedAt   time.Time
+}
+
+// BridgeSpanContext derives a synthetic remote SpanContext from the correlation ID a
+// non-OTel system attached to a message. The trace and span IDs are the first 24 bytes
+// of SHA-256 over system and ID, so every consumer that sees the same correlation ID
+// derives the same context, and links to it from unrelated traces meet in one place.
+// Namespacing by system keeps equal IDs of different systems apart.
+func BridgeSpanContext(system, correlationID string) trace.SpanContext {
+	sum := sha256.Sum256([]byte(system + "\x00" + correlationID))
+	var tid trace.TraceID
+	var sid trace.SpanID
+	copy(tid[:], sum[:16])
+	copy(sid[:], sum[16:24])
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    tid,
+		SpanID:     sid,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+}
+
+// ForeignCorrelationExample demonstrates bridging a legacy producer that knows nothing
+// about W3C trace context. Its messages carry only a correlation ID, so consumers derive
+// a synthetic SpanContext from it (BridgeSpanContext) and link to that
+// (link.type=external_correlation, link.synthetic=true). Two independent consumers —
+// ProcessLegacyOrder and AuditLegacyOrder, each in its own trace — derive the same context
+// and so link to the same place. A LegacyPublish span recorded inside the derived trace
+// (parented on the synthetic span, backdated to the legacy publish time) gives those links
+// a trace to open; without it they point at a trace that does not exist, which is still
+// enough to find every consumer of one correlation ID by trace ID.
+func ForeignCorrelationExample(ctx context.Context) {
+	tracer := otel.Tracer("foreign-correlation-example")
+	const system = "legacy-erp"
+
+	// The legacy producer publishes without any tracing
+	now := time.Now()
+	messages := []legacyMessage{
+		{correlationID: "ERP-2024-000117", orderID: "order-1", publishedAt: now.Add(-1500 * time.Millisecond)},
+		{correlationID: "ERP-2024-000118", orderID: "order-2", publishedAt: now.Add(-900 * time.Millisecond)},
+		{correlationID: "ERP-2024-000119", orderID: "order-3", publishedAt: now.Add(-300 * time.Millisecond)},
+	}
+
+	for _, msg := range messages {
+		bridged := BridgeSpanContext(system, msg.correlationID)
+
+		// Record the legacy publish inside the derived trace so the links resolve
+		_, publishSpan := tracer.Start(trace.ContextWithRemoteSpanContext(ctx, bridged), "LegacyPublish",
+			trace.WithSpanKind(trace.SpanKindProducer),
+			trace.WithTimestamp(msg.publishedAt),
+			trace.WithAttributes(
+				attribute.String("correlation.id", msg.correlationID),
+				attribute.String("correlation.system", system),
+				attribute.String("order.id", msg.orderID),
+				attribute.Bool("bridge.synthetic_parent", true),
+			),
+		)
+		publishSpan.End(trace.WithTimestamp(msg.publishedAt.Add(5 * time.Millisecond)))
+
+		// Each consumer derives the context on its own; neither knows about the other
+		processSpan := consumeLegacyMessage(ctx, tracer, "ProcessLegacyOrder", system, msg)
+		auditSpan := consumeLegacyMessage(ctx, tracer, "AuditLegacyOrder", system, msg)
+
+		log.Printf("Legacy message bridged (correlation.id=%s bridged.trace_id=%s process.trace_id=%s audit.trace_id=%s)",
+			msg.correlationID, bridged.TraceID(), processSpan.TraceID(), auditSpan.TraceID())
+	}
+}
+
+// consumeLegacyMessage handles msg in a new trace linked to the context derived from its
+// correlation ID and returns the consumer span context
+func consumeLegacyMessage(ctx context.Context, tracer trace.Tracer, name, system string, msg legacyMessage) trace.SpanContext {
+	_, span := tracer.Start(ctx, name,
+		trace.WithNewRoot(),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithLinks(trace.Link{
+			SpanContext: BridgeSpanContext(system, msg.correlationID),
+			Attributes: []attribute.KeyValue{
+				attribute.String("link.type", "external_correlation"),
+				attribute.Bool("link.synthetic", true),
+				attribute.String("correlation.id", msg.correlationID),
+				attribute.String("correlation.system", system),
+			},
+		}),
+		trace.WithAttributes(
+			attribute.String("order.id", msg.orderID),
+			attribute.String("correlation.id", msg.correlationID),
+			attribute.String("messaging.message.conversation_id", msg.correlationID),
+			attribute.Int64("messaging.queue_wait_ms", time.Since(msg.publishedAt).Milliseconds()),
+		),
+	)
+	defer span.End()
+
+	time.Sleep(20 * time.Millisecond)
+	return span.SpanContext()
+}
@@ -0,0 +1,152 @@
+package examples_test
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"span-links-signoz-demo/examples"
+	"span-links-signoz-demo/spanbuild"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files from the current output")
+
+// goldenStart is the fixed start of the timelines, so offsets and window bounds are stable
+var goldenStart = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// TestGolden runs the timeline-based examples on a fixed-start timeline and compares
+// their trace structure (names, parents, links, attributes, timing relative to the
+// start) with testdata/<name>.golden. Run with -update after an intended change.
+func TestGolden(t *testing.T) {
+	for name, run := range map[string]func(context.Context, *spanbuild.Timeline){
+		"batch_hierarchy": examples.BatchHierarchyExampleOn,
+		"fanin": func(ctx context.Context, tl *spanbuild.Timeline) {
+			examples.FanInExampleOn(ctx, tl, examples.DefaultFanInOptions())
+		},
+		"fanin_same_trace": func(ctx context.Context, tl *spanbuild.Timeline) {
+			opts := examples.DefaultFanInOptions()
+			opts.SameTrace = true
+			examples.FanInExampleOn(ctx, tl, opts)
+		},
+		"time_window": func(ctx context.Context, tl *spanbuild.Timeline) {
+			examples.TimeWindowExampleOn(ctx, tl, examples.DefaultTimeWindowOptions())
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			defer tp.Shutdown(context.Background())
+
+			run(context.Background(), spanbuild.New(tp.Tracer(name), goldenStart))
+			got := describe(exporter.GetSpans())
+
+			path := filepath.Join("testdata", name+".golden")
+			if *update {
+				if err := os.MkdirAll("testdata", 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if got != string(want) {
+				t.Errorf("trace structure differs from %s (run with -update if intended):\n%s", path, lineDiff(string(want), got))
+			}
+		})
+	}
+}
+
+// volatileAttrs differ on every run and are left out of the golden output
+var volatileAttrs = map[attribute.Key]bool{"aggregation.id": true}
+
+// describe renders spans in export order with IDs replaced by stable labels: traces by
+// order of first appearance (T0, T1, ...), spans by name and occurrence (ProcessOrder#2)
+func describe(spans tracetest.SpanStubs) string {
+	traces := make(map[trace.TraceID]string)
+	labels := make(map[trace.SpanID]string)
+	counts := make(map[string]int)
+	for _, s := range spans {
+		if _, ok := traces[s.SpanContext.TraceID()]; !ok {
+			traces[s.SpanContext.TraceID()] = fmt.Sprintf("T%d", len(traces))
+		}
+		labels[s.SpanContext.SpanID()] = fmt.Sprintf("%s#%d", s.Name, counts[s.Name])
+		counts[s.Name]++
+	}
+	label := func(sc trace.SpanContext) string {
+		if l, ok := labels[sc.SpanID()]; ok {
+			return l
+		}
+		return "(unknown)"
+	}
+
+	var b strings.Builder
+	for _, s := range spans {
+		fmt.Fprintf(&b, "%s trace=%s start=+%s duration=%s", labels[s.SpanContext.SpanID()],
+			traces[s.SpanContext.TraceID()], s.StartTime.Sub(goldenStart), s.EndTime.Sub(s.StartTime))
+		if s.Parent.IsValid() {
+			fmt.Fprintf(&b, " parent=%s", label(s.Parent))
+		}
+		if s.SpanKind != trace.SpanKindInternal {
+			fmt.Fprintf(&b, " kind=%s", s.SpanKind)
+		}
+		b.WriteString(attrs(s.Attributes))
+		b.WriteString("\n")
+		for _, l := range s.Links {
+			fmt.Fprintf(&b, "  link -> %s%s\n", label(l.SpanContext), attrs(l.Attributes))
+		}
+		for _, e := range s.Events {
+			fmt.Fprintf(&b, "  event %s at=+%s%s\n", e.Name, e.Time.Sub(goldenStart), attrs(e.Attributes))
+		}
+	}
+	return b.String()
+}
+
+// attrs renders attributes sorted by key, without the volatile ones
+func attrs(kvs []attribute.KeyValue) string {
+	parts := make([]string, 0, len(kvs))
+	for _, kv := range kvs {
+		if !volatileAttrs[kv.Key] {
+			parts = append(parts, fmt.Sprintf("%s=%s", kv.Key, kv.Value.Emit()))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	sort.Strings(parts)
+	return " " + strings.Join(parts, " ")
+}
+
+// lineDiff lists the lines that differ between want and got
+func lineDiff(want, got string) string {
+	w, g := strings.Split(want, "\n"), strings.Split(got, "\n")
+	var b strings.Builder
+	for i := 0; i < len(w) || i < len(g); i++ {
+		var wl, gl string
+		if i < len(w) {
+			wl = w[i]
+		}
+		if i < len(g) {
+			gl = g[i]
+		}
+		if wl != gl {
+			fmt.Fprintf(&b, "line %d:\n- %s\n+ %s\n", i+1, wl, gl)
+		}
+	}
+	return b.String()
+}
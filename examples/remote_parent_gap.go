@@ -0,0 +1,74 @@
+package examples
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RemoteParentGapExample demonstrates the downside of forcing async work into
+// parent-child via a remote parent context: the parent finishes, and the child
+// starts later (via a handoff channel), inflating apparent latency within one trace.
+func RemoteParentGapExample(ctx context.Context) {
+	tracer := otel.Tracer("remote-parent-gap")
+
+	// Artificial delay to make the "gap" visible in UIs (simulates queue/scheduler delay).
+	// Set REMOTE_PARENT_GAP_DELAY_MS to control it.
+	delay := 2000 * time.Millisecond
+	if v := os.Getenv("REMOTE_PARENT_GAP_DELAY_MS"); v != "" {
+		if ms, err := strconv.Atoi(v); err == nil && ms >= 0 {
+			delay = time.Duration(ms) * time.Millisecond
+		}
+	}
+
+	// Channel simulates a remote handoff of the parent context
+	carrierCh := make(chan propagation.MapCarrier, 1)
+
+	// Parent ends quickly, then hands off its context
+	parentCtx, parentSpan := tracer.Start(ctx, "ParentRequest",
+		trace.WithAttributes(
+			attribute.String("note", "ends immediately"),
+			attribute.Int64("demo.gap_delay_ms", delay.Milliseconds()),
+		),
+	)
+	parentSpan.End()
+
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(parentCtx, carrier)
+	carrierCh <- carrier
+	close(carrierCh)
+
+	// Async worker starts when it receives the carrier (after an artificial delay)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		carrier, ok := <-carrierCh
+		if !ok {
+			return
+		}
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+		remoteCtx := otel.GetTextMapPropagator().Extract(context.Background(), carrier)
+
+		_, childSpan := tracer.Start(remoteCtx, "AsyncWorkerChild",
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				attribute.String("note", "remote-parent-handshake"),
+				attribute.Int64("demo.gap_delay_ms", delay.Milliseconds()),
+			),
+		)
+		// Do real work here if desired (no sleep needed)
+		childSpan.End()
+	}()
+
+	<-done
+	log.Printf("Done. In SigNoz, you’ll see one trace: parent ends immediately; child starts later via remote context after %s, inflating apparent end-to-end duration.", delay)
+}
@@ -13,9 +13,50 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
+// Jitter strategies for retry backoff (see the AWS "Exponential Backoff And Jitter" article)
+const (
+	JitterNone         = "none"         // base * 2^(retry-1), capped
+	JitterFull         = "full"         // random between 0 and the capped exponential delay
+	JitterEqual        = "equal"        // half the capped exponential delay plus a random half
+	JitterDecorrelated = "decorrelated" // random between base and 3x the previous delay, capped
+)
+
+// RetryOptions configures the retry policy of the retry example
+type RetryOptions struct {
+	MaxRetries    int           // retries after the original attempt
+	BaseDelay     time.Duration // backoff before the first retry
+	MaxDelay      time.Duration // backoff cap
+	Jitter        string        // one of the Jitter* strategies
+	FailureScript string        // per-attempt outcomes, 'F' = fail, 'S' = succeed; attempts beyond it succeed with SuccessRate
+	SuccessRate   float64       // 0..1 chance an unscripted attempt succeeds
+	LinkPrevious  bool          // also link each retry to attempt N-1, forming a traversable chain
+}
+
+// DefaultRetryOptions returns the classic behaviour: the first attempt fails, up to two
+// retries with 70% success each
+func DefaultRetryOptions() RetryOptions {
+	return RetryOptions{
+		MaxRetries:    2,
+		BaseDelay:     100 * time.Millisecond,
+		MaxDelay:      2 * time.Second,
+		Jitter:        JitterNone,
+		FailureScript: "F",
+		SuccessRate:   0.7,
+	}
+}
+
 // RetryExample demonstrates retry pattern with Span Links
 // Each retry attempt links back to the original attempt
+// (link.type=retry_of_original) and, with LinkPrevious, to the attempt before it
+// (link.type=retry_of_previous)
 func RetryExample(ctx context.Context) {
+	RetryExampleWithOptions(ctx, DefaultRetryOptions())
+}
+
+// RetryExampleWithOptions runs the retry example with a custom retry policy. The backoff
+// waited before each retry is recorded on the retry's link and span (retry.backoff_ms),
+// so retry behaviour can be analysed from the traces.
+func RetryExampleWithOptions(ctx context.Context, opts RetryOptions) {
 	tracer := otel.Tracer("retry-example")
 	requestID := "req-123"
 
@@ -24,13 +65,16 @@ func RetryExample(ctx context.Context) {
 		trace.WithAttributes(
 			attribute.String("request.id", requestID),
 			attribute.Int("attempt", 1),
+			attribute.String("retry.jitter", opts.Jitter),
+			attribute.Int("retry.max_retries", opts.MaxRetries),
 		),
 	)
 
 	originalSpanCtx := originalSpan.SpanContext()
+	previousSpanCtx := originalSpanCtx
 
 	// Simulate processing that might fail
-	success := simulateProcessing(ctx, originalSpan, 1)
+	success := simulateProcessing(ctx, originalSpan, 1, opts)
 	originalSpan.End()
 
 	if success {
@@ -39,62 +83,107 @@ func RetryExample(ctx context.Context) {
 	}
 
 	// Retry logic with Span Links
-	maxRetries := 3
-	for attempt := 2; attempt <= maxRetries; attempt++ {
-		log.Printf("Retrying request (request.id=%s attempt=%d max_retries=%d)", requestID, attempt, maxRetries)
+	maxAttempts := opts.MaxRetries + 1
+	var backoff time.Duration
+	for attempt := 2; attempt <= maxAttempts; attempt++ {
+		// Wait before the retry
+		backoff = nextBackoff(opts, attempt-1, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			log.Printf("Retry abandoned (request.id=%s attempt=%d): %v", requestID, attempt, ctx.Err())
+			return
+		}
+
+		log.Printf("Retrying request (request.id=%s attempt=%d max_retries=%d backoff=%s)", requestID, attempt, opts.MaxRetries, backoff)
 
 		// Create a link to the original span
-		link := trace.Link{
+		links := []trace.Link{{
 			SpanContext: originalSpanCtx,
 			Attributes: []attribute.KeyValue{
-				attribute.String("link.type", "retry"),
+				attribute.String("link.type", "retry_of_original"),
 				attribute.Int("retry.attempt", attempt),
 				attribute.String("original.request.id", requestID),
+				attribute.Int64("retry.backoff_ms", backoff.Milliseconds()),
+				attribute.String("retry.jitter", opts.Jitter),
 			},
+		}}
+
+		// Optionally also link to the immediately previous attempt (the original for attempt 2)
+		if opts.LinkPrevious && !previousSpanCtx.Equal(originalSpanCtx) {
+			links = append(links, trace.Link{
+				SpanContext: previousSpanCtx,
+				Attributes: []attribute.KeyValue{
+					attribute.String("link.type", "retry_of_previous"),
+					attribute.Int("retry.attempt", attempt),
+					attribute.Int("retry.previous_attempt", attempt-1),
+					attribute.Int64("retry.backoff_ms", backoff.Milliseconds()),
+				},
+			})
 		}
 
-		// Create retry span with link
+		// Create retry span with links
 		retryCtx, retrySpan := tracer.Start(context.Background(), "ProcessRequest",
-			trace.WithLinks(link),
+			trace.WithLinks(links...),
 			trace.WithAttributes(
 				attribute.String("request.id", requestID),
 				attribute.Int("attempt", attempt),
 				attribute.Bool("is_retry", true),
+				attribute.Int64("retry.backoff_ms", backoff.Milliseconds()),
 			),
 		)
 
 		// Simulate processing
-		success := simulateProcessing(retryCtx, retrySpan, attempt)
+		success := simulateProcessing(retryCtx, retrySpan, attempt, opts)
 		retrySpan.End()
+		previousSpanCtx = retrySpan.SpanContext()
 
 		if success {
 			log.Printf("Request processed successfully (request.id=%s attempt=%d)", requestID, attempt)
 			return
 		}
+	}
 
-		// Wait before next retry (exponential backoff)
-		backoff := time.Duration(attempt) * 100 * time.Millisecond
-		time.Sleep(backoff)
+	log.Printf("Request failed after all retry attempts (request.id=%s max_retries=%d)", requestID, opts.MaxRetries)
+}
+
+// nextBackoff computes the delay before the given retry (1-based) from the policy and the
+// previous delay (used by decorrelated jitter)
+func nextBackoff(opts RetryOptions, retry int, previous time.Duration) time.Duration {
+	exp := opts.BaseDelay << (retry - 1)
+	if exp > opts.MaxDelay || exp <= 0 {
+		exp = opts.MaxDelay
 	}
 
-	log.Printf("Request failed after all retry attempts (request.id=%s max_retries=%d)", requestID, maxRetries)
+	switch opts.Jitter {
+	case JitterFull:
+		return time.Duration(rand.Int63n(int64(exp) + 1))
+	case JitterEqual:
+		return exp/2 + time.Duration(rand.Int63n(int64(exp/2)+1))
+	case JitterDecorrelated:
+		if previous < opts.BaseDelay {
+			previous = opts.BaseDelay
+		}
+		d := opts.BaseDelay + time.Duration(rand.Int63n(int64(3*previous-opts.BaseDelay)+1))
+		return min(d, opts.MaxDelay)
+	default:
+		return exp
+	}
 }
 
 // simulateProcessing simulates a processing operation that might fail
-func simulateProcessing(ctx context.Context, span trace.Span, attempt int) bool {
+func simulateProcessing(ctx context.Context, span trace.Span, attempt int, opts RetryOptions) bool {
 	// Simulate processing time
 	time.Sleep(50 * time.Millisecond)
 
-	// First attempt always fails to demonstrate retry
-	if attempt == 1 {
-		err := fmt.Errorf("processing failed on attempt %d", attempt)
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "Processing failed")
-		return false
+	// Scripted attempts fail or succeed deterministically; the rest are random
+	succeeded := rand.Float64() < opts.SuccessRate
+	if attempt <= len(opts.FailureScript) {
+		succeeded = opts.FailureScript[attempt-1] == 'S'
+		span.SetAttributes(attribute.Bool("retry.scripted", true))
 	}
 
-	// Subsequent attempts have 70% success rate
-	if rand.Float32() < 0.7 {
+	if succeeded {
 		span.AddEvent("Processing succeeded",
 			trace.WithAttributes(
 				attribute.String("status", "success"),
@@ -6,6 +6,8 @@ import (
 	"sync"
 	"time"
 
+	"span-links-signoz-demo/spanstore"
+
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -31,7 +33,8 @@ func SameTraceSpanLinks(ctx context.Context) {
 	defer root.End()
 
 	shardIDs := []string{"shard-a", "shard-b", "shard-c", "shard-d"}
-	workerSpanContexts := make([]trace.SpanContext, len(shardIDs))
+	// Workers record their spans by shard ID; the aggregator looks them up afterwards
+	results := spanstore.New(len(shardIDs), 0)
 
 	// If you want worker spans to link *forward* to the aggregator, the aggregator must exist
 	// while workers run (so they can reference its SpanContext). This makes the aggregator
@@ -81,11 +84,12 @@ func SameTraceSpanLinks(ctx context.Context) {
 				})
 			}
 
-			workerSpanContexts[idx] = workerSpan.SpanContext()
+			workerSC := workerSpan.SpanContext()
+			results.Add(shardID, workerSC, attribute.String("shard.id", shardID))
 			workerSpan.End()
 
 			log.Printf("Shard %s completed (trace=%s span=%s)",
-				shardID, workerSpanContexts[idx].TraceID(), workerSpanContexts[idx].SpanID())
+				shardID, workerSC.TraceID(), workerSC.SpanID())
 
 			_ = workerCtx
 		}(i, shard)
@@ -95,18 +99,14 @@ func SameTraceSpanLinks(ctx context.Context) {
 
 	// Aggregator runs after workers finish. It is still in the SAME trace (root ctx),
 	// but it links back to all worker spans to express N:1 relationship.
-	links := make([]trace.Link, 0, len(workerSpanContexts))
-	for i, sc := range workerSpanContexts {
-		if sc.IsValid() {
-			links = append(links, trace.Link{
-				SpanContext: sc,
-				Attributes: []attribute.KeyValue{
-					attribute.String("link.type", "shard_result"),
-					attribute.String("shard.id", shardIDs[i]),
-					attribute.String("link.direction", "backward"),
-					attribute.String("link.trace_relationship", "same_trace"),
-				},
-			})
+	links := make([]trace.Link, 0, len(shardIDs))
+	for _, shardID := range shardIDs {
+		if result, ok := results.Latest(shardID); ok {
+			links = append(links, result.Link(
+				attribute.String("link.type", "shard_result"),
+				attribute.String("link.direction", "backward"),
+				attribute.String("link.trace_relationship", "same_trace"),
+			))
 		}
 	}
 
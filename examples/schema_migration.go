@@ -0,0 +1,120 @@
+package examples
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// versionedOrder is an order message in either schema version
+type versionedOrder struct {
+	id            string
+	schemaVersion int
+	amount        float64 // v1: dollars
+	amountCents   int64   // v2: minor units
+	currency      string  // v2
+	publishSpan   trace.SpanContext
+}
+
+// SchemaMigrationExample demonstrates message schema versioning with links. A v1 order is
+// processed by the legacy consumer; a MigrateOrder transformer (new trace) links back to
+// that old-format processing span, re-publishes the order as v2, and — once the v2
+// consumer has picked it up — adds a forward link to the new-format processing span. The
+// transformer span is therefore the bridge between the two formats' traces.
+func SchemaMigrationExample(ctx context.Context) {
+	tracer := otel.Tracer("schema-migration-example")
+
+	for i, amount := range []float64{19.99, 250.00} {
+		orderID := fmt.Sprintf("order-%d", i+1)
+
+		// Legacy producer publishes v1
+		_, pubSpan := tracer.Start(ctx, "PublishOrder",
+			trace.WithSpanKind(trace.SpanKindProducer),
+			trace.WithAttributes(
+				attribute.String("order.id", orderID),
+				attribute.Int("messaging.message.schema_version", 1),
+			),
+		)
+		v1 := versionedOrder{id: orderID, schemaVersion: 1, amount: amount, publishSpan: pubSpan.SpanContext()}
+		pubSpan.End()
+
+		// Legacy consumer processes v1
+		v1Span := processVersionedOrder(tracer, v1, v1.publishSpan)
+
+		// Transformer upgrades v1 → v2, linking back to the old-format processing span
+		migrateCtx, migrateSpan := tracer.Start(context.Background(), "MigrateOrder",
+			trace.WithLinks(trace.Link{
+				SpanContext: v1Span,
+				Attributes: []attribute.KeyValue{
+					attribute.String("link.type", "migrated_from"),
+					attribute.Int("messaging.message.schema_version", 1),
+				},
+			}),
+			trace.WithAttributes(
+				attribute.String("order.id", orderID),
+				attribute.Int("schema.from_version", 1),
+				attribute.Int("schema.to_version", 2),
+			),
+		)
+		v2 := versionedOrder{
+			id:            orderID,
+			schemaVersion: 2,
+			amountCents:   int64(math.Round(v1.amount * 100)),
+			currency:      "USD",
+			publishSpan:   trace.SpanContextFromContext(migrateCtx),
+		}
+
+		// New consumer processes v2, then the transformer links forward to it
+		v2Span := processVersionedOrder(tracer, v2, v2.publishSpan)
+		migrateSpan.AddLink(trace.Link{
+			SpanContext: v2Span,
+			Attributes: []attribute.KeyValue{
+				attribute.String("link.type", "migrated_to"),
+				attribute.Int("messaging.message.schema_version", 2),
+			},
+		})
+		migrateSpan.End()
+
+		log.Printf("Order migrated (order.id=%s v1.amount=%.2f v2.amount_cents=%d)", orderID, v1.amount, v2.amountCents)
+	}
+}
+
+// processVersionedOrder consumes an order in a new trace linking to its producer span,
+// decoding it according to its schema version, and returns the processing span context
+func processVersionedOrder(tracer trace.Tracer, order versionedOrder, producer trace.SpanContext) trace.SpanContext {
+	_, span := tracer.Start(context.Background(), fmt.Sprintf("ProcessOrderV%d", order.schemaVersion),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithLinks(trace.Link{
+			SpanContext: producer,
+			Attributes: []attribute.KeyValue{
+				attribute.String("link.type", "queue_consumption"),
+				attribute.Int("messaging.message.schema_version", order.schemaVersion),
+			},
+		}),
+		trace.WithAttributes(
+			attribute.String("order.id", order.id),
+			attribute.Int("messaging.message.schema_version", order.schemaVersion),
+		),
+	)
+	defer span.End()
+
+	// v1 carries float dollars; v2 carries integer cents plus a currency
+	switch order.schemaVersion {
+	case 1:
+		span.SetAttributes(attribute.Float64("order.amount", order.amount))
+	case 2:
+		span.SetAttributes(
+			attribute.Int64("order.amount_cents", order.amountCents),
+			attribute.String("order.currency", order.currency),
+		)
+	}
+	time.Sleep(30 * time.Millisecond)
+
+	return span.SpanContext()
+}
@@ -0,0 +1,153 @@
+package examples
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"span-links-signoz-demo/spanbuild"
+	"span-links-signoz-demo/spanstore"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TimeWindowOptions shapes the time-window fan-in example
+type TimeWindowOptions struct {
+	Window   time.Duration // length of each aggregation window
+	Windows  int           // number of windows to aggregate before stopping
+	Workers  int           // concurrent processing workers
+	Capacity int           // span store size; spans evicted before their window closes are lost
+}
+
+// DefaultTimeWindowOptions returns 3 one-second windows over 4 workers, with a ring of 48
+// span contexts (about a window's worth, so a few evictions show up)
+func DefaultTimeWindowOptions() TimeWindowOptions {
+	return TimeWindowOptions{
+		Window:   time.Second,
+		Windows:  3,
+		Workers:  4,
+		Capacity: 48,
+	}
+}
+
+// Virtual durations of the time-window example's spans
+const (
+	windowItemMinTime   = 40 * time.Millisecond
+	windowItemSpread    = 9 // item durations vary over this many 10ms steps above the minimum
+	windowAggregateTime = 10 * time.Millisecond
+)
+
+// TimeWindowExample demonstrates time-bucketed fan-in with the default options
+func TimeWindowExample(ctx context.Context) {
+	TimeWindowExampleWithOptions(ctx, DefaultTimeWindowOptions())
+}
+
+// TimeWindowExampleWithOptions runs the time-window example on a timeline starting now
+func TimeWindowExampleWithOptions(ctx context.Context, opts TimeWindowOptions) {
+	TimeWindowExampleOn(ctx, spanbuild.New(otel.Tracer("time-window-example"), time.Now()), opts)
+}
+
+// TimeWindowExampleOn runs workers that process items continuously on tl, each
+// ProcessItem in its own trace, and an aggregation job that wakes at the end of every
+// window. Its AggregateWindow span (a new trace) links to every ProcessItem that finished
+// within the window (link.type=window_member). Finished spans are kept in a bounded
+// spanstore.Store rather than an ever-growing slice; those overwritten before their window
+// closed are reported as window.items_evicted instead of linked.
+// Each worker is a lane of its own on the timeline and item durations follow a fixed
+// pattern, so every run has the same windows, members and evictions.
+func TimeWindowExampleOn(ctx context.Context, tl *spanbuild.Timeline, opts TimeWindowOptions) {
+	// The store sees the simulated time: when an item ended, or when a window closes.
+	// Spans left over from a late aggregation expire after two windows.
+	var now time.Time
+	store := spanstore.NewWithClock(opts.Capacity, 2*opts.Window, func() time.Time { return now })
+
+	workers := make([]*spanbuild.Timeline, opts.Workers)
+	items := make([]int, opts.Workers)
+	for w := range workers {
+		workers[w] = tl.Fork()
+	}
+
+	start := tl.Now()
+	evicted := 0
+	for w := 0; w < opts.Windows && ctx.Err() == nil; w++ {
+		from := start.Add(time.Duration(w) * opts.Window)
+		to := from.Add(opts.Window)
+		// Workers pick up items in time order until the window closes; an item still
+		// running then ends in the next window
+		for {
+			workerID := nextWorker(workers)
+			if !workers[workerID].Now().Before(to) {
+				break
+			}
+			itemID := fmt.Sprintf("item-%d-%d", workerID, items[workerID])
+			spanCtx := processWindowItem(ctx, workers[workerID], workerID, items[workerID], itemID)
+			items[workerID]++
+			now = workers[workerID].Now()
+			store.Add(itemID, spanCtx, attribute.String("item.id", itemID))
+		}
+		now = to
+		members := store.TakeBetween(from, to)
+		aggregateWindow(ctx, tl, members, store.Evicted()-evicted, w, from, to)
+		evicted = store.Evicted()
+	}
+	tl.Join(workers...)
+}
+
+// nextWorker returns the worker that is free first (the lowest ID on ties)
+func nextWorker(workers []*spanbuild.Timeline) int {
+	next := 0
+	for w := range workers {
+		if workers[w].Now().Before(workers[next].Now()) {
+			next = w
+		}
+	}
+	return next
+}
+
+// processWindowItem processes the n-th item of a worker on its lane, in a new trace, and
+// returns its finished span
+func processWindowItem(ctx context.Context, lane *spanbuild.Timeline, workerID, n int, itemID string) trace.SpanContext {
+	spread := time.Duration((workerID*7+n*13)%windowItemSpread) * 10 * time.Millisecond
+	return lane.Span("ProcessItem").
+		NewRoot().
+		Kind(trace.SpanKindConsumer).
+		Attrs(
+			attribute.String("item.id", itemID),
+			attribute.Int("worker.id", workerID),
+		).
+		Lasting(windowItemMinTime + spread).
+		Emit(ctx)
+}
+
+// aggregateWindow emits the AggregateWindow span for [from, to) at the window's end,
+// linked to the spans the store still held for it
+func aggregateWindow(ctx context.Context, tl *spanbuild.Timeline, members []spanstore.Entry, evicted, index int, from, to time.Time) {
+	links := make([]trace.Link, 0, len(members))
+	for _, m := range members {
+		links = append(links, m.Link(
+			attribute.String("link.type", "window_member"),
+			attribute.Int64("item.ended_offset_ms", m.Recorded.Sub(from).Milliseconds()),
+		))
+	}
+
+	span := tl.Span("AggregateWindow").
+		NewRoot().
+		Links(links...).
+		Attrs(
+			attribute.Int("window.index", index),
+			attribute.String("window.start", from.Format(time.RFC3339Nano)),
+			attribute.String("window.end", to.Format(time.RFC3339Nano)),
+			attribute.Int64("window.length_ms", to.Sub(from).Milliseconds()),
+			attribute.Int("window.items", len(members)),
+			attribute.Int("window.items_evicted", evicted),
+		).
+		At(to).
+		Lasting(windowAggregateTime).
+		Emit(ctx)
+
+	log.Printf("Window aggregated (window.index=%d items=%d evicted=%d trace_id=%s)",
+		index, len(members), evicted, span.TraceID())
+}
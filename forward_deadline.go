@@ -0,0 +1,43 @@
+package main
+
+import "time"
+
+// processingEstimate is the expected processing time of an order in forward-link mode,
+// a moving average of the durations consumers report. It outlives a batch, so later
+// batches (FORWARD_BATCHES) get deadlines fitted to the run.
+type processingEstimate struct {
+	expected time.Duration
+	observed bool
+}
+
+// newProcessingEstimate starts at ForwardLinkExpectedProcessing
+func newProcessingEstimate() *processingEstimate {
+	return &processingEstimate{expected: ForwardLinkExpectedProcessing}
+}
+
+// observe folds a reported processing duration into the estimate
+func (e *processingEstimate) observe(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	if !e.observed {
+		e.expected, e.observed = d, true
+		return
+	}
+	e.expected = (3*e.expected + d) / 4
+}
+
+// deadline returns how long after publishing to wait for the consumer of the order at
+// position (0-based) in its batch: the orders ahead of it are spread over workers, then
+// it is processed itself
+func (e *processingEstimate) deadline(position, workers int, maxWait time.Duration) time.Duration {
+	rounds := position/max(workers, 1) + 1
+	return min(time.Duration(rounds*ForwardLinkDeadlineSlack)*e.expected, maxWait)
+}
+
+// forwardLinkMaxWaitFromEnv reads FORWARD_LINK_MAX_WAIT_MS, the longest the producer waits
+// for an order's consumer to report in forward-link mode
+func forwardLinkMaxWaitFromEnv() time.Duration {
+	ms := intAtLeastFromEnv("FORWARD_LINK_MAX_WAIT_MS", int(DefaultForwardLinkMaxWait.Milliseconds()), 1)
+	return time.Duration(ms) * time.Millisecond
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// seededIDGenerator generates trace and span IDs from a seeded random source, so a run
+// started with the same seed produces the same IDs in the same order (as far as span
+// start order is deterministic). Use it for reproducible demos, never in production.
+type seededIDGenerator struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+var _ sdktrace.IDGenerator = (*seededIDGenerator)(nil)
+
+func newSeededIDGenerator(seed int64) *seededIDGenerator {
+	return &seededIDGenerator{rng: rand.New(rand.NewSource(seed))}
+}
+
+// NewIDs returns a new trace ID and the ID of its root span
+func (g *seededIDGenerator) NewIDs(context.Context) (trace.TraceID, trace.SpanID) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var tid trace.TraceID
+	var sid trace.SpanID
+	for !tid.IsValid() {
+		_, _ = g.rng.Read(tid[:])
+	}
+	for !sid.IsValid() {
+		_, _ = g.rng.Read(sid[:])
+	}
+	return tid, sid
+}
+
+// NewSpanID returns a new span ID in traceID
+func (g *seededIDGenerator) NewSpanID(context.Context, trace.TraceID) trace.SpanID {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var sid trace.SpanID
+	for !sid.IsValid() {
+		_, _ = g.rng.Read(sid[:])
+	}
+	return sid
+}
+
+// traceIDSeedFromEnv reads TRACE_ID_SEED, the seed of reproducible trace and span IDs
+// (false when unset)
+func traceIDSeedFromEnv() (int64, bool) {
+	val := os.Getenv("TRACE_ID_SEED")
+	if val == "" {
+		return 0, false
+	}
+	seed, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		log.Printf("Ignoring invalid TRACE_ID_SEED=%q", val)
+		return 0, false
+	}
+	return seed, true
+}
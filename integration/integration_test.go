@@ -0,0 +1,234 @@
+//go:build integration
+
+// Package integration runs the producer/worker flow against an OpenTelemetry Collector
+// container and asserts the exported link structure. Run with Docker available
+// (the tests skip without it):
+//
+//	go test -tags integration ./integration/
+package integration
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"span-links-signoz-demo/otlpjson"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// defaultCollectorImage is the collector the tests run against unless COLLECTOR_IMAGE is set
+const defaultCollectorImage = "otel/opentelemetry-collector-contrib:0.88.0"
+
+// profile is the traffic profile the backward-link runs replay
+const profile = "traffic/bursty.csv"
+
+func TestBackwardLinks(t *testing.T) {
+	orders := profileOrders(t)
+	for name, env := range map[string][]string{
+		"default":            nil,
+		"semconv_span_kinds": {"SEMCONV_SPAN_KINDS=true"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			spans := runAgainstCollector(t, append([]string{"TRAFFIC_PROFILE_FILE=" + profile}, env...)...)
+			consumed := checkConsumerLinks(t, spans)
+			if len(consumed) != orders {
+				t.Errorf("distinct orders consumed: %d, want %d", len(consumed), orders)
+			}
+			checkRunSummary(t, spans)
+		})
+	}
+}
+
+func TestForwardLinks(t *testing.T) {
+	spans := runAgainstCollector(t, "ENABLE_FORWARD_LINKS_TO_PRODUCER=true", "FORWARD_BATCHES=1")
+	if consumed := checkConsumerLinks(t, spans); len(consumed) == 0 {
+		t.Fatal("no orders consumed")
+	}
+	checkRunSummary(t, spans)
+
+	index := otlpjson.Index(spans)
+	var forward int
+	for _, s := range spans {
+		for _, l := range s.Links {
+			if l.Attributes["link.type"] != "forward_to_consumer" {
+				continue
+			}
+			forward++
+			if s.Name != "PublishOutcome" {
+				t.Errorf("forward link on %s, want PublishOutcome", s.Name)
+			}
+			target, ok := index[l.Key()]
+			if !ok {
+				t.Errorf("forward link of order %s does not resolve", orderID(s))
+				continue
+			}
+			if orderID(target) != orderID(s) {
+				t.Errorf("forward link of order %s targets order %s", orderID(s), orderID(target))
+			}
+			if parent, ok := index[s.TraceID+"/"+s.ParentSpanID]; !ok || parent.Name != "PublishOrder" {
+				t.Errorf("PublishOutcome of order %s is not a child of its PublishOrder span", orderID(s))
+			}
+		}
+	}
+	if forward == 0 {
+		t.Error("no forward_to_consumer links exported")
+	}
+}
+
+// checkConsumerLinks asserts every queue_consumption link resolves to an exported producer
+// span for the same order in another trace, and returns the orders consumed
+func checkConsumerLinks(t *testing.T, spans []otlpjson.Span) map[string]bool {
+	t.Helper()
+	index := otlpjson.Index(spans)
+	consumed := make(map[string]bool)
+	for _, s := range spans {
+		for _, l := range s.Links {
+			if l.Attributes["link.type"] != "queue_consumption" {
+				continue
+			}
+			target, ok := index[l.Key()]
+			switch {
+			case !ok:
+				t.Errorf("%s link of order %s does not resolve to an exported span", s.Name, orderID(s))
+			case target.TraceID == s.TraceID:
+				t.Errorf("%s of order %s links into its own trace", s.Name, orderID(s))
+			case orderID(target) != orderID(s):
+				t.Errorf("%s of order %s links to %s of order %s", s.Name, orderID(s), target.Name, orderID(target))
+			default:
+				consumed[orderID(s)] = true
+			}
+		}
+	}
+	return consumed
+}
+
+// checkRunSummary asserts there is one RunSummary span and all its links resolve
+func checkRunSummary(t *testing.T, spans []otlpjson.Span) {
+	t.Helper()
+	index := otlpjson.Index(spans)
+	var summaries int
+	for _, s := range spans {
+		if s.Name == "RunSummary" {
+			summaries++
+		}
+		if s.Name != "RunSummary" && s.Name != "RunSummaryPage" {
+			continue
+		}
+		for _, l := range s.Links {
+			if _, ok := index[l.Key()]; !ok {
+				t.Errorf("%s link to %s does not resolve", s.Name, l.Key())
+			}
+		}
+	}
+	if summaries != 1 {
+		t.Errorf("RunSummary spans: %d, want 1", summaries)
+	}
+}
+
+// runAgainstCollector starts a collector with a file exporter, runs the app against it with
+// env added to the environment, stops the collector to flush it and returns the spans it
+// wrote. The test is skipped when Docker is not available.
+func runAgainstCollector(t *testing.T, env ...string) []otlpjson.Span {
+	t.Helper()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	config, err := filepath.Abs("collector.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := t.TempDir()
+	// The collector writes as its own user
+	if err := os.Chmod(out, 0o777); err != nil {
+		t.Fatal(err)
+	}
+
+	image := os.Getenv("COLLECTOR_IMAGE")
+	if image == "" {
+		image = defaultCollectorImage
+	}
+	collector, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        image,
+			Cmd:          []string{"--config=/etc/otelcol/config.yaml"},
+			ExposedPorts: []string{"4318/tcp"},
+			HostConfigModifier: func(hc *container.HostConfig) {
+				hc.Binds = append(hc.Binds, config+":/etc/otelcol/config.yaml:ro", out+":/out")
+			},
+			WaitingFor: wait.ForLog("Everything is ready"),
+		},
+		Started: true,
+	})
+	testcontainers.CleanupContainer(t, collector)
+	if err != nil {
+		t.Fatalf("start collector: %v", err)
+	}
+	endpoint, err := collector.PortEndpoint(ctx, "4318/tcp", "http")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := exec.CommandContext(ctx, "go", "run", ".")
+	app.Dir = ".."
+	app.Env = append(os.Environ(),
+		"OTEL_EXPORTER_OTLP_ENDPOINT="+endpoint,
+		"OTEL_EXPORTER_OTLP_PROTOCOL=http/protobuf",
+		"OTEL_SERVICE_NAME=span-links-integration",
+	)
+	app.Env = append(app.Env, env...)
+	if output, err := app.CombinedOutput(); err != nil {
+		t.Fatalf("app run failed: %v\n%s", err, output)
+	}
+
+	// Stopping the collector flushes the batch processor and file exporter
+	timeout := 30 * time.Second
+	if err := collector.Stop(ctx, &timeout); err != nil {
+		t.Fatalf("stop collector: %v", err)
+	}
+	spans, err := otlpjson.LoadFile(filepath.Join(out, "traces.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spans) == 0 {
+		t.Fatal("collector exported no spans")
+	}
+	return spans
+}
+
+// profileOrders returns the number of orders in the traffic profile (its lines after the
+// header)
+func profileOrders(t *testing.T) int {
+	t.Helper()
+	f, err := os.Open(filepath.Join("..", profile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var lines int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if scanner.Text() != "" {
+			lines++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return lines - 1
+}
+
+// orderID returns the order a producer or consumer span is about
+func orderID(s otlpjson.Span) string {
+	if id := s.Attributes["order.id"]; id != "" {
+		return id
+	}
+	return s.Attributes["messaging.message.id"]
+}
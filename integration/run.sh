@@ -0,0 +1,67 @@
+#!/bin/bash
+
+# End-to-end link check: starts an OpenTelemetry Collector container with a file
+# exporter, runs the producer/worker flow against it, and asserts the exported OTLP
+# JSON contains the expected link structure (spanlinks verify). Forward-link runs are
+# also checked for backward/forward link symmetry (spanlinks consistency).
+#
+# Requires Docker. Usage: ./integration/run.sh [extra env for the app, e.g. SEMCONV_SPAN_KINDS=true]
+
+set -euo pipefail
+
+cd "$(dirname "$0")/.."
+
+COLLECTOR_IMAGE="${COLLECTOR_IMAGE:-otel/opentelemetry-collector-contrib:0.88.0}"
+CONTAINER="spanlinks-integration-collector"
+HTTP_PORT="${INTEGRATION_OTLP_HTTP_PORT:-14318}"
+OUT_DIR="$(pwd)/integration/out"
+PROFILE="traffic/bursty.csv"
+EXPECTED_ORDERS=$(tail -n +2 "$PROFILE" | grep -c .)
+
+rm -rf "$OUT_DIR"
+mkdir -p "$OUT_DIR"
+chmod 777 "$OUT_DIR"
+
+cleanup() {
+    docker rm -f "$CONTAINER" >/dev/null 2>&1 || true
+}
+trap cleanup EXIT
+
+echo "Starting collector ($COLLECTOR_IMAGE)..."
+docker run -d --name "$CONTAINER" \
+    -p "$HTTP_PORT:4318" \
+    -v "$(pwd)/integration/collector.yaml:/etc/otelcol/config.yaml:ro" \
+    -v "$OUT_DIR:/out" \
+    "$COLLECTOR_IMAGE" --config=/etc/otelcol/config.yaml >/dev/null
+
+for _ in $(seq 1 30); do
+    if curl -s -o /dev/null -X POST -H 'Content-Type: application/json' -d '{}' "http://localhost:$HTTP_PORT/v1/traces"; then
+        break
+    fi
+    sleep 1
+done
+
+echo "Running producer/worker flow ($EXPECTED_ORDERS orders)..."
+env OTEL_EXPORTER_OTLP_ENDPOINT="http://localhost:$HTTP_PORT" \
+    OTEL_SERVICE_NAME="span-links-integration" \
+    TRAFFIC_PROFILE_FILE="$PROFILE" \
+    "$@" go run .
+
+# Stopping the collector flushes the batch processor and file exporter
+docker stop "$CONTAINER" >/dev/null
+
+# Forward-link mode publishes its own batches instead of replaying the profile
+FORWARD=false
+for arg in "$@"; do
+    case "$arg" in
+        ENABLE_FORWARD_LINKS_TO_PRODUCER=true) FORWARD=true ;;
+    esac
+done
+
+if [ "$FORWARD" = true ]; then
+    go run ./cmd/spanlinks verify "$OUT_DIR/traces.json"
+    go run ./cmd/spanlinks consistency "$OUT_DIR/traces.json"
+else
+    go run ./cmd/spanlinks verify -expect-orders "$EXPECTED_ORDERS" "$OUT_DIR/traces.json"
+fi
+go run ./cmd/spanlinks analyze "$OUT_DIR/traces.json"
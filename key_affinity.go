@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"span-links-signoz-demo/pkg/queue"
+	"span-links-signoz-demo/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// KeyAffinityRouter pins every customer to one worker: orders are moved from the queue
+// into per-worker channels keyed by customer ID, so one customer's orders are processed
+// sequentially, in publish order.
+type KeyAffinityRouter struct {
+	workerIDs []string
+	channels  map[string]chan queue.Order
+}
+
+// NewKeyAffinityRouter creates a router for the given workers
+func NewKeyAffinityRouter(workerIDs []string) *KeyAffinityRouter {
+	channels := make(map[string]chan queue.Order, len(workerIDs))
+	for _, id := range workerIDs {
+		channels[id] = make(chan queue.Order, queue.DefaultCapacity)
+	}
+	return &KeyAffinityRouter{workerIDs: workerIDs, channels: channels}
+}
+
+// Dispatch moves orders from the queue topics to their customer's worker until ctx is done
+func (r *KeyAffinityRouter) Dispatch(ctx context.Context, queue *queue.SimpleQueue, topics ...string) {
+	for {
+		order, err := queue.Consume(ctx, topics...)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		select {
+		case r.channels[r.WorkerFor(order.CustomerID)] <- order:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// WorkerFor returns the worker a customer's orders are pinned to
+func (r *KeyAffinityRouter) WorkerFor(customerID string) string {
+	h := fnv.New32a()
+	h.Write([]byte(customerID))
+	return r.workerIDs[h.Sum32()%uint32(len(r.workerIDs))]
+}
+
+// Consume retrieves the next order pinned to workerID
+func (r *KeyAffinityRouter) Consume(ctx context.Context, workerID string) (queue.Order, error) {
+	select {
+	case order := <-r.channels[workerID]:
+		return order, nil
+	case <-ctx.Done():
+		return queue.Order{}, ctx.Err()
+	}
+}
+
+// Length returns the number of orders waiting in worker channels
+func (r *KeyAffinityRouter) Length() int {
+	var n int
+	for _, ch := range r.channels {
+		n += len(ch)
+	}
+	return n
+}
+
+// windowEntry is one processed order within an ordering window
+type windowEntry struct {
+	orderID   string
+	workerID  string
+	spanCtx   trace.SpanContext
+	createdAt time.Time
+	processed time.Time
+}
+
+// OrderingWindows collects processed orders per customer and, every window, emits one
+// OrderingWindow span (new trace) per customer linking to each ProcessOrder span in the
+// window in processing order. ordering.in_order and ordering.workers show whether the
+// customer's orders were processed in publish order by a single worker.
+type OrderingWindows struct {
+	tracer trace.Tracer
+	window time.Duration
+
+	mu      sync.Mutex
+	windows map[string][]windowEntry // customer ID -> processed orders in this window
+}
+
+// NewOrderingWindows creates a tracker flushing every window
+func NewOrderingWindows(window time.Duration) *OrderingWindows {
+	return &OrderingWindows{
+		tracer:  otel.Tracer("ordering-windows"),
+		window:  window,
+		windows: make(map[string][]windowEntry),
+	}
+}
+
+// Record adds a processed order to its customer's current window
+func (o *OrderingWindows) Record(workerID string, order queue.Order, spanCtx trace.SpanContext) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.windows[order.CustomerID] = append(o.windows[order.CustomerID], windowEntry{
+		orderID:   order.ID,
+		workerID:  workerID,
+		spanCtx:   spanCtx,
+		createdAt: order.CreatedAt,
+		processed: time.Now(),
+	})
+}
+
+// Run flushes windows every window interval until ctx is done
+func (o *OrderingWindows) Run(ctx context.Context) {
+	ticker := time.NewTicker(o.window)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			o.Flush()
+		}
+	}
+}
+
+// Flush emits an OrderingWindow span for every customer with orders in the current window
+func (o *OrderingWindows) Flush() {
+	o.mu.Lock()
+	windows := o.windows
+	o.windows = make(map[string][]windowEntry)
+	o.mu.Unlock()
+
+	customers := make([]string, 0, len(windows))
+	for c := range windows {
+		customers = append(customers, c)
+	}
+	sort.Strings(customers)
+
+	for _, customerID := range customers {
+		entries := windows[customerID]
+		links := make([]trace.Link, 0, len(entries))
+		workers := make(map[string]bool)
+		inOrder := true
+		for i, e := range entries {
+			links = append(links, trace.Link{
+				SpanContext: e.spanCtx,
+				Attributes: []attribute.KeyValue{
+					attribute.String("link.type", "ordering_window_member"),
+					attribute.String("order.id", e.orderID),
+					attribute.Int("ordering.sequence", i),
+					attribute.String("worker.id", e.workerID),
+				},
+			})
+			workers[e.workerID] = true
+			if i > 0 && e.createdAt.Before(entries[i-1].createdAt) {
+				inOrder = false
+			}
+		}
+
+		_, span := o.tracer.Start(context.Background(), "OrderingWindow",
+			trace.WithTimestamp(entries[0].processed),
+			trace.WithLinks(links...),
+			trace.WithAttributes(
+				attribute.String("customer.id", customerID),
+				attribute.Int64("ordering.window_ms", o.window.Milliseconds()),
+				attribute.Int("ordering.orders", len(entries)),
+				attribute.Int("ordering.workers", len(workers)),
+				attribute.Bool("ordering.in_order", inOrder),
+			),
+		)
+		telemetry.EndWithStatus(span, nil, trace.WithTimestamp(entries[len(entries)-1].processed))
+
+		if !inOrder || len(workers) > 1 {
+			log.Printf("Ordering window violation (customer=%s orders=%d workers=%d in_order=%t)", customerID, len(entries), len(workers), inOrder)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Lifecycle runs the app's teardown as an ordered list of named shutdown hooks. Hooks run
+// in reverse registration order, like defers: register a component's hook right after
+// creating it and it is torn down after everything that depends on it (queue drain, then
+// workers, then end-of-run reporting, then clients and servers, then the telemetry
+// providers that flush what all of them recorded). Each hook gets its own timeout; one
+// that overruns is logged and left behind so the rest of the teardown still happens.
+type Lifecycle struct {
+	mu    sync.Mutex
+	hooks []shutdownHook
+}
+
+type shutdownHook struct {
+	name    string
+	timeout time.Duration
+	stop    func(ctx context.Context) error
+}
+
+// NewLifecycle creates a lifecycle with no hooks
+func NewLifecycle() *Lifecycle {
+	return &Lifecycle{}
+}
+
+// OnShutdown registers stop to run at shutdown with the given timeout
+func (l *Lifecycle) OnShutdown(name string, timeout time.Duration, stop func(ctx context.Context) error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, shutdownHook{name: name, timeout: timeout, stop: stop})
+}
+
+// Shutdown runs the registered hooks, last registered first. Hooks run once: a later
+// call (e.g. a deferred one after an explicit shutdown) only runs hooks registered since.
+func (l *Lifecycle) Shutdown() {
+	l.mu.Lock()
+	hooks := l.hooks
+	l.hooks = nil
+	l.mu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i].run()
+	}
+}
+
+func (h shutdownHook) run() {
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+
+	started := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- h.stop(ctx) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			log.Printf("Shutdown: %s failed after %s: %v", h.name, time.Since(started).Round(time.Millisecond), err)
+			return
+		}
+		log.Printf("Shutdown: %s done in %s", h.name, time.Since(started).Round(time.Millisecond))
+	case <-ctx.Done():
+		log.Printf("Shutdown: %s timed out after %s, moving on", h.name, h.timeout)
+	}
+}
+
+// closeHook adapts a Close-style function to a shutdown hook
+func closeHook(close func() error) func(context.Context) error {
+	return func(context.Context) error { return close() }
+}
+
+// stopHook adapts a function without result to a shutdown hook
+func stopHook(stop func()) func(context.Context) error {
+	return func(context.Context) error {
+		stop()
+		return nil
+	}
+}
+
+// waitHook returns a hook waiting for wg, giving up when the hook times out
+func waitHook(wg *sync.WaitGroup) func(context.Context) error {
+	return func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// drainHook returns a hook polling drained until it reports true
+func drainHook(drained func() bool) func(context.Context) error {
+	return func(ctx context.Context) error {
+		for !drained() {
+			select {
+			case <-time.After(50 * time.Millisecond):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestLifecycleOrder checks hooks run last registered first, a failing hook does not stop
+// the teardown, and hooks run once
+func TestLifecycleOrder(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	hook := func(name string, err error) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, name)
+			return err
+		}
+	}
+
+	l := NewLifecycle()
+	l.OnShutdown("telemetry", time.Second, hook("telemetry", nil))
+	l.OnShutdown("server", time.Second, hook("server", errors.New("address in use")))
+	l.OnShutdown("workers", time.Second, hook("workers", nil))
+	l.Shutdown()
+	if want := []string{"workers", "server", "telemetry"}; !slices.Equal(calls, want) {
+		t.Fatalf("hooks ran as %v, want %v", calls, want)
+	}
+
+	// A later call only runs hooks registered since
+	calls = nil
+	l.OnShutdown("late", time.Second, hook("late", nil))
+	l.Shutdown()
+	l.Shutdown()
+	if want := []string{"late"}; !slices.Equal(calls, want) {
+		t.Errorf("later shutdowns ran %v, want %v", calls, want)
+	}
+}
+
+// TestLifecycleTimeout checks a hook that overruns its timeout is left behind with its
+// context cancelled and the hooks after it still run
+func TestLifecycleTimeout(t *testing.T) {
+	const timeout = 20 * time.Millisecond
+	release := make(chan struct{})
+	defer close(release)
+	cancelled := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1) // never done: waitHook gives up at its timeout
+	var ran bool
+
+	l := NewLifecycle()
+	l.OnShutdown("telemetry", time.Second, stopHook(func() { ran = true }))
+	l.OnShutdown("workers", timeout, waitHook(&wg))
+	l.OnShutdown("stuck", timeout, func(ctx context.Context) error {
+		<-ctx.Done()
+		close(cancelled)
+		<-release // ignores the cancellation, as a misbehaving client would
+		return nil
+	})
+
+	began := time.Now()
+	l.Shutdown()
+	if elapsed := time.Since(began); elapsed > time.Second {
+		t.Errorf("shutdown took %s, want the two timeouts of %s", elapsed, timeout)
+	}
+	if !ran {
+		t.Error("hook after the timed-out ones did not run")
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("the stuck hook's context was not cancelled")
+	}
+}
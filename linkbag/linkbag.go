@@ -0,0 +1,68 @@
+// Package linkbag carries "spans to link later" in a context, so deeply nested code can
+// add link targets for an aggregator span without threading SpanContext slices through
+// every function signature.
+package linkbag
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Bag collects links; it is safe for concurrent use
+type Bag struct {
+	mu    sync.Mutex
+	links []trace.Link
+}
+
+// bagKey is the context key of the Bag
+type bagKey struct{}
+
+// NewContext returns a copy of ctx carrying a new, empty Bag, and the Bag itself
+func NewContext(ctx context.Context) (context.Context, *Bag) {
+	b := &Bag{}
+	return context.WithValue(ctx, bagKey{}, b), b
+}
+
+// FromContext returns the Bag carried by ctx, or nil
+func FromContext(ctx context.Context) *Bag {
+	b, _ := ctx.Value(bagKey{}).(*Bag)
+	return b
+}
+
+// Add records a link to spanCtx in the Bag carried by ctx. It reports whether the link
+// was recorded: false when ctx carries no Bag or spanCtx is invalid.
+func Add(ctx context.Context, spanCtx trace.SpanContext, attrs ...attribute.KeyValue) bool {
+	b := FromContext(ctx)
+	if b == nil {
+		return false
+	}
+	return b.Add(spanCtx, attrs...)
+}
+
+// Add records a link to spanCtx, reporting false (and recording nothing) if it is invalid
+func (b *Bag) Add(spanCtx trace.SpanContext, attrs ...attribute.KeyValue) bool {
+	if !spanCtx.IsValid() {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.links = append(b.links, trace.Link{SpanContext: spanCtx, Attributes: attrs})
+	return true
+}
+
+// Links returns a copy of the collected links, in the order they were added
+func (b *Bag) Links() []trace.Link {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]trace.Link(nil), b.links...)
+}
+
+// Len returns the number of collected links
+func (b *Bag) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.links)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// linkEdgeColumns is the header row of the link edge CSV
+var linkEdgeColumns = []string{
+	"run_id",
+	"source_trace_id", "source_span_id", "source_span_name",
+	"target_trace_id", "target_span_id",
+	"link_type", "attributes",
+}
+
+// LinkEdgeWriter is a span processor writing one CSV row per span link (source span ->
+// linked span) as spans end, for analyzing the link graph outside the tracing backend
+// (pandas, DuckDB). All link attributes go into the attributes column as a JSON object;
+// link.type also gets its own column. The file is complete once the tracer provider has
+// shut down.
+type LinkEdgeWriter struct {
+	mu    sync.Mutex
+	file  *os.File
+	csv   *csv.Writer
+	edges int
+}
+
+var _ sdktrace.SpanProcessor = (*LinkEdgeWriter)(nil)
+
+// NewLinkEdgeWriter creates (or truncates) the CSV file at path and writes its header
+func NewLinkEdgeWriter(path string) (*LinkEdgeWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create link edge file: %w", err)
+	}
+	w := &LinkEdgeWriter{file: f, csv: csv.NewWriter(f)}
+	if err := w.csv.Write(linkEdgeColumns); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write link edge header: %w", err)
+	}
+	return w, nil
+}
+
+// OnEnd writes a row for every link of s
+func (w *LinkEdgeWriter) OnEnd(s sdktrace.ReadOnlySpan) {
+	if len(s.Links()) == 0 {
+		return
+	}
+	source := s.SpanContext()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return
+	}
+	for _, l := range s.Links() {
+		attrs := make(map[string]any, len(l.Attributes))
+		linkType := ""
+		for _, kv := range l.Attributes {
+			attrs[string(kv.Key)] = kv.Value.AsInterface()
+			if kv.Key == "link.type" {
+				linkType = kv.Value.Emit()
+			}
+		}
+		encoded, _ := json.Marshal(attrs)
+		_ = w.csv.Write([]string{
+			runID,
+			source.TraceID().String(), source.SpanID().String(), s.Name(),
+			l.SpanContext.TraceID().String(), l.SpanContext.SpanID().String(),
+			linkType, string(encoded),
+		})
+		w.edges++
+	}
+}
+
+// Shutdown flushes and closes the file
+func (w *LinkEdgeWriter) Shutdown(context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	w.csv.Flush()
+	err := w.csv.Error()
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	name := w.file.Name()
+	w.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to write link edges: %w", err)
+	}
+	log.Printf("Link edges written to %s (edges=%d)", name, w.edges)
+	return nil
+}
+
+// ForceFlush flushes buffered rows to the file
+func (w *LinkEdgeWriter) ForceFlush(context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+func (w *LinkEdgeWriter) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
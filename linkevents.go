@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LinkEventMirror is a span processor that records a "span.link" event for every link a
+// span starts with, carrying the linked trace and span IDs (plus the link's own
+// attributes), so the links stay visible in trace UIs that show events but hide links.
+// Links added after the span starts (Span.AddLink) are not mirrored.
+type LinkEventMirror struct{}
+
+var _ sdktrace.SpanProcessor = LinkEventMirror{}
+
+// OnStart adds one span.link event per link of s
+func (LinkEventMirror) OnStart(_ context.Context, s sdktrace.ReadWriteSpan) {
+	for i, l := range s.Links() {
+		attrs := append([]attribute.KeyValue{
+			attribute.String("link.trace_id", l.SpanContext.TraceID().String()),
+			attribute.String("link.span_id", l.SpanContext.SpanID().String()),
+			attribute.Int("link.index", i),
+		}, l.Attributes...)
+		s.AddEvent("span.link", trace.WithAttributes(attrs...))
+	}
+}
+
+func (LinkEventMirror) OnEnd(sdktrace.ReadOnlySpan)      {}
+func (LinkEventMirror) Shutdown(context.Context) error   { return nil }
+func (LinkEventMirror) ForceFlush(context.Context) error { return nil }
@@ -0,0 +1,160 @@
+// Package linkguard caps the cardinality of link attributes. Per-message values such as
+// order.id make links easy to search, but at scale every distinct value becomes a new
+// attribute value in the backend's index; the guard records the first N distinct values
+// of each guarded key as-is and folds the rest into a bounded set.
+package linkguard
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	"span-links-signoz-demo/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Overflow selects what happens to values of a guarded key once its limit is reached
+type Overflow string
+
+// Overflow modes
+const (
+	Bucket Overflow = "bucket" // hash the value into one of Buckets values ("bucket-07")
+	Other  Overflow = "other"  // replace the value with OtherValue
+	Drop   Overflow = "drop"   // leave the attribute off the link
+)
+
+// Buckets is the number of distinct values Bucket mode folds overflowing values into
+const Buckets = 16
+
+// OtherValue replaces overflowing values in Other mode
+const OtherValue = "__other__"
+
+// DefaultKeys are guarded when a guard is created without keys
+var DefaultKeys = []attribute.Key{"order.id"}
+
+// ParseOverflow parses an overflow mode name; "" means Bucket
+func ParseOverflow(s string) (Overflow, error) {
+	switch Overflow(s) {
+	case "", Bucket:
+		return Bucket, nil
+	case Other, Drop:
+		return Overflow(s), nil
+	default:
+		return "", fmt.Errorf("unknown link attribute overflow mode %q (expected bucket, other or drop)", s)
+	}
+}
+
+// Guard caps the distinct values of selected link attribute keys. A nil Guard leaves
+// attributes unchanged, so callers need not check whether guarding is enabled.
+type Guard struct {
+	limit    int
+	overflow Overflow
+	keys     map[attribute.Key]bool
+
+	mu   sync.Mutex
+	seen map[attribute.Key]map[string]struct{}
+
+	suppressed metric.Int64Counter
+}
+
+// New creates a guard recording at most limit distinct values per key (DefaultKeys when
+// none are given) and applying overflow beyond that. Suppressed values are counted by
+// the link.attribute.suppressed metric, by attribute.key.
+func New(limit int, overflow Overflow, keys ...attribute.Key) *Guard {
+	if len(keys) == 0 {
+		keys = DefaultKeys
+	}
+	g := &Guard{
+		limit:    limit,
+		overflow: overflow,
+		keys:     make(map[attribute.Key]bool, len(keys)),
+		seen:     make(map[attribute.Key]map[string]struct{}, len(keys)),
+	}
+	for _, k := range keys {
+		g.keys[k] = true
+		g.seen[k] = make(map[string]struct{})
+	}
+	g.suppressed, _ = otel.Meter("linkguard").Int64Counter(telemetry.MetricLinkAttributeSuppressed,
+		metric.WithDescription("Link attribute values replaced or dropped by the cardinality guard"),
+	)
+	return g
+}
+
+// Attributes returns attrs with guarded keys past their limit folded per the overflow mode
+func (g *Guard) Attributes(attrs []attribute.KeyValue) []attribute.KeyValue {
+	if g == nil {
+		return attrs
+	}
+	var out []attribute.KeyValue
+	for i, kv := range attrs {
+		replacement, keep, changed := g.check(kv)
+		if !changed {
+			if out != nil {
+				out = append(out, kv)
+			}
+			continue
+		}
+		if out == nil {
+			// Copy on first change; the caller's slice may be shared
+			out = append(make([]attribute.KeyValue, 0, len(attrs)), attrs[:i]...)
+		}
+		if keep {
+			out = append(out, replacement)
+		}
+	}
+	if out == nil {
+		return attrs
+	}
+	return out
+}
+
+// Links applies Attributes to the attributes of every link
+func (g *Guard) Links(links []trace.Link) []trace.Link {
+	if g == nil {
+		return links
+	}
+	for i := range links {
+		links[i].Attributes = g.Attributes(links[i].Attributes)
+	}
+	return links
+}
+
+// check decides what happens to kv: its replacement, whether to keep it at all, and
+// whether anything changed
+func (g *Guard) check(kv attribute.KeyValue) (attribute.KeyValue, bool, bool) {
+	if !g.keys[kv.Key] {
+		return kv, true, false
+	}
+	value := kv.Value.Emit()
+
+	g.mu.Lock()
+	seen := g.seen[kv.Key]
+	if _, ok := seen[value]; ok || len(seen) < g.limit {
+		seen[value] = struct{}{}
+		g.mu.Unlock()
+		return kv, true, false
+	}
+	g.mu.Unlock()
+
+	if g.suppressed != nil {
+		g.suppressed.Add(context.Background(), 1, metric.WithAttributes(
+			attribute.String("attribute.key", string(kv.Key)),
+			attribute.String("linkguard.overflow", string(g.overflow)),
+		))
+	}
+	switch g.overflow {
+	case Drop:
+		return kv, false, true
+	case Other:
+		return kv.Key.String(OtherValue), true, true
+	default:
+		h := fnv.New32a()
+		h.Write([]byte(value))
+		return kv.Key.String(fmt.Sprintf("bucket-%02d", h.Sum32()%Buckets)), true, true
+	}
+}
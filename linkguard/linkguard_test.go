@@ -0,0 +1,206 @@
+package linkguard_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"span-links-signoz-demo/linkguard"
+	"span-links-signoz-demo/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const limit = 3
+
+// guard creates a guard on order.id with a manual reader behind its suppressed metric
+func guard(t *testing.T, overflow linkguard.Overflow) (*linkguard.Guard, *sdkmetric.ManualReader) {
+	t.Helper()
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	prev := otel.GetMeterProvider()
+	otel.SetMeterProvider(provider)
+	t.Cleanup(func() { otel.SetMeterProvider(prev) })
+	return linkguard.New(limit, overflow), reader
+}
+
+// orderAttrs returns the attributes of an order link
+func orderAttrs(id string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("link.type", "queue_consumption"),
+		attribute.String("order.id", id),
+	}
+}
+
+func TestGuardOverflow(t *testing.T) {
+	for _, tc := range []struct {
+		overflow linkguard.Overflow
+		check    func(t *testing.T, id string, v attribute.Value, ok bool)
+	}{
+		{
+			overflow: linkguard.Bucket,
+			check: func(t *testing.T, id string, v attribute.Value, ok bool) {
+				var n int
+				if !ok || !strings.HasPrefix(v.AsString(), "bucket-") {
+					t.Errorf("order.id of %s = %q, want a bucket", id, v.Emit())
+				} else if _, err := fmt.Sscanf(v.AsString(), "bucket-%02d", &n); err != nil || n >= linkguard.Buckets {
+					t.Errorf("order.id of %s = %q, want one of %d buckets", id, v.AsString(), linkguard.Buckets)
+				}
+			},
+		},
+		{
+			overflow: linkguard.Other,
+			check: func(t *testing.T, id string, v attribute.Value, ok bool) {
+				if !ok || v.AsString() != linkguard.OtherValue {
+					t.Errorf("order.id of %s = %q, want %q", id, v.Emit(), linkguard.OtherValue)
+				}
+			},
+		},
+		{
+			overflow: linkguard.Drop,
+			check: func(t *testing.T, id string, v attribute.Value, ok bool) {
+				if ok {
+					t.Errorf("order.id of %s = %q, want it dropped", id, v.Emit())
+				}
+			},
+		},
+	} {
+		t.Run(string(tc.overflow), func(t *testing.T) {
+			g, reader := guard(t, tc.overflow)
+			const orders = 10
+			for i := range orders {
+				id := fmt.Sprintf("ORDER-%d", i)
+				in := orderAttrs(id)
+				out := g.Attributes(in)
+				if in[1].Value.AsString() != id {
+					t.Fatalf("the caller's attributes were modified: %v", in)
+				}
+				if v, _ := value(out, "link.type"); v.AsString() != "queue_consumption" {
+					t.Errorf("unguarded link.type changed to %q", v.Emit())
+				}
+				v, ok := value(out, "order.id")
+				if i < limit {
+					if !ok || v.AsString() != id {
+						t.Errorf("order.id of %s within the limit = %q, want it unchanged", id, v.Emit())
+					}
+					continue
+				}
+				tc.check(t, id, v, ok)
+			}
+
+			// Values seen within the limit stay as-is after it is reached
+			if v, _ := value(g.Attributes(orderAttrs("ORDER-0")), "order.id"); v.AsString() != "ORDER-0" {
+				t.Errorf("order.id of ORDER-0 seen again = %q, want it unchanged", v.Emit())
+			}
+			if got, want := suppressed(t, reader, tc.overflow), int64(orders-limit); got != want {
+				t.Errorf("%s = %d, want %d", telemetry.MetricLinkAttributeSuppressed, got, want)
+			}
+		})
+	}
+}
+
+// TestGuardBucketStable checks a value always lands in the same bucket, so links of one
+// overflowing order still group together
+func TestGuardBucketStable(t *testing.T) {
+	g, _ := guard(t, linkguard.Bucket)
+	for i := range limit {
+		g.Attributes(orderAttrs(fmt.Sprintf("SEEN-%d", i)))
+	}
+	first, _ := value(g.Attributes(orderAttrs("ORDER-X")), "order.id")
+	again, _ := value(g.Attributes(orderAttrs("ORDER-X")), "order.id")
+	if first != again {
+		t.Errorf("ORDER-X bucketed as %q then %q", first.Emit(), again.Emit())
+	}
+}
+
+func TestGuardLinks(t *testing.T) {
+	g, reader := guard(t, linkguard.Other)
+	links := make([]trace.Link, limit+2)
+	for i := range links {
+		links[i].Attributes = orderAttrs(fmt.Sprintf("ORDER-%d", i))
+	}
+	links = g.Links(links)
+	for i, l := range links {
+		v, _ := value(l.Attributes, "order.id")
+		want := fmt.Sprintf("ORDER-%d", i)
+		if i >= limit {
+			want = linkguard.OtherValue
+		}
+		if v.AsString() != want {
+			t.Errorf("link %d order.id = %q, want %q", i, v.Emit(), want)
+		}
+	}
+	if got := suppressed(t, reader, linkguard.Other); got != 2 {
+		t.Errorf("%s = %d, want 2", telemetry.MetricLinkAttributeSuppressed, got)
+	}
+}
+
+func TestNilGuard(t *testing.T) {
+	var g *linkguard.Guard
+	in := orderAttrs("ORDER-1")
+	if out := g.Attributes(in); len(out) != len(in) || &out[0] != &in[0] {
+		t.Errorf("nil guard changed the attributes: %v", out)
+	}
+}
+
+func TestParseOverflow(t *testing.T) {
+	for in, want := range map[string]linkguard.Overflow{
+		"":       linkguard.Bucket,
+		"bucket": linkguard.Bucket,
+		"other":  linkguard.Other,
+		"drop":   linkguard.Drop,
+	} {
+		if got, err := linkguard.ParseOverflow(in); err != nil || got != want {
+			t.Errorf("ParseOverflow(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := linkguard.ParseOverflow("truncate"); err == nil {
+		t.Error("ParseOverflow accepted an unknown mode")
+	}
+}
+
+// value returns the value of key in attrs
+func value(attrs []attribute.KeyValue, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range attrs {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+// suppressed reads the suppressed-values counter for order.id in overflow mode
+func suppressed(t *testing.T, reader *sdkmetric.ManualReader, overflow linkguard.Overflow) int64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	want := attribute.NewSet(
+		attribute.String("attribute.key", "order.id"),
+		attribute.String("linkguard.overflow", string(overflow)),
+	)
+	var total int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != telemetry.MetricLinkAttributeSuppressed {
+				continue
+			}
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if !ok {
+				t.Fatalf("%s is a %T, want an int64 sum", m.Name, m.Data)
+			}
+			for _, dp := range sum.DataPoints {
+				if dp.Attributes.Equals(&want) {
+					total += dp.Value
+				}
+			}
+		}
+	}
+	return total
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"span-links-signoz-demo/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LinkIndexRecorder is a span processor that turns span links into metrics: for every
+// ended span with links it records how many distinct other traces the span links to
+// (span.links.linked_traces, by span name) and counts its links by span name and
+// link.type (span.links). Over time this shows which operations generate the most
+// cross-trace links — useful for auditing link sprawl.
+type LinkIndexRecorder struct {
+	linkedTraces metric.Int64Histogram
+	links        metric.Int64Counter
+}
+
+var _ sdktrace.SpanProcessor = (*LinkIndexRecorder)(nil)
+
+// NewLinkIndexRecorder creates the recorder's instruments on the global meter provider
+func NewLinkIndexRecorder() *LinkIndexRecorder {
+	meter := otel.Meter("link-index")
+	linkedTraces, err := meter.Int64Histogram(telemetry.MetricSpanLinkedTraces,
+		metric.WithDescription("Distinct traces, other than its own, that a span links to"),
+		metric.WithUnit("{trace}"),
+		metric.WithExplicitBucketBoundaries(0, 1, 2, 5, 10, 25, 50, 128),
+	)
+	if err != nil {
+		log.Printf("Failed to create linked traces histogram: %v", err)
+	}
+	links, err := meter.Int64Counter(telemetry.MetricSpanLinks,
+		metric.WithDescription("Span links recorded, by span name and link type"),
+		metric.WithUnit("{link}"),
+	)
+	if err != nil {
+		log.Printf("Failed to create span links counter: %v", err)
+	}
+	return &LinkIndexRecorder{linkedTraces: linkedTraces, links: links}
+}
+
+// OnEnd records the link metrics of s
+func (r *LinkIndexRecorder) OnEnd(s sdktrace.ReadOnlySpan) {
+	if len(s.Links()) == 0 {
+		return
+	}
+	ctx := context.Background()
+	name := attribute.String("span.name", s.Name())
+
+	traces := make(map[trace.TraceID]struct{})
+	byType := make(map[string]int64)
+	for _, l := range s.Links() {
+		if l.SpanContext.TraceID() != s.SpanContext().TraceID() {
+			traces[l.SpanContext.TraceID()] = struct{}{}
+		}
+		linkType := "unknown"
+		for _, kv := range l.Attributes {
+			if kv.Key == "link.type" {
+				linkType = kv.Value.AsString()
+			}
+		}
+		byType[linkType]++
+	}
+
+	if r.linkedTraces != nil {
+		r.linkedTraces.Record(ctx, int64(len(traces)), metric.WithAttributes(name))
+	}
+	if r.links != nil {
+		for linkType, n := range byType {
+			r.links.Add(ctx, n, metric.WithAttributes(name, attribute.String("link.type", linkType)))
+		}
+	}
+}
+
+func (r *LinkIndexRecorder) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+func (r *LinkIndexRecorder) Shutdown(context.Context) error                  { return nil }
+func (r *LinkIndexRecorder) ForceFlush(context.Context) error                { return nil }
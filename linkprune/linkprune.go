@@ -0,0 +1,84 @@
+// Package linkprune trims the links of aggregator spans (fan-in, batch consume) that
+// would otherwise exceed the SDK link limit, and records what was left out.
+package linkprune
+
+import (
+	"fmt"
+	"math/rand"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Strategy selects which links survive pruning
+type Strategy string
+
+// Pruning strategies
+const (
+	KeepFirst Strategy = "first"     // the first N links, in order
+	KeepLast  Strategy = "last"      // the last N links, in order
+	Reservoir Strategy = "reservoir" // a uniform random sample of N links, in original order
+)
+
+// DefaultMaxLinks matches the SDK's default span link count limit
+const DefaultMaxLinks = 128
+
+// ParseStrategy parses a strategy name; "" means KeepFirst
+func ParseStrategy(s string) (Strategy, error) {
+	switch Strategy(s) {
+	case "", KeepFirst:
+		return KeepFirst, nil
+	case KeepLast, Reservoir:
+		return Strategy(s), nil
+	default:
+		return "", fmt.Errorf("unknown link prune strategy %q (expected first, last or reservoir)", s)
+	}
+}
+
+// Prune keeps at most max links chosen by strategy and returns them with the number
+// omitted. A max of 0 or less keeps every link.
+func Prune(links []trace.Link, max int, strategy Strategy) ([]trace.Link, int) {
+	if max <= 0 || len(links) <= max {
+		return links, 0
+	}
+	omitted := len(links) - max
+
+	switch strategy {
+	case KeepLast:
+		return links[omitted:], omitted
+	case Reservoir:
+		// Algorithm R over indexes, so the sample can be returned in original order
+		picked := make([]int, max)
+		for i := range picked {
+			picked[i] = i
+		}
+		for i := max; i < len(links); i++ {
+			if j := rand.Intn(i + 1); j < max {
+				picked[j] = i
+			}
+		}
+		keep := make([]bool, len(links))
+		for _, i := range picked {
+			keep[i] = true
+		}
+		kept := make([]trace.Link, 0, max)
+		for i, l := range links {
+			if keep[i] {
+				kept = append(kept, l)
+			}
+		}
+		return kept, omitted
+	default:
+		return links[:max], omitted
+	}
+}
+
+// Attributes describes a pruning outcome for the aggregator span: the strategy, how many
+// links were kept, and how many were omitted
+func Attributes(strategy Strategy, kept, omitted int) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("links.prune_strategy", string(strategy)),
+		attribute.Int("links.kept", kept),
+		attribute.Int("links.omitted", omitted),
+	}
+}
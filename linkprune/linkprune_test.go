@@ -0,0 +1,137 @@
+package linkprune_test
+
+import (
+	"encoding/binary"
+	"slices"
+	"testing"
+
+	"span-links-signoz-demo/linkprune"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const limit = 4
+
+// links returns n links whose span IDs number them 1..n
+func links(n int) []trace.Link {
+	out := make([]trace.Link, n)
+	for i := range out {
+		var sid trace.SpanID
+		binary.BigEndian.PutUint64(sid[:], uint64(i+1))
+		out[i].SpanContext = trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: trace.TraceID{1},
+			SpanID:  sid,
+		})
+	}
+	return out
+}
+
+// positions returns the 1-based position each link had in the input
+func positions(ls []trace.Link) []int {
+	out := make([]int, len(ls))
+	for i, l := range ls {
+		sid := l.SpanContext.SpanID()
+		out[i] = int(binary.BigEndian.Uint64(sid[:]))
+	}
+	return out
+}
+
+func TestPrune(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		n           int
+		strategy    linkprune.Strategy
+		want        []int // nil for the reservoir, checked separately
+		wantOmitted int
+	}{
+		{name: "first below limit", n: 3, strategy: linkprune.KeepFirst, want: []int{1, 2, 3}},
+		{name: "first at limit", n: 4, strategy: linkprune.KeepFirst, want: []int{1, 2, 3, 4}},
+		{name: "first above limit", n: 7, strategy: linkprune.KeepFirst, want: []int{1, 2, 3, 4}, wantOmitted: 3},
+		{name: "last below limit", n: 3, strategy: linkprune.KeepLast, want: []int{1, 2, 3}},
+		{name: "last at limit", n: 4, strategy: linkprune.KeepLast, want: []int{1, 2, 3, 4}},
+		{name: "last above limit", n: 7, strategy: linkprune.KeepLast, want: []int{4, 5, 6, 7}, wantOmitted: 3},
+		{name: "reservoir below limit", n: 3, strategy: linkprune.Reservoir, want: []int{1, 2, 3}},
+		{name: "reservoir at limit", n: 4, strategy: linkprune.Reservoir, want: []int{1, 2, 3, 4}},
+		{name: "reservoir above limit", n: 7, strategy: linkprune.Reservoir, wantOmitted: 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			kept, omitted := linkprune.Prune(links(tc.n), limit, tc.strategy)
+			if omitted != tc.wantOmitted {
+				t.Errorf("omitted %d, want %d", omitted, tc.wantOmitted)
+			}
+			if len(kept)+omitted != tc.n {
+				t.Errorf("kept %d and omitted %d of %d links", len(kept), omitted, tc.n)
+			}
+			got := positions(kept)
+			if tc.want != nil {
+				if !slices.Equal(got, tc.want) {
+					t.Errorf("kept links %v, want %v", got, tc.want)
+				}
+				return
+			}
+			// A reservoir sample is distinct links of the input, still in input order
+			if len(got) != limit || !slices.IsSorted(got) || len(slices.Compact(slices.Clone(got))) != limit {
+				t.Errorf("kept links %v, want %d distinct links in input order", got, limit)
+			}
+			if got[0] < 1 || got[len(got)-1] > tc.n {
+				t.Errorf("kept links %v, want links of the input 1..%d", got, tc.n)
+			}
+		})
+	}
+}
+
+// TestPruneReservoirUniform checks every link has a fair chance to be sampled, including
+// the last ones
+func TestPruneReservoirUniform(t *testing.T) {
+	const n, rounds = 8, 4000
+	counts := make([]int, n+1)
+	for range rounds {
+		kept, _ := linkprune.Prune(links(n), limit, linkprune.Reservoir)
+		for _, p := range positions(kept) {
+			counts[p]++
+		}
+	}
+	// Each link is kept with probability limit/n = 1/2
+	for p := 1; p <= n; p++ {
+		if counts[p] < rounds*4/10 || counts[p] > rounds*6/10 {
+			t.Errorf("link %d kept %d times in %d rounds, want about %d", p, counts[p], rounds, rounds/2)
+		}
+	}
+}
+
+func TestPruneNoLimit(t *testing.T) {
+	kept, omitted := linkprune.Prune(links(200), 0, linkprune.KeepFirst)
+	if len(kept) != 200 || omitted != 0 {
+		t.Errorf("kept %d and omitted %d with no limit, want all 200 kept", len(kept), omitted)
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	got := attribute.NewSet(linkprune.Attributes(linkprune.KeepLast, limit, 3)...)
+	for key, want := range map[attribute.Key]attribute.Value{
+		"links.prune_strategy": attribute.StringValue("last"),
+		"links.kept":           attribute.IntValue(limit),
+		"links.omitted":        attribute.IntValue(3),
+	} {
+		if v, ok := got.Value(key); !ok || v != want {
+			t.Errorf("%s = %v, want %v", key, v.Emit(), want.Emit())
+		}
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	for in, want := range map[string]linkprune.Strategy{
+		"":          linkprune.KeepFirst,
+		"first":     linkprune.KeepFirst,
+		"last":      linkprune.KeepLast,
+		"reservoir": linkprune.Reservoir,
+	} {
+		if got, err := linkprune.ParseStrategy(in); err != nil || got != want {
+			t.Errorf("ParseStrategy(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := linkprune.ParseStrategy("random"); err == nil {
+		t.Error("ParseStrategy accepted an unknown strategy")
+	}
+}
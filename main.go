@@ -2,17 +2,32 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"os"
 	"os/signal"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"span-links-signoz-demo/linkguard"
+	"span-links-signoz-demo/linkprune"
+	"span-links-signoz-demo/pkg/clock"
+	"span-links-signoz-demo/pkg/producer"
+	"span-links-signoz-demo/pkg/queue"
+	"span-links-signoz-demo/pkg/worker"
+	"span-links-signoz-demo/telemetry"
+
 	"github.com/joho/godotenv"
 
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -23,151 +38,600 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Initialize OpenTelemetry (traces only)
-	providers, err := InitTracer(ctx)
+	// Teardown runs through ordered shutdown hooks (last registered, first stopped)
+	lifecycle := NewLifecycle()
+	defer lifecycle.Shutdown()
+
+	// A run interrupted with RUN_STATE_FILE set is resumed under its run.id
+	statePath := os.Getenv("RUN_STATE_FILE")
+	resumed, err := loadRunState(statePath)
+	if err != nil {
+		log.Fatalf("Failed to resume run: %v", err)
+	}
+	if resumed != nil {
+		runID = resumed.RunID
+		runInvocation = resumed.Invocation + 1
+		resumedOrders = len(resumed.Orders)
+	}
+
+	// Initialize OpenTelemetry (traces + metrics); flushed last, after everything else stopped
+	providers, err := InitTelemetry(ctx)
 	if err != nil {
 		log.Fatalf("Failed to initialize OpenTelemetry: %v", err)
 	}
-	defer shutdownProviders(providers)
+	lifecycle.OnShutdown("telemetry providers", ShutdownHookTimeout, func(ctx context.Context) error {
+		return shutdownProviders(ctx, providers)
+	})
+
+	// Optional pprof endpoint / Pyroscope export
+	lifecycle.OnShutdown("profiling", ShutdownHookTimeout, stopHook(StartProfiling()))
+
+	// Ordering comparison mode: the same batch shape processed in order and concurrently
+	if orderingComparisonEnabled() {
+		runOrderingComparison(ctx, providers, concurrentWorkersFromEnv())
+		return
+	}
+
+	// Topology mode: a mesh of producer and consumer instances instead of the single pipeline
+	if path := os.Getenv("TOPOLOGY_FILE"); path != "" {
+		topo, err := LoadTopology(path)
+		if err == nil {
+			err = runTopology(ctx, providers, topo)
+		}
+		if err != nil {
+			log.Printf("Topology run failed: %v", err)
+		}
+		return
+	}
 
 	// Create services
-	queue := NewSimpleQueue()
-	producer := NewProducerService(queue)
-	worker := NewWorkerService(queue)
+	var queueClock clock.Clock = clock.Real{}
+	if fakeClockEnabled() {
+		queueClock = clock.NewFake(time.Now())
+	}
+	orders := queue.NewWithCapacity(queueClock, queueCapacityFromEnv())
+	orders.SetMetrics(queue.NewMetrics(linkDirection()))
+	registry := NewOrderRegistry()
+	stats := NewRunStats()
+	publisher := producer.New(orders)
+	publisher.SetTopicRouting(topicRoutingEnabled())
+	publisher.SetOrderRegistry(registry)
+	publisher.SetSpanNameTemplate(queue.SpanNameTemplate(os.Getenv("SPAN_NAME_TEMPLATE")))
+	publisher.SetRunStats(stats)
+	publisher.SetSemconvSpanKinds(semconvSpanKindsEnabled())
+	publisher.SetSchemaV2Rate(percentFromEnv("ORDER_SCHEMA_V2_PERCENT"))
+	publisher.SetRateLimit(publishRateLimitFromEnv())
+	publisher.SetDuplicateRate(percentFromEnv("DUPLICATE_PUBLISH_PERCENT"))
+	publisher.SetTransactional(transactionalPublishEnabled())
+	publisher.SetTenants(tenantsFromEnv())
+	publisher.SetPublishFailureRate(percentFromEnv("PUBLISH_FAILURE_PERCENT"))
+	publisher.SetOrderDistribution(orderDistributionFromEnv())
+	publisher.SetTraceParentCorruptionRate(percentFromEnv("TRACEPARENT_CORRUPT_PERCENT"))
+	workerOpts := []worker.Option{
+		worker.WithLinkMode(consumerLinkModeFromEnv()),
+		worker.WithRetryPolicy(stepRetryPolicyFromEnv()),
+		worker.WithRedelivery(maxRedeliveriesFromEnv()),
+	}
+	for step, mode := range stepModesFromEnv() {
+		workerOpts = append(workerOpts, worker.WithStepMode(step, mode))
+	}
+	heartbeat := heartbeatIntervalFromEnv()
+	if heartbeat > 0 && fakeClockEnabled() {
+		log.Printf("Ignoring WORKER_HEARTBEAT_INTERVAL_MS with FAKE_CLOCK: heartbeats would fire back to back")
+		heartbeat = 0
+	}
+	budget := NewErrorBudget(errorBudgetTargetFromEnv())
+	workerOpts = append(workerOpts,
+		worker.WithLatencyBudget(latencyBudgetFromEnv()),
+		worker.WithOrderRegistry(registry),
+		worker.WithStrictTraceParent(strictTraceParentEnabled()),
+		worker.WithSpanNameTemplate(queue.SpanNameTemplate(os.Getenv("SPAN_NAME_TEMPLATE"))),
+		worker.WithRunStats(stats),
+		worker.WithHeartbeatInterval(heartbeat),
+		worker.WithPanicRate(percentFromEnv("WORKER_PANIC_PERCENT")),
+		worker.WithFailureRate(percentFromEnv("WORKER_FAILURE_PERCENT")),
+		worker.WithSemconvSpanKinds(semconvSpanKindsEnabled()),
+		worker.WithBackfillQueueWait(backfillQueueWaitFromEnv()),
+		worker.WithClockSkew(clockSkewFromEnv()),
+		worker.WithLagAlertThreshold(lagAlertThresholdFromEnv()),
+		worker.WithOutcomeRecorder(budget),
+	)
+	sealer := payloadSealerFromEnv()
+	if sealer != nil {
+		publisher.SetSealer(sealer)
+		publisher.SetTamperRate(percentFromEnv("PAYLOAD_TAMPER_PERCENT"))
+		workerOpts = append(workerOpts, worker.WithSealer(sealer))
+		log.Printf("Sealing order payloads (key_id=%s)", sealer.KeyID())
+	}
+	var audit *AuditLog
+	var transitions queue.AuditRecorders
+	if auditLogEnabled() {
+		audit = NewAuditLog()
+		transitions = append(transitions, audit)
+	}
+	if orderEventsEnabled() {
+		transitions = append(transitions, NewOrderEvents(sealer != nil))
+	}
+	if len(transitions) > 0 {
+		publisher.SetAuditRecorder(transitions)
+		workerOpts = append(workerOpts, worker.WithAuditRecorder(transitions))
+	}
+
+	// Customer lookups go through Redis when REDIS_ADDR is set, else an in-memory cache
+	cache := NewCustomerCache(ctx, os.Getenv("REDIS_ADDR"), DefaultCustomerCacheTTL)
+	workerOpts = append(workerOpts, worker.WithCustomerCache(cache))
+	lifecycle.OnShutdown("customer cache", ShutdownHookTimeout, closeHook(cache.Close))
+
+	// Processed orders are persisted through otelsql; skip the step if SQLite is unavailable
+	if store, err := OpenOrderStore(ctx, orderDBPath()); err != nil {
+		log.Printf("Skipping order persistence: %v", err)
+	} else {
+		workerOpts = append(workerOpts, worker.WithOrderStore(store))
+		lifecycle.OnShutdown("order store", ShutdownHookTimeout, closeHook(store.Close))
+	}
+
+	// Payment and shipping call embedded fake services; fall back to sleeps if they can't start
+	if downstream, err := StartDownstream(); err != nil {
+		log.Printf("Simulating payment/shipping with sleeps: %v", err)
+	} else {
+		workerOpts = append(workerOpts, worker.WithDownstream(downstream))
+		lifecycle.OnShutdown("downstream services", ShutdownHookTimeout, stopHook(downstream.Stop))
+	}
+
+	// Workers (and the dispatchers feeding them) outlive ctx: at shutdown they first drain
+	// what is already queued, then stop
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	var orderingWindows *OrderingWindows
+	if window := orderingWindowFromEnv(); window > 0 {
+		orderingWindows = NewOrderingWindows(window)
+		workerOpts = append(workerOpts, worker.WithOrderingWindows(orderingWindows))
+		go orderingWindows.Run(workerCtx)
+	}
+
+	// Optional cap on distinct order.id (etc.) values recorded on links
+	linkGuard := linkGuardFromEnv()
+
+	var acks *AckBatcher
+	if size := ackBatchSizeFromEnv(); size > 0 {
+		acks = NewAckBatcher(size)
+		acks.SetLinkPruning(linkPruningFromEnv())
+		acks.SetAttributeGuard(linkGuard)
+		workerOpts = append(workerOpts, worker.WithAckBatcher(acks))
+	}
+
+	var spanCtxSink chan worker.OrderSpanContext
+	if forwardLinksEnabled() {
+		spanCtxSink = make(chan worker.OrderSpanContext, queue.DefaultCapacity)
+		workerOpts = append(workerOpts, worker.WithSpanContextSink(spanCtxSink))
+	}
+	consumer := worker.New(orders, workerOpts...)
+
+	// Producer-only and consumer-only processes exchange orders through a Redis broker
+	role := processRoleFromEnv()
+	var broker *RedisBroker
+	if role != roleCombined {
+		addr := os.Getenv("BROKER_ADDR")
+		if addr == "" {
+			log.Fatalf("BROKER_ADDR is required when running as %s only", role)
+		}
+		if forwardLinksEnabled() {
+			log.Fatalf("Forward links need producer and workers in one process; unset ENABLE_FORWARD_LINKS_TO_PRODUCER")
+		}
+		broker, err = NewRedisBroker(ctx, addr)
+		if err != nil {
+			log.Fatalf("Failed to connect to broker: %v", err)
+		}
+		lifecycle.OnShutdown("broker connection", ShutdownHookTimeout, closeHook(broker.Close))
+		log.Printf("Running as %s only (broker=%s)", role, addr)
+	}
+
+	// Publish rate, failure rate, worker count and link mode can change mid-run
+	fixedWorkers := DefaultWorkerCount
+	if role == roleProducer {
+		fixedWorkers = 0
+	}
+	reconfig := NewReconfigurer(publisher, consumer, fixedWorkers)
+
+	// pending reports orders not yet handed to a worker (used to detect a drained pipeline)
+	var pending func() int
+	if addr := os.Getenv("ADMIN_ADDR"); addr != "" {
+		// pending is complete once the dispatchers below are set up
+		admin := NewAdminServer(stats, consumer, orders, func() int { return pending() })
+		admin.SetReconfigurer(reconfig)
+		if audit != nil {
+			admin.SetAuditLog(audit)
+		}
+		stopAdmin := admin.Start(addr)
+		lifecycle.OnShutdown("admin server", ShutdownHookTimeout, stopHook(stopAdmin))
+	}
+
+	// Optional HTTP push/pull API over the queue for producers and consumers in other languages
+	if addr := os.Getenv("QUEUE_HTTP_ADDR"); addr != "" {
+		stopQueueHTTP := NewQueueHTTPServer(orders).Start(addr)
+		lifecycle.OnShutdown("queue HTTP API", ShutdownHookTimeout, stopHook(stopQueueHTTP))
+	}
+
+	// End-of-run follow-ups, once workers have stopped
+	lifecycle.OnShutdown("run reports", ShutdownHookTimeout, func(context.Context) error {
+		if orderingWindows != nil {
+			orderingWindows.Flush()
+		}
+		if acks != nil {
+			acks.Flush()
+		}
+		runCancellations(registry, orderCancellationsFromEnv())
+		if role != roleProducer {
+			budget.Report()
+		}
+		if stats.TraceParentCorrupted() > 0 || stats.LinkParseFailed() > 0 {
+			log.Printf("Trace context corruption: %d of %d published messages (%.1f%%) carried a corrupted traceparent, %d deliveries skipped the producer link",
+				stats.TraceParentCorrupted(), stats.Published(), stats.CorruptionRate()*100, stats.LinkParseFailed())
+		}
+		EmitRunSummary(providers.RootSpans, runScenarioAttributes(stats)...)
+		return nil
+	})
 
 	// Setup graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	// SIGHUP reloads RUNTIME_CONFIG_FILE instead of terminating the process
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	go reconfig.ReloadOn(hupChan, os.Getenv("RUNTIME_CONFIG_FILE"))
+
 	// Start worker goroutines
 	var wg sync.WaitGroup
-	log.Printf("Starting workers (count=%d)", DefaultWorkerCount)
-
-	var spanCtxSink chan OrderSpanContext
-	if forwardLinksEnabled() {
-		spanCtxSink = make(chan OrderSpanContext, DefaultQueueCapacity)
-		worker.SetSpanContextSink(spanCtxSink)
+	if role != roleProducer {
+		log.Printf("Starting workers (count=%d run.id=%s)", DefaultWorkerCount, runID)
 	}
+	if statePath != "" && role != roleProducer {
+		// Once workers have stopped, hand what is still queued to the next invocation
+		lifecycle.OnShutdown("run state", ShutdownHookTimeout, func(context.Context) error {
+			return persistRunState(statePath, orders.TakeAll())
+		})
+	}
+	lifecycle.OnShutdown("workers", ShutdownHookTimeout, func(ctx context.Context) error {
+		stopWorkers()
+		return waitHook(&wg)(ctx)
+	})
 
-	for i := 1; i <= DefaultWorkerCount; i++ {
-		wg.Add(1)
-		go func(workerID int) {
-			defer wg.Done()
-			worker.ProcessOrders(ctx, fmt.Sprintf("Worker-%d", workerID))
-		}(i)
+	pending = orders.Length
+	if role == roleProducer {
+		// No workers: everything published goes to the broker. At shutdown, hand it the
+		// last published orders; the forwarder finishes pushing the order it holds.
+		forwardCtx, stopForwarding := context.WithCancel(context.Background())
+		var forwarder sync.WaitGroup
+		forwarder.Add(1)
+		go func() {
+			defer forwarder.Done()
+			broker.Forward(forwardCtx, orders, workerTopics(1)...)
+		}()
+		lifecycle.OnShutdown("broker forwarding", QueueDrainTimeout, func(ctx context.Context) error {
+			err := drainHook(func() bool { return broker.Drained(orders) })(ctx)
+			stopForwarding()
+			if err := waitHook(&forwarder)(ctx); err != nil {
+				return err
+			}
+			log.Printf("Forwarded %d orders to the broker", broker.Forwarded())
+			return err
+		})
+	} else if consumerGroupEnabled() {
+		group := NewConsumerGroup(ConsumerGroupName, ConsumerGroupPartitions)
+		group.SetInFlightResolver(consumer.InFlightSpan)
+		pending = func() int { return orders.Length() + group.Length() }
+
+		// Worker-1's subscription covers every topic in use
+		go group.Dispatch(workerCtx, orders, workerTopics(1)...)
+		startGroupWorkers(workerCtx, &wg, consumer, group)
+	} else if keyAffinityEnabled() {
+		workerIDs := make([]string, 0, DefaultWorkerCount)
+		for i := 1; i <= DefaultWorkerCount; i++ {
+			workerIDs = append(workerIDs, fmt.Sprintf("Worker-%d", i))
+		}
+		router := NewKeyAffinityRouter(workerIDs)
+		pending = func() int { return orders.Length() + router.Length() }
+		log.Printf("Key-affinity mode (workers=%d)", len(workerIDs))
+
+		go router.Dispatch(workerCtx, orders, workerTopics(1)...)
+		for _, id := range workerIDs {
+			wg.Add(1)
+			go func(workerID string) {
+				defer wg.Done()
+				consumer.ProcessFrom(workerCtx, workerID, func(ctx context.Context) (queue.Order, error) {
+					return router.Consume(ctx, workerID)
+				})
+			}(id)
+		}
+	} else {
+		pool := NewWorkerPool(workerCtx, &wg, consumer, func(ctx context.Context, workerID int) (queue.Order, error) {
+			return orders.Consume(ctx, workerTopics(workerID)...)
+		})
+		reconfig.SetWorkerPool(pool)
+		if minWorkers, maxWorkers, target, ok := autoscaleFromEnv(); ok {
+			scaler := NewAutoscaler(pool, func() int { return pending() }, minWorkers, maxWorkers, target)
+			publisher.SetBackpressureRecorder(scaler)
+			pool.Resize(minWorkers)
+			log.Printf("Autoscaling workers (min=%d max=%d target_depth=%d)", minWorkers, maxWorkers, target)
+			go scaler.Run(workerCtx)
+		} else {
+			pool.Resize(DefaultWorkerCount)
+		}
+	}
+	registerQueueDepthGauge(pending)
+	// Set on SIGINT/SIGTERM: a resumable run then saves its backlog instead of draining it
+	var interrupted atomic.Bool
+	if role != roleProducer {
+		// Let workers finish what was already published before they are stopped
+		lifecycle.OnShutdown("queue drain", QueueDrainTimeout, drainHook(func() bool {
+			return (statePath != "" && interrupted.Load()) || (pending() == 0 && stats.Outstanding() <= 0)
+		}))
+	}
+	if resumed != nil && role != roleProducer {
+		resumeOrders(ctx, orders, stats, resumed.Orders)
 	}
 
 	if forwardLinksEnabled() {
-		runForwardSingleBatch(ctx, cancel, producer, spanCtxSink)
-		wg.Wait()
+		runForwardBatches(ctx, cancel, publisher, spanCtxSink, forwardBatchesFromEnv(), linkGuard)
 		return
 	}
 
-	// Backward-only mode: publish a single batch then exit (same batch size as forward mode)
-	runBackwardSingleBatch(ctx, cancel, producer)
+	if dashboardEnabled() {
+		// The dashboard owns the terminal; logs would scroll it away
+		log.SetOutput(io.Discard)
+		go RunDashboard(ctx, os.Stdout, stats, pending, consumer.ActiveOrders)
+	}
+
+	if role == roleConsumer {
+		// Consumer-only: process whatever producers put on the broker until interrupted
+		go broker.Pull(ctx, orders, workerTopics(1)...)
+	} else if continuousRunEnabled() {
+		// Continuous mode: publish a batch every BatchPublishInterval until interrupted
+		go runContinuous(ctx, publisher)
+	} else if path := os.Getenv("TRAFFIC_PROFILE_FILE"); path != "" {
+		// Replay mode: reproduce a recorded arrival pattern, then exit once the queue drains
+		drained := func() bool { return pending() == 0 && stats.Outstanding() <= 0 }
+		if role == roleProducer {
+			drained = func() bool { return broker.Drained(orders) }
+		}
+		runTrafficReplay(ctx, cancel, publisher, drained, path)
+	} else if resumed != nil {
+		// Resumed run: process what the previous invocation left, then exit
+		go func() {
+			defer cancel()
+			_ = drainHook(func() bool { return pending() == 0 && stats.Outstanding() <= 0 })(ctx)
+		}()
+	} else {
+		// Backward-only mode: publish a single batch then exit (same batch size as forward mode)
+		runBackwardSingleBatch(ctx, cancel, publisher)
+	}
 
 	// Wait for shutdown signal or completion
 	select {
 	case <-sigChan:
 		log.Printf("Shutdown signal received, initiating graceful shutdown")
+		interrupted.Store(true)
 		cancel()
 	case <-ctx.Done():
 		log.Printf("Completed publishing, shutting down")
 	}
+	log.SetOutput(os.Stderr)
 
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer shutdownCancel()
-
-	done := make(chan struct{})
-	go func() {
-		wg.Wait()
-		close(done)
-	}()
-
-	select {
-	case <-done:
-		log.Printf("All workers stopped successfully")
-	case <-shutdownCtx.Done():
-		log.Printf("Shutdown timeout reached, some workers may not have stopped")
-	}
-
+	lifecycle.Shutdown()
 	log.Printf("Application shutdown complete")
 }
 
-// shutdownProviders gracefully shuts down all OpenTelemetry providers
-func shutdownProviders(providers *TelemetryProviders) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
+// shutdownProviders flushes and shuts down all OpenTelemetry providers
+func shutdownProviders(ctx context.Context, providers *TelemetryProviders) error {
+	var errs []error
 	if err := providers.TracerProvider.Shutdown(ctx); err != nil {
-		log.Printf("Failed to shutdown tracer provider: %v", err)
+		errs = append(errs, fmt.Errorf("tracer provider: %w", err))
+	}
+	if err := providers.MeterProvider.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("meter provider: %w", err))
+	}
+	if providers.LoggerProvider != nil {
+		if err := providers.LoggerProvider.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("logger provider: %w", err))
+		}
 	}
+	return errors.Join(errs...)
 }
 
-// runForwardSingleBatch publishes a single batch, waits for consumer contexts,
-// adds per-order forward links, then exits.
-func runForwardSingleBatch(ctx context.Context, cancel context.CancelFunc, producer *ProducerService, spanCtxSink chan OrderSpanContext) {
-	log.Printf("Forward-link demo enabled: running a single batch and exiting")
+// runForwardBatches publishes batches batches one after another. For each it waits for the
+// consumer contexts, adds per-order forward links, and records a BatchSummary span that
+// links forward to every consumer span of the batch, then exits.
+func runForwardBatches(ctx context.Context, cancel context.CancelFunc, publisher *producer.Service, spanCtxSink chan worker.OrderSpanContext, batches int, guard *linkguard.Guard) {
+	log.Printf("Forward-link demo enabled: running %d batch(es) and exiting", batches)
 
-	batchSpan, orderSpans, produced, err := producer.PublishOrderBatchWithOpenSpan(ctx, DefaultBatchSize)
+	maxLinks, strategy := linkPruningFromEnv()
+	estimate := newProcessingEstimate()
+	for seq := 1; seq <= batches && ctx.Err() == nil; seq++ {
+		runForwardBatch(ctx, publisher, spanCtxSink, seq, maxLinks, strategy, guard, estimate)
+	}
+
+	// Graceful shutdown
+	cancel()
+}
+
+// runForwardBatch publishes one forward-link batch and links it to its consumers. The
+// PublishOrder spans end once their order is published; as each consumer reports, a short
+// PublishOutcome span under the order's PublishOrder span links forward to it and records
+// the outcome. An order whose consumer has not reported by its deadline gets a
+// PublishOutcome without a link.
+func runForwardBatch(ctx context.Context, publisher *producer.Service, spanCtxSink chan worker.OrderSpanContext, seq, maxLinks int, strategy linkprune.Strategy, guard *linkguard.Guard, estimate *processingEstimate) {
+	batchSpan, orders, err := publisher.PublishOrderBatchWithOpenSpan(ctx, DefaultBatchSize)
+	if errors.Is(err, producer.ErrBatchRolledBack) {
+		log.Printf("Skipping forward links of batch %d: %v", seq, err)
+		return
+	}
 	if err != nil {
 		log.Fatalf("Failed to publish order batch: %v", err)
 	}
+	batchSpan.SetAttributes(attribute.Int("order.batch.sequence", seq))
+
+	published := time.Now()
+	maxWait := forwardLinkMaxWaitFromEnv()
+	pending := make(map[string]pendingOutcome, len(orders))
+	for i, o := range orders {
+		wait := estimate.deadline(i, DefaultWorkerCount, maxWait)
+		pending[o.OrderID] = pendingOutcome{publish: o.SpanCtx, wait: wait, deadline: published.Add(wait)}
+	}
+
+	collected := make([]worker.OrderSpanContext, 0, len(orders))
+	failed, expired := 0, 0
+	for len(pending) > 0 && ctx.Err() == nil {
+		next := time.Time{}
+		for _, p := range pending {
+			if next.IsZero() || p.deadline.Before(next) {
+				next = p.deadline
+			}
+		}
+		timer := time.NewTimer(time.Until(next))
 
-	collected := make([]OrderSpanContext, 0, produced)
-	timeout := time.After(30 * time.Second)
-	for len(collected) < produced {
 		select {
 		case sc := <-spanCtxSink:
-			if sc.Ctx.IsValid() {
-				collected = append(collected, sc)
+			timer.Stop()
+			p, ours := pending[sc.OrderID]
+			if !ours {
+				// A late consumer of an earlier batch, or of an order whose deadline passed
+				log.Printf("Dropping consumer context of an order no longer awaited (order=%s)", sc.OrderID)
+				continue
+			}
+			delete(pending, sc.OrderID)
+			estimate.observe(sc.Duration)
+			if !sc.Ctx.IsValid() {
+				// Nothing to link to; still end the order's outcome like a missed deadline
+				emitPublishOutcome(ctx, sc.OrderID, p, time.Since(published), nil, guard)
+				continue
+			}
+			collected = append(collected, sc)
+			if sc.Err != nil {
+				failed++
+			}
+			emitPublishOutcome(ctx, sc.OrderID, p, time.Since(published), &sc, guard)
+		case now := <-timer.C:
+			// Give up on the orders whose consumers did not report in time
+			for oid, p := range pending {
+				if p.deadline.After(now) {
+					continue
+				}
+				log.Printf("No consumer context by the deadline, publish outcome without forward link (order=%s waited=%s)", oid, now.Sub(published).Round(time.Millisecond))
+				emitPublishOutcome(ctx, oid, p, now.Sub(published), nil, guard)
+				delete(pending, oid)
+				expired++
 			}
-		case <-timeout:
-			log.Printf("Timed out waiting for consumer spans; collected=%d expected=%d", len(collected), produced)
-			goto doneCollect
 		case <-ctx.Done():
-			goto doneCollect
+			timer.Stop()
 		}
 	}
-doneCollect:
+	// Interrupted: the orders still awaited get an outcome too, so none is left without one
+	for oid, p := range pending {
+		emitPublishOutcome(ctx, oid, p, time.Since(published), nil, guard)
+	}
+	log.Printf("Added %d forward links from PublishOutcome spans (batch=%d)", len(collected), seq)
 
-	// Per-order forward links only (PublishOrder -> ProcessOrder)
+	// Batch-level forward links (BatchSummary -> every ProcessOrder of the batch)
+	links := make([]trace.Link, 0, len(collected))
 	for _, sc := range collected {
-		if pubSpan, ok := orderSpans[sc.OrderID]; ok && pubSpan != nil {
-			pubSpan.AddLink(trace.Link{
-				SpanContext: sc.Ctx,
-				Attributes: []attribute.KeyValue{
-					attribute.String("link.direction", "forward"),
-					attribute.String("link.type", "forward_to_consumer"),
-					attribute.String("link.level", "order"),
-					attribute.String("order.id", sc.OrderID),
-				},
-			})
-			pubSpan.End()
-			orderSpans[sc.OrderID] = nil
-		}
-	}
-	// End any publish spans that never received a consumer context
-	for oid, s := range orderSpans {
-		if s != nil {
-			log.Printf("Ending publish span without forward link (order=%s)", oid)
-			s.End()
-			orderSpans[oid] = nil
-		}
-	}
-	log.Printf("Added %d forward links to PublishOrder spans", len(collected))
-	batchSpan.End()
+		links = append(links, trace.Link{
+			SpanContext: sc.Ctx,
+			Attributes: []attribute.KeyValue{
+				attribute.String("link.direction", "forward"),
+				attribute.String("link.type", "batch_consumer"),
+				attribute.String("link.level", "batch"),
+				attribute.String("order.id", sc.OrderID),
+			},
+		})
+	}
+	links, omitted := linkprune.Prune(guard.Links(links), maxLinks, strategy)
+	summaryCtx := trace.ContextWithSpan(ctx, batchSpan)
+	_, summary := otel.Tracer("producer-service").Start(summaryCtx, "BatchSummary",
+		trace.WithLinks(links...),
+		trace.WithAttributes(append(linkprune.Attributes(strategy, len(links), omitted),
+			attribute.Int("order.batch.sequence", seq),
+			attribute.Int("order.batch.published", len(orders)),
+			attribute.Int("order.batch.consumed", len(collected)),
+			attribute.Int("order.batch.failed", failed),
+			attribute.Int("order.batch.deadline_exceeded", expired),
+		)...),
+	)
+	telemetry.EndWithStatus(summary, nil)
+	batchSpan.End() // status set when the batch was published
+}
 
-	// Graceful shutdown
-	cancel()
+// pendingOutcome is an order of a forward-link batch whose consumer has not reported yet
+type pendingOutcome struct {
+	publish  trace.SpanContext // the ended PublishOrder span
+	wait     time.Duration
+	deadline time.Time
+}
+
+// emitPublishOutcome records a PublishOutcome span under the order's PublishOrder span,
+// linking forward to the consumer span of sc and carrying its outcome, or, with sc nil,
+// recording that the consumer did not report in time (or before ctx was cancelled). A
+// failed or missing outcome is the span's error status.
+func emitPublishOutcome(ctx context.Context, orderID string, p pendingOutcome, waited time.Duration, sc *worker.OrderSpanContext, guard *linkguard.Guard) {
+	attrs := []attribute.KeyValue{
+		attribute.String("order.id", orderID),
+		attribute.Int64("forward_link.deadline_ms", p.wait.Milliseconds()),
+		attribute.Int64("forward_link.wait_ms", waited.Milliseconds()),
+	}
+	var links []trace.Link
+	var outcome error
+	if sc != nil {
+		outcome = sc.Err
+		attrs = append(attrs, consumerOutcomeAttributes(*sc)...)
+		links = append(links, trace.Link{
+			SpanContext: sc.Ctx,
+			Attributes: guard.Attributes([]attribute.KeyValue{
+				attribute.String("link.direction", "forward"),
+				attribute.String("link.type", "forward_to_consumer"),
+				attribute.String("link.level", "order"),
+				attribute.String("order.id", orderID),
+			}),
+		})
+	} else if ctx.Err() != nil {
+		outcome = fmt.Errorf("interrupted before the consumer of order %s reported", orderID)
+		attrs = append(attrs, attribute.String("consumer.outcome", "interrupted"))
+	} else {
+		outcome = fmt.Errorf("no consumer outcome for order %s within %s", orderID, p.wait)
+		attrs = append(attrs, attribute.String("consumer.outcome", "timeout"))
+	}
+
+	_, span := otel.Tracer("producer-service").Start(trace.ContextWithSpanContext(ctx, p.publish), "PublishOutcome",
+		trace.WithLinks(links...),
+		trace.WithAttributes(attrs...),
+	)
+	telemetry.EndWithStatus(span, outcome)
+}
+
+// consumerOutcomeAttributes describes how the consumer behind a forward link fared
+func consumerOutcomeAttributes(sc worker.OrderSpanContext) []attribute.KeyValue {
+	outcome := "success"
+	if sc.Err != nil {
+		outcome = "failure"
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("consumer.outcome", outcome),
+		attribute.Int64("consumer.duration_ms", sc.Duration.Milliseconds()),
+		attribute.String("consumer.trace_id", sc.Ctx.TraceID().String()),
+		attribute.String("consumer.span_id", sc.Ctx.SpanID().String()),
+	}
+	if sc.Err != nil {
+		attrs = append(attrs, attribute.String("error.message", sc.Err.Error()))
+	}
+	return attrs
 }
 
 // runBackwardSingleBatch publishes exactly one batch (DefaultBatchSize) and exits.
 // This keeps the run length comparable to forward mode.
-func runBackwardSingleBatch(ctx context.Context, cancel context.CancelFunc, producer *ProducerService) {
+func runBackwardSingleBatch(ctx context.Context, cancel context.CancelFunc, publisher *producer.Service) {
 	log.Printf("Backward-link mode: publishing a single batch (size=%d) and exiting", DefaultBatchSize)
 	go func() {
-		_, err := producer.PublishOrderBatch(ctx, DefaultBatchSize)
+		_, err := publisher.PublishOrderBatch(ctx, DefaultBatchSize)
 		if err != nil {
 			log.Printf("Failed to publish order batch: %v", err)
 		}
@@ -175,6 +639,360 @@ func runBackwardSingleBatch(ctx context.Context, cancel context.CancelFunc, prod
 	}()
 }
 
+// runContinuous publishes a batch of DefaultBatchSize orders every BatchPublishInterval
+// until ctx is done.
+func runContinuous(ctx context.Context, publisher *producer.Service) {
+	log.Printf("Continuous mode: publishing a batch (size=%d) every %s until interrupted", DefaultBatchSize, BatchPublishInterval)
+
+	ticker := time.NewTicker(BatchPublishInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := publisher.PublishOrderBatch(ctx, DefaultBatchSize); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to publish order batch: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runTrafficReplay replays the traffic profile at path through the producer, waits until
+// drained reports every order has been handled, then exits.
+func runTrafficReplay(ctx context.Context, cancel context.CancelFunc, publisher *producer.Service, drained func() bool, path string) {
+	events, err := producer.LoadTrafficProfile(path)
+	if err != nil {
+		log.Fatalf("Failed to load traffic profile: %v", err)
+	}
+	log.Printf("Replay mode: replaying %d orders from %s", len(events), path)
+
+	go func() {
+		defer cancel()
+		if _, err := publisher.ReplayTrafficProfile(ctx, events); err != nil {
+			log.Printf("Failed to replay traffic profile: %v", err)
+			return
+		}
+		for !drained() {
+			select {
+			case <-time.After(100 * time.Millisecond):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// runScenarioAttributes describes the run's mode, parameters, and outcome for the run summary span
+func runScenarioAttributes(stats *RunStats) []attribute.KeyValue {
+	mode := "backward"
+	switch {
+	case forwardLinksEnabled():
+		mode = "forward"
+	case continuousRunEnabled():
+		mode = "continuous"
+	case os.Getenv("TRAFFIC_PROFILE_FILE") != "":
+		mode = "replay"
+	case resumedOrders > 0:
+		mode = "resume"
+	}
+
+	publishRate, _ := publishRateLimitFromEnv()
+	dist := orderDistributionFromEnv()
+	return []attribute.KeyValue{
+		runIDKey.String(runID),
+		attribute.String("run.scenario", mode),
+		attribute.String("run.traffic_profile", os.Getenv("TRAFFIC_PROFILE_FILE")),
+		attribute.String("run.runtime_config_file", os.Getenv("RUNTIME_CONFIG_FILE")),
+		attribute.Int("run.batch_size", DefaultBatchSize),
+		attribute.Int("run.forward_batches", forwardBatchesFromEnv()),
+		attribute.Int64("run.forward_link_max_wait_ms", forwardLinkMaxWaitFromEnv().Milliseconds()),
+		attribute.Int("run.customer_pool", dist.CustomerPool),
+		attribute.Float64("run.amount_min", dist.AmountMin),
+		attribute.Float64("run.amount_max", dist.AmountMax),
+		attribute.Bool("run.amount_heavy_tail", dist.HeavyTail),
+		attribute.Int("run.worker_count", DefaultWorkerCount),
+		attribute.String("run.autoscale_workers", autoscaleRange()),
+		attribute.Int("run.queue_capacity", queueCapacityFromEnv()),
+		attribute.Bool("run.topic_routing", topicRoutingEnabled()),
+		attribute.Bool("run.consumer_group", consumerGroupEnabled()),
+		attribute.Bool("run.key_affinity", keyAffinityEnabled()),
+		attribute.Bool("run.strict_traceparent", strictTraceParentEnabled()),
+		attribute.Bool("run.semconv_span_kinds", semconvSpanKindsEnabled()),
+		attribute.Bool("run.link_events", linkEventsEnabled()),
+		attribute.Bool("run.sync_span_export", syncSpanExportEnabled()),
+		attribute.Bool("run.link_edges_csv", os.Getenv("LINK_EDGES_CSV") != ""),
+		attribute.Bool("run.fake_clock", fakeClockEnabled()),
+		attribute.Int64("run.export_outage_ms", exportOutageFromEnv().Milliseconds()),
+		attribute.Bool("run.spill_enabled", os.Getenv("SPILL_DIR") != ""),
+		attribute.String("run.process_role", processRoleFromEnv()),
+		attribute.Float64("run.error_budget_target", errorBudgetTargetFromEnv()),
+		attribute.StringSlice("run.dropped_spans", droppedSpanNamesFromEnv()),
+		attribute.String("run.consumer_link_mode", string(consumerLinkModeFromEnv())),
+		attribute.String("run.link_direction", linkDirection()),
+		attribute.Int("run.step_max_attempts", stepRetryPolicyFromEnv().MaxAttempts),
+		attribute.Int("run.max_redeliveries", maxRedeliveriesFromEnv()),
+		attribute.String("run.step_spans", os.Getenv("STEP_SPANS")),
+		attribute.Int64("run.backfill_queue_wait_ms", backfillQueueWaitFromEnv().Milliseconds()),
+		attribute.Int64("run.clock_skew_ms", clockSkewFromEnv().Milliseconds()),
+		attribute.Int64("run.latency_budget_ms", latencyBudgetFromEnv().Milliseconds()),
+		attribute.Int64("run.lag_alert_threshold_ms", lagAlertThresholdFromEnv().Milliseconds()),
+		attribute.Int("run.ack_batch_size", ackBatchSizeFromEnv()),
+		attribute.Float64("run.publish_rate_limit", publishRate),
+		attribute.Float64("run.duplicate_publish_rate", percentFromEnv("DUPLICATE_PUBLISH_PERCENT")),
+		attribute.Bool("run.transactional_publish", transactionalPublishEnabled()),
+		attribute.StringSlice("run.tenants", tenantsFromEnv()),
+		attribute.Float64("run.publish_failure_rate", percentFromEnv("PUBLISH_FAILURE_PERCENT")),
+		attribute.Bool("run.payload_sealing", os.Getenv("PAYLOAD_SEAL_KEY") != ""),
+		attribute.Float64("run.payload_tamper_rate", percentFromEnv("PAYLOAD_TAMPER_PERCENT")),
+		attribute.Bool("run.audit_log", auditLogEnabled()),
+		attribute.Bool("run.order_events", orderEventsEnabled()),
+		attribute.Bool("run.queue_http", os.Getenv("QUEUE_HTTP_ADDR") != ""),
+		attribute.Float64("run.traceparent_corrupt_rate", percentFromEnv("TRACEPARENT_CORRUPT_PERCENT")),
+		attribute.Int("run.cancellations", orderCancellationsFromEnv()),
+		attribute.Int64("run.orders.published", stats.Published()),
+		attribute.Int64("run.orders.processed", stats.Processed()),
+		attribute.Int64("run.orders.failed", stats.Failed()),
+		attribute.Int64("run.orders.rolled_back", stats.RolledBack()),
+		attribute.Int64("run.orders.traceparent_corrupted", stats.TraceParentCorrupted()),
+		attribute.Int64("run.orders.link_parse_failed", stats.LinkParseFailed()),
+		attribute.Float64("run.traceparent_corruption_rate", stats.CorruptionRate()),
+		attribute.Int("run.orders.resumed", resumedOrders),
+		runInvocationKey.Int(runInvocation),
+	}
+}
+
+// runCancellations cancels the last count processed orders through the cancellation API.
+// Each CancelOrder span starts a new trace linking back to the order's publish and processing spans.
+func runCancellations(registry *OrderRegistry, count int) {
+	processed := registry.Processed()
+	if count <= 0 || len(processed) == 0 {
+		return
+	}
+	if count > len(processed) {
+		count = len(processed)
+	}
+
+	canceller := NewCancellationService(registry)
+	for _, orderID := range processed[len(processed)-count:] {
+		if err := canceller.CancelOrder(context.Background(), orderID, "customer_request"); err != nil {
+			log.Printf("Failed to cancel order %s: %v", orderID, err)
+		}
+	}
+}
+
+// orderCancellationsFromEnv reads ORDER_CANCELLATIONS (number of processed orders to cancel at the end of the run)
+func orderCancellationsFromEnv() int {
+	return intFromEnv("ORDER_CANCELLATIONS", 0)
+}
+
+// startGroupWorkers runs DefaultWorkerCount consumer-group members, plus one extra member
+// that joins late and leaves early so rebalances happen while orders are in flight.
+func startGroupWorkers(ctx context.Context, wg *sync.WaitGroup, consumer *worker.Service, group *ConsumerGroup) {
+	log.Printf("Consumer group mode (group=%s partitions=%d)", ConsumerGroupName, ConsumerGroupPartitions)
+
+	for i := 1; i <= DefaultWorkerCount; i++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+			group.Process(ctx, consumer, fmt.Sprintf("Worker-%d", workerID))
+		}(i)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		select {
+		case <-time.After(ConsumerGroupLateJoinDelay):
+		case <-ctx.Done():
+			return
+		}
+		memberCtx, memberCancel := context.WithTimeout(ctx, ConsumerGroupLateMemberTTL)
+		defer memberCancel()
+		group.Process(memberCtx, consumer, fmt.Sprintf("Worker-%d", DefaultWorkerCount+1))
+	}()
+}
+
+func consumerGroupEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("ENABLE_CONSUMER_GROUP"))
+	return err == nil && enabled
+}
+
+// workerTopics returns the topics a worker subscribes to. With topic routing enabled,
+// every worker takes priority orders and only Worker-1 also takes standard orders.
+func workerTopics(workerID int) []string {
+	if !topicRoutingEnabled() {
+		return []string{queue.DefaultTopic}
+	}
+	if workerID == 1 {
+		return []string{queue.PriorityOrdersTopic, queue.StandardOrdersTopic}
+	}
+	return []string{queue.PriorityOrdersTopic}
+}
+
+func keyAffinityEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("KEY_AFFINITY"))
+	return err == nil && enabled
+}
+
+// ackBatchSizeFromEnv reads ACK_BATCH_SIZE (orders per AckBatch; 0 or unset disables batched acks).
+func ackBatchSizeFromEnv() int {
+	return intFromEnv("ACK_BATCH_SIZE", 0)
+}
+
+// stepModesFromEnv reads STEP_SPANS, comma-separated step=mode pairs such as
+// "validate=event,shipping=off" (steps: lookup, validate, payment, shipping, persist;
+// modes: span, event, off). Unlisted steps stay spans.
+func stepModesFromEnv() map[string]worker.StepMode {
+	modes := make(map[string]worker.StepMode)
+	val := os.Getenv("STEP_SPANS")
+	if val == "" {
+		return modes
+	}
+	for _, pair := range strings.Split(val, ",") {
+		step, name, _ := strings.Cut(strings.TrimSpace(pair), "=")
+		mode, err := worker.ParseStepMode(strings.TrimSpace(name))
+		if err != nil || !slices.Contains(worker.Steps, step) {
+			log.Printf("Ignoring invalid STEP_SPANS entry %q", pair)
+			continue
+		}
+		modes[step] = mode
+	}
+	return modes
+}
+
+// maxRedeliveriesFromEnv reads ORDER_MAX_REDELIVERIES (times a failed order is put back on
+// the queue; 0 or unset drops failed orders)
+func maxRedeliveriesFromEnv() int {
+	return intFromEnv("ORDER_MAX_REDELIVERIES", 0)
+}
+
+// linkGuardFromEnv reads LINK_ATTR_MAX_VALUES (distinct order.id values recorded on
+// links; 0 or unset disables the guard) and LINK_ATTR_OVERFLOW (bucket, other or drop)
+func linkGuardFromEnv() *linkguard.Guard {
+	limit := intFromEnv("LINK_ATTR_MAX_VALUES", 0)
+	if limit == 0 {
+		return nil
+	}
+	overflow, err := linkguard.ParseOverflow(os.Getenv("LINK_ATTR_OVERFLOW"))
+	if err != nil {
+		log.Printf("Ignoring invalid LINK_ATTR_OVERFLOW: %v", err)
+		overflow = linkguard.Bucket
+	}
+	return linkguard.New(limit, overflow)
+}
+
+// linkPruningFromEnv reads MAX_AGGREGATE_LINKS (link cap for aggregator spans, default the
+// SDK limit; 0 disables pruning) and LINK_PRUNE_STRATEGY (first, last or reservoir).
+func linkPruningFromEnv() (int, linkprune.Strategy) {
+	maxLinks := intFromEnv("MAX_AGGREGATE_LINKS", linkprune.DefaultMaxLinks)
+	strategy, err := linkprune.ParseStrategy(os.Getenv("LINK_PRUNE_STRATEGY"))
+	if err != nil {
+		log.Printf("Ignoring invalid LINK_PRUNE_STRATEGY: %v", err)
+		strategy = linkprune.KeepFirst
+	}
+	return maxLinks, strategy
+}
+
+// orderingWindowFromEnv reads ORDERING_WINDOW_MS. Unset means DefaultOrderingWindow in
+// key-affinity mode and disabled otherwise; 0 disables OrderingWindow spans.
+func orderingWindowFromEnv() time.Duration {
+	var def time.Duration
+	if keyAffinityEnabled() {
+		def = DefaultOrderingWindow
+	}
+	return durationMsFromEnv("ORDERING_WINDOW_MS", def)
+}
+
+// payloadSealerFromEnv reads PAYLOAD_SEAL_KEY, the secret order payloads are encrypted
+// and signed with (nil when unset)
+func payloadSealerFromEnv() *queue.Sealer {
+	secret := os.Getenv("PAYLOAD_SEAL_KEY")
+	if secret == "" {
+		return nil
+	}
+	sealer, err := queue.NewSealer(secret)
+	if err != nil {
+		log.Printf("Ignoring invalid PAYLOAD_SEAL_KEY: %v", err)
+		return nil
+	}
+	return sealer
+}
+
+// queueCapacityFromEnv reads QUEUE_CAPACITY (messages buffered per topic before publishes
+// block, default queue.DefaultCapacity)
+func queueCapacityFromEnv() int {
+	return intAtLeastFromEnv("QUEUE_CAPACITY", queue.DefaultCapacity, 1)
+}
+
+func continuousRunEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("CONTINUOUS_RUN"))
+	return err == nil && enabled
+}
+
+// dashboardEnabled reports whether DASHBOARD is set and stdout is a terminal
+func dashboardEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("DASHBOARD"))
+	if err != nil || !enabled {
+		return false
+	}
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func semconvSpanKindsEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("SEMCONV_SPAN_KINDS"))
+	return err == nil && enabled
+}
+
+func transactionalPublishEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("TRANSACTIONAL_PUBLISH"))
+	return err == nil && enabled
+}
+
+func strictTraceParentEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("STRICT_TRACEPARENT"))
+	return err == nil && enabled
+}
+
+// orderingComparisonEnabled reports whether to run the ordered vs concurrent processing
+// comparison (COMPARE_ORDERING) instead of the regular pipeline
+func orderingComparisonEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("COMPARE_ORDERING"))
+	return err == nil && enabled
+}
+
+// concurrentWorkersFromEnv reads COMPARE_CONCURRENT_WORKERS (workers on the concurrent side
+// of the ordering comparison)
+func concurrentWorkersFromEnv() int {
+	return intAtLeastFromEnv("COMPARE_CONCURRENT_WORKERS", DefaultConcurrentWorkers, 1)
+}
+
+// fakeClockEnabled reports whether the pipeline runs on a fake clock (FAKE_CLOCK): step
+// sleeps, throttling and replay offsets advance simulated time instead of waiting
+func fakeClockEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("FAKE_CLOCK"))
+	return err == nil && enabled
+}
+
+// syncSpanExportEnabled reports whether spans are exported one by one as they end instead
+// of in batches
+func syncSpanExportEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("SYNC_SPAN_EXPORT"))
+	return err == nil && enabled
+}
+
+// linkEventsEnabled reports whether span links are also recorded as span.link events
+func linkEventsEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("MIRROR_LINKS_AS_EVENTS"))
+	return err == nil && enabled
+}
+
+func topicRoutingEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("ENABLE_TOPIC_ROUTING"))
+	return err == nil && enabled
+}
+
 func forwardLinksEnabled() bool {
 	val := os.Getenv("ENABLE_FORWARD_LINKS_TO_PRODUCER")
 	if val == "" {
@@ -187,6 +1005,214 @@ func forwardLinksEnabled() bool {
 	return enabled
 }
 
+// linkDirection names how the run links consumers and producers, for the queue metrics:
+// consumers link back (CONSUMER_LINK_MODE=link) and/or producers link forward
+// (ENABLE_FORWARD_LINKS_TO_PRODUCER)
+func linkDirection() string {
+	backward := consumerLinkModeFromEnv() == worker.LinkModeLink
+	forward := forwardLinksEnabled()
+	switch {
+	case backward && forward:
+		return "forward_and_backward"
+	case backward:
+		return "backward"
+	case forward:
+		return "forward"
+	default:
+		return "none"
+	}
+}
+
+// forwardBatchesFromEnv reads FORWARD_BATCHES, the number of batches forward-link mode publishes
+func forwardBatchesFromEnv() int {
+	return intAtLeastFromEnv("FORWARD_BATCHES", DefaultForwardBatches, 1)
+}
+
+// intFromEnv reads a non-negative integer from key, returning def when it is unset or
+// invalid
+func intFromEnv(key string, def int) int {
+	return intAtLeastFromEnv(key, def, 0)
+}
+
+// intAtLeastFromEnv reads an integer of at least min from key, returning def when it is
+// unset or invalid
+func intAtLeastFromEnv(key string, def, min int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil || n < min {
+		log.Printf("Ignoring invalid %s=%q", key, val)
+		return def
+	}
+	return n
+}
+
+// durationMsFromEnv reads a non-negative duration in milliseconds from key, returning def
+// when it is unset or invalid
+func durationMsFromEnv(key string, def time.Duration) time.Duration {
+	return time.Duration(intFromEnv(key, int(def.Milliseconds()))) * time.Millisecond
+}
+
+// percentFromEnv reads a 0-100 percentage from key and returns it as a 0..1 fraction
+func percentFromEnv(key string) float64 {
+	val := os.Getenv(key)
+	if val == "" {
+		return 0
+	}
+	pct, err := strconv.ParseFloat(val, 64)
+	if err != nil || pct < 0 || pct > 100 {
+		log.Printf("Ignoring invalid %s=%q (expected 0-100)", key, val)
+		return 0
+	}
+	return pct / 100
+}
+
+// heartbeatIntervalFromEnv reads WORKER_HEARTBEAT_INTERVAL_MS (0 or unset disables heartbeat spans)
+func heartbeatIntervalFromEnv() time.Duration {
+	return durationMsFromEnv("WORKER_HEARTBEAT_INTERVAL_MS", DefaultHeartbeatInterval)
+}
+
+// backfillQueueWaitFromEnv reads BACKFILL_QUEUE_WAIT_MS (0 or unset records consumer spans at wall-clock time).
+func backfillQueueWaitFromEnv() time.Duration {
+	return durationMsFromEnv("BACKFILL_QUEUE_WAIT_MS", DefaultBackfillQueueWait)
+}
+
+// clockSkewFromEnv reads CLOCK_SKEW_MS (may be negative; 0 or unset disables skew).
+func clockSkewFromEnv() time.Duration {
+	def := time.Duration(DefaultClockSkew)
+	ms := intAtLeastFromEnv("CLOCK_SKEW_MS", int(def.Milliseconds()), math.MinInt)
+	return time.Duration(ms) * time.Millisecond
+}
+
+// droppedSpanNamesFromEnv reads DROP_SPANS, comma-separated span names to drop before
+// export (e.g. "ValidateOrder,LookupCustomer")
+func droppedSpanNamesFromEnv() []string {
+	var names []string
+	for _, name := range strings.Split(os.Getenv("DROP_SPANS"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// errorBudgetTargetFromEnv reads ERROR_BUDGET_TARGET_PERCENT, the success rate the run's
+// error budget is measured against (unset means DefaultErrorBudgetTarget)
+func errorBudgetTargetFromEnv() float64 {
+	if os.Getenv("ERROR_BUDGET_TARGET_PERCENT") == "" {
+		return DefaultErrorBudgetTarget
+	}
+	return percentFromEnv("ERROR_BUDGET_TARGET_PERCENT")
+}
+
+// processRoleFromEnv reads PRODUCER_ONLY and CONSUMER_ONLY (at most one may be true)
+func processRoleFromEnv() string {
+	producerOnly, _ := strconv.ParseBool(os.Getenv("PRODUCER_ONLY"))
+	consumerOnly, _ := strconv.ParseBool(os.Getenv("CONSUMER_ONLY"))
+	switch {
+	case producerOnly && consumerOnly:
+		log.Fatalf("PRODUCER_ONLY and CONSUMER_ONLY are mutually exclusive")
+	case producerOnly:
+		return roleProducer
+	case consumerOnly:
+		return roleConsumer
+	}
+	return roleCombined
+}
+
+// exportOutageFromEnv reads EXPORT_OUTAGE_MS, how long trace exports fail after startup
+// (0 or unset means no simulated outage)
+func exportOutageFromEnv() time.Duration {
+	return durationMsFromEnv("EXPORT_OUTAGE_MS", 0)
+}
+
+// consumerLinkModeFromEnv reads CONSUMER_LINK_MODE (link, parent or none; unset means link)
+func consumerLinkModeFromEnv() worker.LinkMode {
+	mode, err := worker.ParseLinkMode(os.Getenv("CONSUMER_LINK_MODE"))
+	if err != nil {
+		log.Printf("Ignoring invalid CONSUMER_LINK_MODE: %v", err)
+		return worker.LinkModeLink
+	}
+	return mode
+}
+
+// stepRetryPolicyFromEnv reads STEP_MAX_ATTEMPTS (attempts per failed processing step;
+// unset means 1, no retries), backing off exponentially from DefaultRetryBackoff
+func stepRetryPolicyFromEnv() worker.RetryPolicy {
+	return worker.RetryPolicy{
+		MaxAttempts: intAtLeastFromEnv("STEP_MAX_ATTEMPTS", 1, 1),
+		Backoff:     worker.DefaultRetryBackoff,
+		Multiplier:  2,
+	}
+}
+
+// orderDistributionFromEnv reads ORDER_CUSTOMER_POOL, ORDER_AMOUNT_MIN, ORDER_AMOUNT_MAX and
+// ORDER_AMOUNT_HEAVY_TAIL over the default order distribution
+func orderDistributionFromEnv() producer.OrderDistribution {
+	d := producer.DefaultOrderDistribution()
+	d.CustomerPool = intAtLeastFromEnv("ORDER_CUSTOMER_POOL", d.CustomerPool, 1)
+	amountMin, amountMax := d.AmountMin, d.AmountMax
+	if val := os.Getenv("ORDER_AMOUNT_MIN"); val != "" {
+		if f, err := strconv.ParseFloat(val, 64); err == nil && f >= 0 {
+			amountMin = f
+		} else {
+			log.Printf("Ignoring invalid ORDER_AMOUNT_MIN=%q", val)
+		}
+	}
+	if val := os.Getenv("ORDER_AMOUNT_MAX"); val != "" {
+		if f, err := strconv.ParseFloat(val, 64); err == nil && f >= 0 {
+			amountMax = f
+		} else {
+			log.Printf("Ignoring invalid ORDER_AMOUNT_MAX=%q", val)
+		}
+	}
+	if amountMax < amountMin {
+		log.Printf("Ignoring order amount range %g-%g (max below min)", amountMin, amountMax)
+	} else {
+		d.AmountMin, d.AmountMax = amountMin, amountMax
+	}
+	if enabled, err := strconv.ParseBool(os.Getenv("ORDER_AMOUNT_HEAVY_TAIL")); err == nil {
+		d.HeavyTail = enabled
+	}
+	return d
+}
+
+// publishRateLimitFromEnv reads PUBLISH_RATE_LIMIT (orders per second; 0 or unset disables
+// the limiter) and PUBLISH_RATE_BURST.
+func publishRateLimitFromEnv() (float64, int) {
+	val := os.Getenv("PUBLISH_RATE_LIMIT")
+	if val == "" {
+		return 0, 0
+	}
+	rate, err := strconv.ParseFloat(val, 64)
+	if err != nil || rate < 0 {
+		log.Printf("Ignoring invalid PUBLISH_RATE_LIMIT=%q", val)
+		return 0, 0
+	}
+
+	return rate, intAtLeastFromEnv("PUBLISH_RATE_BURST", DefaultPublishBurst, 1)
+}
+
+// lagAlertThresholdFromEnv reads CONSUMER_LAG_ALERT_MS (0 or unset disables LagAlert spans).
+func lagAlertThresholdFromEnv() time.Duration {
+	return durationMsFromEnv("CONSUMER_LAG_ALERT_MS", DefaultLagAlertThreshold)
+}
+
+// orderDBPath reads ORDER_DB_PATH (a SQLite file or DSN; unset uses an in-memory database).
+func orderDBPath() string {
+	if path := os.Getenv("ORDER_DB_PATH"); path != "" {
+		return path
+	}
+	return DefaultOrderDBPath
+}
+
+// latencyBudgetFromEnv reads ORDER_LATENCY_BUDGET_MS (0 or unset disables SLO breach detection).
+func latencyBudgetFromEnv() time.Duration {
+	return durationMsFromEnv("ORDER_LATENCY_BUDGET_MS", DefaultLatencyBudget)
+}
+
 func init() {
 	// Load .env file if it exists (ignore errors if file doesn't exist)
 	_ = godotenv.Load()
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestIntFromEnv(t *testing.T) {
+	const key = "TEST_INT_FROM_ENV"
+	for _, tc := range []struct {
+		val  string
+		min  int
+		want int
+	}{
+		{val: "", want: 7},
+		{val: "3", want: 3},
+		{val: "0", want: 0},
+		{val: "0", min: 1, want: 7},
+		{val: "-2", want: 7},
+		{val: "-2", min: math.MinInt, want: -2},
+		{val: "2.5", want: 7},
+		{val: "many", want: 7},
+	} {
+		t.Setenv(key, tc.val)
+		if got := intAtLeastFromEnv(key, 7, tc.min); got != tc.want {
+			t.Errorf("%s=%q (min %d) read as %d, want %d", key, tc.val, tc.min, got, tc.want)
+		}
+	}
+
+	t.Setenv(key, "-1")
+	if got := intFromEnv(key, 4); got != 4 {
+		t.Errorf("intFromEnv accepted a negative value: %d", got)
+	}
+}
+
+func TestDurationMsFromEnv(t *testing.T) {
+	const key = "TEST_DURATION_MS_FROM_ENV"
+	for val, want := range map[string]time.Duration{
+		"":     time.Second,
+		"250":  250 * time.Millisecond,
+		"0":    0,
+		"-5":   time.Second,
+		"1s":   time.Second,
+		"1500": 1500 * time.Millisecond,
+	} {
+		t.Setenv(key, val)
+		if got := durationMsFromEnv(key, time.Second); got != want {
+			t.Errorf("%s=%q read as %s, want %s", key, val, got, want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OrderTrace records the span contexts produced for a single order, so follow-up
+// actions (cancellations, audits, summaries) can link back to them later.
+type OrderTrace struct {
+	OrderID string
+	Publish trace.SpanContext // PublishOrder span
+	Process trace.SpanContext // ProcessOrder span
+}
+
+// OrderRegistry is an in-memory index of order span contexts for the current run
+type OrderRegistry struct {
+	mu        sync.RWMutex
+	orders    map[string]*OrderTrace
+	processed []string
+}
+
+// NewOrderRegistry creates an empty order registry
+func NewOrderRegistry() *OrderRegistry {
+	return &OrderRegistry{
+		orders: make(map[string]*OrderTrace),
+	}
+}
+
+// RecordPublish stores the PublishOrder span context of an order
+func (r *OrderRegistry) RecordPublish(orderID string, sc trace.SpanContext) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entry(orderID).Publish = sc
+}
+
+// RecordProcess stores the ProcessOrder span context of an order
+func (r *OrderRegistry) RecordProcess(orderID string, sc trace.SpanContext) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entry(orderID).Process = sc
+	r.processed = append(r.processed, orderID)
+}
+
+// Lookup returns the recorded span contexts of an order
+func (r *OrderRegistry) Lookup(orderID string) (OrderTrace, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.orders[orderID]
+	if !ok {
+		return OrderTrace{}, false
+	}
+	return *t, true
+}
+
+// Processed returns the IDs of processed orders in processing order
+func (r *OrderRegistry) Processed() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]string(nil), r.processed...)
+}
+
+// entry returns the trace entry for orderID, creating it if needed. Caller must hold r.mu.
+func (r *OrderRegistry) entry(orderID string) *OrderTrace {
+	t, ok := r.orders[orderID]
+	if !ok {
+		t = &OrderTrace{OrderID: orderID}
+		r.orders[orderID] = t
+	}
+	return t
+}
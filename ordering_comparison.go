@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"span-links-signoz-demo/pkg/producer"
+	"span-links-signoz-demo/pkg/queue"
+	"span-links-signoz-demo/pkg/worker"
+	"span-links-signoz-demo/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Processing modes compared by the ordering comparison
+const (
+	ProcessingOrdered    = "ordered"
+	ProcessingConcurrent = "concurrent"
+)
+
+// processingRun is the outcome of one side of the ordering comparison
+type processingRun struct {
+	mode    string
+	workers int
+	spanCtx trace.SpanContext // ProcessingModeRun span
+	elapsed time.Duration
+}
+
+// runOrderingComparison publishes one batch to each of two independent pipelines at the
+// same time: one processed strictly in order by a single worker, one processed
+// concurrently by workers workers. Each side runs under a ProcessingModeRun span (parent of
+// its PublishOrderBatch) and tags its ProcessOrder spans with processing.mode; an
+// OrderingComparison span in its own trace links to both runs and records their
+// publish-to-drained latencies side by side.
+func runOrderingComparison(ctx context.Context, providers *TelemetryProviders, workers int) {
+	log.Printf("Ordering comparison mode (batch=%d ordered_workers=1 concurrent_workers=%d)", DefaultBatchSize, workers)
+
+	sides := []struct {
+		mode    string
+		workers int
+	}{
+		{ProcessingOrdered, 1},
+		{ProcessingConcurrent, workers},
+	}
+	runs := make([]processingRun, len(sides))
+	stats := make([]*RunStats, len(sides))
+
+	var wg sync.WaitGroup
+	for i, side := range sides {
+		stats[i] = NewRunStats()
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			runs[i] = runProcessingMode(ctx, side.mode, side.workers, stats[i])
+		}(i)
+	}
+	wg.Wait()
+
+	ordered, concurrent := runs[0], runs[1]
+	links := make([]trace.Link, 0, len(runs))
+	for _, r := range runs {
+		links = append(links, trace.Link{
+			SpanContext: r.spanCtx,
+			Attributes: []attribute.KeyValue{
+				attribute.String("link.type", "compared_run"),
+				attribute.String("processing.mode", r.mode),
+			},
+		})
+	}
+	attrs := []attribute.KeyValue{
+		attribute.Int("order.batch.size", DefaultBatchSize),
+		attribute.Int64("ordering.ordered.duration_ms", ordered.elapsed.Milliseconds()),
+		attribute.Int64("ordering.concurrent.duration_ms", concurrent.elapsed.Milliseconds()),
+		attribute.Int("ordering.concurrent.workers", concurrent.workers),
+	}
+	if concurrent.elapsed > 0 {
+		attrs = append(attrs, attribute.Float64("ordering.speedup", float64(ordered.elapsed)/float64(concurrent.elapsed)))
+	}
+	_, span := otel.Tracer("ordering-comparison").Start(context.Background(), "OrderingComparison",
+		trace.WithLinks(links...),
+		trace.WithAttributes(attrs...),
+	)
+	telemetry.EndWithStatus(span, nil)
+
+	log.Printf("Ordering comparison completed (ordered=%s concurrent=%s workers=%d)",
+		ordered.elapsed.Round(time.Millisecond), concurrent.elapsed.Round(time.Millisecond), concurrent.workers)
+
+	summary := append(attrs,
+		runIDKey.String(runID),
+		attribute.String("run.scenario", "ordering_comparison"),
+	)
+	for i, r := range runs {
+		summary = append(summary,
+			attribute.Int64("run."+r.mode+".orders.processed", stats[i].Processed()),
+			attribute.Int64("run."+r.mode+".orders.failed", stats[i].Failed()),
+		)
+	}
+	EmitRunSummary(providers.RootSpans, summary...)
+}
+
+// runProcessingMode publishes a batch to a fresh queue and processes it with the given
+// number of workers, returning once every order was handled
+func runProcessingMode(ctx context.Context, mode string, workers int, stats *RunStats) processingRun {
+	orders := queue.New()
+	publisher := producer.New(orders)
+	publisher.SetRunStats(stats)
+	consumer := worker.New(orders,
+		worker.WithSpanAttributes(
+			attribute.String("processing.mode", mode),
+			attribute.Int("processing.workers", workers),
+		),
+		worker.WithRunStats(stats),
+	)
+
+	runCtx, span := otel.Tracer("ordering-comparison").Start(ctx, "ProcessingModeRun",
+		trace.WithAttributes(
+			attribute.String("processing.mode", mode),
+			attribute.Int("processing.workers", workers),
+		),
+	)
+	defer span.End()
+
+	workCtx, stopWorkers := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	for i := 1; i <= workers; i++ {
+		wg.Add(1)
+		go func(workerID string) {
+			defer wg.Done()
+			consumer.ProcessOrders(workCtx, workerID)
+		}(fmt.Sprintf("%s-worker-%d", mode, i))
+	}
+
+	start := time.Now()
+	_, err := publisher.PublishOrderBatch(runCtx, DefaultBatchSize)
+	telemetry.SetStatus(span, err)
+	if err != nil {
+		log.Printf("Failed to publish %s batch: %v", mode, err)
+	}
+	drained := waitForDrain(ctx, stats, DrainTimeout)
+	elapsed := time.Since(start)
+	stopWorkers()
+	wg.Wait()
+
+	span.SetAttributes(
+		attribute.Int64("processing.duration_ms", elapsed.Milliseconds()),
+		attribute.Bool("processing.drained", drained),
+	)
+	log.Printf("Processed batch %s (workers=%d elapsed=%s)", mode, workers, elapsed.Round(time.Millisecond))
+	return processingRun{mode: mode, workers: workers, spanCtx: span.SpanContext(), elapsed: elapsed}
+}
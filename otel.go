@@ -4,85 +4,190 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
+	"span-links-signoz-demo/telemetry"
+
+	"go.opentelemetry.io/contrib/instrumentation/host"
+	"go.opentelemetry.io/contrib/instrumentation/runtime"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/log/global"
 	"go.opentelemetry.io/otel/propagation"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
 	"go.opentelemetry.io/otel/trace"
 )
 
-// TelemetryProviders holds trace provider only
+// TelemetryProviders holds the trace, metric and (optional) log providers
 type TelemetryProviders struct {
 	TracerProvider *sdktrace.TracerProvider
+	MeterProvider  *sdkmetric.MeterProvider
+	LoggerProvider *sdklog.LoggerProvider // nil unless AUDIT_LOG or ORDER_EVENTS is enabled
+	RootSpans      *RootSpanRecorder
+	Resource       *resource.Resource
+	SpanProcessors []sdktrace.SpanProcessor // shared with per-instance providers (see NewInstanceTracerProvider)
+	IDGenerator    sdktrace.IDGenerator     // nil: the SDK's random IDs
 }
 
-// InitTracer initializes OpenTelemetry for traces only
-func InitTracer(ctx context.Context) (*TelemetryProviders, error) {
-	// Get configuration from environment variables
-	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
-	if endpoint == "" {
-		endpoint = "http://localhost:4317" // Default local endpoint
-	}
+// InitTelemetry initializes OpenTelemetry traces and metrics (including Go runtime and host metrics)
+func InitTelemetry(ctx context.Context) (*TelemetryProviders, error) {
+	// Get endpoint and protocol from environment variables (probing a local collector if unset)
+	target := resolveOTLPTarget()
 
-	serviceName := os.Getenv("OTEL_SERVICE_NAME")
-	if serviceName == "" {
-		serviceName = "span-links-demo"
-	}
+	// Get headers for authentication (SigNoz Cloud); per-signal variables override the generic one
+	traceHeaders := signalHeaders("TRACES")
+	metricHeaders := signalHeaders("METRICS")
 
-	// Get headers for authentication (SigNoz Cloud)
-	headersStr := os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")
-	var headers map[string]string
-	if headersStr != "" {
-		headers = parseHeaders(headersStr)
-	}
+	// Compression and timeout; per-signal variables override the generic ones
+	traceSettings := signalExportSettings("TRACES")
+	metricSettings := signalExportSettings("METRICS")
 
 	// Create resource describing the service
-	res, err := resource.New(ctx,
-		resource.WithAttributes(
-			semconv.ServiceName(serviceName),
-			semconv.ServiceVersion("1.0.0"),
-			attribute.String("environment", "demo"),
-		),
-	)
+	resAttrs := []attribute.KeyValue{runIDKey.String(runID)}
+	if os.Getenv("RUN_STATE_FILE") != "" {
+		resAttrs = append(resAttrs, runInvocationKey.Int(runInvocation))
+	}
+	res, err := telemetry.ServiceResource(ctx, serviceName(), resAttrs...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create resource: %w", err)
 	}
 
-	// Parse endpoint URL to extract host:port
-	endpointHost, useInsecure := parseEndpoint(endpoint)
-
 	// Create OTLP trace exporter
-	traceExporterOptions := []otlptracehttp.Option{
-		otlptracehttp.WithEndpoint(endpointHost),
-		otlptracehttp.WithURLPath("/v1/traces"),
+	traceExporter, err := newTraceExporter(ctx, target, traceHeaders, traceSettings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
 	}
-	if useInsecure {
-		traceExporterOptions = append(traceExporterOptions, otlptracehttp.WithInsecure())
+	if outage := exportOutageFromEnv(); outage > 0 {
+		log.Printf("Simulating a %s collector outage", outage)
+		traceExporter = outageExporter{SpanExporter: traceExporter, until: time.Now().Add(outage)}
 	}
-	if len(headers) > 0 {
-		traceExporterOptions = append(traceExporterOptions, otlptracehttp.WithHeaders(headers))
+	if dir := os.Getenv("SPILL_DIR"); dir != "" {
+		spill, err := NewSpillExporter(traceExporter, dir)
+		if err != nil {
+			return nil, err
+		}
+		traceExporter = spill
+	}
+
+	// With tenants configured, each tenant's spans go to its own endpoint/ingestion key
+	export := exportProcessor(traceExporter)
+	tenants := tenantExportsFromEnv()
+	if len(tenants) > 0 {
+		tenantProcessors := make(map[string]sdktrace.SpanProcessor, len(tenants))
+		for tenant, te := range tenants {
+			tenantTarget := target
+			if te.endpoint != "" {
+				tenantTarget = endpointTarget(te.endpoint, target.protocol)
+			}
+			headers := make(map[string]string, len(traceHeaders)+len(te.headers))
+			for k, v := range traceHeaders {
+				headers[k] = v
+			}
+			for k, v := range te.headers {
+				headers[k] = v
+			}
+			exp, err := newTraceExporter(ctx, tenantTarget, headers, traceSettings)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create trace exporter for tenant %s: %w", tenant, err)
+			}
+			tenantProcessors[tenant] = exportProcessor(exp)
+			log.Printf("Tenant %s: exporting traces to %s (%s)", tenant, tenantTarget.host, tenantTarget.protocol)
+		}
+		export = NewTenantRouter(tenantProcessors, export)
+	}
+
+	// Create tracer provider with batch span processor (plus root span recording for the run
+	// summary and link metrics for the linked traces index)
+	rootSpans := NewRootSpanRecorder()
+	processors := []sdktrace.SpanProcessor{
+		rootSpans,
+		NewLinkIndexRecorder(),
+		export,
+	}
+	if linkEventsEnabled() {
+		processors = append(processors, LinkEventMirror{})
+	}
+	if path := os.Getenv("LINK_EDGES_CSV"); path != "" {
+		edges, err := NewLinkEdgeWriter(path)
+		if err != nil {
+			return nil, err
+		}
+		processors = append(processors, edges)
 	}
 
-	traceExporter, err := otlptracehttp.New(ctx, traceExporterOptions...)
+	// Optionally tee every span to a second backend (e.g. Jaeger next to SigNoz) with its
+	// own batcher, so a slow or unreachable backend does not hold up the other
+	secondary, teeSpans := resolveSecondaryOTLPTarget()
+	if teeSpans {
+		secondaryExporter, err := newTraceExporter(ctx, secondary, parseHeaders(os.Getenv("SECONDARY_OTLP_HEADERS")), traceSettings)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create secondary trace exporter: %w", err)
+		}
+		processors = append(processors, exportProcessor(secondaryExporter))
+	}
+	// Reproducible IDs; a resumed run continues with the next seed so its IDs do not
+	// repeat those of the invocations before it
+	var ids sdktrace.IDGenerator
+	if seed, ok := traceIDSeedFromEnv(); ok {
+		ids = newSeededIDGenerator(seed + int64(runInvocation-1))
+		log.Printf("Trace and span IDs are seeded (TRACE_ID_SEED=%d invocation=%d)", seed, runInvocation)
+	}
+	tp := newTracerProvider(res, processors, ids)
+
+	// Create meter provider with periodic OTLP export
+	metricExporter, err := newMetricExporter(ctx, target, metricHeaders, metricSettings)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+		return nil, fmt.Errorf("failed to create metric exporter: %w", err)
 	}
 
-	// Create tracer provider with batch span processor
-	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(traceExporter),
-		sdktrace.WithResource(res),
-		sdktrace.WithSampler(sdktrace.AlwaysSample()), // Sample all for demo
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter,
+			sdkmetric.WithInterval(MetricExportInterval),
+		)),
+		sdkmetric.WithResource(res),
 	)
 
-	// Set global providers
-	otel.SetTracerProvider(tp)
+	// Audit records and order events are the only logs the demo exports over OTLP
+	var lp *sdklog.LoggerProvider
+	if auditLogEnabled() || orderEventsEnabled() {
+		logExporter, err := newLogExporter(ctx, target, signalHeaders("LOGS"), signalExportSettings("LOGS"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create log exporter: %w", err)
+		}
+		lp = sdklog.NewLoggerProvider(
+			sdklog.WithProcessor(sdklog.NewBatchProcessor(logExporter)),
+			sdklog.WithResource(res),
+		)
+		global.SetLoggerProvider(lp)
+	}
+
+	// Set global providers (links get run.id via the wrapper)
+	otel.SetTracerProvider(runTracerProvider{tp: tp})
+	otel.SetMeterProvider(mp)
+
+	// Go runtime (goroutines, GC, memory) and host (CPU, memory, network) metrics
+	if err := runtime.Start(
+		runtime.WithMeterProvider(mp),
+		runtime.WithMinimumReadMemStatsInterval(RuntimeMetricsReadInterval),
+	); err != nil {
+		return nil, fmt.Errorf("failed to start runtime metrics: %w", err)
+	}
+	if err := host.Start(host.WithMeterProvider(mp)); err != nil {
+		return nil, fmt.Errorf("failed to start host metrics: %w", err)
+	}
 
 	// Set propagator for distributed tracing
 	otel.SetTextMapPropagator(
@@ -92,15 +197,92 @@ func InitTracer(ctx context.Context) (*TelemetryProviders, error) {
 		),
 	)
 
-	log.Printf("OpenTelemetry tracing initialized successfully")
-	log.Printf("  Endpoint: %s", endpointHost)
-	log.Printf("  Traces: /v1/traces")
+	log.Printf("OpenTelemetry tracing and metrics initialized successfully")
+	log.Printf("  Endpoint: %s (%s)", target.host, target.protocol)
+	if teeSpans {
+		log.Printf("  Secondary endpoint (traces): %s (%s)", secondary.host, secondary.protocol)
+	}
+	log.Printf("  Export: traces(gzip=%t timeout=%s) metrics(gzip=%t timeout=%s)",
+		traceSettings.gzip, traceSettings.timeout, metricSettings.gzip, metricSettings.timeout)
+	log.Printf("  Signals: traces, metrics (runtime + host + link index)")
+	if auditLogEnabled() {
+		log.Printf("  Order state transitions are exported as audit logs")
+	}
+	if orderEventsEnabled() {
+		log.Printf("  Order business events are exported as log records")
+	}
+	if syncSpanExportEnabled() {
+		log.Printf("  Spans are exported synchronously as they end (SYNC_SPAN_EXPORT)")
+	}
+	if linkEventsEnabled() {
+		log.Printf("  Span links are mirrored as span.link events")
+	}
 
 	return &TelemetryProviders{
 		TracerProvider: tp,
+		MeterProvider:  mp,
+		LoggerProvider: lp,
+		RootSpans:      rootSpans,
+		Resource:       res,
+		SpanProcessors: processors,
+		IDGenerator:    ids,
 	}, nil
 }
 
+// newTracerProvider creates a tracer provider for res feeding processors, generating IDs
+// with ids unless it is nil
+func newTracerProvider(res *resource.Resource, processors []sdktrace.SpanProcessor, ids sdktrace.IDGenerator) *sdktrace.TracerProvider {
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.AlwaysSample()), // Sample all for demo
+	}
+	if ids != nil {
+		opts = append(opts, sdktrace.WithIDGenerator(ids))
+	}
+	for _, sp := range processors {
+		opts = append(opts, sdktrace.WithSpanProcessor(sp))
+	}
+	return sdktrace.NewTracerProvider(opts...)
+}
+
+// exportProcessor batches spans for exp (or, with SYNC_SPAN_EXPORT, exports each span as
+// it ends), dropping the span names listed in DROP_SPANS
+func exportProcessor(exp sdktrace.SpanExporter) sdktrace.SpanProcessor {
+	var sp sdktrace.SpanProcessor
+	if syncSpanExportEnabled() {
+		sp = sdktrace.NewSimpleSpanProcessor(exp)
+	} else {
+		sp = sdktrace.NewBatchSpanProcessor(exp)
+	}
+	if names := droppedSpanNamesFromEnv(); len(names) > 0 {
+		return NewSpanDropFilter(sp, names)
+	}
+	return sp
+}
+
+// NewInstanceTracerProvider returns a tracer provider for one simulated service instance
+// (service.name and service.instance.id override the app's resource). It feeds the app's
+// span processors, so its spans are exported, indexed and summarized like any other and
+// are flushed when the app's provider shuts down; it must not be shut down itself.
+func NewInstanceTracerProvider(p *TelemetryProviders, service, instanceID string) (trace.TracerProvider, error) {
+	res, err := resource.Merge(p.Resource, resource.NewSchemaless(
+		telemetry.ServiceName(service),
+		telemetry.ServiceInstanceID(instanceID),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource for %s/%s: %w", service, instanceID, err)
+	}
+	return runTracerProvider{tp: newTracerProvider(res, p.SpanProcessors, p.IDGenerator)}, nil
+}
+
+// serviceName returns OTEL_SERVICE_NAME or the demo default
+func serviceName() string {
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+		return name
+	}
+	return "span-links-demo"
+}
+
 // parseEndpoint extracts host:port from URL and returns insecure flag
 func parseEndpoint(endpoint string) (string, bool) {
 	var useInsecure bool
@@ -118,42 +300,167 @@ func parseEndpoint(endpoint string) (string, bool) {
 	return endpoint, useInsecure
 }
 
-// Helper function to create a span context from stored trace info
-func SpanContextFromMessage(order Order) trace.SpanContext {
-	// In production, properly parse the traceparent header
-	// For this demo, we construct it from the stored values
-	if len(order.TraceParent) < 53 {
-		return trace.SpanContext{}
+// newTraceExporter creates an OTLP trace exporter for target's protocol
+func newTraceExporter(ctx context.Context, target otlpTarget, headers map[string]string, settings exportSettings) (sdktrace.SpanExporter, error) {
+	if target.protocol == ProtocolGRPC {
+		opts := []otlptracegrpc.Option{
+			otlptracegrpc.WithEndpoint(target.host),
+			otlptracegrpc.WithTimeout(settings.timeout),
+		}
+		if target.insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		if len(headers) > 0 {
+			opts = append(opts, otlptracegrpc.WithHeaders(headers))
+		}
+		if settings.gzip {
+			opts = append(opts, otlptracegrpc.WithCompressor("gzip"))
+		}
+		return otlptracegrpc.New(ctx, opts...)
 	}
 
-	// Parse traceparent format: 00-<trace-id>-<span-id>-<flags>
-	// Example: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
-	traceIDStr := order.TraceParent[3:35]  // 32 hex chars
-	spanIDStr := order.TraceParent[36:52]  // 16 hex chars
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(target.host),
+		otlptracehttp.WithURLPath("/v1/traces"),
+		otlptracehttp.WithTimeout(settings.timeout),
+	}
+	if target.insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	if len(headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(headers))
+	}
+	if settings.gzip {
+		opts = append(opts, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+	}
+	return otlptracehttp.New(ctx, opts...)
+}
 
-	tid, err := trace.TraceIDFromHex(traceIDStr)
-	if err != nil {
-		// Note: Using log.Printf as this may be called before slog initialization
-		log.Printf("Failed to parse trace ID from message: %v", err)
-		return trace.SpanContext{}
+// newMetricExporter creates an OTLP metric exporter for target's protocol
+func newMetricExporter(ctx context.Context, target otlpTarget, headers map[string]string, settings exportSettings) (sdkmetric.Exporter, error) {
+	if target.protocol == ProtocolGRPC {
+		opts := []otlpmetricgrpc.Option{
+			otlpmetricgrpc.WithEndpoint(target.host),
+			otlpmetricgrpc.WithTimeout(settings.timeout),
+		}
+		if target.insecure {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		}
+		if len(headers) > 0 {
+			opts = append(opts, otlpmetricgrpc.WithHeaders(headers))
+		}
+		if settings.gzip {
+			opts = append(opts, otlpmetricgrpc.WithCompressor("gzip"))
+		}
+		return otlpmetricgrpc.New(ctx, opts...)
 	}
 
-	sid, err := trace.SpanIDFromHex(spanIDStr)
-	if err != nil {
-		// Note: Using log.Printf as this may be called before slog initialization
-		log.Printf("Failed to parse span ID from message: %v", err)
-		return trace.SpanContext{}
+	opts := []otlpmetrichttp.Option{
+		otlpmetrichttp.WithEndpoint(target.host),
+		otlpmetrichttp.WithURLPath("/v1/metrics"),
+		otlpmetrichttp.WithTimeout(settings.timeout),
+	}
+	if target.insecure {
+		opts = append(opts, otlpmetrichttp.WithInsecure())
+	}
+	if len(headers) > 0 {
+		opts = append(opts, otlpmetrichttp.WithHeaders(headers))
+	}
+	if settings.gzip {
+		opts = append(opts, otlpmetrichttp.WithCompression(otlpmetrichttp.GzipCompression))
+	}
+	return otlpmetrichttp.New(ctx, opts...)
+}
+
+// newLogExporter creates an OTLP log exporter for target's protocol
+func newLogExporter(ctx context.Context, target otlpTarget, headers map[string]string, settings exportSettings) (sdklog.Exporter, error) {
+	if target.protocol == ProtocolGRPC {
+		opts := []otlploggrpc.Option{
+			otlploggrpc.WithEndpoint(target.host),
+			otlploggrpc.WithTimeout(settings.timeout),
+		}
+		if target.insecure {
+			opts = append(opts, otlploggrpc.WithInsecure())
+		}
+		if len(headers) > 0 {
+			opts = append(opts, otlploggrpc.WithHeaders(headers))
+		}
+		if settings.gzip {
+			opts = append(opts, otlploggrpc.WithCompressor("gzip"))
+		}
+		return otlploggrpc.New(ctx, opts...)
+	}
+
+	opts := []otlploghttp.Option{
+		otlploghttp.WithEndpoint(target.host),
+		otlploghttp.WithURLPath("/v1/logs"),
+		otlploghttp.WithTimeout(settings.timeout),
+	}
+	if target.insecure {
+		opts = append(opts, otlploghttp.WithInsecure())
+	}
+	if len(headers) > 0 {
+		opts = append(opts, otlploghttp.WithHeaders(headers))
+	}
+	if settings.gzip {
+		opts = append(opts, otlploghttp.WithCompression(otlploghttp.GzipCompression))
+	}
+	return otlploghttp.New(ctx, opts...)
+}
+
+// exportSettings holds per-signal OTLP exporter transport settings
+type exportSettings struct {
+	gzip    bool
+	timeout time.Duration
+}
+
+// signalExportSettings reads OTEL_EXPORTER_OTLP_[<SIGNAL>_]COMPRESSION ("gzip" or "none")
+// and OTEL_EXPORTER_OTLP_[<SIGNAL>_]TIMEOUT (milliseconds); per-signal values win.
+func signalExportSettings(signal string) exportSettings {
+	settings := exportSettings{timeout: DefaultExportTimeout}
+
+	compression := signalEnv(signal, "COMPRESSION")
+	switch strings.ToLower(compression) {
+	case "gzip":
+		settings.gzip = true
+	case "", "none":
+	default:
+		log.Printf("Ignoring unsupported OTLP compression %q (use gzip or none)", compression)
 	}
 
-	return trace.NewSpanContext(trace.SpanContextConfig{
-		TraceID:    tid,
-		SpanID:     sid,
-		TraceFlags: trace.FlagsSampled,
-		Remote:     true, // Indicates this context comes from a remote source
-	})
+	if timeout := signalEnv(signal, "TIMEOUT"); timeout != "" {
+		ms, err := strconv.Atoi(timeout)
+		if err != nil || ms <= 0 {
+			log.Printf("Ignoring invalid OTLP timeout %q", timeout)
+		} else {
+			settings.timeout = time.Duration(ms) * time.Millisecond
+		}
+	}
+
+	return settings
+}
+
+// signalEnv returns OTEL_EXPORTER_OTLP_<SIGNAL>_<NAME>, falling back to OTEL_EXPORTER_OTLP_<NAME>
+func signalEnv(signal, name string) string {
+	if v := os.Getenv("OTEL_EXPORTER_OTLP_" + signal + "_" + name); v != "" {
+		return v
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_" + name)
+}
+
+// signalHeaders merges OTEL_EXPORTER_OTLP_HEADERS with the per-signal
+// OTEL_EXPORTER_OTLP_<SIGNAL>_HEADERS (signal is TRACES, METRICS or LOGS); per-signal values win.
+func signalHeaders(signal string) map[string]string {
+	headers := parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"))
+	for key, value := range parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_" + signal + "_HEADERS")) {
+		headers[key] = value
+	}
+	return headers
 }
 
-// parseHeaders parses header string in format "key1=value1,key2=value2" or "key=value"
+// parseHeaders parses header string in format "key1=value1,key2=value2" or "key=value".
+// Keys and values are percent-decoded per the OTLP exporter spec, so values containing
+// '=' or ',' can be passed URL-encoded (e.g. "signoz-ingestion-key=abc%2Cdef%3D").
 func parseHeaders(headersStr string) map[string]string {
 	headers := make(map[string]string)
 	
@@ -165,14 +472,23 @@ func parseHeaders(headersStr string) map[string]string {
 			continue
 		}
 		
-		// Split key=value
+		// Split key=value (only on the first '=' so raw values may still contain '=')
 		parts := strings.SplitN(pair, "=", 2)
 		if len(parts) == 2 {
-			key := strings.TrimSpace(parts[0])
-			value := strings.TrimSpace(parts[1])
+			key := unescapeHeader(strings.TrimSpace(parts[0]))
+			value := unescapeHeader(strings.TrimSpace(parts[1]))
 			headers[key] = value
 		}
 	}
 	
 	return headers
 }
+
+// unescapeHeader percent-decodes s, returning it unchanged if it is not valid encoding
+func unescapeHeader(s string) string {
+	decoded, err := url.PathUnescape(s)
+	if err != nil {
+		return s
+	}
+	return decoded
+}
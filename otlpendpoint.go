@@ -0,0 +1,132 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// OTLP transport protocols (OTEL_EXPORTER_OTLP_PROTOCOL values)
+const (
+	ProtocolHTTP = "http/protobuf"
+	ProtocolGRPC = "grpc"
+)
+
+// Conventional OTLP ports
+const (
+	OTLPGRPCPort = "4317"
+	OTLPHTTPPort = "4318"
+)
+
+// otlpProbeTimeout bounds each local port probe when no endpoint is configured
+const otlpProbeTimeout = 300 * time.Millisecond
+
+// otlpTarget is where and how telemetry is exported
+type otlpTarget struct {
+	host     string // host:port
+	insecure bool
+	protocol string
+}
+
+// resolveOTLPTarget picks the export endpoint and protocol. OTEL_EXPORTER_OTLP_PROTOCOL
+// wins when set; otherwise the protocol follows the endpoint's port (4317 → gRPC, anything
+// else → HTTP). Without an endpoint, a local collector is probed on 4318 (HTTP) and then
+// 4317 (gRPC). Mismatches that would silently drop every span are logged at startup.
+func resolveOTLPTarget() otlpTarget {
+	protocol := protocolFromEnv("OTEL_EXPORTER_OTLP_PROTOCOL")
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	if endpoint == "" {
+		return defaultOTLPTarget(protocol)
+	}
+	return endpointTarget(endpoint, protocol)
+}
+
+// resolveSecondaryOTLPTarget returns the second backend spans are teed to
+// (SECONDARY_OTLP_ENDPOINT, with SECONDARY_OTLP_PROTOCOL), if one is configured
+func resolveSecondaryOTLPTarget() (otlpTarget, bool) {
+	endpoint := os.Getenv("SECONDARY_OTLP_ENDPOINT")
+	if endpoint == "" {
+		return otlpTarget{}, false
+	}
+	return endpointTarget(endpoint, protocolFromEnv("SECONDARY_OTLP_PROTOCOL")), true
+}
+
+// protocolFromEnv reads an OTLP protocol variable; "" means infer it from the port
+func protocolFromEnv(name string) string {
+	protocol := strings.ToLower(os.Getenv(name))
+	switch protocol {
+	case "", ProtocolHTTP, ProtocolGRPC:
+	case "http", "http/json":
+		log.Printf("%s=%q is not supported, using %s", name, protocol, ProtocolHTTP)
+		protocol = ProtocolHTTP
+	default:
+		log.Printf("Ignoring unknown %s=%q", name, protocol)
+		protocol = ""
+	}
+	return protocol
+}
+
+// endpointTarget builds the target for an explicit endpoint, inferring the protocol from
+// its port when protocol is "" and warning about protocol/port mismatches
+func endpointTarget(endpoint, protocol string) otlpTarget {
+	host, insecure := parseEndpoint(endpoint)
+	port := endpointPort(endpoint)
+	switch {
+	case protocol == "" && port == OTLPGRPCPort:
+		log.Printf("Endpoint %s uses the OTLP/gRPC port, exporting with gRPC (set the protocol to %s to force HTTP)", endpoint, ProtocolHTTP)
+		protocol = ProtocolGRPC
+	case protocol == "":
+		protocol = ProtocolHTTP
+	case protocol == ProtocolHTTP && port == OTLPGRPCPort:
+		log.Printf("WARNING: exporting OTLP/HTTP to %s, port %s is normally OTLP/gRPC; spans will likely be dropped. Use port %s or protocol %s", endpoint, OTLPGRPCPort, OTLPHTTPPort, ProtocolGRPC)
+	case protocol == ProtocolGRPC && port == OTLPHTTPPort:
+		log.Printf("WARNING: exporting OTLP/gRPC to %s, port %s is normally OTLP/HTTP; spans will likely be dropped. Use port %s or protocol %s", endpoint, OTLPHTTPPort, OTLPGRPCPort, ProtocolHTTP)
+	}
+	return otlpTarget{host: host, insecure: insecure, protocol: protocol}
+}
+
+// defaultOTLPTarget picks a local collector endpoint for protocol, probing which port
+// answers when the protocol is not set either
+func defaultOTLPTarget(protocol string) otlpTarget {
+	switch protocol {
+	case ProtocolGRPC:
+		return otlpTarget{host: "localhost:" + OTLPGRPCPort, insecure: true, protocol: ProtocolGRPC}
+	case ProtocolHTTP:
+		return otlpTarget{host: "localhost:" + OTLPHTTPPort, insecure: true, protocol: ProtocolHTTP}
+	}
+
+	if portOpen("localhost:" + OTLPHTTPPort) {
+		return otlpTarget{host: "localhost:" + OTLPHTTPPort, insecure: true, protocol: ProtocolHTTP}
+	}
+	if portOpen("localhost:" + OTLPGRPCPort) {
+		log.Printf("No collector on localhost:%s, falling back to OTLP/gRPC on localhost:%s", OTLPHTTPPort, OTLPGRPCPort)
+		return otlpTarget{host: "localhost:" + OTLPGRPCPort, insecure: true, protocol: ProtocolGRPC}
+	}
+	log.Printf("WARNING: no OTLP collector found on localhost:%s or :%s; set OTEL_EXPORTER_OTLP_ENDPOINT (spans will be dropped until one is reachable)", OTLPHTTPPort, OTLPGRPCPort)
+	return otlpTarget{host: "localhost:" + OTLPHTTPPort, insecure: true, protocol: ProtocolHTTP}
+}
+
+// endpointPort returns the explicit port of endpoint, or "" when it has none
+func endpointPort(endpoint string) string {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
+	}
+	return u.Port()
+}
+
+// portOpen reports whether a TCP connection to addr succeeds
+func portOpen(addr string) bool {
+	conn, err := net.DialTimeout("tcp", addr, otlpProbeTimeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
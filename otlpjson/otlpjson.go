@@ -0,0 +1,178 @@
+// Package otlpjson reads spans written by the OpenTelemetry Collector file exporter
+// (one OTLP/JSON ExportTraceServiceRequest per line) into a flat, easy-to-query form.
+package otlpjson
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Span is one exported span with attribute values flattened to strings
+type Span struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Name         string
+	Kind         int
+	Start        time.Time
+	End          time.Time
+	StatusCode   int
+	Service      string
+	Attributes   map[string]string
+	Resource     map[string]string
+	Links        []Link
+}
+
+// Link is one span link
+type Link struct {
+	TraceID    string
+	SpanID     string
+	Attributes map[string]string
+}
+
+// Status codes as encoded in OTLP
+const (
+	StatusUnset = 0
+	StatusOK    = 1
+	StatusError = 2
+)
+
+// Duration returns the span's duration
+func (s Span) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// Key identifies a span across traces
+func (s Span) Key() string {
+	return s.TraceID + "/" + s.SpanID
+}
+
+// Key identifies the link target across traces
+func (l Link) Key() string {
+	return l.TraceID + "/" + l.SpanID
+}
+
+// LoadFile reads every span from a file exporter output file
+func LoadFile(path string) ([]Span, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read reads every span from r, one OTLP/JSON request per line
+func Read(r io.Reader) ([]Span, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 64*1024*1024)
+
+	var spans []Span
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var req exportRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		for _, rs := range req.ResourceSpans {
+			resource := flatten(rs.Resource.Attributes)
+			for _, ss := range rs.ScopeSpans {
+				for _, s := range ss.Spans {
+					span := Span{
+						TraceID:      s.TraceID,
+						SpanID:       s.SpanID,
+						ParentSpanID: s.ParentSpanID,
+						Name:         s.Name,
+						Kind:         s.Kind,
+						Start:        unixNano(s.StartTimeUnixNano),
+						End:          unixNano(s.EndTimeUnixNano),
+						StatusCode:   s.Status.Code,
+						Service:      resource["service.name"],
+						Attributes:   flatten(s.Attributes),
+						Resource:     resource,
+					}
+					for _, l := range s.Links {
+						span.Links = append(span.Links, Link{
+							TraceID:    l.TraceID,
+							SpanID:     l.SpanID,
+							Attributes: flatten(l.Attributes),
+						})
+					}
+					spans = append(spans, span)
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return spans, nil
+}
+
+// Index maps span keys (TraceID/SpanID) to spans
+func Index(spans []Span) map[string]Span {
+	idx := make(map[string]Span, len(spans))
+	for _, s := range spans {
+		idx[s.Key()] = s
+	}
+	return idx
+}
+
+type exportRequest struct {
+	ResourceSpans []struct {
+		Resource struct {
+			Attributes []keyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeSpans []struct {
+			Spans []struct {
+				TraceID           string     `json:"traceId"`
+				SpanID            string     `json:"spanId"`
+				ParentSpanID      string     `json:"parentSpanId"`
+				Name              string     `json:"name"`
+				Kind              int        `json:"kind"`
+				StartTimeUnixNano string     `json:"startTimeUnixNano"`
+				EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+				Attributes        []keyValue `json:"attributes"`
+				Links             []struct {
+					TraceID    string     `json:"traceId"`
+					SpanID     string     `json:"spanId"`
+					Attributes []keyValue `json:"attributes"`
+				} `json:"links"`
+				Status struct {
+					Code int `json:"code"`
+				} `json:"status"`
+			} `json:"spans"`
+		} `json:"scopeSpans"`
+	} `json:"resourceSpans"`
+}
+
+type keyValue struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+// flatten converts OTLP AnyValues to strings (intValue is JSON-encoded as a string already)
+func flatten(kvs []keyValue) map[string]string {
+	m := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		for _, v := range kv.Value {
+			m[kv.Key] = fmt.Sprint(v)
+		}
+	}
+	return m
+}
+
+func unixNano(s string) time.Time {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
@@ -0,0 +1,272 @@
+// Package otlpsink is an in-process OTLP/HTTP receiver that keeps every export request it
+// gets. Point an exporter (or the app, through OTEL_EXPORTER_OTLP_ENDPOINT) at it to assert
+// exported spans and their links in tests without a collector container:
+//
+//	sink := otlpsink.Start()
+//	defer sink.Close()
+//	exp, _ := otlptracehttp.New(ctx, otlptracehttp.WithEndpoint(sink.Endpoint()), otlptracehttp.WithInsecure())
+//	... run the flow, flush the tracer provider ...
+//	spans, err := sink.WaitForSpans(ctx, 3)
+//
+// Spans are returned in the otlpjson form, so the checks spanlinks runs on collector
+// output (otlpjson.Index, link resolution) apply to them unchanged.
+package otlpsink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"span-links-signoz-demo/otlpjson"
+
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Signals, as named in the OTLP/HTTP paths (/v1/traces, ...)
+const (
+	Traces  = "traces"
+	Metrics = "metrics"
+	Logs    = "logs"
+)
+
+// pollInterval is how often WaitForSpans looks at the received spans
+const pollInterval = 10 * time.Millisecond
+
+// Payload is one export request as received, decompressed
+type Payload struct {
+	Signal   string
+	Body     []byte // protobuf, or OTLP/JSON when ContentType says so
+	Header   http.Header
+	Received time.Time
+}
+
+// IsJSON reports whether the payload is OTLP/JSON rather than protobuf
+func (p Payload) IsJSON() bool {
+	return strings.HasPrefix(p.Header.Get("Content-Type"), "application/json")
+}
+
+// Receiver is a running in-process OTLP/HTTP receiver; it is safe for concurrent use
+type Receiver struct {
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	payloads []Payload
+	status   int
+}
+
+// Start starts a receiver on a free loopback port
+func Start() *Receiver {
+	r := &Receiver{status: http.StatusOK}
+	mux := http.NewServeMux()
+	for _, signal := range []string{Traces, Metrics, Logs} {
+		mux.HandleFunc("POST /v1/"+signal, func(w http.ResponseWriter, req *http.Request) {
+			r.receive(signal, w, req)
+		})
+	}
+	r.srv = httptest.NewServer(mux)
+	return r
+}
+
+// Endpoint returns the receiver's host:port, for the exporters' WithEndpoint (with
+// WithInsecure)
+func (r *Receiver) Endpoint() string {
+	return r.srv.Listener.Addr().String()
+}
+
+// URL returns the receiver's base URL, for OTEL_EXPORTER_OTLP_ENDPOINT
+func (r *Receiver) URL() string {
+	return r.srv.URL
+}
+
+// Close stops the receiver
+func (r *Receiver) Close() {
+	r.srv.Close()
+}
+
+// SetStatus makes the receiver answer export requests with code (it still keeps them),
+// e.g. http.StatusServiceUnavailable to exercise exporter retries
+func (r *Receiver) SetStatus(code int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = code
+}
+
+// Reset forgets every payload received so far
+func (r *Receiver) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.payloads = nil
+}
+
+// Payloads returns the payloads received for signal, oldest first
+func (r *Receiver) Payloads(signal string) []Payload {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []Payload
+	for _, p := range r.payloads {
+		if p.Signal == signal {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Spans decodes every span received so far
+func (r *Receiver) Spans() ([]otlpjson.Span, error) {
+	var spans []otlpjson.Span
+	for i, p := range r.Payloads(Traces) {
+		var decoded []otlpjson.Span
+		var err error
+		if p.IsJSON() {
+			// otlpjson reads one request per line; JSON strings hold no raw newlines
+			decoded, err = otlpjson.Read(bytes.NewReader(bytes.ReplaceAll(p.Body, []byte("\n"), nil)))
+		} else {
+			decoded, err = decodeTraces(p.Body)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("trace payload %d: %w", i+1, err)
+		}
+		spans = append(spans, decoded...)
+	}
+	return spans, nil
+}
+
+// WaitForSpans waits until at least n spans have been received, or ctx ends, and returns
+// the spans received so far
+func (r *Receiver) WaitForSpans(ctx context.Context, n int) ([]otlpjson.Span, error) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		spans, err := r.Spans()
+		if err != nil || len(spans) >= n {
+			return spans, err
+		}
+		select {
+		case <-ctx.Done():
+			return spans, fmt.Errorf("received %d of %d spans: %w", len(spans), n, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// receive keeps one export request
+func (r *Receiver) receive(signal string, w http.ResponseWriter, req *http.Request) {
+	var body io.Reader = req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(req.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid gzip body: %v", err), http.StatusBadRequest)
+			return
+		}
+		defer gz.Close()
+		body = gz
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	r.mu.Lock()
+	r.payloads = append(r.payloads, Payload{Signal: signal, Body: data, Header: req.Header.Clone(), Received: time.Now()})
+	status := r.status
+	r.mu.Unlock()
+
+	// An empty protobuf body and an empty JSON object both decode as a full success
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("{}"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.WriteHeader(status)
+}
+
+// decodeTraces converts a protobuf ExportTraceServiceRequest to otlpjson spans
+func decodeTraces(body []byte) ([]otlpjson.Span, error) {
+	var req collectortrace.ExportTraceServiceRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	var spans []otlpjson.Span
+	for _, rs := range req.GetResourceSpans() {
+		resource := flatten(rs.GetResource().GetAttributes())
+		for _, ss := range rs.GetScopeSpans() {
+			for _, s := range ss.GetSpans() {
+				span := otlpjson.Span{
+					TraceID:      hexID(s.GetTraceId()),
+					SpanID:       hexID(s.GetSpanId()),
+					ParentSpanID: hexID(s.GetParentSpanId()),
+					Name:         s.GetName(),
+					Kind:         int(s.GetKind()),
+					Start:        time.Unix(0, int64(s.GetStartTimeUnixNano())),
+					End:          time.Unix(0, int64(s.GetEndTimeUnixNano())),
+					StatusCode:   int(s.GetStatus().GetCode()),
+					Service:      resource["service.name"],
+					Attributes:   flatten(s.GetAttributes()),
+					Resource:     resource,
+				}
+				for _, l := range s.GetLinks() {
+					span.Links = append(span.Links, otlpjson.Link{
+						TraceID:    hexID(l.GetTraceId()),
+						SpanID:     hexID(l.GetSpanId()),
+						Attributes: flatten(l.GetAttributes()),
+					})
+				}
+				spans = append(spans, span)
+			}
+		}
+	}
+	return spans, nil
+}
+
+// hexID encodes a trace or span ID as in OTLP/JSON, empty when unset
+func hexID(id []byte) string {
+	if len(id) == 0 {
+		return ""
+	}
+	return hex.EncodeToString(id)
+}
+
+// flatten converts attribute values to strings the way otlpjson does
+func flatten(kvs []*commonpb.KeyValue) map[string]string {
+	m := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		m[kv.GetKey()] = valueString(kv.GetValue())
+	}
+	return m
+}
+
+// valueString renders one attribute value
+func valueString(v *commonpb.AnyValue) string {
+	switch val := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return val.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(val.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(val.IntValue, 10)
+	case *commonpb.AnyValue_DoubleValue:
+		return fmt.Sprint(val.DoubleValue)
+	case *commonpb.AnyValue_ArrayValue:
+		values := make([]string, 0, len(val.ArrayValue.GetValues()))
+		for _, e := range val.ArrayValue.GetValues() {
+			values = append(values, valueString(e))
+		}
+		return fmt.Sprint(values)
+	default:
+		return ""
+	}
+}
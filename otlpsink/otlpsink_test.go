@@ -0,0 +1,135 @@
+package otlpsink_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"span-links-signoz-demo/otlpjson"
+	"span-links-signoz-demo/otlpsink"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TestLinksRoundTrip exports a producer span and a consumer span linking to it (in a new
+// trace) through otlptracehttp, and checks the link arrives intact, with and without gzip
+func TestLinksRoundTrip(t *testing.T) {
+	for name, compression := range map[string]otlptracehttp.Compression{
+		"plain": otlptracehttp.NoCompression,
+		"gzip":  otlptracehttp.GzipCompression,
+	} {
+		t.Run(name, func(t *testing.T) {
+			sink := otlpsink.Start()
+			defer sink.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			exporter, err := otlptracehttp.New(ctx,
+				otlptracehttp.WithEndpoint(sink.Endpoint()),
+				otlptracehttp.WithInsecure(),
+				otlptracehttp.WithCompression(compression),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter))
+			defer tp.Shutdown(context.Background())
+			tracer := tp.Tracer("otlpsink-test")
+
+			_, publish := tracer.Start(ctx, "PublishOrder", trace.WithAttributes(attribute.String("order.id", "ORDER-1")))
+			publish.End()
+			_, process := tracer.Start(ctx, "ProcessOrder",
+				trace.WithNewRoot(),
+				trace.WithLinks(trace.Link{
+					SpanContext: publish.SpanContext(),
+					Attributes: []attribute.KeyValue{
+						attribute.String("link.type", "queue_consumption"),
+						attribute.String("order.id", "ORDER-1"),
+					},
+				}),
+			)
+			process.End()
+			if err := tp.ForceFlush(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			spans, err := sink.WaitForSpans(ctx, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			index := otlpjson.Index(spans)
+			producer, ok := index[publish.SpanContext().TraceID().String()+"/"+publish.SpanContext().SpanID().String()]
+			if !ok || producer.Name != "PublishOrder" {
+				t.Fatalf("PublishOrder span not received: %+v", spans)
+			}
+			consumer, ok := index[process.SpanContext().TraceID().String()+"/"+process.SpanContext().SpanID().String()]
+			if !ok {
+				t.Fatalf("ProcessOrder span not received: %+v", spans)
+			}
+			if consumer.TraceID == producer.TraceID {
+				t.Error("ProcessOrder was received in the producer's trace")
+			}
+			if len(consumer.Links) != 1 {
+				t.Fatalf("ProcessOrder has %d links, want 1", len(consumer.Links))
+			}
+			link := consumer.Links[0]
+			if link.Key() != producer.Key() {
+				t.Errorf("link targets %s, want %s", link.Key(), producer.Key())
+			}
+			if got := link.Attributes["link.type"]; got != "queue_consumption" {
+				t.Errorf("link.type = %q, want queue_consumption", got)
+			}
+			if got := link.Attributes["order.id"]; got != "ORDER-1" {
+				t.Errorf("link order.id = %q, want ORDER-1", got)
+			}
+
+			payloads := sink.Payloads(otlpsink.Traces)
+			if len(payloads) == 0 {
+				t.Fatal("no trace payloads kept")
+			}
+			for _, p := range payloads {
+				if p.IsJSON() {
+					t.Error("otlptracehttp payload kept as JSON, want protobuf")
+				}
+				if gzipped := p.Header.Get("Content-Encoding") == "gzip"; gzipped != (compression == otlptracehttp.GzipCompression) {
+					t.Errorf("Content-Encoding %q with %s compression", p.Header.Get("Content-Encoding"), name)
+				}
+			}
+		})
+	}
+}
+
+// TestJSONPayload posts an OTLP/JSON export request, as the collector's file exporter
+// writes them, and reads its span back
+func TestJSONPayload(t *testing.T) {
+	sink := otlpsink.Start()
+	defer sink.Close()
+
+	body := `{"resourceSpans":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"json-test"}}]},` +
+		`"scopeSpans":[{"spans":[{"traceId":"0af7651916cd43dd8448eb211c80319c","spanId":"b7ad6b7169203331","name":"ProcessOrder",` +
+		`"links":[{"traceId":"4bf92f3577b34da6a3ce929d0e0e4736","spanId":"00f067aa0ba902b7","attributes":[{"key":"link.type","value":{"stringValue":"queue_consumption"}}]}]}]}]}]}`
+	resp, err := http.Post(sink.URL()+"/v1/traces", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want 200", resp.StatusCode)
+	}
+
+	spans, err := sink.Spans()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spans) != 1 || spans[0].Service != "json-test" || len(spans[0].Links) != 1 {
+		t.Fatalf("got %+v, want one json-test span with one link", spans)
+	}
+	if got := spans[0].Links[0].Key(); got != "4bf92f3577b34da6a3ce929d0e0e4736/00f067aa0ba902b7" {
+		t.Errorf("link targets %s", got)
+	}
+}
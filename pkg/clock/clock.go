@@ -0,0 +1,68 @@
+// Package clock abstracts the wall clock the queue, producer and worker read and wait on,
+// so embedders and tests can substitute their own time source.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time and waits
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+}
+
+// Real is the system clock
+type Real struct{}
+
+// Now returns the current wall-clock time
+func (Real) Now() time.Time { return time.Now() }
+
+// Sleep pauses the calling goroutine for d
+func (Real) Sleep(d time.Duration) { time.Sleep(d) }
+
+// After waits for d and then sends the current time on the returned channel
+func (Real) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Fake is a manually driven clock for deterministic runs: Sleep and After advance the
+// fake time by the requested duration and return immediately, so code that waits (step
+// durations, throttling, replay offsets) runs instantly while still observing the time
+// it would have spent. Waits from concurrent goroutines add up, as if run one by one.
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFake creates a fake clock reading start
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start}
+}
+
+// Now returns the fake time
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Advance moves the fake time forward by d and returns the new time
+func (f *Fake) Advance(d time.Duration) time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if d > 0 {
+		f.now = f.now.Add(d)
+	}
+	return f.now
+}
+
+// Sleep advances the fake time by d without blocking
+func (f *Fake) Sleep(d time.Duration) { f.Advance(d) }
+
+// After advances the fake time by d and returns a channel that already holds it
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	ch <- f.Advance(d)
+	return ch
+}
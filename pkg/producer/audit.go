@@ -0,0 +1,8 @@
+package producer
+
+import "span-links-signoz-demo/pkg/queue"
+
+// SetAuditRecorder sets an optional recorder of the published transition of every order
+func (p *Service) SetAuditRecorder(recorder queue.AuditRecorder) {
+	p.audit = recorder
+}
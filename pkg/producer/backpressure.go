@@ -0,0 +1,37 @@
+package producer
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// BlockedPublishThreshold is how long a publish must wait on a full topic to count as
+// blocked
+const BlockedPublishThreshold = 5 * time.Millisecond
+
+// BackpressureRecorder is told about every publish that blocked on a full topic
+type BackpressureRecorder interface {
+	RecordBlockedPublish(pubSpan trace.SpanContext, wait time.Duration)
+}
+
+// SetBackpressureRecorder sets an optional recorder of blocked publishes
+func (p *Service) SetBackpressureRecorder(recorder BackpressureRecorder) {
+	p.backpressure = recorder
+}
+
+// recordBlocked marks pubSpan as blocked when the publish waited at least
+// BlockedPublishThreshold for room on its topic
+func (p *Service) recordBlocked(pubSpan trace.Span, wait time.Duration) {
+	if wait < BlockedPublishThreshold {
+		return
+	}
+	pubSpan.SetAttributes(
+		attribute.Bool("messaging.publish.blocked", true),
+		attribute.Int64("messaging.publish.blocked_ms", wait.Milliseconds()),
+	)
+	if p.backpressure != nil {
+		p.backpressure.RecordBlockedPublish(pubSpan.SpanContext(), wait)
+	}
+}
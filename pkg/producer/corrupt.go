@@ -0,0 +1,62 @@
+package producer
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+
+	"span-links-signoz-demo/pkg/queue"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceParentCorruptions are the ways a corrupted traceparent is mangled, one picked at
+// random per message; each leaves a value no consumer can link to
+var traceParentCorruptions = []struct {
+	kind    string
+	corrupt func(traceParent string) string
+}{
+	{"truncated", func(tp string) string { return tp[:len(tp)/2] }},
+	{"non_hex_trace_id", func(tp string) string { return tp[:3] + "zz" + tp[5:] }},
+	{"zero_trace_id", func(tp string) string { return tp[:3] + strings.Repeat("0", 32) + tp[35:] }},
+}
+
+// SetTraceParentCorruptionRate corrupts the traceparent header of the given fraction
+// (0..1) of messages, as a buggy proxy or hand-rolled propagator would, so consumers have
+// to cope with contexts they cannot link to
+func (p *Service) SetTraceParentCorruptionRate(rate float64) {
+	p.corruptRate = rate
+}
+
+// corruptTraceParent wraps publish so the message goes out with a corrupted traceparent of
+// the PublishOrder span in ctx: the headers are injected here and mangled, and publish
+// gets a context without a span, so it keeps them instead of injecting its own
+func (p *Service) corruptTraceParent(publish func(context.Context, queue.Order) error) func(context.Context, queue.Order) error {
+	return func(ctx context.Context, order queue.Order) error {
+		headers := propagation.MapCarrier{}
+		for k, v := range order.Headers {
+			headers[k] = v
+		}
+		otel.GetTextMapPropagator().Inject(ctx, headers)
+		traceParent := headers[queue.TraceParentHeader]
+		if traceParent == "" {
+			return publish(ctx, order)
+		}
+
+		c := traceParentCorruptions[rand.Intn(len(traceParentCorruptions))]
+		headers[queue.TraceParentHeader] = c.corrupt(traceParent)
+		order.Headers = headers
+		trace.SpanFromContext(ctx).AddEvent("traceparent.corrupted", trace.WithAttributes(
+			attribute.String("traceparent.corruption", c.kind),
+			attribute.String("traceparent", headers[queue.TraceParentHeader]),
+		))
+		err := publish(trace.ContextWithSpanContext(ctx, trace.SpanContext{}), order)
+		if err == nil && p.stats != nil {
+			p.stats.IncTraceParentCorrupted()
+		}
+		return err
+	}
+}
@@ -0,0 +1,117 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"span-links-signoz-demo/pkg/clock"
+	"span-links-signoz-demo/pkg/queue"
+	"span-links-signoz-demo/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// IdempotencyWindow is how long the producer remembers an idempotency key
+const IdempotencyWindow = 5 * time.Minute
+
+// ErrDuplicatePublish is returned for a publish whose idempotency key was already published
+var ErrDuplicatePublish = errors.New("duplicate publish suppressed")
+
+// publishRecord is the first publish of an idempotency key
+type publishRecord struct {
+	spanCtx     trace.SpanContext
+	publishedAt time.Time
+}
+
+// IdempotencyIndex remembers the PublishOrder span of each idempotency key for a window,
+// so a repeated publish can be suppressed and linked to the original
+type IdempotencyIndex struct {
+	window time.Duration
+	clock  clock.Clock
+
+	mu        sync.Mutex
+	published map[string]publishRecord
+	lastSweep time.Time
+}
+
+// NewIdempotencyIndex creates an index remembering keys for window, timed by c
+func NewIdempotencyIndex(window time.Duration, c clock.Clock) *IdempotencyIndex {
+	return &IdempotencyIndex{
+		window:    window,
+		clock:     c,
+		published: make(map[string]publishRecord),
+		lastSweep: c.Now(),
+	}
+}
+
+// Lookup returns the original publish of key, if it is still within the window
+func (x *IdempotencyIndex) Lookup(key string) (publishRecord, bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	rec, ok := x.published[key]
+	if !ok || x.clock.Now().Sub(rec.publishedAt) > x.window {
+		return publishRecord{}, false
+	}
+	return rec, true
+}
+
+// Record remembers spanCtx as the publish of key, evicting expired keys once per window
+func (x *IdempotencyIndex) Record(key string, spanCtx trace.SpanContext) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	now := x.clock.Now()
+	x.published[key] = publishRecord{spanCtx: spanCtx, publishedAt: now}
+	if now.Sub(x.lastSweep) < x.window {
+		return
+	}
+	for k, rec := range x.published {
+		if now.Sub(rec.publishedAt) > x.window {
+			delete(x.published, k)
+		}
+	}
+	x.lastSweep = now
+}
+
+// idempotencyKey returns the order's idempotency key header, defaulting to its ID
+func idempotencyKey(order queue.Order) string {
+	if key := order.Header(queue.IdempotencyKeyHeader); key != "" {
+		return key
+	}
+	return order.ID
+}
+
+// suppressDuplicate records a DuplicatePublish span for a publish of an already published
+// idempotency key. The span links to the original PublishOrder span (link.type=duplicate_of)
+// and marks itself suppressed; the order is not enqueued again.
+func (p *Service) suppressDuplicate(ctx context.Context, order queue.Order, key string, original publishRecord) {
+	_, span := p.tracer.Start(ctx, "DuplicatePublish",
+		trace.WithLinks(trace.Link{
+			SpanContext: original.spanCtx,
+			Attributes: []attribute.KeyValue{
+				attribute.String("link.type", "duplicate_of"),
+				attribute.String("order.id", order.ID),
+			},
+		}),
+		trace.WithAttributes(
+			attribute.String("order.id", order.ID),
+			attribute.String("messaging.message.idempotency_key", key),
+			attribute.Bool("publish.suppressed", true),
+			attribute.Int64("publish.original_age_ms", p.clock.Now().Sub(original.publishedAt).Milliseconds()),
+		),
+	)
+	telemetry.EndWithStatus(span, nil)
+	log.Printf("Suppressed duplicate publish (%s)", queue.OrderLogFields(order).With("idempotency_key", key))
+}
+
+// maybeRetryPublish re-publishes an already published order with the duplicate rate's
+// probability, simulating a client retry that the idempotency key suppresses
+func (p *Service) maybeRetryPublish(ctx context.Context, order queue.Order) {
+	if p.dupRate > 0 && rand.Float64() < p.dupRate {
+		p.publishOrder(ctx, order, nil)
+	}
+}
@@ -0,0 +1,33 @@
+package producer
+
+import (
+	"span-links-signoz-demo/pkg/clock"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Option customizes a Service at construction
+type Option func(*Service)
+
+// WithTracer sets the tracer producer spans are started with (default: the global
+// provider's "producer-service" tracer)
+func WithTracer(tracer trace.Tracer) Option {
+	return func(p *Service) { p.tracer = tracer }
+}
+
+// WithClock sets the clock used to stamp orders, pace replays and wait out throttling
+// (default: the queue's clock)
+func WithClock(c clock.Clock) Option {
+	return func(p *Service) { p.clock = c }
+}
+
+// WithTopic publishes to topic instead of DefaultTopic (ignored when topic routing is on)
+func WithTopic(topic string) Option {
+	return func(p *Service) { p.topic = topic }
+}
+
+// WithSpanNamePrefix prepends prefix to the PublishOrderBatch and PublishOrder span names,
+// after any SpanNameTemplate is applied
+func WithSpanNamePrefix(prefix string) Option {
+	return func(p *Service) { p.namePrefix = prefix }
+}
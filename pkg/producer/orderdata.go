@@ -0,0 +1,60 @@
+package producer
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Generated order data defaults
+const (
+	DefaultCustomerPoolSize = 10
+	DefaultAmountMin        = 100.0
+	DefaultAmountMax        = 200.0
+	HeavyTailAlpha          = 1.16 // Pareto shape of the "80/20" rule
+)
+
+// OrderDistribution describes how generated orders pick their customer and amount, so
+// trace attribute cardinality can resemble production data instead of a fixed sequence
+type OrderDistribution struct {
+	CustomerPool int     // customer IDs are drawn uniformly from CUST-1000 .. CUST-(1000+pool-1)
+	AmountMin    float64 // amounts fall in [AmountMin, AmountMax]
+	AmountMax    float64
+	HeavyTail    bool // bounded Pareto amounts (many small orders, a few large ones) instead of uniform
+}
+
+// DefaultOrderDistribution returns the distribution used when none is configured
+func DefaultOrderDistribution() OrderDistribution {
+	return OrderDistribution{
+		CustomerPool: DefaultCustomerPoolSize,
+		AmountMin:    DefaultAmountMin,
+		AmountMax:    DefaultAmountMax,
+	}
+}
+
+// Customer draws a customer ID from the pool
+func (d OrderDistribution) Customer() string {
+	pool := d.CustomerPool
+	if pool <= 0 {
+		pool = 1
+	}
+	return fmt.Sprintf("CUST-%d", 1000+rand.Intn(pool))
+}
+
+// Amount draws an order amount, rounded to cents
+func (d OrderDistribution) Amount() float64 {
+	lo, hi := d.AmountMin, d.AmountMax
+	var amount float64
+	switch {
+	case hi <= lo:
+		amount = lo
+	case d.HeavyTail && lo > 0:
+		// Inverse CDF of the Pareto distribution truncated to [lo, hi]
+		u := rand.Float64()
+		la, ha := math.Pow(lo, HeavyTailAlpha), math.Pow(hi, HeavyTailAlpha)
+		amount = math.Pow((ha+u*la-u*ha)/(ha*la), -1/HeavyTailAlpha)
+	default:
+		amount = lo + rand.Float64()*(hi-lo)
+	}
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,407 @@
+// Package producer publishes orders to the queue under PublishOrderBatch/PublishOrder
+// spans whose context travels in the message headers, so consumers can link back to them.
+package producer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync/atomic"
+
+	"span-links-signoz-demo/pkg/clock"
+	"span-links-signoz-demo/pkg/queue"
+	"span-links-signoz-demo/telemetry"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PriorityAmountThreshold is the order amount from which topic routing picks the priority topic
+const PriorityAmountThreshold = 150.0
+
+// Registry records the PublishOrder span context of each published order
+type Registry interface {
+	RecordPublish(orderID string, spanCtx trace.SpanContext)
+}
+
+// Stats counts published orders, orders withdrawn by a rolled-back batch, and messages
+// sent with a corrupted traceparent
+type Stats interface {
+	IncPublished()
+	AddRolledBack(n int)
+	IncTraceParentCorrupted()
+}
+
+// Service publishes orders to the queue
+type Service struct {
+	queue        *queue.SimpleQueue
+	tracer       trace.Tracer
+	topicRouting bool
+	registry     Registry
+	spanNames    queue.SpanNameTemplate
+	stats        Stats
+	semconvKinds bool
+	schemaV2Rate float64
+	limiter      atomic.Pointer[TokenBucket] // nil when publishing is not rate limited
+	idempotency  *IdempotencyIndex
+	dupRate      float64
+	orders       OrderDistribution
+	clock        clock.Clock
+	namePrefix   string
+	topic        string
+	published    metric.Int64Counter
+
+	transactional      bool
+	publishFailureRate float64
+	tenants            []string
+	nextTenant         atomic.Uint64
+	backpressure       BackpressureRecorder
+	sealer             *queue.Sealer
+	tamperRate         float64
+	corruptRate        float64
+	audit              queue.AuditRecorder
+}
+
+// New creates a new producer service publishing to q, customized by opts
+func New(q *queue.SimpleQueue, opts ...Option) *Service {
+	p := &Service{
+		queue:  q,
+		tracer: otel.Tracer("producer-service"),
+		orders: DefaultOrderDistribution(),
+		clock:  q.Clock(),
+		topic:  queue.DefaultTopic,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.idempotency = NewIdempotencyIndex(IdempotencyWindow, p.clock)
+
+	published, err := otel.Meter("producer-service").Int64Counter(telemetry.MetricOrdersPublished,
+		metric.WithDescription("Orders published to the queue"),
+		metric.WithUnit("{order}"),
+	)
+	if err != nil {
+		log.Printf("Failed to create published orders counter: %v", err)
+	}
+	p.published = published
+	return p
+}
+
+// SetTopicRouting enables routing orders to per-tier topics by amount
+// (PriorityOrdersTopic / StandardOrdersTopic). When disabled, all orders go to the
+// producer's topic (DefaultTopic unless set with WithTopic).
+func (p *Service) SetTopicRouting(enabled bool) {
+	p.topicRouting = enabled
+}
+
+// SetTenants assigns each batch (and each traffic replay) to the next of tenants in turn:
+// its span gets a tenant.id attribute and its orders a TenantHeader, so all spans of a
+// batch and the consumer spans linking to them belong to the same tenant
+func (p *Service) SetTenants(tenants []string) {
+	p.tenants = tenants
+}
+
+// tenant returns the tenant of the next batch, "" without tenants
+func (p *Service) tenant() string {
+	if len(p.tenants) == 0 {
+		return ""
+	}
+	return p.tenants[(p.nextTenant.Add(1)-1)%uint64(len(p.tenants))]
+}
+
+// tenantAttributes returns the span attributes for tenant
+func tenantAttributes(tenant string) []attribute.KeyValue {
+	if tenant == "" {
+		return nil
+	}
+	return []attribute.KeyValue{attribute.String("tenant.id", tenant)}
+}
+
+// SetOrderRegistry sets an optional registry that records each PublishOrder span context
+func (p *Service) SetOrderRegistry(registry Registry) {
+	p.registry = registry
+}
+
+// SetRunStats sets optional live run counters updated on every publish
+func (p *Service) SetRunStats(stats Stats) {
+	p.stats = stats
+}
+
+// SetSemconvSpanKinds switches to messaging-semconv span kinds: each PublishOrder is a
+// SpanKindProducer "send" span and the batch span becomes SpanKindInternal.
+func (p *Service) SetSemconvSpanKinds(enabled bool) {
+	p.semconvKinds = enabled
+}
+
+// SetSchemaV2Rate publishes the given fraction (0..1) of orders in the v2 message schema
+// (AmountCents + Currency) instead of v1, simulating a rolling producer migration
+func (p *Service) SetSchemaV2Rate(rate float64) {
+	p.schemaV2Rate = rate
+}
+
+// SetRateLimit limits publishing to rate orders per second with the given burst; throttled
+// publishes wait and emit a Throttled span. A rate of 0 disables the limiter. It may be
+// called while publishing; the new limit starts with a full bucket.
+func (p *Service) SetRateLimit(rate float64, burst int) {
+	if rate <= 0 {
+		p.limiter.Store(nil)
+		return
+	}
+	p.limiter.Store(NewTokenBucket(rate, burst, p.clock))
+}
+
+// RateLimit returns the current publish rate limit and burst (0, 0 when unlimited)
+func (p *Service) RateLimit() (float64, int) {
+	limiter := p.limiter.Load()
+	if limiter == nil {
+		return 0, 0
+	}
+	return limiter.rate, int(limiter.burst)
+}
+
+// SetDuplicateRate re-publishes the given fraction (0..1) of orders with the same
+// idempotency key, as a client retrying after a lost acknowledgment would; the duplicates
+// are suppressed and emit DuplicatePublish spans
+func (p *Service) SetDuplicateRate(rate float64) {
+	p.dupRate = rate
+}
+
+// SetOrderDistribution sets how generated orders pick their customer and amount
+func (p *Service) SetOrderDistribution(d OrderDistribution) {
+	p.orders = d
+}
+
+// SetSpanNameTemplate sets the template used to name PublishOrderBatch/PublishOrder spans
+func (p *Service) SetSpanNameTemplate(tmpl queue.SpanNameTemplate) {
+	p.spanNames = tmpl
+}
+
+// spanName names a pipeline span: the template applied to operation on topic, prefixed
+func (p *Service) spanName(operation, topic string) string {
+	return p.namePrefix + p.spanNames.Format(operation, topic)
+}
+
+// route picks the destination topic and routing key for an order
+func (p *Service) route(order queue.Order) (topic, routingKey string) {
+	if !p.topicRouting {
+		return p.topic, ""
+	}
+	if order.Amount >= PriorityAmountThreshold {
+		return queue.PriorityOrdersTopic, queue.PriorityRoutingKey
+	}
+	return queue.StandardOrdersTopic, queue.StandardRoutingKey
+}
+
+// PublishOrderBatch publishes multiple orders to the queue and returns the span context
+// for workers to link back to.
+// The documentation refers to actions performed in publishInternal to simplify removing the complexity of dual/backward linking.
+func (p *Service) PublishOrderBatch(ctx context.Context, count int) (trace.SpanContext, error) {
+	span, _, err := p.publishInternal(ctx, count, false)
+	if err != nil {
+		return trace.SpanContext{}, err
+	}
+	return span.SpanContext(), nil
+}
+
+// PublishedOrder is a published order and its (ended) PublishOrder span
+type PublishedOrder struct {
+	OrderID string
+	SpanCtx trace.SpanContext
+}
+
+// PublishOrderBatchWithOpenSpan publishes orders and returns the open batch span
+// (caller must End it) along with the published orders, in publish order. The
+// PublishOrder spans are ended, so their durations are the publishing alone. Used for
+// forward-link demo.
+func (p *Service) PublishOrderBatchWithOpenSpan(ctx context.Context, count int) (trace.Span, []PublishedOrder, error) {
+	return p.publishInternal(ctx, count, true)
+}
+
+// publishInternal publishes a batch and returns its span, open when keepOpen, and the
+// orders published, in publish order
+func (p *Service) publishInternal(ctx context.Context, count int, keepOpen bool) (trace.Span, []PublishedOrder, error) {
+	if count <= 0 {
+		return nil, nil, errors.New("batch size must be greater than zero")
+	}
+
+	batchKind := trace.SpanKindProducer
+	if p.semconvKinds {
+		batchKind = trace.SpanKindInternal
+	}
+
+	tenant := p.tenant()
+	ctx, span := p.tracer.Start(ctx, p.spanName("PublishOrderBatch", ""),
+		trace.WithSpanKind(batchKind),
+		trace.WithAttributes(
+			attribute.Int("order.batch.size", count),
+		),
+		trace.WithAttributes(tenantAttributes(tenant)...),
+	)
+
+	var published []PublishedOrder
+	orderSpans := make(map[string]trace.Span, count)
+	var lastErr error
+	var tx *queue.Tx
+	if p.transactional {
+		tx = p.queue.Begin()
+	}
+
+	for i := 0; i < count; i++ {
+		order := queue.Order{
+			ID:         fmt.Sprintf("ORDER-%s", uuid.New().String()[:8]),
+			CustomerID: p.orders.Customer(),
+			Amount:     p.orders.Amount(),
+			CreatedAt:  p.clock.Now(),
+		}
+		if tenant != "" {
+			order.Headers = map[string]string{queue.TenantHeader: tenant}
+		}
+
+		pubSpan, err := p.publishOrder(ctx, order, tx)
+		if err != nil {
+			lastErr = err
+			if tx != nil {
+				break
+			}
+			continue
+		}
+
+		published = append(published, PublishedOrder{OrderID: order.ID, SpanCtx: pubSpan.SpanContext()})
+		orderSpans[order.ID] = pubSpan
+		p.maybeRetryPublish(ctx, order)
+		telemetry.EndWithStatus(pubSpan, nil)
+	}
+
+	if tx != nil && lastErr != nil {
+		p.rollback(ctx, tx, orderSpans, lastErr)
+		err := fmt.Errorf("%w after %d of %d orders: %w", ErrBatchRolledBack, len(published), count, lastErr)
+		telemetry.EndWithStatus(span, err)
+		return span, nil, err
+	}
+	if tx != nil {
+		tx.Commit()
+	}
+
+	if len(published) == 0 {
+		err := fmt.Errorf("failed to publish any orders: %w", lastErr)
+		telemetry.SetStatus(span, err)
+		if !keepOpen {
+			span.End()
+		}
+		return span, nil, err
+	}
+
+	span.AddEvent("Batch published",
+		trace.WithAttributes(
+			attribute.Int("published.count", len(published)),
+			attribute.Int("total.count", count),
+		),
+	)
+
+	log.Printf("Order batch published successfully (published=%d)", len(published))
+
+	// When keepOpen, caller is responsible to End the batch span
+	telemetry.SetStatus(span, nil)
+	if !keepOpen {
+		span.End()
+	}
+	return span, published, nil
+}
+
+// publishOrder publishes a single order under its own PublishOrder span, as part of tx
+// when it is not nil. On success the span is returned open (caller ends it); on failure
+// it is ended with the error as its status.
+func (p *Service) publishOrder(ctx context.Context, order queue.Order, tx *queue.Tx) (trace.Span, error) {
+	key := idempotencyKey(order)
+	if original, ok := p.idempotency.Lookup(key); ok {
+		p.suppressDuplicate(ctx, order, key, original)
+		return nil, ErrDuplicatePublish
+	}
+
+	if limiter := p.limiter.Load(); limiter != nil {
+		if wait := limiter.Reserve(); wait > 0 {
+			if err := p.throttle(ctx, order, limiter, wait); err != nil {
+				return nil, fmt.Errorf("publish of order %s abandoned while throttled: %w", order.ID, err)
+			}
+		}
+	}
+
+	order.Topic, order.RoutingKey = p.route(order)
+	amount := order.Amount
+	order.SchemaVersion = queue.OrderSchemaV1
+	if p.schemaV2Rate > 0 && rand.Float64() < p.schemaV2Rate {
+		order = queue.UpgradeToV2(order)
+	}
+
+	pubKind := trace.SpanKindInternal
+	attrs := []attribute.KeyValue{
+		attribute.String("order.id", order.ID),
+		attribute.String("customer.id", order.CustomerID),
+		attribute.Float64("order.amount", amount),
+		attribute.String("messaging.destination.name", order.Topic),
+		attribute.String("messaging.destination.routing_key", order.RoutingKey),
+		attribute.Int("messaging.message.schema_version", order.SchemaVersion),
+	}
+	if p.semconvKinds {
+		pubKind = trace.SpanKindProducer
+		attrs = append(attrs, queue.MessagingAttributes(order, "send")...)
+	}
+
+	ctx, pubSpan := p.tracer.Start(ctx, p.spanName("PublishOrder", order.Topic),
+		trace.WithSpanKind(pubKind),
+		trace.WithAttributes(attrs...),
+	)
+
+	if order.Headers == nil {
+		order.Headers = make(map[string]string)
+	}
+	order.Headers[queue.IdempotencyKeyHeader] = key
+	pubSpan.SetAttributes(attribute.String("messaging.message.idempotency_key", key))
+
+	if p.sealer != nil {
+		sealed, err := p.seal(ctx, order)
+		if err != nil {
+			telemetry.EndWithStatus(pubSpan, err)
+			return nil, err
+		}
+		order = sealed
+	}
+
+	publish := p.queue.Publish
+	if tx != nil {
+		publish = tx.Publish
+	}
+	if p.corruptRate > 0 && rand.Float64() < p.corruptRate {
+		publish = p.corruptTraceParent(publish)
+	}
+	err := ErrPublishRejected
+	if p.publishFailureRate <= 0 || rand.Float64() >= p.publishFailureRate {
+		start := p.clock.Now()
+		err = publish(ctx, order)
+		p.recordBlocked(pubSpan, p.clock.Now().Sub(start))
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to publish order %s: %w", order.ID, err)
+		telemetry.EndWithStatus(pubSpan, err)
+		return nil, err
+	}
+	p.idempotency.Record(key, pubSpan.SpanContext())
+	if p.registry != nil {
+		p.registry.RecordPublish(order.ID, pubSpan.SpanContext())
+	}
+	if p.stats != nil {
+		p.stats.IncPublished()
+	}
+	if p.published != nil {
+		p.published.Add(ctx, 1, metric.WithAttributes(attribute.String("messaging.destination.name", order.Topic)))
+	}
+	if p.audit != nil {
+		p.audit.RecordTransition(ctx, queue.OrderPublished, order, pubSpan.SpanContext())
+	}
+	return pubSpan, nil
+}
@@ -0,0 +1,93 @@
+package producer
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"span-links-signoz-demo/pkg/clock"
+	"span-links-signoz-demo/pkg/queue"
+	"span-links-signoz-demo/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TokenBucket is a reservation-style token bucket: Reserve always takes a token and
+// returns how long the caller must wait before using it
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+	clock  clock.Clock
+}
+
+// NewTokenBucket creates a full bucket refilling at rate tokens per second up to burst,
+// timed by c
+func NewTokenBucket(rate float64, burst int, c clock.Clock) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   c.Now(),
+		clock:  c,
+	}
+}
+
+// Reserve takes one token and returns the wait until it is available (0 if it is now)
+func (b *TokenBucket) Reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// throttle waits out a rate-limit reservation for order. The wait is recorded as a
+// Throttled span in its own trace, linking to the batch span that was throttled
+// (link.type=throttled_batch) and carrying the retry-after delay.
+func (p *Service) throttle(ctx context.Context, order queue.Order, limiter *TokenBucket, retryAfter time.Duration) (err error) {
+	var links []trace.Link
+	if batch := trace.SpanContextFromContext(ctx); batch.IsValid() {
+		links = append(links, trace.Link{
+			SpanContext: batch,
+			Attributes: []attribute.KeyValue{
+				attribute.String("link.type", "throttled_batch"),
+				attribute.String("order.id", order.ID),
+			},
+		})
+	}
+
+	_, span := p.tracer.Start(context.Background(), "Throttled",
+		trace.WithLinks(links...),
+		trace.WithAttributes(
+			attribute.String("order.id", order.ID),
+			attribute.Int64("ratelimit.retry_after_ms", retryAfter.Milliseconds()),
+			attribute.Float64("ratelimit.rate_per_sec", limiter.rate),
+			attribute.Int("ratelimit.burst", int(limiter.burst)),
+		),
+	)
+	defer func() { telemetry.EndWithStatus(span, err) }()
+
+	log.Printf("Publish throttled (%s)", queue.OrderLogFields(order).With("retry_after", retryAfter))
+
+	select {
+	case <-p.clock.After(retryAfter):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,159 @@
+package producer
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"span-links-signoz-demo/pkg/queue"
+	"span-links-signoz-demo/telemetry"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TrafficEvent describes one order arrival in a recorded traffic profile
+type TrafficEvent struct {
+	OffsetMs   int64   `json:"offset_ms"`             // arrival time relative to replay start
+	Amount     float64 `json:"amount"`                // order amount
+	CustomerID string  `json:"customer_id,omitempty"` // optional; generated when empty
+}
+
+// LoadTrafficProfile reads a traffic profile from a .json file (array of TrafficEvent)
+// or a .csv file with the header "offset_ms,amount[,customer_id]". Events are returned
+// sorted by offset.
+func LoadTrafficProfile(path string) ([]TrafficEvent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open traffic profile: %w", err)
+	}
+	defer f.Close()
+
+	var events []TrafficEvent
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if err := json.NewDecoder(f).Decode(&events); err != nil {
+			return nil, fmt.Errorf("failed to decode traffic profile: %w", err)
+		}
+	case ".csv":
+		events, err = parseTrafficCSV(f)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported traffic profile format %q (use .csv or .json)", filepath.Ext(path))
+	}
+
+	if len(events) == 0 {
+		return nil, errors.New("traffic profile contains no events")
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].OffsetMs < events[j].OffsetMs })
+	return events, nil
+}
+
+func parseTrafficCSV(r io.Reader) ([]TrafficEvent, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read traffic profile: %w", err)
+	}
+
+	var events []TrafficEvent
+	for i, record := range records {
+		if i == 0 && len(record) > 0 && record[0] == "offset_ms" {
+			continue // header
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: expected offset_ms,amount[,customer_id]", i+1)
+		}
+		offset, err := strconv.ParseInt(record[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid offset_ms: %w", i+1, err)
+		}
+		amount, err := strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid amount: %w", i+1, err)
+		}
+		event := TrafficEvent{OffsetMs: offset, Amount: amount}
+		if len(record) > 2 {
+			event.CustomerID = record[2]
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// ReplayTrafficProfile publishes one order per event at its recorded offset, so bursty
+// arrival patterns can be reproduced. All PublishOrder spans are children of a single
+// ReplayTrafficProfile span; consumers link back to them as usual.
+func (p *Service) ReplayTrafficProfile(ctx context.Context, events []TrafficEvent) (_ int, err error) {
+	tenant := p.tenant()
+	ctx, span := p.tracer.Start(ctx, "ReplayTrafficProfile",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.Int("replay.events", len(events)),
+		),
+		trace.WithAttributes(tenantAttributes(tenant)...),
+	)
+	defer func() { telemetry.EndWithStatus(span, err) }()
+
+	start := p.clock.Now()
+	var publishedCount int
+	var lastErr error
+
+	for _, event := range events {
+		if wait := start.Add(time.Duration(event.OffsetMs) * time.Millisecond).Sub(p.clock.Now()); wait > 0 {
+			select {
+			case <-p.clock.After(wait):
+			case <-ctx.Done():
+				return publishedCount, ctx.Err()
+			}
+		}
+
+		customerID := event.CustomerID
+		if customerID == "" {
+			customerID = p.orders.Customer()
+		}
+		order := queue.Order{
+			ID:         fmt.Sprintf("ORDER-%s", uuid.New().String()[:8]),
+			CustomerID: customerID,
+			Amount:     event.Amount,
+			CreatedAt:  p.clock.Now(),
+		}
+		if tenant != "" {
+			order.Headers = map[string]string{queue.TenantHeader: tenant}
+		}
+
+		pubSpan, err := p.publishOrder(ctx, order, nil)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		pubSpan.SetAttributes(attribute.Int64("replay.offset_ms", event.OffsetMs))
+		telemetry.EndWithStatus(pubSpan, nil)
+		publishedCount++
+		p.maybeRetryPublish(ctx, order)
+	}
+
+	span.SetAttributes(attribute.Int("published.count", publishedCount))
+	if publishedCount == 0 {
+		return 0, fmt.Errorf("failed to publish any orders: %w", lastErr)
+	}
+
+	log.Printf("Traffic profile replayed (published=%d events=%d elapsed=%s)", publishedCount, len(events), p.clock.Now().Sub(start).Round(time.Millisecond))
+	return publishedCount, nil
+}
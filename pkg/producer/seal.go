@@ -0,0 +1,65 @@
+package producer
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	"span-links-signoz-demo/pkg/queue"
+	"span-links-signoz-demo/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SetSealer encrypts and signs every order's payload with s before publishing (nil
+// publishes in clear)
+func (p *Service) SetSealer(s *queue.Sealer) {
+	p.sealer = s
+}
+
+// SetTamperRate corrupts the given fraction (0..1) of sealed payloads after signing, as an
+// attacker on the wire would, so consumers reject them
+func (p *Service) SetTamperRate(rate float64) {
+	p.tamperRate = rate
+}
+
+// seal encrypts and signs order under EncryptPayload and SignPayload spans, children of
+// the PublishOrder span in ctx
+func (p *Service) seal(ctx context.Context, order queue.Order) (queue.Order, error) {
+	keyID := attribute.String("crypto.key_id", p.sealer.KeyID())
+
+	_, encSpan := p.tracer.Start(ctx, "EncryptPayload",
+		trace.WithAttributes(
+			attribute.String("order.id", order.ID),
+			attribute.String("crypto.algorithm", queue.SealCipher),
+			keyID,
+		),
+	)
+	order, err := p.sealer.Encrypt(order)
+	if err != nil {
+		telemetry.EndWithStatus(encSpan, err)
+		return order, fmt.Errorf("failed to encrypt order %s: %w", order.ID, err)
+	}
+	encSpan.SetAttributes(attribute.Int("payload.sealed_bytes", len(order.Sealed)))
+	telemetry.EndWithStatus(encSpan, nil)
+
+	_, signSpan := p.tracer.Start(ctx, "SignPayload",
+		trace.WithAttributes(
+			attribute.String("order.id", order.ID),
+			attribute.String("crypto.algorithm", queue.SealSignature),
+			keyID,
+		),
+	)
+	order = p.sealer.Sign(order)
+	telemetry.EndWithStatus(signSpan, nil)
+
+	if p.tamperRate > 0 && rand.Float64() < p.tamperRate {
+		// Flip one character of the ciphertext; the signature no longer matches
+		sealed := []byte(order.Sealed)
+		sealed[len(sealed)/2] ^= 0x01
+		order.Sealed = string(sealed)
+		trace.SpanFromContext(ctx).AddEvent("payload.tampered")
+	}
+	return order, nil
+}
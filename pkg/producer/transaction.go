@@ -0,0 +1,76 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"span-links-signoz-demo/pkg/queue"
+	"span-links-signoz-demo/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrPublishRejected is returned for a publish the simulated broker rejected
+// (see SetPublishFailureRate)
+var ErrPublishRejected = errors.New("broker rejected publish")
+
+// ErrBatchRolledBack is returned for a transactional batch that was rolled back
+var ErrBatchRolledBack = errors.New("batch rolled back")
+
+// SetTransactional publishes each batch N-or-nothing: when an order of the batch fails to
+// publish, the rest of the batch is abandoned and the orders already published are
+// tombstoned, under a RollbackPublish span linked to each withdrawn PublishOrder span
+func (p *Service) SetTransactional(enabled bool) {
+	p.transactional = enabled
+}
+
+// SetPublishFailureRate makes the queue reject the given fraction (0..1) of publishes
+// with ErrPublishRejected, to exercise partial batches and rollbacks
+func (p *Service) SetPublishFailureRate(rate float64) {
+	p.publishFailureRate = rate
+}
+
+// rollback withdraws the orders tx published and emits a RollbackPublish span, a child of
+// the batch span, linked to the PublishOrder span of each tombstoned order. Orders a
+// consumer already received cannot be withdrawn; they are listed on the span instead.
+func (p *Service) rollback(ctx context.Context, tx *queue.Tx, orderSpans map[string]trace.Span, cause error) {
+	tombstoned, delivered := tx.Rollback()
+
+	links := make([]trace.Link, 0, len(tombstoned))
+	for _, id := range tombstoned {
+		pubSpan, ok := orderSpans[id]
+		if !ok {
+			continue
+		}
+		links = append(links, trace.Link{
+			SpanContext: pubSpan.SpanContext(),
+			Attributes: []attribute.KeyValue{
+				attribute.String("link.type", "rolled_back_publish"),
+				attribute.String("order.id", id),
+			},
+		})
+	}
+
+	_, span := p.tracer.Start(ctx, "RollbackPublish",
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithLinks(links...),
+		trace.WithAttributes(
+			attribute.String("rollback.reason", cause.Error()),
+			attribute.Int("rollback.tombstoned", len(tombstoned)),
+			attribute.Int("rollback.already_delivered", len(delivered)),
+			attribute.StringSlice("rollback.delivered_order_ids", delivered),
+		),
+	)
+	var err error
+	if len(delivered) > 0 {
+		err = errors.New("some orders were delivered before the rollback")
+	}
+	defer telemetry.EndWithStatus(span, err)
+
+	if p.stats != nil {
+		p.stats.AddRolledBack(len(tombstoned))
+	}
+	log.Printf("Order batch rolled back (tombstoned=%d already_delivered=%d): %v", len(tombstoned), len(delivered), cause)
+}
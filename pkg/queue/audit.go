@@ -0,0 +1,32 @@
+package queue
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Order state transitions recorded by an AuditRecorder
+const (
+	OrderPublished = "published"
+	OrderValidated = "validated"
+	OrderPaid      = "paid"
+	OrderShipped   = "shipped"
+)
+
+// AuditRecorder records order state transitions. The span in ctx is the one that caused
+// the transition (PublishOrder, ValidateOrder, ProcessPayment, ShipOrder); producer is the
+// PublishOrder span the order's processing links to.
+type AuditRecorder interface {
+	RecordTransition(ctx context.Context, state string, order Order, producer trace.SpanContext)
+}
+
+// AuditRecorders records every transition with each of its recorders in turn
+type AuditRecorders []AuditRecorder
+
+// RecordTransition implements AuditRecorder
+func (rs AuditRecorders) RecordTransition(ctx context.Context, state string, order Order, producer trace.SpanContext) {
+	for _, r := range rs {
+		r.RecordTransition(ctx, state, order, producer)
+	}
+}
@@ -0,0 +1,93 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Well-known message header keys
+const (
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+
+	// IdempotencyKeyHeader identifies a logical publish; retries of it reuse the key
+	IdempotencyKeyHeader = "idempotency-key"
+
+	// RedeliveryCountHeader counts how often a message was put back after a failed delivery
+	RedeliveryCountHeader = "x-redelivery-count"
+
+	// TenantHeader names the simulated tenant the message belongs to
+	TenantHeader = "tenant-id"
+
+	// TraceOriginHeader is "legacy_fields" when traceparent was migrated from the legacy
+	// trace fields rather than set by the producer (see ExtractProducerContext)
+	TraceOriginHeader = "x-trace-origin"
+
+	// PublishedAtHeader is when the message was last put on its topic by Publish or
+	// Redeliver (RFC 3339 with nanoseconds)
+	PublishedAtHeader = "x-published-at"
+)
+
+// Header returns the message header key, or "" when absent
+func (o Order) Header(key string) string {
+	return o.Headers[key]
+}
+
+// RedeliveryCount returns how often the message has been redelivered (0 for a first delivery)
+func (o Order) RedeliveryCount() int {
+	n, _ := strconv.Atoi(o.Header(RedeliveryCountHeader))
+	return n
+}
+
+// PublishedAt returns when the message was last published or redelivered, or the zero
+// time when the header is missing or malformed
+func (o Order) PublishedAt() time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, o.Header(PublishedAtHeader))
+	return t
+}
+
+// legacyTraceFields are the per-field trace context of messages written before Headers
+type legacyTraceFields struct {
+	TraceParent string `json:"trace_parent"`
+	TraceState  string `json:"trace_state"`
+}
+
+// UnmarshalJSON decodes an order, migrating legacy trace fields into Headers so old
+// messages still link back to their producer
+func (o *Order) UnmarshalJSON(data []byte) error {
+	type order Order // drops methods, avoiding recursion
+	var msg struct {
+		order
+		legacyTraceFields
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("failed to decode order: %w", err)
+	}
+	*o = Order(msg.order)
+	migrateLegacyHeaders(o, msg.legacyTraceFields)
+	return nil
+}
+
+// migrateLegacyHeaders copies legacy trace fields into o.Headers without overwriting
+// headers already present, marking a migrated traceparent with TraceOriginHeader
+func migrateLegacyHeaders(o *Order, legacy legacyTraceFields) {
+	migrated := legacy.TraceParent != "" && o.Headers[TraceParentHeader] == ""
+	fields := map[string]string{
+		TraceParentHeader: legacy.TraceParent,
+		TraceStateHeader:  legacy.TraceState,
+	}
+	for key, val := range fields {
+		if val == "" || o.Headers[key] != "" {
+			continue
+		}
+		if o.Headers == nil {
+			o.Headers = make(map[string]string)
+		}
+		o.Headers[key] = val
+	}
+	if migrated {
+		o.Headers[TraceOriginHeader] = string(LinkSourceLegacy)
+	}
+}
@@ -0,0 +1,42 @@
+package queue
+
+import (
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LogFields are the correlation fields of a log line about an order, rendered as the
+// space-separated key=value pairs the demo puts in parentheses at the end of its log
+// lines. Starting from OrderLogFields keeps order.id (and, on the consumer, the link
+// target) on every line and spelled the same as the span attributes.
+type LogFields struct {
+	pairs []string
+}
+
+// OrderLogFields starts the fields of a log line about order with its order.id
+func OrderLogFields(order Order) LogFields {
+	return LogFields{}.With("order.id", order.ID)
+}
+
+// Link adds the trace and span IDs of the span the consumer links to (link.trace_id,
+// link.span_id), or link=none when there is none
+func (f LogFields) Link(target trace.SpanContext) LogFields {
+	if !target.IsValid() {
+		return f.With("link", "none")
+	}
+	return f.With("link.trace_id", target.TraceID()).With("link.span_id", target.SpanID())
+}
+
+// With adds key=value
+func (f LogFields) With(key string, value any) LogFields {
+	pairs := make([]string, len(f.pairs), len(f.pairs)+1)
+	copy(pairs, f.pairs)
+	return LogFields{pairs: append(pairs, fmt.Sprintf("%s=%v", key, value))}
+}
+
+// String renders the fields as "key=value key=value"
+func (f LogFields) String() string {
+	return strings.Join(f.pairs, " ")
+}
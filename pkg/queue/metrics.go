@@ -0,0 +1,114 @@
+package queue
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"span-links-signoz-demo/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// LinkDirectionKey is the attribute queue metrics are tagged with: how the run links
+// consumers and producers (e.g. "backward", "forward_and_backward", "none")
+const LinkDirectionKey = "link.direction"
+
+// Metrics counts the queue's traffic per topic (messaging.destination.name): messages
+// published, consumed and acknowledged, and the time from publish to consume. Every
+// measurement also carries the run's link direction, so throughput and latency can be
+// compared across link modes the way their traces are.
+type Metrics struct {
+	published metric.Int64Counter
+	consumed  metric.Int64Counter
+	acked     metric.Int64Counter
+	latency   metric.Float64Histogram
+	direction attribute.KeyValue
+}
+
+// NewMetrics creates the queue instruments, tagged with linkDirection
+func NewMetrics(linkDirection string) *Metrics {
+	meter := otel.Meter("queue")
+	published, err := meter.Int64Counter(telemetry.MetricQueuePublished,
+		metric.WithDescription("Messages published, by topic and link direction"),
+		metric.WithUnit("{message}"),
+	)
+	if err != nil {
+		log.Printf("Failed to create queue published counter: %v", err)
+	}
+	consumed, err := meter.Int64Counter(telemetry.MetricQueueConsumed,
+		metric.WithDescription("Messages handed to consumers, by topic and link direction"),
+		metric.WithUnit("{message}"),
+	)
+	if err != nil {
+		log.Printf("Failed to create queue consumed counter: %v", err)
+	}
+	acked, err := meter.Int64Counter(telemetry.MetricQueueAcked,
+		metric.WithDescription("Messages acknowledged by consumers, by topic and link direction"),
+		metric.WithUnit("{message}"),
+	)
+	if err != nil {
+		log.Printf("Failed to create queue acked counter: %v", err)
+	}
+	latency, err := meter.Float64Histogram(telemetry.MetricQueuePublishToConsume,
+		metric.WithDescription("Time from a message being published to a consumer taking it, by topic and link direction"),
+		metric.WithUnit("ms"),
+	)
+	if err != nil {
+		log.Printf("Failed to create queue publish-to-consume histogram: %v", err)
+	}
+	return &Metrics{
+		published: published,
+		consumed:  consumed,
+		acked:     acked,
+		latency:   latency,
+		direction: attribute.String(LinkDirectionKey, linkDirection),
+	}
+}
+
+// SetMetrics records the queue's traffic in m (nil records nothing)
+func (q *SimpleQueue) SetMetrics(m *Metrics) {
+	q.metrics = m
+}
+
+// Ack acknowledges a consumed message, once its processing succeeded. The in-memory queue
+// forgets messages as they are consumed, so this only counts the acknowledgment.
+func (q *SimpleQueue) Ack(ctx context.Context, order Order) {
+	if m := q.metrics; m != nil && m.acked != nil {
+		m.acked.Add(ctx, 1, metric.WithAttributes(m.attributes(order)...))
+	}
+}
+
+// recordPublish counts a published message
+func (m *Metrics) recordPublish(ctx context.Context, order Order) {
+	if m.published != nil {
+		m.published.Add(ctx, 1, metric.WithAttributes(m.attributes(order)...))
+	}
+}
+
+// recordConsume counts a consumed message and how long ago it was published (or last
+// redelivered). Messages without a publish time header fall back to their creation time.
+func (m *Metrics) recordConsume(ctx context.Context, order Order, now time.Time) {
+	attrs := metric.WithAttributes(m.attributes(order)...)
+	if m.consumed != nil {
+		m.consumed.Add(ctx, 1, attrs)
+	}
+	published := order.PublishedAt()
+	if published.IsZero() {
+		published = order.CreatedAt
+	}
+	if m.latency != nil && !published.IsZero() {
+		m.latency.Record(ctx, float64(now.Sub(published).Microseconds())/1000, attrs)
+	}
+}
+
+// attributes tags a measurement of order
+func (m *Metrics) attributes(order Order) []attribute.KeyValue {
+	topic := order.Topic
+	if topic == "" {
+		topic = DefaultTopic
+	}
+	return []attribute.KeyValue{attribute.String("messaging.destination.name", topic), m.direction}
+}
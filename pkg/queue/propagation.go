@@ -0,0 +1,159 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LinkSource names where a consumer found the producer's span context
+type LinkSource string
+
+// Sources tried by ExtractProducerContext, in order
+const (
+	LinkSourceW3C    LinkSource = "w3c"           // traceparent / tracestate headers
+	LinkSourceB3     LinkSource = "b3"            // Zipkin B3, single or multi header
+	LinkSourceLegacy LinkSource = "legacy_fields" // top-level trace_parent / trace_state fields
+	LinkSourceNone   LinkSource = "none"          // no usable context; nothing to link to
+)
+
+// B3 propagation headers (https://github.com/openzipkin/b3-propagation), matched
+// case-insensitively
+const (
+	B3Header        = "b3"
+	B3TraceIDHeader = "x-b3-traceid"
+	B3SpanIDHeader  = "x-b3-spanid"
+	B3SampledHeader = "x-b3-sampled"
+	B3FlagsHeader   = "x-b3-flags"
+)
+
+// ErrB3Malformed is returned for B3 headers that cannot be parsed
+var ErrB3Malformed = errors.New("b3 header is malformed")
+
+// ExtractProducerContext finds the producer's span context in order, trying in turn W3C
+// headers, B3 headers and the legacy trace fields, so messages from producers on other
+// propagators still link back. With strict set, traceparent is validated by
+// ParseTraceParent. It returns LinkSourceNone and an invalid context when no source has
+// one; the error is then the first parse failure, or nil when the message carried no
+// trace context at all.
+func ExtractProducerContext(order Order, strict bool) (trace.SpanContext, LinkSource, error) {
+	var firstErr error
+	try := func(sc trace.SpanContext, err error) bool {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		return err == nil && sc.IsValid()
+	}
+
+	legacy := order.Header(TraceOriginHeader) == string(LinkSourceLegacy)
+	if !legacy {
+		if sc, err := traceParentContext(order, strict); try(sc, err) {
+			return sc, LinkSourceW3C, nil
+		}
+	}
+	if sc, err := B3SpanContext(order); try(sc, err) {
+		return sc, LinkSourceB3, nil
+	}
+	if legacy {
+		if sc, err := traceParentContext(order, strict); try(sc, err) {
+			return sc, LinkSourceLegacy, nil
+		}
+	}
+	return trace.SpanContext{}, LinkSourceNone, firstErr
+}
+
+// traceParentContext reads the traceparent header; a missing header is not an error, one
+// that yields no context is, even when not strict
+func traceParentContext(order Order, strict bool) (trace.SpanContext, error) {
+	if order.Header(TraceParentHeader) == "" {
+		return trace.SpanContext{}, nil
+	}
+	if !strict {
+		if sc := SpanContextFromMessage(order); sc.IsValid() {
+			return sc, nil
+		}
+		return trace.SpanContext{}, fmt.Errorf("%w: %q", ErrTraceParentMalformed, order.Header(TraceParentHeader))
+	}
+	return ParseTraceParent(order.Header(TraceParentHeader), order.Header(TraceStateHeader))
+}
+
+// B3SpanContext parses the single "b3" header or, without it, the X-B3-* headers. It
+// returns an invalid context and no error when the message has no B3 context; a bare
+// sampling decision ("b3: 0") carries none. 64-bit trace IDs are left-padded to 128 bits.
+func B3SpanContext(order Order) (trace.SpanContext, error) {
+	if single := headerFold(order, B3Header); single != "" {
+		fields := strings.Split(single, "-")
+		if len(fields) == 1 {
+			return trace.SpanContext{}, nil
+		}
+		sampled := ""
+		if len(fields) > 2 {
+			sampled = fields[2]
+		}
+		if len(fields) > 4 {
+			return trace.SpanContext{}, fmt.Errorf("%w: %q", ErrB3Malformed, single)
+		}
+		return b3SpanContext(fields[0], fields[1], sampled)
+	}
+
+	traceID := headerFold(order, B3TraceIDHeader)
+	spanID := headerFold(order, B3SpanIDHeader)
+	if traceID == "" && spanID == "" {
+		return trace.SpanContext{}, nil
+	}
+	sampled := headerFold(order, B3SampledHeader)
+	if headerFold(order, B3FlagsHeader) == "1" {
+		sampled = "d"
+	}
+	return b3SpanContext(traceID, spanID, sampled)
+}
+
+func b3SpanContext(traceID, spanID, sampled string) (trace.SpanContext, error) {
+	if len(traceID) == 16 {
+		traceID = strings.Repeat("0", 16) + traceID
+	}
+	if len(traceID) != 32 || !isLowerHex(traceID) {
+		return trace.SpanContext{}, fmt.Errorf("%w: trace id %q", ErrB3Malformed, traceID)
+	}
+	if len(spanID) != 16 || !isLowerHex(spanID) {
+		return trace.SpanContext{}, fmt.Errorf("%w: span id %q", ErrB3Malformed, spanID)
+	}
+	tid, err := trace.TraceIDFromHex(traceID) // rejects all-zero IDs
+	if err != nil {
+		return trace.SpanContext{}, fmt.Errorf("%w: %v", ErrB3Malformed, err)
+	}
+	sid, err := trace.SpanIDFromHex(spanID)
+	if err != nil {
+		return trace.SpanContext{}, fmt.Errorf("%w: %v", ErrB3Malformed, err)
+	}
+
+	var flags trace.TraceFlags
+	switch sampled {
+	case "1", "d", "true":
+		flags = trace.FlagsSampled
+	case "", "0", "false":
+	default:
+		return trace.SpanContext{}, fmt.Errorf("%w: sampling state %q", ErrB3Malformed, sampled)
+	}
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    tid,
+		SpanID:     sid,
+		TraceFlags: flags,
+		Remote:     true,
+	}), nil
+}
+
+// headerFold returns the header key, matched case-insensitively
+func headerFold(order Order, key string) string {
+	if val := order.Header(key); val != "" {
+		return val
+	}
+	for k, val := range order.Headers {
+		if strings.EqualFold(k, key) {
+			return val
+		}
+	}
+	return ""
+}
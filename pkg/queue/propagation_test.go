@@ -0,0 +1,160 @@
+package queue_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"span-links-signoz-demo/pkg/queue"
+)
+
+const (
+	b3TraceID = "80f198ee56343ba864fe8b2a57d3eff7"
+	b3SpanID  = "e457b5a2e4d86bd1"
+)
+
+// TestExtractProducerContext pins the order sources are tried in: W3C headers, B3 single
+// header, B3 multi headers, the legacy trace fields, then no source at all
+func TestExtractProducerContext(t *testing.T) {
+	w3c := "00-" + testTraceID + "-" + testSpanID + "-01"
+	for _, tc := range []struct {
+		name       string
+		order      queue.Order
+		wantSource queue.LinkSource
+		wantSpanID string
+		wantErr    error
+	}{
+		{
+			name:       "w3c",
+			order:      queue.Order{Headers: map[string]string{queue.TraceParentHeader: w3c}},
+			wantSource: queue.LinkSourceW3C,
+			wantSpanID: testSpanID,
+		},
+		{
+			name:       "b3 single header",
+			order:      queue.Order{Headers: map[string]string{"b3": b3TraceID + "-" + b3SpanID + "-1"}},
+			wantSource: queue.LinkSourceB3,
+			wantSpanID: b3SpanID,
+		},
+		{
+			name: "b3 multi headers",
+			order: queue.Order{Headers: map[string]string{
+				"X-B3-TraceId": b3TraceID,
+				"X-B3-SpanId":  b3SpanID,
+				"X-B3-Sampled": "1",
+			}},
+			wantSource: queue.LinkSourceB3,
+			wantSpanID: b3SpanID,
+		},
+		{
+			name:       "b3 64-bit trace id",
+			order:      queue.Order{Headers: map[string]string{"b3": b3TraceID[16:] + "-" + b3SpanID}},
+			wantSource: queue.LinkSourceB3,
+			wantSpanID: b3SpanID,
+		},
+		{
+			name:       "legacy fields",
+			order:      decodeOrder(t, `{"id":"ORDER-1","trace_parent":"`+w3c+`"}`),
+			wantSource: queue.LinkSourceLegacy,
+			wantSpanID: testSpanID,
+		},
+		{
+			name:       "no context",
+			order:      queue.Order{Headers: map[string]string{"vendor": "x"}},
+			wantSource: queue.LinkSourceNone,
+		},
+		{
+			name:       "b3 sampling decision only",
+			order:      queue.Order{Headers: map[string]string{"b3": "0"}},
+			wantSource: queue.LinkSourceNone,
+		},
+		{
+			name:       "malformed b3 only",
+			order:      queue.Order{Headers: map[string]string{"b3": "not-hex"}},
+			wantSource: queue.LinkSourceNone,
+			wantErr:    queue.ErrB3Malformed,
+		},
+		{
+			name: "w3c wins over b3",
+			order: queue.Order{Headers: map[string]string{
+				queue.TraceParentHeader: w3c,
+				"b3":                    b3TraceID + "-" + b3SpanID + "-1",
+				"x-b3-traceid":          b3TraceID,
+				"x-b3-spanid":           b3SpanID,
+			}},
+			wantSource: queue.LinkSourceW3C,
+			wantSpanID: testSpanID,
+		},
+		{
+			name: "b3 single wins over b3 multi",
+			order: queue.Order{Headers: map[string]string{
+				"b3":           b3TraceID + "-" + b3SpanID,
+				"x-b3-traceid": testTraceID,
+				"x-b3-spanid":  testSpanID,
+			}},
+			wantSource: queue.LinkSourceB3,
+			wantSpanID: b3SpanID,
+		},
+		{
+			name: "b3 wins over legacy fields",
+			order: func() queue.Order {
+				o := decodeOrder(t, `{"id":"ORDER-1","trace_parent":"`+w3c+`"}`)
+				o.Headers["b3"] = b3TraceID + "-" + b3SpanID
+				return o
+			}(),
+			wantSource: queue.LinkSourceB3,
+			wantSpanID: b3SpanID,
+		},
+		{
+			name: "b3 after a malformed traceparent",
+			order: queue.Order{Headers: map[string]string{
+				queue.TraceParentHeader: "00-" + testTraceID + "-" + testSpanID + "-0g",
+				"b3":                    b3TraceID + "-" + b3SpanID,
+			}},
+			wantSource: queue.LinkSourceB3,
+			wantSpanID: b3SpanID,
+		},
+		{
+			name:       "malformed traceparent only",
+			order:      queue.Order{Headers: map[string]string{queue.TraceParentHeader: "00-" + testTraceID + "-" + testSpanID + "-0g"}},
+			wantSource: queue.LinkSourceNone,
+			wantErr:    queue.ErrTraceParentFlags,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sc, source, err := queue.ExtractProducerContext(tc.order, true)
+			if source != tc.wantSource {
+				t.Errorf("source %q, want %q", source, tc.wantSource)
+			}
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("error %v, want %v", err, tc.wantErr)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tc.wantSpanID == "" {
+				if sc.IsValid() {
+					t.Errorf("got context %s/%s, want none", sc.TraceID(), sc.SpanID())
+				}
+				return
+			}
+			if got := sc.SpanID().String(); got != tc.wantSpanID {
+				t.Errorf("span id %s, want %s", got, tc.wantSpanID)
+			}
+			if !sc.IsRemote() {
+				t.Error("context is not remote")
+			}
+		})
+	}
+}
+
+// decodeOrder decodes a JSON message, migrating legacy trace fields as consumers do
+func decodeOrder(t *testing.T, data string) queue.Order {
+	t.Helper()
+	var o queue.Order
+	if err := json.Unmarshal([]byte(data), &o); err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
@@ -0,0 +1,358 @@
+// Package queue is the in-memory message queue of the demo: the Order message, its
+// propagation headers and schema versions, and a topic-based SimpleQueue that injects
+// the publishing span's context into every message.
+package queue
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"span-links-signoz-demo/pkg/clock"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// DefaultCapacity is the buffer size of each topic
+const DefaultCapacity = 100
+
+// MessagingSystem is the messaging.system attribute value for the in-memory queue
+const MessagingSystem = "in-memory"
+
+// Topics and routing keys
+const (
+	DefaultTopic        = "orders"
+	StandardOrdersTopic = "orders.standard"
+	PriorityOrdersTopic = "orders.priority"
+	StandardRoutingKey  = "order.standard"
+	PriorityRoutingKey  = "order.priority"
+)
+
+// Order represents a message in our queue
+type Order struct {
+	ID            string            `json:"id"`
+	CustomerID    string            `json:"customer_id"`
+	Amount        float64           `json:"amount"`
+	CreatedAt     time.Time         `json:"created_at"`
+	Topic         string            `json:"topic"`            // destination topic (DefaultTopic when empty)
+	RoutingKey    string            `json:"routing_key"`      // routing key the producer used to pick the topic
+	Headers       map[string]string `json:"headers"`          // propagation headers (traceparent, tracestate, baggage, vendor fields)
+	SchemaVersion int               `json:"schema_version"`   // message schema version (OrderSchemaV1 when 0)
+	AmountCents   int64             `json:"amount_cents"`     // v2: amount in minor units (replaces Amount)
+	Currency      string            `json:"currency"`         // v2: ISO 4217 currency code
+	Sealed        string            `json:"sealed,omitempty"` // encrypted payload fields (see Sealer)
+
+	seq uint64 // sequence number of the publish that put the message on its topic
+}
+
+// SimpleQueue mimics a message queue (in production, use RabbitMQ, Kafka, etc.)
+// Messages are stored per named topic; consumers subscribe to one or more topics.
+type SimpleQueue struct {
+	topics   map[string]chan Order
+	topicsMu sync.RWMutex
+	mu       sync.Mutex
+	clock    clock.Clock
+	capacity int // buffer size of each topic
+	metrics  *Metrics
+
+	seq        atomic.Uint64 // last sequence number handed out by Publish
+	txMu       sync.Mutex
+	open       map[uint64]bool     // sequence number → delivered, for messages of open transactions
+	tombstones map[uint64]struct{} // messages of rolled-back transactions still on their topic
+}
+
+// New creates an empty queue with the DefaultTopic on the system clock
+func New() *SimpleQueue {
+	return NewWithClock(clock.Real{})
+}
+
+// NewWithClock creates an empty queue whose time source is c. Producers and workers
+// built on the queue default to the same clock, so one fake clock drives a whole pipeline.
+func NewWithClock(c clock.Clock) *SimpleQueue {
+	return NewWithCapacity(c, DefaultCapacity)
+}
+
+// NewWithCapacity creates an empty queue on clock c whose topics buffer capacity messages
+// each; publishing to a full topic blocks until a consumer takes one
+func NewWithCapacity(c clock.Clock, capacity int) *SimpleQueue {
+	if capacity < 1 {
+		capacity = DefaultCapacity
+	}
+	return &SimpleQueue{
+		topics: map[string]chan Order{
+			DefaultTopic: make(chan Order, capacity),
+		},
+		clock:      c,
+		capacity:   capacity,
+		open:       make(map[uint64]bool),
+		tombstones: make(map[uint64]struct{}),
+	}
+}
+
+// Capacity returns the buffer size of each topic
+func (q *SimpleQueue) Capacity() int {
+	return q.capacity
+}
+
+// Clock returns the queue's time source
+func (q *SimpleQueue) Clock() clock.Clock {
+	return q.clock
+}
+
+// topic returns the channel for the named topic, creating it on first use
+func (q *SimpleQueue) topic(name string) chan Order {
+	if name == "" {
+		name = DefaultTopic
+	}
+
+	q.topicsMu.RLock()
+	ch, ok := q.topics[name]
+	q.topicsMu.RUnlock()
+	if ok {
+		return ch
+	}
+
+	q.topicsMu.Lock()
+	defer q.topicsMu.Unlock()
+	if ch, ok := q.topics[name]; ok {
+		return ch
+	}
+	ch = make(chan Order, q.capacity)
+	q.topics[name] = ch
+	return ch
+}
+
+// Publish adds a message to the queue on order.Topic (DefaultTopic when empty)
+func (q *SimpleQueue) Publish(ctx context.Context, order Order) error {
+	order.seq = q.seq.Add(1)
+	return q.publish(ctx, order)
+}
+
+// publish adds a message whose sequence number is already set
+func (q *SimpleQueue) publish(ctx context.Context, order Order) error {
+	// Inject the publishing span's context (and baggage) into the message headers so
+	// workers can link back; headers the producer already set are kept
+	headers := propagation.MapCarrier{}
+	for k, v := range order.Headers {
+		headers[k] = v
+	}
+	otel.GetTextMapPropagator().Inject(ctx, headers)
+	now := q.clock.Now()
+	headers[PublishedAtHeader] = now.Format(time.RFC3339Nano)
+	order.Headers = headers
+	if order.Topic == "" {
+		order.Topic = DefaultTopic
+	}
+	if order.CreatedAt.IsZero() {
+		order.CreatedAt = now
+	}
+	ch := q.topic(order.Topic)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	select {
+	case ch <- order:
+		if q.metrics != nil {
+			q.metrics.recordPublish(ctx, order)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Redeliver puts a message that failed processing back on its topic with its redelivery
+// count incremented and a new publish time. The trace headers are kept as they are, so
+// the next consumer still links to the original publish, however old that trace is by then.
+func (q *SimpleQueue) Redeliver(ctx context.Context, order Order) error {
+	headers := make(map[string]string, len(order.Headers)+2)
+	for k, v := range order.Headers {
+		headers[k] = v
+	}
+	headers[RedeliveryCountHeader] = strconv.Itoa(order.RedeliveryCount() + 1)
+	headers[PublishedAtHeader] = q.clock.Now().Format(time.RFC3339Nano)
+	order.Headers = headers
+	ch := q.topic(order.Topic)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	select {
+	case ch <- order:
+		if q.metrics != nil {
+			q.metrics.recordPublish(ctx, order)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// PendingMessage describes a message waiting in the queue (see Pending)
+type PendingMessage struct {
+	OrderID         string    `json:"order_id"`
+	CustomerID      string    `json:"customer_id"`
+	Topic           string    `json:"topic"`
+	TraceParent     string    `json:"traceparent"`
+	CreatedAt       time.Time `json:"created_at"`
+	AgeMs           int64     `json:"age_ms"`
+	RedeliveryCount int       `json:"redelivery_count"`
+}
+
+// Pending lists the messages waiting in every topic, oldest first within a topic. The
+// snapshot is taken by briefly draining and refilling each topic while publishes are
+// blocked, so consumers may wait a moment but never lose or reorder messages.
+func (q *SimpleQueue) Pending() []PendingMessage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.topicsMu.RLock()
+	defer q.topicsMu.RUnlock()
+
+	names := make([]string, 0, len(q.topics))
+	for name := range q.topics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	now := q.clock.Now()
+	var pending []PendingMessage
+	for _, name := range names {
+		ch := q.topics[name]
+		var held []Order
+	drain:
+		for range len(ch) {
+			select {
+			case msg := <-ch:
+				held = append(held, msg)
+			default:
+				break drain // a consumer got there first
+			}
+		}
+		for _, msg := range held {
+			ch <- msg
+			if q.tombstoned(msg) {
+				continue
+			}
+			pending = append(pending, PendingMessage{
+				OrderID:         msg.ID,
+				CustomerID:      msg.CustomerID,
+				Topic:           name,
+				TraceParent:     msg.Header(TraceParentHeader),
+				CreatedAt:       msg.CreatedAt,
+				AgeMs:           now.Sub(msg.CreatedAt).Milliseconds(),
+				RedeliveryCount: msg.RedeliveryCount(),
+			})
+		}
+	}
+	return pending
+}
+
+// TakeAll removes and returns every message waiting in the queue, topic by topic, for a
+// process that hands its backlog to a later one (messages of rolled-back transactions are
+// dropped). Consumers should be stopped first.
+func (q *SimpleQueue) TakeAll() []Order {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.topicsMu.RLock()
+	defer q.topicsMu.RUnlock()
+
+	names := make([]string, 0, len(q.topics))
+	for name := range q.topics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var taken []Order
+	for _, name := range names {
+		ch := q.topics[name]
+	drain:
+		for {
+			select {
+			case msg := <-ch:
+				if q.settle(msg) {
+					taken = append(taken, msg)
+				}
+			default:
+				break drain
+			}
+		}
+	}
+	return taken
+}
+
+// Consume retrieves a message from any of the given topics (DefaultTopic when none are
+// given). Messages of rolled-back transactions are skipped.
+func (q *SimpleQueue) Consume(ctx context.Context, topics ...string) (Order, error) {
+	for {
+		msg, err := q.receive(ctx, topics...)
+		if err != nil {
+			return msg, err
+		}
+		if q.settle(msg) {
+			if q.metrics != nil {
+				q.metrics.recordConsume(ctx, msg, q.clock.Now())
+			}
+			return msg, nil
+		}
+	}
+}
+
+// receive takes the next message from any of the given topics
+func (q *SimpleQueue) receive(ctx context.Context, topics ...string) (Order, error) {
+	if len(topics) <= 1 {
+		name := DefaultTopic
+		if len(topics) == 1 {
+			name = topics[0]
+		}
+		select {
+		case msg := <-q.topic(name):
+			return msg, nil
+		case <-ctx.Done():
+			return Order{}, ctx.Err()
+		}
+	}
+
+	cases := make([]reflect.SelectCase, 0, len(topics)+1)
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())})
+	for _, name := range topics {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(q.topic(name))})
+	}
+
+	chosen, value, _ := reflect.Select(cases)
+	if chosen == 0 {
+		return Order{}, ctx.Err()
+	}
+	return value.Interface().(Order), nil
+}
+
+// Length returns the number of messages in the queue across all topics, not counting
+// messages of rolled-back transactions
+func (q *SimpleQueue) Length() int {
+	q.topicsMu.RLock()
+	var n int
+	for _, ch := range q.topics {
+		n += len(ch)
+	}
+	q.topicsMu.RUnlock()
+
+	q.txMu.Lock()
+	defer q.txMu.Unlock()
+	return max(n-len(q.tombstones), 0)
+}
+
+// MessagingAttributes returns messaging semantic-convention attributes for an operation
+// ("send", "receive", "process") on order
+func MessagingAttributes(order Order, operation string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("messaging.system", MessagingSystem),
+		attribute.String("messaging.operation.type", operation),
+		attribute.String("messaging.message.id", order.ID),
+	}
+}
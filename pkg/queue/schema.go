@@ -0,0 +1,41 @@
+package queue
+
+import (
+	"fmt"
+	"math"
+)
+
+// Order message schema versions
+const (
+	// OrderSchemaV1 carries the amount as a float Amount (dollars)
+	OrderSchemaV1 = 1
+	// OrderSchemaV2 carries the amount as integer AmountCents plus a Currency
+	OrderSchemaV2 = 2
+)
+
+// UpgradeToV2 rewrites a v1 order in the v2 format
+func UpgradeToV2(order Order) Order {
+	order.SchemaVersion = OrderSchemaV2
+	order.AmountCents = int64(math.Round(order.Amount * 100))
+	order.Currency = "USD"
+	order.Amount = 0
+	return order
+}
+
+// Normalize decodes an order of any supported schema version into the in-memory form
+// the worker uses (Amount in dollars). Orders without a version are v1.
+func Normalize(order Order) (Order, error) {
+	switch order.SchemaVersion {
+	case 0, OrderSchemaV1:
+		order.SchemaVersion = OrderSchemaV1
+		return order, nil
+	case OrderSchemaV2:
+		if order.Currency != "USD" {
+			return order, fmt.Errorf("unsupported currency %q", order.Currency)
+		}
+		order.Amount = float64(order.AmountCents) / 100
+		return order, nil
+	default:
+		return order, fmt.Errorf("unsupported order schema version %d", order.SchemaVersion)
+	}
+}
@@ -0,0 +1,155 @@
+package queue
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Headers of sealed messages
+const (
+	// PayloadSignatureHeader carries the HMAC-SHA256 of a sealed message (see Sealer.Sign)
+	PayloadSignatureHeader = "x-payload-signature"
+
+	// PayloadKeyIDHeader names the key a message was sealed with
+	PayloadKeyIDHeader = "x-payload-key-id"
+)
+
+// Algorithms a Sealer uses, for span attributes
+const (
+	SealCipher    = "AES-256-GCM"
+	SealSignature = "HMAC-SHA256"
+)
+
+// Errors returned when opening a sealed message
+var (
+	ErrPayloadKey       = errors.New("payload sealed with an unknown key")
+	ErrPayloadSignature = errors.New("payload signature is invalid")
+	ErrPayloadDecrypt   = errors.New("payload cannot be decrypted")
+)
+
+// sealedPayload is the part of an order a Sealer encrypts. The ID, customer ID (the
+// message key consumer groups and key-affinity routing partition by), topic and headers
+// stay in clear.
+type sealedPayload struct {
+	Amount      float64 `json:"amount,omitempty"`
+	AmountCents int64   `json:"amount_cents,omitempty"`
+	Currency    string  `json:"currency,omitempty"`
+}
+
+// Sealer encrypts order payloads and signs the sealed messages (encrypt-then-MAC), so the
+// payload is unreadable and tamper-evident on the queue. Encryption and signing keys are
+// derived from one shared secret.
+type Sealer struct {
+	aead   cipher.AEAD
+	macKey []byte
+	keyID  string
+}
+
+// NewSealer creates a sealer from a shared secret
+func NewSealer(secret string) (*Sealer, error) {
+	if secret == "" {
+		return nil, errors.New("seal secret is empty")
+	}
+	encKey := sha256.Sum256([]byte("enc\x00" + secret))
+	macKey := sha256.Sum256([]byte("mac\x00" + secret))
+	keyID := sha256.Sum256([]byte("id\x00" + secret))
+
+	block, err := aes.NewCipher(encKey[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealer{aead: aead, macKey: macKey[:], keyID: hex.EncodeToString(keyID[:4])}, nil
+}
+
+// KeyID returns a fingerprint of the secret, safe to record on spans
+func (s *Sealer) KeyID() string {
+	return s.keyID
+}
+
+// IsSealed reports whether the order's payload is encrypted
+func (o Order) IsSealed() bool {
+	return o.Sealed != ""
+}
+
+// Encrypt moves the order's payload fields into Sealed, encrypted with a fresh nonce and
+// bound to the order ID
+func (s *Sealer) Encrypt(order Order) (Order, error) {
+	plain, err := json.Marshal(sealedPayload{Amount: order.Amount, AmountCents: order.AmountCents, Currency: order.Currency})
+	if err != nil {
+		return order, err
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return order, err
+	}
+	sealed := s.aead.Seal(nonce, nonce, plain, []byte(order.ID))
+
+	order.Sealed = base64.StdEncoding.EncodeToString(sealed)
+	order.Amount, order.AmountCents, order.Currency = 0, 0, ""
+	return order, nil
+}
+
+// Sign sets the key ID and signature headers of a sealed order. The signature covers the
+// ID, customer ID, key ID and sealed payload.
+func (s *Sealer) Sign(order Order) Order {
+	if order.Headers == nil {
+		order.Headers = make(map[string]string)
+	}
+	order.Headers[PayloadKeyIDHeader] = s.keyID
+	order.Headers[PayloadSignatureHeader] = hex.EncodeToString(s.mac(order))
+	return order
+}
+
+// Verify checks the signature of a sealed order
+func (s *Sealer) Verify(order Order) error {
+	if keyID := order.Header(PayloadKeyIDHeader); keyID != s.keyID {
+		return fmt.Errorf("%w: %q", ErrPayloadKey, keyID)
+	}
+	sig, err := hex.DecodeString(order.Header(PayloadSignatureHeader))
+	if err != nil || !hmac.Equal(sig, s.mac(order)) {
+		return ErrPayloadSignature
+	}
+	return nil
+}
+
+// Decrypt restores the payload fields of a sealed order and clears Sealed
+func (s *Sealer) Decrypt(order Order) (Order, error) {
+	sealed, err := base64.StdEncoding.DecodeString(order.Sealed)
+	if err != nil || len(sealed) < s.aead.NonceSize() {
+		return order, fmt.Errorf("%w: malformed ciphertext", ErrPayloadDecrypt)
+	}
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	plain, err := s.aead.Open(nil, nonce, ciphertext, []byte(order.ID))
+	if err != nil {
+		return order, fmt.Errorf("%w: %v", ErrPayloadDecrypt, err)
+	}
+	var payload sealedPayload
+	if err := json.Unmarshal(plain, &payload); err != nil {
+		return order, fmt.Errorf("%w: %v", ErrPayloadDecrypt, err)
+	}
+
+	order.Amount, order.AmountCents, order.Currency = payload.Amount, payload.AmountCents, payload.Currency
+	order.Sealed = ""
+	return order, nil
+}
+
+func (s *Sealer) mac(order Order) []byte {
+	m := hmac.New(sha256.New, s.macKey)
+	for _, field := range []string{order.ID, order.CustomerID, order.Header(PayloadKeyIDHeader), order.Sealed} {
+		m.Write([]byte(field))
+		m.Write([]byte{0})
+	}
+	return m.Sum(nil)
+}
@@ -0,0 +1,133 @@
+package queue_test
+
+import (
+	"errors"
+	"testing"
+
+	"span-links-signoz-demo/pkg/queue"
+)
+
+// seal encrypts and signs a v2 order the way the producer does
+func seal(t *testing.T, s *queue.Sealer) queue.Order {
+	t.Helper()
+	order, err := s.Encrypt(queue.Order{
+		ID:            "ORDER-1",
+		CustomerID:    "CUST-1",
+		Amount:        12.5,
+		AmountCents:   1250,
+		Currency:      "EUR",
+		SchemaVersion: queue.OrderSchemaV2,
+		Headers:       map[string]string{queue.TraceParentHeader: "00-" + testTraceID + "-" + testSpanID + "-01"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.Sign(order)
+}
+
+func TestSealRoundTrip(t *testing.T) {
+	s, err := queue.NewSealer("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed := seal(t, s)
+	if !sealed.IsSealed() || sealed.Amount != 0 || sealed.AmountCents != 0 || sealed.Currency != "" {
+		t.Fatalf("payload left in clear: amount %v, cents %d, currency %q", sealed.Amount, sealed.AmountCents, sealed.Currency)
+	}
+	if got := sealed.Header(queue.PayloadKeyIDHeader); got != s.KeyID() {
+		t.Errorf("key ID header %q, want %q", got, s.KeyID())
+	}
+
+	// A second sealer from the same secret stands in for the consumer
+	opener, err := queue.NewSealer("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := opener.Verify(sealed); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	opened, err := opener.Decrypt(sealed)
+	if err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	if opened.IsSealed() || opened.Amount != 12.5 || opened.AmountCents != 1250 || opened.Currency != "EUR" {
+		t.Errorf("decrypted payload: sealed %t, amount %v, cents %d, currency %q", opened.IsSealed(), opened.Amount, opened.AmountCents, opened.Currency)
+	}
+	if opened.ID != "ORDER-1" || opened.CustomerID != "CUST-1" || opened.Header(queue.TraceParentHeader) != sealed.Header(queue.TraceParentHeader) {
+		t.Errorf("clear fields changed: %+v", opened)
+	}
+}
+
+func TestSealRejectsTampering(t *testing.T) {
+	s, err := queue.NewSealer("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := queue.NewSealer("other secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		tamper  func(queue.Order) queue.Order
+		opener  *queue.Sealer
+		wantErr error
+	}{
+		{
+			name: "signature",
+			tamper: func(o queue.Order) queue.Order {
+				sig := []byte(o.Headers[queue.PayloadSignatureHeader])
+				sig[0] ^= 1
+				o.Headers[queue.PayloadSignatureHeader] = string(sig)
+				return o
+			},
+			wantErr: queue.ErrPayloadSignature,
+		},
+		{
+			name:    "missing signature",
+			tamper:  func(o queue.Order) queue.Order { delete(o.Headers, queue.PayloadSignatureHeader); return o },
+			wantErr: queue.ErrPayloadSignature,
+		},
+		{
+			name:    "customer ID",
+			tamper:  func(o queue.Order) queue.Order { o.CustomerID = "CUST-2"; return o },
+			wantErr: queue.ErrPayloadSignature,
+		},
+		{
+			name: "sealed payload",
+			tamper: func(o queue.Order) queue.Order {
+				first := byte('A')
+				if o.Sealed[0] == first {
+					first = 'B'
+				}
+				o.Sealed = string(first) + o.Sealed[1:]
+				return o
+			},
+			wantErr: queue.ErrPayloadSignature,
+		},
+		{
+			name:    "other key",
+			tamper:  func(o queue.Order) queue.Order { return o },
+			opener:  other,
+			wantErr: queue.ErrPayloadKey,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			opener := tc.opener
+			if opener == nil {
+				opener = s
+			}
+			if err := opener.Verify(tc.tamper(seal(t, s))); !errors.Is(err, tc.wantErr) {
+				t.Errorf("verify error %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+
+	// Decrypt checks the ciphertext on its own: it is bound to the order ID
+	moved := seal(t, s)
+	moved.ID = "ORDER-2"
+	if _, err := s.Decrypt(moved); !errors.Is(err, queue.ErrPayloadDecrypt) {
+		t.Errorf("decrypt under another order ID: error %v, want %v", err, queue.ErrPayloadDecrypt)
+	}
+}
@@ -0,0 +1,23 @@
+package queue
+
+import "strings"
+
+// SpanNameTemplate formats span names for the producer/worker pipeline. Supported
+// placeholders are {operation} (e.g. PublishOrder, ProcessOrder, ValidateOrder) and
+// {topic} (the order's destination topic; empty for batch-level spans).
+// An empty template keeps the plain operation name.
+type SpanNameTemplate string
+
+// Format renders the span name for operation on topic
+func (t SpanNameTemplate) Format(operation, topic string) string {
+	if t == "" {
+		return operation
+	}
+	name := strings.NewReplacer("{operation}", operation, "{topic}", topic).Replace(string(t))
+	// Collapse whitespace left behind by empty placeholders
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return operation
+	}
+	return name
+}
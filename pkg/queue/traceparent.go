@@ -0,0 +1,142 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Errors returned by ParseTraceParent
+var (
+	ErrTraceParentMissing   = errors.New("traceparent is missing")
+	ErrTraceParentMalformed = errors.New("traceparent is malformed")
+	ErrTraceParentVersion   = errors.New("traceparent version is invalid")
+	ErrTraceParentTraceID   = errors.New("traceparent trace-id is invalid")
+	ErrTraceParentSpanID    = errors.New("traceparent parent-id is invalid")
+	ErrTraceParentFlags     = errors.New("traceparent trace-flags are invalid")
+)
+
+// traceParentLen is the length of a version-00 traceparent: 2+1+32+1+16+1+2
+const traceParentLen = 55
+
+// ParseTraceParent strictly parses a W3C traceparent header
+// (https://www.w3.org/TR/trace-context/#traceparent-header):
+//   - version must be two lowercase hex digits and not "ff"
+//   - version 00 must be exactly 55 characters; future versions may append
+//     "-"-prefixed fields, which are ignored
+//   - trace-id and parent-id must be lowercase hex and not all zeros
+//   - the sampled flag is taken from trace-flags instead of being assumed
+//
+// tracestate, when non-empty, is parsed and attached to the returned context.
+func ParseTraceParent(traceParent, traceState string) (trace.SpanContext, error) {
+	if traceParent == "" {
+		return trace.SpanContext{}, ErrTraceParentMissing
+	}
+	if len(traceParent) < traceParentLen {
+		return trace.SpanContext{}, fmt.Errorf("%w: length %d < %d", ErrTraceParentMalformed, len(traceParent), traceParentLen)
+	}
+
+	version := traceParent[0:2]
+	if !isLowerHex(version) || version == "ff" {
+		return trace.SpanContext{}, fmt.Errorf("%w: %q", ErrTraceParentVersion, version)
+	}
+	if version == "00" && len(traceParent) != traceParentLen {
+		return trace.SpanContext{}, fmt.Errorf("%w: version 00 must be %d characters, got %d", ErrTraceParentMalformed, traceParentLen, len(traceParent))
+	}
+	if len(traceParent) > traceParentLen && traceParent[traceParentLen] != '-' {
+		return trace.SpanContext{}, fmt.Errorf("%w: unexpected data after trace-flags", ErrTraceParentMalformed)
+	}
+	if traceParent[2] != '-' || traceParent[35] != '-' || traceParent[52] != '-' {
+		return trace.SpanContext{}, fmt.Errorf("%w: fields must be separated by '-'", ErrTraceParentMalformed)
+	}
+
+	traceIDStr := traceParent[3:35]
+	spanIDStr := traceParent[36:52]
+	flagsStr := traceParent[53:55]
+
+	if !isLowerHex(traceIDStr) {
+		return trace.SpanContext{}, fmt.Errorf("%w: %q", ErrTraceParentTraceID, traceIDStr)
+	}
+	tid, err := trace.TraceIDFromHex(traceIDStr) // rejects all-zero IDs
+	if err != nil {
+		return trace.SpanContext{}, fmt.Errorf("%w: %v", ErrTraceParentTraceID, err)
+	}
+
+	if !isLowerHex(spanIDStr) {
+		return trace.SpanContext{}, fmt.Errorf("%w: %q", ErrTraceParentSpanID, spanIDStr)
+	}
+	sid, err := trace.SpanIDFromHex(spanIDStr) // rejects all-zero IDs
+	if err != nil {
+		return trace.SpanContext{}, fmt.Errorf("%w: %v", ErrTraceParentSpanID, err)
+	}
+
+	if !isLowerHex(flagsStr) {
+		return trace.SpanContext{}, fmt.Errorf("%w: %q", ErrTraceParentFlags, flagsStr)
+	}
+	flags, err := strconv.ParseUint(flagsStr, 16, 8)
+	if err != nil {
+		return trace.SpanContext{}, fmt.Errorf("%w: %v", ErrTraceParentFlags, err)
+	}
+
+	cfg := trace.SpanContextConfig{
+		TraceID:    tid,
+		SpanID:     sid,
+		TraceFlags: trace.TraceFlags(flags) & trace.FlagsSampled,
+		Remote:     true,
+	}
+	if traceState != "" {
+		ts, err := trace.ParseTraceState(traceState)
+		if err != nil {
+			return trace.SpanContext{}, fmt.Errorf("invalid tracestate: %w", err)
+		}
+		cfg.TraceState = ts
+	}
+
+	return trace.NewSpanContext(cfg), nil
+}
+
+// isLowerHex reports whether s is non-empty and only contains 0-9a-f
+func isLowerHex(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789abcdef") == ""
+}
+
+// SpanContextFromMessage builds a span context from the message's traceparent header,
+// without the validation of ParseTraceParent
+func SpanContextFromMessage(order Order) trace.SpanContext {
+	// In production, properly parse the traceparent header
+	// For this demo, we construct it from the stored values
+	traceParent := order.Header(TraceParentHeader)
+	if len(traceParent) < 53 {
+		return trace.SpanContext{}
+	}
+
+	// Parse traceparent format: 00-<trace-id>-<span-id>-<flags>
+	// Example: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+	traceIDStr := traceParent[3:35] // 32 hex chars
+	spanIDStr := traceParent[36:52] // 16 hex chars
+
+	tid, err := trace.TraceIDFromHex(traceIDStr)
+	if err != nil {
+		// Note: Using log.Printf as this may be called before slog initialization
+		log.Printf("Failed to parse trace ID from message: %v", err)
+		return trace.SpanContext{}
+	}
+
+	sid, err := trace.SpanIDFromHex(spanIDStr)
+	if err != nil {
+		// Note: Using log.Printf as this may be called before slog initialization
+		log.Printf("Failed to parse span ID from message: %v", err)
+		return trace.SpanContext{}
+	}
+
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    tid,
+		SpanID:     sid,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true, // Indicates this context comes from a remote source
+	})
+}
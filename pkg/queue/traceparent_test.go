@@ -0,0 +1,70 @@
+package queue_test
+
+import (
+	"errors"
+	"testing"
+
+	"span-links-signoz-demo/pkg/queue"
+)
+
+const (
+	testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testSpanID  = "00f067aa0ba902b7"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		traceParent string
+		traceState  string
+		wantErr     error
+		wantSampled bool
+	}{
+		{name: "sampled", traceParent: "00-" + testTraceID + "-" + testSpanID + "-01", wantSampled: true},
+		{name: "not sampled", traceParent: "00-" + testTraceID + "-" + testSpanID + "-00"},
+		{name: "unknown flags ignored", traceParent: "00-" + testTraceID + "-" + testSpanID + "-fe"},
+		{name: "with tracestate", traceParent: "00-" + testTraceID + "-" + testSpanID + "-01", traceState: "vendor=value", wantSampled: true},
+		{name: "future version with extra fields", traceParent: "cc-" + testTraceID + "-" + testSpanID + "-01-what-the-future-holds", wantSampled: true},
+		{name: "missing", traceParent: "", wantErr: queue.ErrTraceParentMissing},
+		{name: "too short", traceParent: "00-" + testTraceID + "-" + testSpanID, wantErr: queue.ErrTraceParentMalformed},
+		{name: "version 00 with extra fields", traceParent: "00-" + testTraceID + "-" + testSpanID + "-01-extra", wantErr: queue.ErrTraceParentMalformed},
+		{name: "future version without separator", traceParent: "cc-" + testTraceID + "-" + testSpanID + "-01extra", wantErr: queue.ErrTraceParentMalformed},
+		{name: "bad separator", traceParent: "00_" + testTraceID + "-" + testSpanID + "-01", wantErr: queue.ErrTraceParentMalformed},
+		{name: "version ff", traceParent: "ff-" + testTraceID + "-" + testSpanID + "-01", wantErr: queue.ErrTraceParentVersion},
+		{name: "uppercase version", traceParent: "0A-" + testTraceID + "-" + testSpanID + "-01", wantErr: queue.ErrTraceParentVersion},
+		{name: "all-zero trace id", traceParent: "00-00000000000000000000000000000000-" + testSpanID + "-01", wantErr: queue.ErrTraceParentTraceID},
+		{name: "uppercase trace id", traceParent: "00-4BF92F3577B34DA6A3CE929D0E0E4736-" + testSpanID + "-01", wantErr: queue.ErrTraceParentTraceID},
+		{name: "all-zero span id", traceParent: "00-" + testTraceID + "-0000000000000000-01", wantErr: queue.ErrTraceParentSpanID},
+		{name: "uppercase span id", traceParent: "00-" + testTraceID + "-00F067AA0BA902B7-01", wantErr: queue.ErrTraceParentSpanID},
+		{name: "non-hex flags", traceParent: "00-" + testTraceID + "-" + testSpanID + "-0g", wantErr: queue.ErrTraceParentFlags},
+		{name: "uppercase flags", traceParent: "00-" + testTraceID + "-" + testSpanID + "-0A", wantErr: queue.ErrTraceParentFlags},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sc, err := queue.ParseTraceParent(tc.traceParent, tc.traceState)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("error %v, want %v", err, tc.wantErr)
+				}
+				if sc.IsValid() {
+					t.Errorf("got a valid context %v with an error", sc)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sc.TraceID().String() != testTraceID || sc.SpanID().String() != testSpanID {
+				t.Errorf("got %s/%s, want %s/%s", sc.TraceID(), sc.SpanID(), testTraceID, testSpanID)
+			}
+			if !sc.IsRemote() {
+				t.Error("context is not remote")
+			}
+			if sc.IsSampled() != tc.wantSampled {
+				t.Errorf("sampled = %t, want %t", sc.IsSampled(), tc.wantSampled)
+			}
+			if got := sc.TraceState().String(); got != tc.traceState {
+				t.Errorf("tracestate %q, want %q", got, tc.traceState)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package queue
+
+import "context"
+
+// Tx publishes a group of messages that is kept or withdrawn as a whole. Messages are
+// put on their topics as they are published, so consumers may pick them up before the
+// transaction ends; Rollback tombstones the ones still waiting, and consumers silently
+// drop a tombstoned message instead of receiving it. Messages are told apart by the
+// publish that put them on the queue, so a duplicate or redelivery of the same order
+// outside the transaction is unaffected. A Tx is not safe for concurrent use.
+type Tx struct {
+	q    *SimpleQueue
+	msgs []txMessage
+	done bool
+}
+
+// txMessage is a message published in a transaction
+type txMessage struct {
+	id  string
+	seq uint64
+}
+
+// Begin starts a transactional publish
+func (q *SimpleQueue) Begin() *Tx {
+	return &Tx{q: q}
+}
+
+// Publish publishes order as part of the transaction (see SimpleQueue.Publish)
+func (tx *Tx) Publish(ctx context.Context, order Order) error {
+	q := tx.q
+	order.seq = q.seq.Add(1)
+	// Registered before the message is visible, so a consumer that is quick to take it
+	// is recorded as having received it
+	q.txMu.Lock()
+	q.open[order.seq] = false
+	q.txMu.Unlock()
+
+	if err := q.publish(ctx, order); err != nil {
+		q.txMu.Lock()
+		delete(q.open, order.seq)
+		q.txMu.Unlock()
+		return err
+	}
+	tx.msgs = append(tx.msgs, txMessage{id: order.ID, seq: order.seq})
+	return nil
+}
+
+// Commit keeps every message of the transaction
+func (tx *Tx) Commit() {
+	if tx.done {
+		return
+	}
+	tx.done = true
+	q := tx.q
+	q.txMu.Lock()
+	defer q.txMu.Unlock()
+	for _, msg := range tx.msgs {
+		delete(q.open, msg.seq)
+	}
+}
+
+// Rollback withdraws the messages of the transaction. It returns the IDs of the orders
+// it tombstoned and of those a consumer had already received, which cannot be recalled.
+func (tx *Tx) Rollback() (tombstoned, delivered []string) {
+	if tx.done {
+		return nil, nil
+	}
+	tx.done = true
+	q := tx.q
+	q.txMu.Lock()
+	defer q.txMu.Unlock()
+	for _, msg := range tx.msgs {
+		if q.open[msg.seq] {
+			delivered = append(delivered, msg.id)
+		} else {
+			q.tombstones[msg.seq] = struct{}{}
+			tombstoned = append(tombstoned, msg.id)
+		}
+		delete(q.open, msg.seq)
+	}
+	return tombstoned, delivered
+}
+
+// settle records the delivery of msg. It returns false for a tombstoned message, which
+// is dropped instead of delivered.
+func (q *SimpleQueue) settle(msg Order) bool {
+	q.txMu.Lock()
+	defer q.txMu.Unlock()
+	if _, ok := q.tombstones[msg.seq]; ok {
+		delete(q.tombstones, msg.seq)
+		return false
+	}
+	if _, ok := q.open[msg.seq]; ok {
+		q.open[msg.seq] = true
+	}
+	return true
+}
+
+// tombstoned reports whether msg was withdrawn by a rolled-back transaction
+func (q *SimpleQueue) tombstoned(msg Order) bool {
+	q.txMu.Lock()
+	defer q.txMu.Unlock()
+	_, ok := q.tombstones[msg.seq]
+	return ok
+}
@@ -0,0 +1,134 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"span-links-signoz-demo/pkg/clock"
+	"span-links-signoz-demo/pkg/queue"
+)
+
+func TestTxRollbackBeforeConsume(t *testing.T) {
+	q := queue.NewWithClock(clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)))
+	tx := q.Begin()
+	publish(t, tx.Publish, "ORDER-1", "CUST-1", "")
+	publish(t, tx.Publish, "ORDER-2", "CUST-2", queue.PriorityOrdersTopic)
+
+	tombstoned, delivered := tx.Rollback()
+	if !slices.Equal(tombstoned, []string{"ORDER-1", "ORDER-2"}) || len(delivered) != 0 {
+		t.Fatalf("rollback tombstoned %v and found %v delivered, want both tombstoned", tombstoned, delivered)
+	}
+	if tombstoned, delivered := tx.Rollback(); tombstoned != nil || delivered != nil {
+		t.Errorf("second rollback returned %v, %v, want nothing", tombstoned, delivered)
+	}
+	assertEmpty(t, q, queue.DefaultTopic, queue.PriorityOrdersTopic)
+
+	// The topics still work for later messages
+	publish(t, q.Publish, "ORDER-3", "CUST-3", "")
+	if got := consumeID(t, q); got != "ORDER-3" {
+		t.Errorf("consumed %q after the rollback, want ORDER-3", got)
+	}
+}
+
+func TestTxRollbackAfterPartialConsume(t *testing.T) {
+	q := queue.NewWithClock(clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)))
+	tx := q.Begin()
+	publish(t, tx.Publish, "ORDER-1", "CUST-1", "")
+	publish(t, tx.Publish, "ORDER-2", "CUST-2", "")
+	if got := consumeID(t, q); got != "ORDER-1" {
+		t.Fatalf("consumed %q, want ORDER-1", got)
+	}
+
+	tombstoned, delivered := tx.Rollback()
+	if !slices.Equal(tombstoned, []string{"ORDER-2"}) || !slices.Equal(delivered, []string{"ORDER-1"}) {
+		t.Fatalf("rollback tombstoned %v and found %v delivered, want ORDER-2 tombstoned and ORDER-1 delivered", tombstoned, delivered)
+	}
+	assertEmpty(t, q, queue.DefaultTopic)
+}
+
+// TestTxRollbackKeepsDuplicates checks a rollback only withdraws the transaction's own
+// messages, not other publishes of the same order ID
+func TestTxRollbackKeepsDuplicates(t *testing.T) {
+	q := queue.NewWithClock(clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)))
+	tx := q.Begin()
+	publish(t, tx.Publish, "ORDER-1", "CUST-TX", "")
+	publish(t, q.Publish, "ORDER-1", "CUST-DUP", "")
+	if tombstoned, _ := tx.Rollback(); !slices.Equal(tombstoned, []string{"ORDER-1"}) {
+		t.Fatalf("rollback tombstoned %v, want ORDER-1", tombstoned)
+	}
+
+	if n := q.Length(); n != 1 {
+		t.Errorf("Length() = %d, want the duplicate only", n)
+	}
+	pending := q.Pending()
+	if len(pending) != 1 || pending[0].CustomerID != "CUST-DUP" {
+		t.Errorf("Pending() = %+v, want the duplicate only", pending)
+	}
+	taken := q.TakeAll()
+	if len(taken) != 1 || taken[0].CustomerID != "CUST-DUP" {
+		t.Errorf("TakeAll() = %+v, want the duplicate only", taken)
+	}
+	assertEmpty(t, q, queue.DefaultTopic)
+}
+
+func TestTxCommit(t *testing.T) {
+	q := queue.NewWithClock(clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)))
+	tx := q.Begin()
+	publish(t, tx.Publish, "ORDER-1", "CUST-1", "")
+	publish(t, tx.Publish, "ORDER-2", "CUST-2", "")
+	tx.Commit()
+	if tombstoned, delivered := tx.Rollback(); tombstoned != nil || delivered != nil {
+		t.Errorf("rollback after commit returned %v, %v, want nothing", tombstoned, delivered)
+	}
+
+	if n := q.Length(); n != 2 {
+		t.Errorf("Length() = %d, want 2", n)
+	}
+	if pending := q.Pending(); len(pending) != 2 {
+		t.Errorf("Pending() lists %d messages, want 2", len(pending))
+	}
+	for _, want := range []string{"ORDER-1", "ORDER-2"} {
+		if got := consumeID(t, q); got != want {
+			t.Errorf("consumed %q, want %q", got, want)
+		}
+	}
+}
+
+func publish(t *testing.T, fn func(context.Context, queue.Order) error, id, customerID, topic string) {
+	t.Helper()
+	if err := fn(context.Background(), queue.Order{ID: id, CustomerID: customerID, Amount: 10, Topic: topic}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func consumeID(t *testing.T, q *queue.SimpleQueue) string {
+	t.Helper()
+	order, err := q.Consume(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return order.ID
+}
+
+// assertEmpty checks no message is left for consumers on topics, by every account of
+// the queue
+func assertEmpty(t *testing.T, q *queue.SimpleQueue, topics ...string) {
+	t.Helper()
+	if n := q.Length(); n != 0 {
+		t.Errorf("Length() = %d, want 0", n)
+	}
+	if pending := q.Pending(); len(pending) != 0 {
+		t.Errorf("Pending() = %+v, want none", pending)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if order, err := q.Consume(ctx, topics...); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("consumed %q (err %v), want nothing", order.ID, err)
+	}
+	if taken := q.TakeAll(); len(taken) != 0 {
+		t.Errorf("TakeAll() = %+v, want none", taken)
+	}
+}
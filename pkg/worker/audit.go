@@ -0,0 +1,22 @@
+package worker
+
+import (
+	"context"
+
+	"span-links-signoz-demo/pkg/queue"
+)
+
+// WithAuditRecorder reports the validated, paid and shipped transitions of every order to
+// recorder, each caused by its step span
+func WithAuditRecorder(recorder queue.AuditRecorder) Option {
+	return func(w *Service) { w.audit = recorder }
+}
+
+// recordTransition reports that order reached state, caused by the span in ctx
+func (w *Service) recordTransition(ctx context.Context, state string, order queue.Order) {
+	if w.audit == nil {
+		return
+	}
+	producer, _ := producerSpan(ctx)
+	w.audit.RecordTransition(ctx, state, order, producer)
+}
@@ -0,0 +1,35 @@
+package worker
+
+import (
+	"context"
+
+	"span-links-signoz-demo/pkg/queue"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// producerSpanKey carries the producer span context extracted from the order being processed
+type producerSpanKey struct{}
+
+// withProducerSpan makes the producer span context of the order available to its steps
+// (audit records, log lines)
+func withProducerSpan(ctx context.Context, producer trace.SpanContext) context.Context {
+	return context.WithValue(ctx, producerSpanKey{}, producer)
+}
+
+// producerSpan returns the producer span context carried by ctx, if any
+func producerSpan(ctx context.Context) (trace.SpanContext, bool) {
+	producer, ok := ctx.Value(producerSpanKey{}).(trace.SpanContext)
+	return producer, ok
+}
+
+// logFields returns the correlation fields of a log line about order: its order.id and
+// the producer span its processing links to. Outside processing (ctx without the
+// extracted context) the link target is read from the message headers.
+func logFields(ctx context.Context, order queue.Order) queue.LogFields {
+	producer, ok := producerSpan(ctx)
+	if !ok {
+		producer, _, _ = queue.ExtractProducerContext(order, false)
+	}
+	return queue.OrderLogFields(order).Link(producer)
+}
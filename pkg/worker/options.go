@@ -0,0 +1,226 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"span-links-signoz-demo/pkg/clock"
+	"span-links-signoz-demo/pkg/queue"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LinkMode selects how a ProcessOrder span relates to the PublishOrder span of its order
+type LinkMode string
+
+// Link modes
+const (
+	LinkModeLink   LinkMode = "link"   // new trace with a queue_consumption link (default)
+	LinkModeParent LinkMode = "parent" // child span in the producer's trace, no link
+	LinkModeNone   LinkMode = "none"   // new trace with no relationship, for comparison
+)
+
+// ParseLinkMode parses a link mode name; "" means LinkModeLink
+func ParseLinkMode(s string) (LinkMode, error) {
+	switch LinkMode(s) {
+	case "", LinkModeLink:
+		return LinkModeLink, nil
+	case LinkModeParent, LinkModeNone:
+		return LinkMode(s), nil
+	default:
+		return "", fmt.Errorf("unknown link mode %q (expected link, parent or none)", s)
+	}
+}
+
+// DefaultRetryBackoff is the wait before the first retry of a failed processing step
+const DefaultRetryBackoff = 50 * time.Millisecond
+
+// RetryPolicy controls how often a failed processing step (validate, payment, shipping,
+// persist) is attempted before the order fails. Each attempt gets its own step span and
+// every retry adds a "step.retry" event to the ProcessOrder span. Invalid orders and
+// panics are never retried.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts per step; 1 or less disables retries
+	Backoff     time.Duration // wait before the first retry
+	Multiplier  float64       // backoff growth per retry; below 1 keeps it constant
+}
+
+// ErrInvalidOrder marks validation failures that retrying cannot fix
+var ErrInvalidOrder = errors.New("invalid order")
+
+// Option customizes a Service at construction
+type Option func(*Service)
+
+// WithTracer sets the tracer worker spans are started with (default: the global
+// provider's "worker-service" tracer)
+func WithTracer(tracer trace.Tracer) Option {
+	return func(w *Service) { w.tracer = tracer }
+}
+
+// WithLinkMode sets how consumer spans relate to the producer span (default LinkModeLink);
+// SetLinkMode changes it mid-run
+func WithLinkMode(mode LinkMode) Option {
+	return func(w *Service) { w.linkMode = mode }
+}
+
+// WithRetryPolicy retries failed processing steps according to policy (default: no retries)
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(w *Service) { w.retry = policy }
+}
+
+// WithRedelivery puts orders that fail processing back on the queue, up to max times per
+// order (default 0, failed orders are dropped). Redelivered messages keep their original
+// traceparent, so every attempt links to the same publish.
+func WithRedelivery(max int) Option {
+	return func(w *Service) { w.redeliveries = max }
+}
+
+// WithStepMode records step (StepLookup, StepValidate, ...) as a span (default), as an
+// event on ProcessOrder, or not at all, to trade span count against detail
+func WithStepMode(step string, mode StepMode) Option {
+	return func(w *Service) {
+		if w.stepModes == nil {
+			w.stepModes = make(map[string]StepMode)
+		}
+		w.stepModes[step] = mode
+	}
+}
+
+// WithSpanAttributes adds attrs to every ProcessOrder span, to tell apart consumers
+// sharing one tracer (e.g. the two sides of a comparison run)
+func WithSpanAttributes(attrs ...attribute.KeyValue) Option {
+	return func(w *Service) { w.spanAttrs = append(w.spanAttrs, attrs...) }
+}
+
+// WithClock sets the clock used for step durations, retry backoff, latency, lag and
+// heartbeat intervals (default: the queue's clock). A clock.Fake fires heartbeats back to
+// back, so leave them off with one.
+func WithClock(c clock.Clock) Option {
+	return func(w *Service) { w.clock = c }
+}
+
+// WithSpanNamePrefix prepends prefix to the pipeline span names (ProcessOrder, the step
+// spans, ReceiveOrder), after any SpanNameTemplate is applied
+func WithSpanNamePrefix(prefix string) Option {
+	return func(w *Service) { w.namePrefix = prefix }
+}
+
+// WithSpanContextSink emits finished processing span contexts on ch (used for the
+// forward-link demo): every successful processing, and failed processing that will not
+// be redelivered
+func WithSpanContextSink(ch chan OrderSpanContext) Option {
+	return func(w *Service) { w.spanCtxSink = ch }
+}
+
+// WithOrderRegistry records each processed order's span context in registry
+func WithOrderRegistry(registry Registry) Option {
+	return func(w *Service) { w.registry = registry }
+}
+
+// WithOrderingWindows groups processed orders into per-customer OrderingWindow spans
+// through windows
+func WithOrderingWindows(windows ProcessedRecorder) Option {
+	return func(w *Service) { w.ordering = windows }
+}
+
+// WithStrictTraceParent enables strict W3C traceparent validation (see ParseTraceParent).
+// A traceparent that fails it is skipped in favour of the B3 headers and legacy fields
+// (see ExtractProducerContext); when none of them has a context the failure is recorded
+// as a "link_parse_failed" event on the consumer span and no link is created. Without it
+// only a traceparent that yields no context at all fails.
+func WithStrictTraceParent(enabled bool) Option {
+	return func(w *Service) { w.strictParse = enabled }
+}
+
+// WithRunStats updates the live run counters in stats for every processed order
+func WithRunStats(stats Stats) Option {
+	return func(w *Service) { w.stats = stats }
+}
+
+// WithSpanNameTemplate sets the template used to name ProcessOrder and step spans
+func WithSpanNameTemplate(tmpl queue.SpanNameTemplate) Option {
+	return func(w *Service) { w.spanNames = tmpl }
+}
+
+// WithHeartbeatInterval emits a WorkerHeartbeat span per worker every interval (default
+// 0, no heartbeats)
+func WithHeartbeatInterval(interval time.Duration) Option {
+	return func(w *Service) { w.heartbeat = interval }
+}
+
+// WithSemconvSpanKinds switches to messaging-semconv span kinds: a short SpanKindConsumer
+// ReceiveOrder span carries the producer link, and ProcessOrder becomes its
+// SpanKindInternal child.
+func WithSemconvSpanKinds(enabled bool) Option {
+	return func(w *Service) { w.semconvKinds = enabled }
+}
+
+// WithBackfillQueueWait records consumer spans as backfilled history: ProcessOrder (and
+// its children) get explicit timestamps starting at publish time plus wait, instead of
+// the wall-clock time the worker actually picked the order up. Zero disables it.
+func WithBackfillQueueWait(wait time.Duration) Option {
+	return func(w *Service) { w.backfillWait = wait }
+}
+
+// WithClockSkew simulates a consumer host whose clock is off by skew: every consumer span
+// timestamp is shifted by it (negative values make processing appear to start before
+// the order was published). Zero disables it.
+func WithClockSkew(skew time.Duration) Option {
+	return func(w *Service) { w.clockSkew = skew }
+}
+
+// WithLagAlertThreshold flags orders whose consumer lag (pickup time minus CreatedAt)
+// exceeds threshold with a LagAlert span linking to their consumer span. Zero disables it;
+// the lag is recorded on every ProcessOrder span and histogram regardless.
+func WithLagAlertThreshold(threshold time.Duration) Option {
+	return func(w *Service) { w.lagThreshold = threshold }
+}
+
+// WithLatencyBudget sets the end-to-end (publish → processed) latency budget per order.
+// Orders exceeding it emit an SLOBreach span. Zero disables the check.
+func WithLatencyBudget(budget time.Duration) Option {
+	return func(w *Service) { w.latencyBudget = budget }
+}
+
+// WithDownstream makes payment and shipping call the fake downstream services over HTTP
+// instead of sleeping, adding client/server spans under the step spans
+func WithDownstream(d Downstream) Option {
+	return func(w *Service) { w.downstream = d }
+}
+
+// WithOrderStore adds a PersistOrder step that saves each shipped order to store, so the
+// consumer trace includes otelsql DB spans
+func WithOrderStore(store Store) Option {
+	return func(w *Service) { w.store = store }
+}
+
+// WithCustomerCache adds a LookupCustomer step before validation that reads the customer
+// profile through cache, recording the hit or miss
+func WithCustomerCache(cache CustomerCache) Option {
+	return func(w *Service) { w.cache = cache }
+}
+
+// WithOutcomeRecorder reports the outcome of every processed order to outcomes
+func WithOutcomeRecorder(outcomes OutcomeRecorder) Option {
+	return func(w *Service) { w.outcomes = outcomes }
+}
+
+// WithAckBatcher acknowledges processed orders in batches through acks
+func WithAckBatcher(acks ProcessedRecorder) Option {
+	return func(w *Service) { w.acks = acks }
+}
+
+// WithPanicRate makes payment processing panic for the given fraction (0..1) of orders,
+// to exercise panic recovery. Zero disables it.
+func WithPanicRate(rate float64) Option {
+	return func(w *Service) { w.panicRate = rate }
+}
+
+// WithFailureRate makes each processing step (validate, payment, shipping) fail with the
+// given probability (0..1), to exercise ErrorReport spans (default 0); SetFailureRate
+// changes it mid-run
+func WithFailureRate(rate float64) Option {
+	return func(w *Service) { w.failureRate = rate }
+}
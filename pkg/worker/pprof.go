@@ -0,0 +1,24 @@
+package worker
+
+import (
+	"context"
+	runtimepprof "runtime/pprof"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// setProfileLabels tags the calling goroutine's CPU samples with the trace and span IDs
+// of spanCtx, so profile samples can be attributed to specific traces. The returned
+// function restores the labels carried by ctx.
+func setProfileLabels(ctx context.Context, spanCtx trace.SpanContext) func() {
+	if !spanCtx.IsValid() {
+		return func() {}
+	}
+	runtimepprof.SetGoroutineLabels(runtimepprof.WithLabels(ctx, runtimepprof.Labels(
+		"trace_id", spanCtx.TraceID().String(),
+		"span_id", spanCtx.SpanID().String(),
+	)))
+	return func() {
+		runtimepprof.SetGoroutineLabels(ctx)
+	}
+}
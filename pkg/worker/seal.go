@@ -0,0 +1,59 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+
+	"span-links-signoz-demo/pkg/queue"
+	"span-links-signoz-demo/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithSealer verifies and decrypts sealed orders with s. Without one, sealed orders are
+// rejected.
+func WithSealer(s *queue.Sealer) Option {
+	return func(w *Service) { w.sealer = s }
+}
+
+// openPayload verifies and decrypts a sealed order under VerifyPayload and DecryptPayload
+// spans, children of the ProcessOrder span in ctx, and decodes the result
+func (w *Service) openPayload(ctx context.Context, order queue.Order) (queue.Order, error) {
+	keyID := attribute.String("crypto.key_id", order.Header(queue.PayloadKeyIDHeader))
+
+	_, verifySpan := w.startSpan(ctx, "VerifyPayload",
+		trace.WithAttributes(
+			attribute.String("order.id", order.ID),
+			attribute.String("crypto.algorithm", queue.SealSignature),
+			keyID,
+		),
+	)
+	err := queue.ErrPayloadKey
+	if w.sealer != nil {
+		err = w.sealer.Verify(order)
+	}
+	verifySpan.SetAttributes(attribute.Bool("crypto.verified", err == nil))
+	telemetry.EndWithStatus(verifySpan, err)
+	if err != nil {
+		return order, err
+	}
+
+	_, decryptSpan := w.startSpan(ctx, "DecryptPayload",
+		trace.WithAttributes(
+			attribute.String("order.id", order.ID),
+			attribute.String("crypto.algorithm", queue.SealCipher),
+			keyID,
+			attribute.Int("payload.sealed_bytes", len(order.Sealed)),
+		),
+	)
+	order, err = w.sealer.Decrypt(order)
+	if err == nil {
+		order, err = queue.Normalize(order)
+		if err != nil {
+			err = fmt.Errorf("failed to decode order %s: %w", order.ID, err)
+		}
+	}
+	telemetry.EndWithStatus(decryptSpan, err)
+	return order, err
+}
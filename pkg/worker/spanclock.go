@@ -0,0 +1,50 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"span-links-signoz-demo/pkg/clock"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// clockOffsetKey carries the clock shift applied to consumer spans (backfill, clock skew)
+type clockOffsetKey struct{}
+
+// withClockOffset makes spans started via Service.startSpan record timestamps
+// shifted by offset, so a whole consumer subtree can be placed at a historical time or
+// on a host whose clock is off.
+func withClockOffset(ctx context.Context, offset time.Duration) context.Context {
+	return context.WithValue(ctx, clockOffsetKey{}, offset)
+}
+
+// clockOffset returns the clock shift carried by ctx, if any
+func clockOffset(ctx context.Context) (time.Duration, bool) {
+	offset, ok := ctx.Value(clockOffsetKey{}).(time.Duration)
+	return offset, ok
+}
+
+// shiftedSpan ends the wrapped span at the shifted time, keeping its duration intact
+type shiftedSpan struct {
+	trace.Span
+	offset time.Duration
+	clock  clock.Clock
+}
+
+// End ends the span with an explicit timestamp shifted by the clock offset
+func (s shiftedSpan) End(options ...trace.SpanEndOption) {
+	s.Span.End(append([]trace.SpanEndOption{trace.WithTimestamp(s.clock.Now().Add(s.offset))}, options...)...)
+}
+
+// startSpan starts a worker span; when ctx carries a clock offset the span gets an
+// explicit start timestamp (trace.WithTimestamp) and is ended at the same shifted clock.
+func (w *Service) startSpan(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	offset, ok := clockOffset(ctx)
+	if !ok {
+		return w.tracer.Start(ctx, name, opts...)
+	}
+	opts = append(opts, trace.WithTimestamp(w.clock.Now().Add(offset)))
+	ctx, span := w.tracer.Start(ctx, name, opts...)
+	return ctx, shiftedSpan{Span: span, offset: offset, clock: w.clock}
+}
@@ -0,0 +1,118 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Processing steps, as used by WithStepMode and the error.step attribute
+const (
+	StepLookup   = "lookup"
+	StepValidate = "validate"
+	StepPayment  = "payment"
+	StepShipping = "shipping"
+	StepPersist  = "persist"
+)
+
+// Steps lists the processing steps in pipeline order
+var Steps = []string{StepLookup, StepValidate, StepPayment, StepShipping, StepPersist}
+
+// StepMode selects how a processing step shows up in the consumer trace
+type StepMode string
+
+// Step modes
+const (
+	StepAsSpan  StepMode = "span"  // a child span of ProcessOrder (default)
+	StepAsEvent StepMode = "event" // a span event on ProcessOrder carrying the step's attributes and duration
+	StepOff     StepMode = "off"   // not recorded; failures still surface on ProcessOrder
+)
+
+// ParseStepMode parses a step mode name; "" means StepAsSpan
+func ParseStepMode(s string) (StepMode, error) {
+	switch StepMode(s) {
+	case "", StepAsSpan:
+		return StepAsSpan, nil
+	case StepAsEvent, StepOff:
+		return StepMode(s), nil
+	default:
+		return "", fmt.Errorf("unknown step mode %q (expected span, event or off)", s)
+	}
+}
+
+// startStep starts the telemetry of a processing step according to its mode. In span
+// mode it is a regular child span. Otherwise the returned span stands in for it: ctx is
+// unchanged (downstream spans attach to ProcessOrder), SpanContext is the parent's, and in
+// event mode End adds a span event named after the step to the parent instead.
+func (w *Service) startStep(ctx context.Context, step, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	mode := w.stepModes[step]
+	if mode == "" || mode == StepAsSpan {
+		return w.startSpan(ctx, name, opts...)
+	}
+
+	cfg := trace.NewSpanStartConfig(opts...)
+	began := w.clock.Now()
+	start := began
+	if offset, ok := clockOffset(ctx); ok {
+		start = began.Add(offset)
+	}
+	return ctx, &stepEvent{
+		Span:  trace.SpanFromContext(ctx),
+		name:  name,
+		emit:  mode == StepAsEvent,
+		start: start,
+		began: began,
+		now:   w.clock.Now,
+		attrs: cfg.Attributes(),
+	}
+}
+
+// stepEvent is the stand-in span of a step recorded as an event (or not at all). It
+// collects what would have gone on the step span and, on End, records it as one event on
+// the parent span. Methods it does not override act on the parent.
+type stepEvent struct {
+	trace.Span // the parent (ProcessOrder) span
+	name       string
+	emit       bool
+	start      time.Time // event timestamp, shifted like the step span would be
+	began      time.Time
+	now        func() time.Time
+	attrs      []attribute.KeyValue
+	err        error
+	failed     bool
+}
+
+// SetAttributes collects attributes for the event
+func (s *stepEvent) SetAttributes(kv ...attribute.KeyValue) { s.attrs = append(s.attrs, kv...) }
+
+// RecordError remembers err for the event
+func (s *stepEvent) RecordError(err error, _ ...trace.EventOption) { s.err = err }
+
+// SetStatus remembers an error status for the event
+func (s *stepEvent) SetStatus(code codes.Code, _ string) { s.failed = code == codes.Error }
+
+// SetName is ignored: the stand-in must not rename the parent span
+func (s *stepEvent) SetName(string) {}
+
+// AddEvent is ignored: step-level events have no place to go
+func (s *stepEvent) AddEvent(string, ...trace.EventOption) {}
+
+// End adds the step event to the parent span (event mode only)
+func (s *stepEvent) End(...trace.SpanEndOption) {
+	if !s.emit {
+		return
+	}
+	attrs := append(s.attrs,
+		attribute.String("step.name", s.name),
+		attribute.Int64("step.duration_ms", s.now().Sub(s.began).Milliseconds()),
+		attribute.Bool("step.failed", s.failed || s.err != nil),
+	)
+	if s.err != nil {
+		attrs = append(attrs, attribute.String("exception.message", s.err.Error()))
+	}
+	s.Span.AddEvent(s.name, trace.WithTimestamp(s.start), trace.WithAttributes(attrs...))
+}
@@ -0,0 +1,937 @@
+// Package worker consumes orders from the queue: every ProcessOrder span starts a new
+// trace that links back to the PublishOrder span carried in the message headers.
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"span-links-signoz-demo/pkg/clock"
+	"span-links-signoz-demo/pkg/queue"
+	"span-links-signoz-demo/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Processing timeouts for order processing steps
+const (
+	ValidationTimeout = 100 * time.Millisecond
+	PaymentTimeout    = 150 * time.Millisecond
+	ShippingTimeout   = 120 * time.Millisecond
+)
+
+// Registry records the ProcessOrder span context of each processed order
+type Registry interface {
+	RecordProcess(orderID string, spanCtx trace.SpanContext)
+}
+
+// Stats counts processed and failed orders and deliveries whose producer context could
+// not be parsed, and remembers recent traces
+type Stats interface {
+	IncProcessed(workerID string)
+	IncFailed()
+	IncLinkParseFailed()
+	RecordTrace(summary TraceSummary)
+}
+
+// ProcessedRecorder receives every successfully processed order with its ProcessOrder
+// span context (ordering windows, batched acknowledgment)
+type ProcessedRecorder interface {
+	Record(workerID string, order queue.Order, spanCtx trace.SpanContext)
+}
+
+// OutcomeRecorder receives the outcome of every ProcessOrder span (error budget report)
+type OutcomeRecorder interface {
+	RecordOutcome(outcome OrderOutcome)
+}
+
+// OrderOutcome is how processing one delivery of an order ended
+type OrderOutcome struct {
+	OrderID  string
+	WorkerID string
+	SpanCtx  trace.SpanContext // the ProcessOrder span
+	Duration time.Duration
+	Err      error // nil when the order was processed successfully
+}
+
+// Downstream performs the payment and shipping calls of an order
+type Downstream interface {
+	Charge(ctx context.Context, order queue.Order) error
+	Ship(ctx context.Context, order queue.Order) error
+}
+
+// Store persists processed orders
+type Store interface {
+	SaveOrder(ctx context.Context, order queue.Order, workerID string) error
+}
+
+// CustomerCache looks up customer profiles
+type CustomerCache interface {
+	Lookup(ctx context.Context, customerID string) (profile string, hit bool, err error)
+	System() string // cache.system attribute value
+}
+
+// ConsumeFunc returns the next order for a worker, blocking until one is available or
+// ctx is done
+type ConsumeFunc func(ctx context.Context) (queue.Order, error)
+
+// TraceSummary describes a recently processed order's trace and the span contexts
+// its ProcessOrder span links to
+type TraceSummary struct {
+	OrderID     string   `json:"order_id"`
+	WorkerID    string   `json:"worker_id"`
+	TraceID     string   `json:"trace_id"`
+	SpanID      string   `json:"span_id"`
+	LinkTargets []string `json:"link_targets"` // "<trace-id>/<span-id>"
+}
+
+// Service processes orders from the queue with observability instrumentation
+type Service struct {
+	queue         *queue.SimpleQueue
+	tracer        trace.Tracer
+	activeOrders  int64
+	spanCtxSink   chan OrderSpanContext
+	latencyBudget time.Duration
+	sloBreaches   metric.Int64Counter
+	consumerLag   metric.Float64Histogram
+	processed     metric.Int64Counter
+	processingMs  metric.Float64Histogram
+	lagThreshold  time.Duration
+	downstream    Downstream
+	store         Store
+	cache         CustomerCache
+	acks          ProcessedRecorder
+	inFlight      sync.Map // workerID -> InFlightOrder being processed
+	registry      Registry
+	strictParse   bool
+	spanNames     queue.SpanNameTemplate
+	stats         Stats
+	heartbeat     time.Duration
+	panicRate     float64
+	semconvKinds  bool
+	backfillWait  time.Duration
+	clockSkew     time.Duration
+	ordering      ProcessedRecorder
+	retry         RetryPolicy
+	tunablesMu    sync.RWMutex // guards the settings below, which can change mid-run
+	failureRate   float64
+	linkMode      LinkMode
+	clock         clock.Clock
+	namePrefix    string
+	redeliveries  int // max redeliveries per failed order
+	stepModes     map[string]StepMode
+	spanAttrs     []attribute.KeyValue
+	outcomes      OutcomeRecorder
+	sealer        *queue.Sealer
+	audit         queue.AuditRecorder
+}
+
+// InFlightOrder is the order a worker is currently processing
+type InFlightOrder struct {
+	OrderID string
+	SpanCtx trace.SpanContext
+	Started time.Time
+}
+
+// OrderSpanContext is used to emit consumer span contexts back to the producer, with the
+// outcome of the processing they describe.
+type OrderSpanContext struct {
+	OrderID  string
+	Ctx      trace.SpanContext
+	Duration time.Duration
+	Err      error // nil when the order was processed successfully
+}
+
+// New creates a new worker service consuming from q, with metrics instrumentation,
+// customized by opts
+func New(q *queue.SimpleQueue, opts ...Option) *Service {
+	meter := otel.Meter("worker-service")
+	sloBreaches, err := meter.Int64Counter(telemetry.MetricOrdersSLOBreaches,
+		metric.WithDescription("Orders whose publish-to-processed latency exceeded the latency budget"),
+		metric.WithUnit("{order}"),
+	)
+	if err != nil {
+		log.Printf("Failed to create SLO breach counter: %v", err)
+	}
+	consumerLag, err := meter.Float64Histogram(telemetry.MetricOrdersConsumerLag,
+		metric.WithDescription("Time between an order being created and a worker picking it up"),
+		metric.WithUnit("ms"),
+	)
+	if err != nil {
+		log.Printf("Failed to create consumer lag histogram: %v", err)
+	}
+	processed, err := meter.Int64Counter(telemetry.MetricOrdersProcessed,
+		metric.WithDescription("Orders processed, by outcome"),
+		metric.WithUnit("{order}"),
+	)
+	if err != nil {
+		log.Printf("Failed to create processed orders counter: %v", err)
+	}
+	processingMs, err := meter.Float64Histogram(telemetry.MetricOrdersProcessingDuration,
+		metric.WithDescription("Time a worker spent processing an order, by outcome"),
+		metric.WithUnit("ms"),
+	)
+	if err != nil {
+		log.Printf("Failed to create processing duration histogram: %v", err)
+	}
+
+	w := &Service{
+		queue:        q,
+		tracer:       otel.Tracer("worker-service"),
+		sloBreaches:  sloBreaches,
+		consumerLag:  consumerLag,
+		processed:    processed,
+		processingMs: processingMs,
+		linkMode:     LinkModeLink,
+		clock:        q.Clock(),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// ActiveOrders returns the number of orders currently being processed
+func (w *Service) ActiveOrders() int64 {
+	return atomic.LoadInt64(&w.activeOrders)
+}
+
+// SetFailureRate changes the injected step failure probability (see WithFailureRate) while
+// the worker runs
+func (w *Service) SetFailureRate(rate float64) {
+	w.tunablesMu.Lock()
+	defer w.tunablesMu.Unlock()
+	w.failureRate = rate
+}
+
+// FailureRate returns the current injected step failure probability
+func (w *Service) FailureRate() float64 {
+	w.tunablesMu.RLock()
+	defer w.tunablesMu.RUnlock()
+	return w.failureRate
+}
+
+// SetLinkMode changes how consumer spans relate to the producer span; orders already
+// being processed keep the mode they started with
+func (w *Service) SetLinkMode(mode LinkMode) {
+	w.tunablesMu.Lock()
+	defer w.tunablesMu.Unlock()
+	w.linkMode = mode
+}
+
+// LinkMode returns the current link mode
+func (w *Service) LinkMode() LinkMode {
+	w.tunablesMu.RLock()
+	defer w.tunablesMu.RUnlock()
+	return w.linkMode
+}
+
+// ProcessOrders continuously consumes and processes orders from the given topics
+// (DefaultTopic when none are given)
+func (w *Service) ProcessOrders(ctx context.Context, workerID string, topics ...string) {
+	w.consumeLoop(ctx, workerID, func(ctx context.Context) (queue.Order, error) {
+		return w.queue.Consume(ctx, topics...)
+	})
+}
+
+// ProcessFrom processes the orders consume returns for workerID until ctx is done, for
+// dispatchers other than the queue itself (consumer groups, key-affinity routing)
+func (w *Service) ProcessFrom(ctx context.Context, workerID string, consume ConsumeFunc) {
+	w.consumeLoop(ctx, workerID, consume)
+}
+
+// InFlightSpan returns the ProcessOrder span context workerID is currently working on, if any
+func (w *Service) InFlightSpan(workerID string) (trace.SpanContext, bool) {
+	v, ok := w.inFlight.Load(workerID)
+	if !ok {
+		return trace.SpanContext{}, false
+	}
+	return v.(InFlightOrder).SpanCtx, true
+}
+
+// InFlightOrders returns the order each busy worker is currently processing, keyed by worker ID
+func (w *Service) InFlightOrders() map[string]InFlightOrder {
+	orders := make(map[string]InFlightOrder)
+	w.inFlight.Range(func(k, v any) bool {
+		orders[k.(string)] = v.(InFlightOrder)
+		return true
+	})
+	return orders
+}
+
+// consumeLoop processes orders returned by consume until ctx is done
+func (w *Service) consumeLoop(ctx context.Context, workerID string, consume ConsumeFunc) {
+	if w.heartbeat > 0 {
+		go w.runHeartbeats(ctx, workerID)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			order, err := consume(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				continue
+			}
+
+			if err := w.processOrderWithLink(ctx, order, workerID); err != nil {
+				log.Printf("Failed to process order (%s): %v", logFields(ctx, order).With("worker.id", workerID), err)
+				if w.stats != nil {
+					w.stats.IncFailed()
+				}
+				w.maybeRedeliver(ctx, order)
+			}
+		}
+	}
+}
+
+// maybeRedeliver puts a failed order back on the queue unless it has used up its redeliveries
+func (w *Service) maybeRedeliver(ctx context.Context, order queue.Order) {
+	if order.RedeliveryCount() >= w.redeliveries {
+		return
+	}
+	if err := w.queue.Redeliver(ctx, order); err != nil {
+		log.Printf("Failed to redeliver order (%s): %v", logFields(ctx, order), err)
+		return
+	}
+	log.Printf("Order redelivered (%s)", logFields(ctx, order).With("redelivery", order.RedeliveryCount()+1))
+}
+
+// processOrderWithLink processes an order and creates a span link to the producer span
+func (w *Service) processOrderWithLink(ctx context.Context, order queue.Order, workerID string) (err error) {
+	if order.ID == "" {
+		return errors.New("order ID is required")
+	}
+
+	// Decode v1 (float dollars) and v2 (cents + currency) messages into one in-memory form;
+	// sealed messages are decoded once opened under the ProcessOrder span
+	sealed := order.IsSealed()
+	if !sealed {
+		order, err = queue.Normalize(order)
+		if err != nil {
+			return fmt.Errorf("failed to decode order %s: %w", order.ID, err)
+		}
+	}
+
+	startTime := w.clock.Now()
+	// W3C headers, then B3, then the legacy trace fields; LinkSourceNone when none has a context
+	originalSpanCtx, linkSource, parseErr := queue.ExtractProducerContext(order, w.strictParse)
+	ctx = withProducerSpan(ctx, originalSpanCtx)
+
+	// Backfill: shift the consumer subtree so it starts at publish time + queue wait.
+	// Clock skew: shift it further, as a host with a drifting clock would.
+	var offset time.Duration
+	backfill := w.backfillWait > 0 && !order.CreatedAt.IsZero()
+	if backfill {
+		offset = order.CreatedAt.Add(w.backfillWait).Sub(w.clock.Now())
+	}
+	if backfill || w.clockSkew != 0 {
+		ctx = withClockOffset(ctx, offset+w.clockSkew)
+	}
+
+	// Queue-level redeliveries (as opposed to step retries within one delivery) go on the
+	// consumer span and its backward link
+	delivery := []attribute.KeyValue{
+		attribute.Int("messaging.redelivery_count", order.RedeliveryCount()),
+		attribute.Int("messaging.delivery_attempt", order.RedeliveryCount()+1),
+	}
+	if tenant := order.Header(queue.TenantHeader); tenant != "" {
+		delivery = append(delivery, attribute.String("tenant.id", tenant))
+	}
+
+	// Create span link to producer span, or continue its trace in parent mode
+	linkMode := w.LinkMode()
+	var links []trace.Link
+	switch {
+	case !originalSpanCtx.IsValid() || linkMode == LinkModeNone:
+		// no relationship to the producer span
+	case linkMode == LinkModeParent:
+		ctx = trace.ContextWithRemoteSpanContext(ctx, originalSpanCtx)
+	default:
+		links = append(links, trace.Link{
+			SpanContext: originalSpanCtx,
+			Attributes: append([]attribute.KeyValue{
+				attribute.String("link.type", "queue_consumption"),
+				attribute.String("link.source", string(linkSource)),
+				attribute.String("source.service", "producer-service"),
+				attribute.String("messaging.destination.name", order.Topic),
+				attribute.String("messaging.destination.routing_key", order.RoutingKey),
+				attribute.Int("messaging.message.schema_version", order.SchemaVersion),
+			}, delivery...),
+		})
+	}
+
+	processKind := trace.SpanKindConsumer
+	processLinks := links
+	if w.semconvKinds {
+		// Semconv form: the consumer "receive" span carries the link, processing is its child
+		var receiveSpan trace.Span
+		ctx, receiveSpan = w.startSpan(ctx, w.spanName("ReceiveOrder", order.Topic),
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithLinks(links...),
+			trace.WithAttributes(append(queue.MessagingAttributes(order, "receive"),
+				attribute.String("messaging.destination.name", order.Topic),
+				attribute.String("worker.id", workerID),
+			)...),
+			trace.WithAttributes(delivery...),
+		)
+		telemetry.EndWithStatus(receiveSpan, nil)
+		processKind = trace.SpanKindInternal
+		processLinks = nil
+	}
+
+	// Start processing span with link
+	ctx, span := w.startSpan(ctx, w.spanName("ProcessOrder", order.Topic),
+		trace.WithSpanKind(processKind),
+		trace.WithLinks(processLinks...),
+		trace.WithAttributes(
+			attribute.String("order.id", order.ID),
+			attribute.String("customer.id", order.CustomerID),
+			attribute.Float64("order.amount", order.Amount),
+			attribute.String("worker.id", workerID),
+			attribute.String("messaging.destination.name", order.Topic),
+			attribute.String("messaging.destination.routing_key", order.RoutingKey),
+			attribute.Int("messaging.message.schema_version", order.SchemaVersion),
+			attribute.String("consumer.link_mode", string(linkMode)),
+			attribute.String("consumer.link_source", string(linkSource)),
+		),
+		trace.WithAttributes(delivery...),
+		trace.WithAttributes(w.spanAttrs...),
+	)
+	defer func() { telemetry.EndWithStatus(span, err) }()
+	// Registered before recoverProcessing so a recovered panic counts as a failure
+	defer func() {
+		duration := w.clock.Now().Sub(startTime)
+		w.recordProcessed(ctx, workerID, duration, err)
+		if w.outcomes != nil {
+			w.outcomes.RecordOutcome(OrderOutcome{
+				OrderID:  order.ID,
+				WorkerID: workerID,
+				SpanCtx:  span.SpanContext(),
+				Duration: duration,
+				Err:      err,
+			})
+		}
+		// Emit span context for optional forward-linking demo; a failure that will be
+		// redelivered is not the order's last word
+		if w.spanCtxSink != nil && (err == nil || order.RedeliveryCount() >= w.redeliveries) {
+			select {
+			case w.spanCtxSink <- OrderSpanContext{OrderID: order.ID, Ctx: span.SpanContext(), Duration: duration, Err: err}:
+			default:
+				// drop if channel full
+			}
+		}
+	}()
+
+	if backfill {
+		span.SetAttributes(
+			attribute.Bool("backfill", true),
+			attribute.Int64("backfill.queue_wait_ms", w.backfillWait.Milliseconds()),
+			attribute.Int64("backfill.actual_queue_wait_ms", w.clock.Now().Sub(order.CreatedAt).Milliseconds()),
+			attribute.Int64("backfill.shift_ms", offset.Milliseconds()),
+		)
+	}
+	if w.clockSkew != 0 {
+		span.SetAttributes(attribute.Int64("host.clock_skew_ms", w.clockSkew.Milliseconds()))
+	}
+
+	w.recordConsumerLag(ctx, order, startTime, originalSpanCtx, span)
+
+	// Convert panics into errors + a linked crash report; runs before the span ends
+	defer w.recoverProcessing(span, order, originalSpanCtx, workerID, &err)
+
+	if w.stats != nil {
+		targets := make([]string, 0, len(links))
+		for _, l := range links {
+			targets = append(targets, l.SpanContext.TraceID().String()+"/"+l.SpanContext.SpanID().String())
+		}
+		w.stats.RecordTrace(TraceSummary{
+			OrderID:     order.ID,
+			WorkerID:    workerID,
+			TraceID:     span.SpanContext().TraceID().String(),
+			SpanID:      span.SpanContext().SpanID().String(),
+			LinkTargets: targets,
+		})
+	}
+
+	if parseErr != nil {
+		span.AddEvent("link_parse_failed", trace.WithAttributes(
+			attribute.String("traceparent", order.Header(queue.TraceParentHeader)),
+			attribute.String("error.message", parseErr.Error()),
+		))
+		if w.stats != nil {
+			w.stats.IncLinkParseFailed()
+		}
+		log.Printf("Skipping producer link: %v (%s)", parseErr, logFields(ctx, order))
+	}
+
+	if sealed {
+		if order, err = w.openPayload(ctx, order); err != nil {
+			return fmt.Errorf("payload rejected: %w", err)
+		}
+		span.SetAttributes(attribute.Float64("order.amount", order.Amount))
+	}
+
+	// Attribute CPU profile samples of this order to its trace
+	defer setProfileLabels(ctx, span.SpanContext())()
+
+	atomic.AddInt64(&w.activeOrders, 1)
+	defer atomic.AddInt64(&w.activeOrders, -1)
+
+	w.inFlight.Store(workerID, InFlightOrder{OrderID: order.ID, SpanCtx: span.SpanContext(), Started: startTime})
+	defer w.inFlight.Delete(workerID)
+
+	log.Printf("Order processing started (%s)", logFields(ctx, order).With("worker.id", workerID).With("amount", order.Amount))
+
+	// Process order steps
+	w.lookupCustomer(ctx, order)
+
+	if err := w.withRetry(ctx, span, StepValidate, func() error { return w.validateOrder(ctx, order) }); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	if err := w.withRetry(ctx, span, StepPayment, func() error { return w.processPayment(ctx, order) }); err != nil {
+		return fmt.Errorf("payment processing failed: %w", err)
+	}
+
+	if err := w.withRetry(ctx, span, StepShipping, func() error { return w.shipOrder(ctx, order) }); err != nil {
+		return fmt.Errorf("shipping failed: %w", err)
+	}
+
+	if err := w.withRetry(ctx, span, StepPersist, func() error { return w.persistOrder(ctx, order, workerID) }); err != nil {
+		return fmt.Errorf("persistence failed: %w", err)
+	}
+
+	duration := w.clock.Now().Sub(startTime).Round(10 * time.Millisecond)
+	log.Printf("Order processing completed successfully (%s)", logFields(ctx, order).With("worker.id", workerID).With("duration", duration))
+
+	w.checkLatencyBudget(ctx, order, originalSpanCtx, span)
+
+	if w.registry != nil {
+		w.registry.RecordProcess(order.ID, span.SpanContext())
+	}
+	if w.ordering != nil {
+		w.ordering.Record(workerID, order, span.SpanContext())
+	}
+	if w.acks != nil {
+		w.acks.Record(workerID, order, span.SpanContext())
+	}
+	if w.stats != nil {
+		w.stats.IncProcessed(workerID)
+	}
+	w.queue.Ack(ctx, order)
+
+	return nil
+}
+
+// spanName names a pipeline span: the template applied to operation on topic, prefixed
+func (w *Service) spanName(operation, topic string) string {
+	return w.namePrefix + w.spanNames.Format(operation, topic)
+}
+
+// withRetry runs a processing step, retrying it per the retry policy. Each retry is
+// recorded as a "step.retry" event on span (the ProcessOrder span).
+func (w *Service) withRetry(ctx context.Context, span trace.Span, step string, run func() error) error {
+	backoff := w.retry.Backoff
+	for attempt := 1; ; attempt++ {
+		err := run()
+		if err == nil || attempt >= w.retry.MaxAttempts || errors.Is(err, ErrInvalidOrder) || ctx.Err() != nil {
+			return err
+		}
+		span.AddEvent("step.retry", trace.WithAttributes(
+			attribute.String("error.step", step),
+			attribute.Int("retry.attempt", attempt),
+			attribute.Int64("retry.backoff_ms", backoff.Milliseconds()),
+			attribute.String("exception.message", err.Error()),
+		))
+		w.clock.Sleep(backoff)
+		if w.retry.Multiplier > 1 {
+			backoff = time.Duration(float64(backoff) * w.retry.Multiplier)
+		}
+	}
+}
+
+// recoverProcessing recovers a panic raised while processing an order: the panic is
+// recorded on the (still open) ProcessOrder span with its stack, *errp is set so the span
+// ends with it as its error and the worker loop logs the failure and keeps running, and a
+// CrashReport span (new trace) carrying the stack links
+// to both the crashed span and the producer span. Must be deferred.
+func (w *Service) recoverProcessing(span trace.Span, order queue.Order, producerSpanCtx trace.SpanContext, workerID string, errp *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	err := fmt.Errorf("panic while processing order %s: %v", order.ID, r)
+	stack := string(debug.Stack())
+
+	links := []trace.Link{{
+		SpanContext: span.SpanContext(),
+		Attributes: []attribute.KeyValue{
+			attribute.String("link.type", "crash_of_processing"),
+			attribute.String("order.id", order.ID),
+		},
+	}}
+	if producerSpanCtx.IsValid() {
+		links = append(links, trace.Link{
+			SpanContext: producerSpanCtx,
+			Attributes: []attribute.KeyValue{
+				attribute.String("link.type", "crash_of_publish"),
+				attribute.String("order.id", order.ID),
+			},
+		})
+	}
+
+	_, report := w.tracer.Start(context.Background(), "CrashReport",
+		trace.WithLinks(links...),
+		trace.WithAttributes(
+			attribute.String("order.id", order.ID),
+			attribute.String("worker.id", workerID),
+			attribute.String("exception.type", "panic"),
+			attribute.String("exception.message", fmt.Sprint(r)),
+			attribute.String("exception.stacktrace", stack),
+		),
+	)
+	telemetry.EndWithStatus(report, err)
+
+	*errp = telemetry.RecordError(span, err, attribute.String("exception.stacktrace", stack))
+}
+
+// runHeartbeats emits a WorkerHeartbeat span every heartbeat interval until ctx is done.
+// Each heartbeat is its own trace and links to the ProcessOrder span the worker is
+// currently working on (if any), so stuck workers can be spotted from their heartbeats.
+// The interval is waited out on the worker's clock.
+func (w *Service) runHeartbeats(ctx context.Context, workerID string) {
+	for seq := 1; ; seq++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.clock.After(w.heartbeat):
+		}
+
+		attrs := []attribute.KeyValue{
+			attribute.String("worker.id", workerID),
+			attribute.Int64("worker.active_orders", w.ActiveOrders()),
+			attribute.Int("heartbeat.sequence", seq),
+		}
+		var links []trace.Link
+		if v, ok := w.inFlight.Load(workerID); ok {
+			current := v.(InFlightOrder)
+			busyFor := w.clock.Now().Sub(current.Started)
+			attrs = append(attrs,
+				attribute.String("worker.current_order.id", current.OrderID),
+				attribute.Int64("worker.current_order.elapsed_ms", busyFor.Milliseconds()),
+			)
+			links = append(links, trace.Link{
+				SpanContext: current.SpanCtx,
+				Attributes: []attribute.KeyValue{
+					attribute.String("link.type", "heartbeat_current_order"),
+					attribute.String("order.id", current.OrderID),
+				},
+			})
+		}
+		attrs = append(attrs, attribute.Bool("worker.busy", len(links) > 0))
+
+		_, span := w.tracer.Start(context.Background(), "WorkerHeartbeat",
+			trace.WithLinks(links...),
+			trace.WithAttributes(attrs...),
+		)
+		telemetry.EndWithStatus(span, nil)
+	}
+}
+
+// checkLatencyBudget emits an SLOBreach span (new trace) linking to both the producer
+// and consumer spans of an order whose publish → processed latency exceeded the budget.
+func (w *Service) checkLatencyBudget(ctx context.Context, order queue.Order, producerSpanCtx trace.SpanContext, consumerSpan trace.Span) {
+	if w.latencyBudget <= 0 || order.CreatedAt.IsZero() {
+		return
+	}
+
+	latency := w.clock.Now().Sub(order.CreatedAt)
+	consumerSpan.SetAttributes(
+		attribute.Int64("slo.budget_ms", w.latencyBudget.Milliseconds()),
+		attribute.Int64("slo.latency_ms", latency.Milliseconds()),
+	)
+	if latency <= w.latencyBudget {
+		return
+	}
+
+	links := []trace.Link{
+		{
+			SpanContext: consumerSpan.SpanContext(),
+			Attributes: []attribute.KeyValue{
+				attribute.String("link.type", "slo_breach_consumer"),
+				attribute.String("order.id", order.ID),
+			},
+		},
+	}
+	if producerSpanCtx.IsValid() {
+		links = append(links, trace.Link{
+			SpanContext: producerSpanCtx,
+			Attributes: []attribute.KeyValue{
+				attribute.String("link.type", "slo_breach_producer"),
+				attribute.String("order.id", order.ID),
+			},
+		})
+	}
+
+	_, breachSpan := w.tracer.Start(context.Background(), "SLOBreach",
+		trace.WithLinks(links...),
+		trace.WithAttributes(
+			attribute.String("order.id", order.ID),
+			attribute.Int64("slo.budget_ms", w.latencyBudget.Milliseconds()),
+			attribute.Int64("slo.latency_ms", latency.Milliseconds()),
+			attribute.Int64("slo.exceeded_by_ms", (latency-w.latencyBudget).Milliseconds()),
+		),
+	)
+	telemetry.EndWithStatus(breachSpan, nil)
+
+	if w.sloBreaches != nil {
+		w.sloBreaches.Add(ctx, 1)
+	}
+
+	log.Printf("Latency budget exceeded (%s)", logFields(ctx, order).With("latency", latency).With("budget", w.latencyBudget))
+}
+
+// recordConsumerLag records how long order waited before being picked up at pickedUp, as a
+// histogram sample and a messaging.consumer.lag_ms attribute on the consumer span. When
+// the lag exceeds the alert threshold, a LagAlert span (new trace) links to the consumer
+// span and, if known, the producer span.
+func (w *Service) recordConsumerLag(ctx context.Context, order queue.Order, pickedUp time.Time, producerSpanCtx trace.SpanContext, consumerSpan trace.Span) {
+	if order.CreatedAt.IsZero() {
+		return
+	}
+
+	lag := pickedUp.Sub(order.CreatedAt)
+	consumerSpan.SetAttributes(attribute.Int64("messaging.consumer.lag_ms", lag.Milliseconds()))
+	if w.consumerLag != nil {
+		w.consumerLag.Record(ctx, float64(lag)/float64(time.Millisecond),
+			metric.WithAttributes(attribute.String("messaging.destination.name", order.Topic)),
+		)
+	}
+	if w.lagThreshold <= 0 || lag <= w.lagThreshold {
+		return
+	}
+
+	consumerSpan.SetAttributes(attribute.Bool("messaging.consumer.lag_alert", true))
+	links := []trace.Link{{
+		SpanContext: consumerSpan.SpanContext(),
+		Attributes: []attribute.KeyValue{
+			attribute.String("link.type", "high_lag_consumer"),
+			attribute.String("order.id", order.ID),
+		},
+	}}
+	if producerSpanCtx.IsValid() {
+		links = append(links, trace.Link{
+			SpanContext: producerSpanCtx,
+			Attributes: []attribute.KeyValue{
+				attribute.String("link.type", "high_lag_producer"),
+				attribute.String("order.id", order.ID),
+			},
+		})
+	}
+
+	_, alertSpan := w.tracer.Start(context.Background(), "LagAlert",
+		trace.WithLinks(links...),
+		trace.WithAttributes(
+			attribute.String("order.id", order.ID),
+			attribute.Int64("messaging.consumer.lag_ms", lag.Milliseconds()),
+			attribute.Int64("lag.threshold_ms", w.lagThreshold.Milliseconds()),
+		),
+	)
+	telemetry.EndWithStatus(alertSpan, nil)
+
+	log.Printf("Consumer lag above threshold (%s)", logFields(ctx, order).With("lag", lag).With("threshold", w.lagThreshold))
+}
+
+// recordProcessed records the orders.processed and orders.processing.duration metrics of
+// one delivery
+func (w *Service) recordProcessed(ctx context.Context, workerID string, duration time.Duration, err error) {
+	outcome := telemetry.OutcomeSuccess
+	if err != nil {
+		outcome = telemetry.OutcomeFailure
+	}
+	attrs := attribute.String(telemetry.OutcomeKey, outcome)
+	if w.processed != nil {
+		w.processed.Add(ctx, 1, metric.WithAttributes(attrs, attribute.String("worker.id", workerID)))
+	}
+	if w.processingMs != nil {
+		w.processingMs.Record(ctx, float64(duration)/float64(time.Millisecond), metric.WithAttributes(attrs))
+	}
+}
+
+// injectedFailure returns an error with probability failureRate
+func (w *Service) injectedFailure(message string) error {
+	if rate := w.FailureRate(); rate > 0 && rand.Float64() < rate {
+		return errors.New(message)
+	}
+	return nil
+}
+
+// reportStepError sets err as the status of the failing step span and emits an ErrorReport span in a
+// new trace (as an error-tracking pipeline would) linking back to it. ErrorReport spans
+// carry an error.fingerprint so occurrences of the same error can be aggregated, with the
+// links leading to every failing span.
+func (w *Service) reportStepError(span trace.Span, order queue.Order, step string, err error) {
+	telemetry.SetStatus(span, err)
+
+	_, report := w.tracer.Start(context.Background(), "ErrorReport",
+		trace.WithLinks(trace.Link{
+			SpanContext: span.SpanContext(),
+			Attributes: []attribute.KeyValue{
+				attribute.String("link.type", "error_source"),
+				attribute.String("order.id", order.ID),
+			},
+		}),
+		trace.WithAttributes(
+			attribute.String("order.id", order.ID),
+			attribute.String("error.step", step),
+			attribute.String("error.fingerprint", step+": "+err.Error()),
+			attribute.String("exception.message", err.Error()),
+		),
+	)
+	telemetry.EndWithStatus(report, err)
+}
+
+// validateOrder validates the order
+func (w *Service) validateOrder(ctx context.Context, order queue.Order) error {
+	ctx, span := w.startStep(ctx, StepValidate, w.spanName("ValidateOrder", order.Topic))
+	defer span.End()
+
+	w.clock.Sleep(ValidationTimeout)
+
+	if order.Amount <= 0 {
+		err := fmt.Errorf("%w: amount %.2f", ErrInvalidOrder, order.Amount)
+		w.reportStepError(span, order, StepValidate, err)
+		return err
+	}
+	if err := w.injectedFailure("validation rules service unavailable"); err != nil {
+		w.reportStepError(span, order, StepValidate, err)
+		return err
+	}
+	telemetry.SetStatus(span, nil)
+	w.recordTransition(ctx, queue.OrderValidated, order)
+	return nil
+}
+
+// processPayment processes payment for the order
+func (w *Service) processPayment(ctx context.Context, order queue.Order) error {
+	ctx, span := w.startStep(ctx, StepPayment, w.spanName("ProcessPayment", order.Topic),
+		trace.WithAttributes(
+			attribute.Float64("payment.amount", order.Amount),
+		),
+	)
+	defer span.End()
+
+	if w.downstream != nil {
+		if err := w.downstream.Charge(ctx, order); err != nil {
+			w.reportStepError(span, order, StepPayment, err)
+			return err
+		}
+	} else {
+		w.clock.Sleep(PaymentTimeout)
+	}
+
+	if w.panicRate > 0 && rand.Float64() < w.panicRate {
+		panic(fmt.Sprintf("payment gateway client crashed (order=%s)", order.ID))
+	}
+	if err := w.injectedFailure("payment declined by gateway"); err != nil {
+		w.reportStepError(span, order, StepPayment, err)
+		return err
+	}
+
+	telemetry.SetStatus(span, nil)
+	log.Printf("Payment processed successfully (%s)", logFields(ctx, order).With("amount", order.Amount))
+	w.recordTransition(ctx, queue.OrderPaid, order)
+
+	return nil
+}
+
+// shipOrder ships the order to the customer
+func (w *Service) shipOrder(ctx context.Context, order queue.Order) error {
+	ctx, span := w.startStep(ctx, StepShipping, w.spanName("ShipOrder", order.Topic),
+		trace.WithAttributes(
+			attribute.String("customer.id", order.CustomerID),
+		),
+	)
+	defer span.End()
+
+	if w.downstream != nil {
+		if err := w.downstream.Ship(ctx, order); err != nil {
+			w.reportStepError(span, order, StepShipping, err)
+			return err
+		}
+	} else {
+		w.clock.Sleep(ShippingTimeout)
+	}
+
+	if err := w.injectedFailure("carrier API rejected shipment"); err != nil {
+		w.reportStepError(span, order, StepShipping, err)
+		return err
+	}
+
+	telemetry.SetStatus(span, nil)
+	log.Printf("Order shipped to customer (%s)", logFields(ctx, order).With("customer.id", order.CustomerID))
+	w.recordTransition(ctx, queue.OrderShipped, order)
+
+	return nil
+}
+
+// lookupCustomer reads the order's customer profile through the cache under a
+// LookupCustomer span. Cache errors are recorded but never fail the order.
+func (w *Service) lookupCustomer(ctx context.Context, order queue.Order) {
+	if w.cache == nil {
+		return
+	}
+
+	ctx, span := w.startStep(ctx, StepLookup, w.spanName("LookupCustomer", order.Topic),
+		trace.WithAttributes(
+			attribute.String("customer.id", order.CustomerID),
+			attribute.String("cache.system", w.cache.System()),
+		),
+	)
+	defer span.End()
+
+	_, hit, err := w.cache.Lookup(ctx, order.CustomerID)
+	span.SetAttributes(attribute.Bool("cache.hit", hit))
+	telemetry.SetStatus(span, err)
+	if err != nil {
+		log.Printf("Customer cache lookup failed (%s): %v", logFields(ctx, order).With("customer.id", order.CustomerID), err)
+	}
+}
+
+// persistOrder saves the order to the order store under a PersistOrder span
+func (w *Service) persistOrder(ctx context.Context, order queue.Order, workerID string) error {
+	if w.store == nil {
+		return nil
+	}
+
+	ctx, span := w.startStep(ctx, StepPersist, w.spanName("PersistOrder", order.Topic),
+		trace.WithAttributes(
+			attribute.String("db.system", "sqlite"),
+			attribute.String("order.id", order.ID),
+		),
+	)
+	defer span.End()
+
+	if err := w.store.SaveOrder(ctx, order, workerID); err != nil {
+		w.reportStepError(span, order, StepPersist, err)
+		return err
+	}
+	telemetry.SetStatus(span, nil)
+	return nil
+}
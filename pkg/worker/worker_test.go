@@ -0,0 +1,392 @@
+package worker_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"span-links-signoz-demo/pkg/clock"
+	"span-links-signoz-demo/pkg/producer"
+	"span-links-signoz-demo/pkg/queue"
+	"span-links-signoz-demo/pkg/worker"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// orderTime is the fake time one order takes to process: its validate, payment and
+// shipping steps
+const orderTime = worker.ValidationTimeout + worker.PaymentTimeout + worker.ShippingTimeout
+
+// TestQueueConsumptionLinks drives a batch through the producer, queue and worker on a
+// fake clock: every ProcessOrder span starts a new trace linking to its order's
+// PublishOrder span, and the waits advance the fake clock instead of sleeping.
+func TestQueueConsumptionLinks(t *testing.T) {
+	const orders = 3
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	// The queue carries the producer span in a traceparent header
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	q := queue.NewWithClock(fake)
+	publisher := producer.New(q, producer.WithTracer(tp.Tracer("producer")))
+	consumer := worker.New(q, worker.WithTracer(tp.Tracer("worker")))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := publisher.PublishOrderBatch(ctx, orders); err != nil {
+		t.Fatal(err)
+	}
+
+	began := time.Now()
+	processOrders(ctx, cancel, q, consumer, orders)
+	if elapsed := time.Since(began); elapsed >= orderTime {
+		t.Errorf("processing %d orders took %s of real time, want no sleeping", orders, elapsed)
+	}
+	if got, want := fake.Now().Sub(start), orders*orderTime; got != want {
+		t.Errorf("fake clock advanced %s, want %s", got, want)
+	}
+
+	published := make(map[string]sdktrace.ReadOnlySpan)
+	var processed []sdktrace.ReadOnlySpan
+	for _, s := range recorder.Ended() {
+		switch s.Name() {
+		case "PublishOrder":
+			published[attr(s, "order.id")] = s
+		case "ProcessOrder":
+			processed = append(processed, s)
+		}
+	}
+	if len(published) != orders || len(processed) != orders {
+		t.Fatalf("got %d PublishOrder and %d ProcessOrder spans, want %d of each", len(published), len(processed), orders)
+	}
+
+	for i, s := range processed {
+		orderID := attr(s, "order.id")
+		publish, ok := published[orderID]
+		if !ok {
+			t.Errorf("ProcessOrder of order %s has no PublishOrder span", orderID)
+			continue
+		}
+		if s.SpanContext().TraceID() == publish.SpanContext().TraceID() {
+			t.Errorf("ProcessOrder of order %s is in its producer's trace", orderID)
+		}
+		if s.Parent().IsValid() {
+			t.Errorf("ProcessOrder of order %s has a parent, want a new trace", orderID)
+		}
+
+		links := s.Links()
+		if len(links) != 1 {
+			t.Errorf("ProcessOrder of order %s has %d links, want 1", orderID, len(links))
+			continue
+		}
+		link := links[0]
+		// The link target was read from the message, so it is remote
+		if link.SpanContext.TraceID() != publish.SpanContext().TraceID() || link.SpanContext.SpanID() != publish.SpanContext().SpanID() {
+			t.Errorf("ProcessOrder of order %s links to %s, want its PublishOrder span %s",
+				orderID, link.SpanContext.SpanID(), publish.SpanContext().SpanID())
+		}
+		if got := linkAttr(link, "link.type"); got != "queue_consumption" {
+			t.Errorf("link of order %s has link.type %q, want queue_consumption", orderID, got)
+		}
+		if got := linkAttr(link, "order.id"); got != "" && got != orderID {
+			t.Errorf("link of order %s has order.id %q", orderID, got)
+		}
+
+		// Orders are created at the same fake instant and wait for those ahead of them
+		if got, want := intAttr(s, "messaging.consumer.lag_ms"), (time.Duration(i) * orderTime).Milliseconds(); got != want {
+			t.Errorf("order %d (%s) consumer lag %dms, want %dms", i, orderID, got, want)
+		}
+	}
+}
+
+// TestPanicRecordedWithStack checks a panic while processing ends ProcessOrder with an
+// Error status and a single exception event carrying the stack trace
+func TestPanicRecordedWithStack(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	q := queue.NewWithClock(fake)
+	publisher := producer.New(q, producer.WithTracer(tp.Tracer("producer")))
+	consumer := worker.New(q, worker.WithTracer(tp.Tracer("worker")), worker.WithPanicRate(1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := publisher.PublishOrderBatch(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	processOrders(ctx, cancel, q, consumer, 1)
+
+	for _, s := range recorder.Ended() {
+		if s.Name() != "ProcessOrder" {
+			continue
+		}
+		if s.Status().Code != codes.Error {
+			t.Errorf("ProcessOrder status %v, want Error", s.Status().Code)
+		}
+		var exceptions []sdktrace.Event
+		for _, e := range s.Events() {
+			if e.Name == "exception" {
+				exceptions = append(exceptions, e)
+			}
+		}
+		if len(exceptions) != 1 {
+			t.Fatalf("ProcessOrder has %d exception events, want 1", len(exceptions))
+		}
+		for _, kv := range exceptions[0].Attributes {
+			if kv.Key == "exception.stacktrace" && strings.Contains(kv.Value.AsString(), "processPayment") {
+				return
+			}
+		}
+		t.Fatalf("exception event has no stack trace through processPayment: %v", exceptions[0].Attributes)
+	}
+	t.Fatal("no ProcessOrder span recorded")
+}
+
+// TestStrictTraceParentFailure checks a traceparent rejected by strict parsing leaves
+// ProcessOrder without a link and records the failure as a link_parse_failed event
+func TestStrictTraceParentFailure(t *testing.T) {
+	const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0g"
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	q := queue.NewWithClock(clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)))
+	consumer := worker.New(q, worker.WithTracer(tp.Tracer("worker")), worker.WithStrictTraceParent(true))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := q.Publish(ctx, queue.Order{
+		ID:         "ORDER-1",
+		CustomerID: "CUST-1",
+		Amount:     10,
+		Headers:    map[string]string{queue.TraceParentHeader: traceParent},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	processOrders(ctx, cancel, q, consumer, 1)
+
+	for _, s := range recorder.Ended() {
+		if s.Name() != "ProcessOrder" {
+			continue
+		}
+		if len(s.Links()) != 0 {
+			t.Errorf("ProcessOrder has %d links, want none", len(s.Links()))
+		}
+		for _, e := range s.Events() {
+			if e.Name != "link_parse_failed" {
+				continue
+			}
+			var got string
+			for _, kv := range e.Attributes {
+				if kv.Key == "traceparent" {
+					got = kv.Value.AsString()
+				}
+			}
+			if got != traceParent {
+				t.Errorf("link_parse_failed traceparent %q, want %q", got, traceParent)
+			}
+			return
+		}
+		t.Fatalf("ProcessOrder has no link_parse_failed event: %v", s.Events())
+	}
+	t.Fatal("no ProcessOrder span recorded")
+}
+
+// TestRuntimeTunables changes the link mode and failure rate while orders are processed,
+// for the race detector, and checks the last values set are in effect
+func TestRuntimeTunables(t *testing.T) {
+	const orders = 20
+	q := queue.NewWithClock(clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)))
+	tp := sdktrace.NewTracerProvider()
+	publisher := producer.New(q, producer.WithTracer(tp.Tracer("producer")))
+	consumer := worker.New(q, worker.WithTracer(tp.Tracer("worker")), worker.WithFailureRate(0.5))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := publisher.PublishOrderBatch(ctx, orders); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		modes := []worker.LinkMode{worker.LinkModeParent, worker.LinkModeNone, worker.LinkModeLink}
+		for i := 0; ctx.Err() == nil; i++ {
+			consumer.SetLinkMode(modes[i%len(modes)])
+			consumer.SetFailureRate(float64(i%2) / 2)
+		}
+	}()
+	processOrders(ctx, cancel, q, consumer, orders)
+	<-done
+
+	consumer.SetLinkMode(worker.LinkModeParent)
+	consumer.SetFailureRate(0.25)
+	if consumer.LinkMode() != worker.LinkModeParent || consumer.FailureRate() != 0.25 {
+		t.Errorf("link mode %q and failure rate %v, want parent and 0.25", consumer.LinkMode(), consumer.FailureRate())
+	}
+}
+
+// stepClock is a fake clock whose heartbeat ticks are sent by the test and whose step
+// sleeps report on sleeping and wait for resume, so a test can hold a worker mid-order
+type stepClock struct {
+	*clock.Fake
+	ticks    chan time.Time
+	sleeping chan struct{}
+	resume   chan struct{}
+}
+
+func (c *stepClock) After(time.Duration) <-chan time.Time { return c.ticks }
+
+func (c *stepClock) Sleep(d time.Duration) {
+	c.sleeping <- struct{}{}
+	<-c.resume
+	c.Fake.Sleep(d)
+}
+
+// TestHeartbeats checks WorkerHeartbeat spans tick on the worker's clock: one sent while
+// an order is in flight links to its ProcessOrder span, one sent while idle has no link
+func TestHeartbeats(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	steps := &stepClock{
+		Fake:     fake,
+		ticks:    make(chan time.Time),
+		sleeping: make(chan struct{}, 3), // validate, payment, shipping
+		resume:   make(chan struct{}),
+	}
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	q := queue.NewWithClock(fake)
+	publisher := producer.New(q, producer.WithTracer(tp.Tracer("producer")))
+	consumer := worker.New(q,
+		worker.WithTracer(tp.Tracer("worker")),
+		worker.WithClock(steps),
+		worker.WithHeartbeatInterval(time.Second),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := publisher.PublishOrderBatch(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var consumed bool
+		consumer.ProcessFrom(ctx, "worker-1", func(ctx context.Context) (queue.Order, error) {
+			if consumed {
+				<-ctx.Done()
+				return queue.Order{}, ctx.Err()
+			}
+			consumed = true
+			return q.Consume(ctx)
+		})
+	}()
+
+	<-steps.sleeping
+	steps.ticks <- fake.Now()
+	busy := waitForSpans(t, recorder, "WorkerHeartbeat", 1)[0]
+	close(steps.resume)
+	process := waitForSpans(t, recorder, "ProcessOrder", 1)[0]
+
+	steps.ticks <- fake.Now()
+	idle := waitForSpans(t, recorder, "WorkerHeartbeat", 2)[1]
+	cancel()
+	<-done
+
+	if !boolAttr(busy, "worker.busy") || intAttr(busy, "heartbeat.sequence") != 1 {
+		t.Errorf("first heartbeat attributes %v, want busy with sequence 1", busy.Attributes())
+	}
+	if got, want := attr(busy, "worker.current_order.id"), attr(process, "order.id"); got != want {
+		t.Errorf("first heartbeat current order %q, want %q", got, want)
+	}
+	if links := busy.Links(); len(links) != 1 || links[0].SpanContext.SpanID() != process.SpanContext().SpanID() {
+		t.Errorf("first heartbeat links %v, want its ProcessOrder span %s", links, process.SpanContext().SpanID())
+	} else if got := linkAttr(links[0], "link.type"); got != "heartbeat_current_order" {
+		t.Errorf("heartbeat link has link.type %q, want heartbeat_current_order", got)
+	}
+	if boolAttr(idle, "worker.busy") || intAttr(idle, "heartbeat.sequence") != 2 || len(idle.Links()) != 0 {
+		t.Errorf("second heartbeat attributes %v with %d links, want idle with sequence 2 and no link", idle.Attributes(), len(idle.Links()))
+	}
+}
+
+// waitForSpans waits until recorder holds n ended spans named name and returns them
+func waitForSpans(t *testing.T, recorder *tracetest.SpanRecorder, name string, n int) []sdktrace.ReadOnlySpan {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var spans []sdktrace.ReadOnlySpan
+		for _, s := range recorder.Ended() {
+			if s.Name() == name {
+				spans = append(spans, s)
+			}
+		}
+		if len(spans) >= n {
+			return spans
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d %s spans, want %d", len(spans), name, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// processOrders runs consumer until it has taken n orders from q, then cancels ctx
+func processOrders(ctx context.Context, cancel context.CancelFunc, q *queue.SimpleQueue, consumer *worker.Service, n int) {
+	var consumed int
+	consumer.ProcessFrom(ctx, "worker-1", func(ctx context.Context) (queue.Order, error) {
+		if consumed == n {
+			cancel()
+			return queue.Order{}, ctx.Err()
+		}
+		consumed++
+		return q.Consume(ctx)
+	})
+}
+
+func attr(s sdktrace.ReadOnlySpan, key attribute.Key) string {
+	for _, kv := range s.Attributes() {
+		if kv.Key == key {
+			return kv.Value.AsString()
+		}
+	}
+	return ""
+}
+
+func intAttr(s sdktrace.ReadOnlySpan, key attribute.Key) int64 {
+	for _, kv := range s.Attributes() {
+		if kv.Key == key {
+			return kv.Value.AsInt64()
+		}
+	}
+	return -1
+}
+
+func boolAttr(s sdktrace.ReadOnlySpan, key attribute.Key) bool {
+	for _, kv := range s.Attributes() {
+		if kv.Key == key {
+			return kv.Value.AsBool()
+		}
+	}
+	return false
+}
+
+func linkAttr(l sdktrace.Link, key attribute.Key) string {
+	for _, kv := range l.Attributes {
+		if kv.Key == key {
+			return kv.Value.AsString()
+		}
+	}
+	return ""
+}
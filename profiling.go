@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"time"
+
+	"github.com/grafana/pyroscope-go"
+)
+
+// StartProfiling starts the optional profiling integrations and returns a function
+// that stops them:
+//   - PPROF_ADDR (e.g. "localhost:6060") serves net/http/pprof, which Parca or a
+//     Grafana Alloy pyroscope.scrape job can pull from.
+//   - PYROSCOPE_SERVER_ADDRESS (e.g. "http://localhost:4040") pushes continuous
+//     profiles to Pyroscope (PYROSCOPE_BASIC_AUTH_USER/PASSWORD for Grafana Cloud).
+func StartProfiling() func() {
+	var stops []func()
+
+	if addr := os.Getenv("PPROF_ADDR"); addr != "" {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+		srv := &http.Server{Addr: addr, Handler: mux}
+		go func() {
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("pprof server stopped: %v", err)
+			}
+		}()
+		log.Printf("pprof endpoint listening on http://%s/debug/pprof/", addr)
+
+		stops = append(stops, func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			_ = srv.Shutdown(ctx)
+		})
+	}
+
+	if serverAddr := os.Getenv("PYROSCOPE_SERVER_ADDRESS"); serverAddr != "" {
+		profiler, err := pyroscope.Start(pyroscope.Config{
+			ApplicationName:   serviceName(),
+			ServerAddress:     serverAddr,
+			BasicAuthUser:     os.Getenv("PYROSCOPE_BASIC_AUTH_USER"),
+			BasicAuthPassword: os.Getenv("PYROSCOPE_BASIC_AUTH_PASSWORD"),
+			Tags:              map[string]string{"environment": "demo"},
+			ProfileTypes: []pyroscope.ProfileType{
+				pyroscope.ProfileCPU,
+				pyroscope.ProfileAllocObjects,
+				pyroscope.ProfileAllocSpace,
+				pyroscope.ProfileInuseObjects,
+				pyroscope.ProfileInuseSpace,
+				pyroscope.ProfileGoroutines,
+			},
+		})
+		if err != nil {
+			log.Printf("Failed to start pyroscope profiler: %v", err)
+		} else {
+			log.Printf("Pyroscope profiling enabled (server=%s)", serverAddr)
+			stops = append(stops, func() {
+				if err := profiler.Stop(); err != nil {
+					log.Printf("Failed to stop pyroscope profiler: %v", err)
+				}
+			})
+		}
+	}
+
+	return func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+}
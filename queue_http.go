@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"span-links-signoz-demo/pkg/queue"
+	"span-links-signoz-demo/telemetry"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// QueueHTTPServer exposes the in-memory queue over HTTP so consumers and producers in
+// other languages can take part in the link demo. Both endpoints are instrumented with
+// otelhttp and speak W3C trace context:
+//
+//   - POST /queue/messages publishes the JSON order in the body. The request's
+//     traceparent parents the server span, and the server span is what the message
+//     carries, so consumers link into the caller's trace.
+//   - GET /queue/messages?wait=30s&topic=orders takes the next message, waiting up to
+//     wait (default 0, at most MaxQueueHTTPWait) before answering 204 No Content. The
+//     message's producer context is returned as traceparent/tracestate response headers
+//     (converted from B3 or the legacy fields if that is what it carries) for the caller
+//     to link its processing span to, and the server span links to it too.
+//
+// Delivery is at most once: a message handed out is gone from the queue.
+type QueueHTTPServer struct {
+	queue *queue.SimpleQueue
+}
+
+// NewQueueHTTPServer creates an HTTP API over orders
+func NewQueueHTTPServer(orders *queue.SimpleQueue) *QueueHTTPServer {
+	return &QueueHTTPServer{queue: orders}
+}
+
+// Start serves the queue API on addr and returns a function that stops the server, ending
+// pending long polls first
+func (s *QueueHTTPServer) Start(addr string) func() {
+	pollCtx, cancelPolls := context.WithCancel(context.Background())
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /queue/messages", otelhttp.NewHandler(http.HandlerFunc(s.publish), "POST /queue/messages"))
+	mux.Handle("GET /queue/messages", otelhttp.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.pull(pollCtx, w, r)
+	}), "GET /queue/messages"))
+
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Queue HTTP API stopped: %v", err)
+		}
+	}()
+	log.Printf("Queue HTTP API listening on http://%s/queue/messages", addr)
+
+	return func() {
+		cancelPolls()
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	}
+}
+
+// publish handles POST /queue/messages
+func (s *QueueHTTPServer) publish(w http.ResponseWriter, r *http.Request) {
+	var order queue.Order
+	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+		http.Error(w, fmt.Sprintf("invalid order: %v", err), http.StatusBadRequest)
+		return
+	}
+	if order.ID == "" {
+		order.ID = fmt.Sprintf("ORDER-%s", uuid.New().String()[:8])
+	}
+	if order.Topic == "" {
+		order.Topic = queue.DefaultTopic
+	}
+
+	span := trace.SpanFromContext(r.Context())
+	span.SetAttributes(
+		attribute.String("order.id", order.ID),
+		attribute.String("customer.id", order.CustomerID),
+		attribute.String("messaging.destination.name", order.Topic),
+		attribute.String("messaging.operation.type", "send"),
+	)
+	// The server span replaces any trace context in the body's headers
+	delete(order.Headers, queue.TraceParentHeader)
+	delete(order.Headers, queue.TraceStateHeader)
+	err := s.queue.Publish(r.Context(), order)
+	telemetry.SetStatus(span, err)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("publish failed: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+	log.Printf("Order published over HTTP (%s)", queue.OrderLogFields(order).With("topic", order.Topic).With("trace_id", span.SpanContext().TraceID()))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	writeJSON(w, map[string]string{
+		"id":       order.ID,
+		"topic":    order.Topic,
+		"trace_id": span.SpanContext().TraceID().String(),
+		"span_id":  span.SpanContext().SpanID().String(),
+	}, "publish receipt")
+}
+
+// pull handles GET /queue/messages; pollCtx ends waits when the server stops
+func (s *QueueHTTPServer) pull(pollCtx context.Context, w http.ResponseWriter, r *http.Request) {
+	wait := time.Duration(0)
+	if val := r.URL.Query().Get("wait"); val != "" {
+		d, err := time.ParseDuration(val)
+		if err != nil || d < 0 {
+			http.Error(w, fmt.Sprintf("invalid wait %q", val), http.StatusBadRequest)
+			return
+		}
+		wait = min(d, MaxQueueHTTPWait)
+	}
+	topics := r.URL.Query()["topic"]
+
+	// wait=0 still gives an already queued message the chance to be picked
+	ctx, cancel := context.WithTimeout(r.Context(), max(wait, time.Millisecond))
+	defer cancel()
+	stop := context.AfterFunc(pollCtx, cancel)
+	defer stop()
+
+	span := trace.SpanFromContext(r.Context())
+	span.SetAttributes(
+		attribute.StringSlice("messaging.source.names", topics),
+		attribute.String("messaging.operation.type", "receive"),
+		attribute.Int64("queue.http.wait_ms", wait.Milliseconds()),
+	)
+	order, err := s.queue.Consume(ctx, topics...)
+	if err != nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	span.SetAttributes(
+		attribute.String("order.id", order.ID),
+		attribute.String("messaging.destination.name", order.Topic),
+	)
+	producer, source, _ := queue.ExtractProducerContext(order, false)
+	if producer.IsValid() {
+		span.AddLink(trace.Link{
+			SpanContext: producer,
+			Attributes: []attribute.KeyValue{
+				attribute.String("link.type", "queue_delivery"),
+				attribute.String("link.source", string(source)),
+				attribute.String("order.id", order.ID),
+			},
+		})
+		propagation.TraceContext{}.Inject(trace.ContextWithRemoteSpanContext(r.Context(), producer), propagation.HeaderCarrier(w.Header()))
+	}
+	writeJSON(w, order, "order")
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"span-links-signoz-demo/pkg/producer"
+	"span-links-signoz-demo/pkg/worker"
+	"span-links-signoz-demo/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RuntimeConfig is the part of the configuration that can change mid-run
+type RuntimeConfig struct {
+	PublishRateLimit     float64         `json:"publish_rate_limit"`
+	PublishRateBurst     int             `json:"publish_rate_burst"`
+	WorkerFailurePercent float64         `json:"worker_failure_percent"`
+	WorkerCount          int             `json:"worker_count"`
+	ConsumerLinkMode     worker.LinkMode `json:"consumer_link_mode"`
+}
+
+// ConfigUpdate changes some runtime settings; nil fields keep their current value
+type ConfigUpdate struct {
+	PublishRateLimit     *float64 `json:"publish_rate_limit,omitempty"`
+	PublishRateBurst     *int     `json:"publish_rate_burst,omitempty"`
+	WorkerFailurePercent *float64 `json:"worker_failure_percent,omitempty"`
+	WorkerCount          *int     `json:"worker_count,omitempty"`
+	ConsumerLinkMode     *string  `json:"consumer_link_mode,omitempty"`
+}
+
+// Reconfigurer applies runtime configuration changes, from SIGHUP reloads of
+// RUNTIME_CONFIG_FILE or the admin endpoint. Every applied change emits a Reconfigure span
+// (own trace) with the new settings, linked to the previous Reconfigure span, so the point
+// where traces start to look different is easy to find.
+type Reconfigurer struct {
+	publisher *producer.Service
+	consumer  *worker.Service
+
+	mu           sync.Mutex
+	pool         *WorkerPool // nil when the worker count is fixed
+	fixedWorkers int
+	applied      int
+	last         trace.SpanContext
+}
+
+// NewReconfigurer creates a reconfigurer for the run's publisher and consumer, which run
+// fixedWorkers workers until a resizable pool is set
+func NewReconfigurer(publisher *producer.Service, consumer *worker.Service, fixedWorkers int) *Reconfigurer {
+	return &Reconfigurer{publisher: publisher, consumer: consumer, fixedWorkers: fixedWorkers}
+}
+
+// SetWorkerPool makes the worker count changeable through pool
+func (r *Reconfigurer) SetWorkerPool(pool *WorkerPool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pool = pool
+}
+
+// Current returns the settings in effect
+func (r *Reconfigurer) Current() RuntimeConfig {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current()
+}
+
+// current reads the settings in effect; r.mu must be held
+func (r *Reconfigurer) current() RuntimeConfig {
+	rate, burst := r.publisher.RateLimit()
+	workers := r.fixedWorkers
+	if r.pool != nil {
+		workers = r.pool.Size()
+	}
+	return RuntimeConfig{
+		PublishRateLimit:     rate,
+		PublishRateBurst:     burst,
+		WorkerFailurePercent: r.consumer.FailureRate() * 100,
+		WorkerCount:          workers,
+		ConsumerLinkMode:     r.consumer.LinkMode(),
+	}
+}
+
+// Apply validates u and, if all of it is valid, applies it; source names where the
+// change came from (sighup, admin). It returns the settings in effect afterwards.
+func (r *Reconfigurer) Apply(source string, u ConfigUpdate) (RuntimeConfig, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old := r.current()
+	next := old
+	var errs []error
+	if u.PublishRateLimit != nil {
+		if *u.PublishRateLimit < 0 {
+			errs = append(errs, errors.New("publish_rate_limit must not be negative"))
+		}
+		next.PublishRateLimit = *u.PublishRateLimit
+	}
+	if u.PublishRateBurst != nil {
+		if *u.PublishRateBurst < 0 {
+			errs = append(errs, errors.New("publish_rate_burst must not be negative"))
+		}
+		next.PublishRateBurst = *u.PublishRateBurst
+	}
+	if u.WorkerFailurePercent != nil {
+		if *u.WorkerFailurePercent < 0 || *u.WorkerFailurePercent > 100 {
+			errs = append(errs, errors.New("worker_failure_percent must be 0-100"))
+		}
+		next.WorkerFailurePercent = *u.WorkerFailurePercent
+	}
+	if u.WorkerCount != nil {
+		switch {
+		case *u.WorkerCount < 1:
+			errs = append(errs, errors.New("worker_count must be at least 1"))
+		case r.pool == nil && *u.WorkerCount != old.WorkerCount:
+			errs = append(errs, errors.New("worker_count is fixed in this mode (consumer group, key affinity and producer-only runs)"))
+		}
+		next.WorkerCount = *u.WorkerCount
+	}
+	if u.ConsumerLinkMode != nil {
+		mode, err := worker.ParseLinkMode(*u.ConsumerLinkMode)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		next.ConsumerLinkMode = mode
+	}
+	if err := errors.Join(errs...); err != nil {
+		return old, err
+	}
+
+	changed := changedSettings(old, next)
+	if len(changed) == 0 {
+		return old, nil
+	}
+	if next.PublishRateLimit != old.PublishRateLimit || next.PublishRateBurst != old.PublishRateBurst {
+		r.publisher.SetRateLimit(next.PublishRateLimit, next.PublishRateBurst)
+	}
+	if next.WorkerFailurePercent != old.WorkerFailurePercent {
+		r.consumer.SetFailureRate(next.WorkerFailurePercent / 100)
+	}
+	if next.ConsumerLinkMode != old.ConsumerLinkMode {
+		r.consumer.SetLinkMode(next.ConsumerLinkMode)
+	}
+	if next.WorkerCount != old.WorkerCount {
+		r.pool.Resize(next.WorkerCount)
+	}
+	r.applied++
+	cfg := r.current()
+	r.last = r.emitSpan(source, cfg, changed)
+
+	log.Printf("Runtime configuration changed via %s (%s): rate_limit=%g burst=%d failure_percent=%g workers=%d link_mode=%s",
+		source, strings.Join(changed, ", "), cfg.PublishRateLimit, cfg.PublishRateBurst,
+		cfg.WorkerFailurePercent, cfg.WorkerCount, cfg.ConsumerLinkMode)
+	return cfg, nil
+}
+
+// Reload applies the settings in the file at path (see parseRuntimeConfigFile)
+func (r *Reconfigurer) Reload(path string) error {
+	u, err := parseRuntimeConfigFile(path)
+	if err != nil {
+		return err
+	}
+	_, err = r.Apply("sighup", u)
+	return err
+}
+
+// ReloadOn reloads the file at path every time a signal arrives on signals, until the
+// channel is closed
+func (r *Reconfigurer) ReloadOn(signals <-chan os.Signal, path string) {
+	for range signals {
+		if path == "" {
+			log.Printf("Ignoring SIGHUP: set RUNTIME_CONFIG_FILE to reload configuration")
+			continue
+		}
+		if err := r.Reload(path); err != nil {
+			log.Printf("Configuration reload failed, keeping current settings: %v", err)
+		}
+	}
+}
+
+func (r *Reconfigurer) emitSpan(source string, cfg RuntimeConfig, changed []string) trace.SpanContext {
+	var links []trace.Link
+	if r.last.IsValid() {
+		links = append(links, trace.Link{
+			SpanContext: r.last,
+			Attributes:  []attribute.KeyValue{attribute.String("link.type", "previous_config")},
+		})
+	}
+	_, span := otel.Tracer("runtime-config").Start(context.Background(), "Reconfigure",
+		trace.WithNewRoot(),
+		trace.WithLinks(links...),
+		trace.WithAttributes(
+			runIDKey.String(runID),
+			attribute.String("config.source", source),
+			attribute.Int("config.generation", r.applied),
+			attribute.StringSlice("config.changed", changed),
+			attribute.Float64("config.publish_rate_limit", cfg.PublishRateLimit),
+			attribute.Int("config.publish_rate_burst", cfg.PublishRateBurst),
+			attribute.Float64("config.worker_failure_percent", cfg.WorkerFailurePercent),
+			attribute.Int("config.worker_count", cfg.WorkerCount),
+			attribute.String("config.consumer_link_mode", string(cfg.ConsumerLinkMode)),
+		),
+	)
+	telemetry.EndWithStatus(span, nil)
+	return span.SpanContext()
+}
+
+// changedSettings names the settings that differ between old and next
+func changedSettings(old, next RuntimeConfig) []string {
+	var changed []string
+	if next.PublishRateLimit != old.PublishRateLimit {
+		changed = append(changed, "publish_rate_limit")
+	}
+	if next.PublishRateBurst != old.PublishRateBurst {
+		changed = append(changed, "publish_rate_burst")
+	}
+	if next.WorkerFailurePercent != old.WorkerFailurePercent {
+		changed = append(changed, "worker_failure_percent")
+	}
+	if next.WorkerCount != old.WorkerCount {
+		changed = append(changed, "worker_count")
+	}
+	if next.ConsumerLinkMode != old.ConsumerLinkMode {
+		changed = append(changed, "consumer_link_mode")
+	}
+	return changed
+}
+
+// parseRuntimeConfigFile reads KEY=VALUE lines (blank lines and # comments are skipped)
+// using the names of the matching environment variables: PUBLISH_RATE_LIMIT,
+// PUBLISH_RATE_BURST, WORKER_FAILURE_PERCENT, WORKER_COUNT and CONSUMER_LINK_MODE.
+// Keys left out keep their current value.
+func parseRuntimeConfigFile(path string) (ConfigUpdate, error) {
+	var u ConfigUpdate
+	f, err := os.Open(path)
+	if err != nil {
+		return u, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, val, ok := strings.Cut(text, "=")
+		if !ok {
+			return u, fmt.Errorf("%s:%d: expected KEY=VALUE", path, line)
+		}
+		key, val = strings.TrimSpace(key), strings.Trim(strings.TrimSpace(val), `"'`)
+		if err := u.set(key, val); err != nil {
+			return u, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+	}
+	return u, scanner.Err()
+}
+
+// set sets the field for the environment variable key from val
+func (u *ConfigUpdate) set(key, val string) error {
+	switch key {
+	case "PUBLISH_RATE_LIMIT", "WORKER_FAILURE_PERCENT":
+		f, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s=%q", key, val)
+		}
+		if key == "PUBLISH_RATE_LIMIT" {
+			u.PublishRateLimit = &f
+		} else {
+			u.WorkerFailurePercent = &f
+		}
+	case "PUBLISH_RATE_BURST", "WORKER_COUNT":
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			return fmt.Errorf("invalid %s=%q", key, val)
+		}
+		if key == "PUBLISH_RATE_BURST" {
+			u.PublishRateBurst = &n
+		} else {
+			u.WorkerCount = &n
+		}
+	case "CONSUMER_LINK_MODE":
+		u.ConsumerLinkMode = &val
+	default:
+		return fmt.Errorf("%s cannot be changed at runtime", key)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"span-links-signoz-demo/pkg/producer"
+	"span-links-signoz-demo/pkg/queue"
+	"span-links-signoz-demo/pkg/worker"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newTestReconfigurer creates a reconfigurer over a fresh publisher and consumer with 2
+// fixed workers, recording its spans
+func newTestReconfigurer(t *testing.T) (*Reconfigurer, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	q := queue.New()
+	return NewReconfigurer(producer.New(q), worker.New(q), 2), recorder
+}
+
+func ptr[T any](v T) *T { return &v }
+
+// TestReconfigureRejectsInvalid checks an update with any invalid value is rejected as a
+// whole: its valid values are not applied either and no Reconfigure span is emitted
+func TestReconfigureRejectsInvalid(t *testing.T) {
+	r, recorder := newTestReconfigurer(t)
+	before := r.Current()
+
+	for _, tc := range []struct {
+		name    string
+		update  ConfigUpdate
+		wantErr string
+	}{
+		{
+			name:    "failure percent",
+			update:  ConfigUpdate{PublishRateLimit: ptr(5.0), WorkerFailurePercent: ptr(150.0)},
+			wantErr: "worker_failure_percent",
+		},
+		{
+			name:    "negative rate",
+			update:  ConfigUpdate{PublishRateLimit: ptr(-1.0), ConsumerLinkMode: ptr("parent")},
+			wantErr: "publish_rate_limit",
+		},
+		{
+			name:    "link mode",
+			update:  ConfigUpdate{WorkerFailurePercent: ptr(10.0), ConsumerLinkMode: ptr("sideways")},
+			wantErr: "sideways",
+		},
+		{
+			name:    "fixed worker count",
+			update:  ConfigUpdate{PublishRateBurst: ptr(3), WorkerCount: ptr(4)},
+			wantErr: "worker_count is fixed",
+		},
+		{
+			name:    "every error reported",
+			update:  ConfigUpdate{PublishRateBurst: ptr(-3), WorkerCount: ptr(0)},
+			wantErr: "publish_rate_burst must not be negative\nworker_count must be at least 1",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := r.Apply("admin", tc.update)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("error %v, want one mentioning %q", err, tc.wantErr)
+			}
+			if got != before {
+				t.Errorf("Apply returned %+v, want the unchanged %+v", got, before)
+			}
+			if cur := r.Current(); cur != before {
+				t.Errorf("settings changed to %+v, want %+v", cur, before)
+			}
+		})
+	}
+	if n := len(recorder.Ended()); n != 0 {
+		t.Errorf("rejected updates emitted %d spans", n)
+	}
+}
+
+// TestReconfigureLinksGenerations checks each applied change emits a Reconfigure span
+// linked to the previous one, and an update that changes nothing emits none
+func TestReconfigureLinksGenerations(t *testing.T) {
+	r, recorder := newTestReconfigurer(t)
+
+	cfg, err := r.Apply("admin", ConfigUpdate{PublishRateLimit: ptr(5.0), ConsumerLinkMode: ptr("parent")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PublishRateLimit != 5 || cfg.ConsumerLinkMode != worker.LinkModeParent || r.Current() != cfg {
+		t.Errorf("settings after the update %+v", cfg)
+	}
+	if _, err := r.Apply("sighup", ConfigUpdate{WorkerFailurePercent: ptr(10.0)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Apply("sighup", ConfigUpdate{WorkerFailurePercent: ptr(10.0), WorkerCount: ptr(2)}); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d Reconfigure spans, want 2 (the last update changed nothing)", len(spans))
+	}
+	first, second := spans[0], spans[1]
+	if len(first.Links()) != 0 {
+		t.Errorf("first generation has %d links, want none", len(first.Links()))
+	}
+	if got := spanAttr(first, "config.changed").AsStringSlice(); strings.Join(got, ",") != "publish_rate_limit,consumer_link_mode" {
+		t.Errorf("first generation changed %v", got)
+	}
+	if spanAttr(second, "config.generation").AsInt64() != 2 || spanAttr(second, "config.source").AsString() != "sighup" {
+		t.Errorf("second generation attributes %v", second.Attributes())
+	}
+	if second.SpanContext().TraceID() == first.SpanContext().TraceID() {
+		t.Error("generations share a trace, want one each")
+	}
+	links := second.Links()
+	if len(links) != 1 || !links[0].SpanContext.Equal(first.SpanContext()) {
+		t.Fatalf("second generation links %v, want the first generation %s", links, first.SpanContext().SpanID())
+	}
+	for _, kv := range links[0].Attributes {
+		if kv.Key == "link.type" && kv.Value.AsString() == "previous_config" {
+			return
+		}
+	}
+	t.Errorf("link attributes %v, want link.type=previous_config", links[0].Attributes)
+}
+
+func TestParseRuntimeConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "runtime.env")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	u, err := parseRuntimeConfigFile(write(`
+# Slow the producer down and make workers flaky
+PUBLISH_RATE_LIMIT = 2.5
+PUBLISH_RATE_BURST=4
+WORKER_FAILURE_PERCENT="20"
+WORKER_COUNT='3'
+CONSUMER_LINK_MODE=none
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.PublishRateLimit == nil || *u.PublishRateLimit != 2.5 ||
+		u.PublishRateBurst == nil || *u.PublishRateBurst != 4 ||
+		u.WorkerFailurePercent == nil || *u.WorkerFailurePercent != 20 ||
+		u.WorkerCount == nil || *u.WorkerCount != 3 ||
+		u.ConsumerLinkMode == nil || *u.ConsumerLinkMode != "none" {
+		t.Errorf("parsed %+v", u)
+	}
+
+	u, err = parseRuntimeConfigFile(write("WORKER_COUNT=5\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.WorkerCount == nil || u.PublishRateLimit != nil || u.WorkerFailurePercent != nil || u.ConsumerLinkMode != nil {
+		t.Errorf("keys left out were set: %+v", u)
+	}
+
+	for content, wantErr := range map[string]string{
+		"# comment\nPUBLISH_RATE_LIMIT\n":  "runtime.env:2: expected KEY=VALUE",
+		"PUBLISH_RATE_BURST=lots\n":        "runtime.env:1: invalid PUBLISH_RATE_BURST",
+		"WORKER_FAILURE_PERCENT=ten\n":     "invalid WORKER_FAILURE_PERCENT",
+		"\n\nOTEL_SERVICE_NAME=other\n":    "runtime.env:3: OTEL_SERVICE_NAME cannot be changed at runtime",
+		"WORKER_COUNT=2\nPUBLISH_RATE=1\n": "PUBLISH_RATE cannot be changed at runtime",
+	} {
+		if _, err := parseRuntimeConfigFile(write(content)); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("parsing %q: error %v, want %q", content, err, wantErr)
+		}
+	}
+}
+
+// spanAttr returns the value of key on s
+func spanAttr(s sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range s.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
@@ -12,7 +12,7 @@ echo ""
 # Prompt for OTEL endpoint
 echo "Enter OTEL Exporter Endpoint:"
 echo "  - SigNoz Cloud: https://ingest.<REGION>.signoz.cloud:443"
-echo "  - Local SigNoz: http://localhost:4317"
+echo "  - Local SigNoz: http://localhost:4318"
 echo "  - Leave empty for unit tests (no network calls)"
 read -p "OTEL_EXPORTER_OTLP_ENDPOINT: " OTEL_ENDPOINT
 
@@ -99,6 +99,27 @@ run_example "Retry Example" \
 run_example "Remote Parent Gap (Pitfall Demo)" \
     "export OTEL_SERVICE_NAME='remote-parent-gap' && go run ./examples/cmd/remote-parent-gap"
 
+run_example "Context Cancellation Mid-Batch" \
+    "export OTEL_SERVICE_NAME='context-cancellation' && go run ./examples/cmd/context-cancellation"
+
+run_example "Chained Queues (Two Hops)" \
+    "export OTEL_SERVICE_NAME='chained-queue' && go run ./examples/cmd/chained-queue"
+
+run_example "Schema Migration (v1 → v2)" \
+    "export OTEL_SERVICE_NAME='schema-migration' && go run ./examples/cmd/schema-migration"
+
+run_example "Chunked Upload" \
+    "export OTEL_SERVICE_NAME='chunked-upload' && go run ./examples/cmd/chunked-upload"
+
+run_example "Foreign Correlation IDs (Legacy Producer Bridge)" \
+    "export OTEL_SERVICE_NAME='foreign-correlation' && go run ./examples/cmd/foreign-correlation"
+
+run_example "Time-Window Fan-In (Aggregation Job)" \
+    "export OTEL_SERVICE_NAME='time-window' && go run ./examples/cmd/time-window"
+
+run_example "Batch-of-Batches Hierarchy" \
+    "export OTEL_SERVICE_NAME='batch-hierarchy' && go run ./examples/cmd/batch-hierarchy"
+
 # Run main producer/consumer with backward links (default)
 run_example "Producer/Consumer - Backward Links" \
     "export OTEL_SERVICE_NAME='span-links-demo' && unset ENABLE_FORWARD_LINKS_TO_PRODUCER && go run ."
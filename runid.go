@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+)
+
+// runID identifies this demo run: RUN_ID when set, otherwise a fresh UUID. It is attached
+// as a resource attribute and to every link, so one SigNoz filter (run.id = ...) finds all
+// traces and links of a session.
+var runID = func() string {
+	if id := os.Getenv("RUN_ID"); id != "" {
+		return id
+	}
+	return uuid.New().String()
+}()
+
+// runIDKey is the attribute carrying runID
+const runIDKey = attribute.Key("run.id")
+
+// runTracerProvider wraps a TracerProvider so every link — set at span start or added
+// later with AddLink — carries the run.id attribute.
+type runTracerProvider struct {
+	embedded.TracerProvider
+	tp trace.TracerProvider
+}
+
+// Tracer returns a tracer whose spans stamp run.id on their links
+func (p runTracerProvider) Tracer(name string, opts ...trace.TracerOption) trace.Tracer {
+	return runTracer{tracer: p.tp.Tracer(name, opts...)}
+}
+
+type runTracer struct {
+	embedded.Tracer
+	tracer trace.Tracer
+}
+
+// Start rebuilds the start options with run.id added to each link
+func (t runTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+	if len(cfg.Links()) > 0 {
+		links := make([]trace.Link, 0, len(cfg.Links()))
+		for _, l := range cfg.Links() {
+			links = append(links, withRunID(l))
+		}
+		opts = []trace.SpanStartOption{
+			trace.WithAttributes(cfg.Attributes()...),
+			trace.WithLinks(links...),
+			trace.WithSpanKind(cfg.SpanKind()),
+		}
+		if cfg.NewRoot() {
+			opts = append(opts, trace.WithNewRoot())
+		}
+		if !cfg.Timestamp().IsZero() {
+			opts = append(opts, trace.WithTimestamp(cfg.Timestamp()))
+		}
+	}
+
+	ctx, span := t.tracer.Start(ctx, name, opts...)
+	wrapped := runSpan{Span: span}
+	return trace.ContextWithSpan(ctx, wrapped), wrapped
+}
+
+type runSpan struct {
+	trace.Span
+}
+
+// AddLink adds run.id to links added after span start (forward links)
+func (s runSpan) AddLink(link trace.Link) {
+	s.Span.AddLink(withRunID(link))
+}
+
+func withRunID(link trace.Link) trace.Link {
+	attrs := make([]attribute.KeyValue, 0, len(link.Attributes)+1)
+	attrs = append(attrs, link.Attributes...)
+	link.Attributes = append(attrs, runIDKey.String(runID))
+	return link
+}
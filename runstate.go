@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"span-links-signoz-demo/pkg/queue"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// runInvocation numbers the processes of a resumable run: 1 for the first, one more for
+// each process that resumed it from RUN_STATE_FILE. Set before telemetry is initialized.
+var runInvocation = 1
+
+// resumedOrders is the number of orders taken over from the previous invocation
+var resumedOrders int
+
+// runInvocationKey is the resource attribute carrying runInvocation
+const runInvocationKey = attribute.Key("run.invocation")
+
+// RunState is what an interrupted run leaves in RUN_STATE_FILE for the next invocation:
+// the orders still queued. Their headers carry the context of the PublishOrder spans the
+// earlier invocation emitted, so the next invocation's consumers link back to them.
+type RunState struct {
+	RunID      string        `json:"run_id"`
+	Invocation int           `json:"invocation"` // the invocation that saved the state
+	SavedAt    time.Time     `json:"saved_at"`
+	Orders     []queue.Order `json:"orders"`
+}
+
+// loadRunState reads the state an earlier invocation saved to path (nil when there is none)
+func loadRunState(path string) (*RunState, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run state: %w", err)
+	}
+	var state RunState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid run state %s: %w", path, err)
+	}
+	if state.RunID == "" || state.Invocation < 1 {
+		return nil, fmt.Errorf("invalid run state %s: missing run_id or invocation", path)
+	}
+	return &state, nil
+}
+
+// persistRunState saves the orders left in the queue to path for the next invocation, or
+// removes path when none are left and the run is complete
+func persistRunState(path string, orders []queue.Order) error {
+	if len(orders) == 0 {
+		err := os.Remove(path)
+		if err == nil {
+			log.Printf("Run complete; removed %s", path)
+		}
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	data, err := json.MarshalIndent(RunState{
+		RunID:      runID,
+		Invocation: runInvocation,
+		SavedAt:    time.Now(),
+		Orders:     orders,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Written next to the target and renamed, so a crash never leaves half a state file
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	log.Printf("Saved %d queued orders to %s; run again with the same RUN_STATE_FILE to resume run %s", len(orders), path, runID)
+	return nil
+}
+
+// resumeOrders puts the orders an earlier invocation left behind back on the queue, with
+// their original headers
+func resumeOrders(ctx context.Context, q *queue.SimpleQueue, stats *RunStats, orders []queue.Order) {
+	for _, order := range orders {
+		if err := q.Publish(ctx, order); err != nil {
+			log.Printf("Failed to resume order %s: %v", order.ID, err)
+			return
+		}
+		// Counted as published so the drain check waits for them
+		stats.IncPublished()
+	}
+	log.Printf("Resumed run %s (invocation %d): %d orders from the previous invocation", runID, runInvocation, len(orders))
+}
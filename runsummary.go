@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"span-links-signoz-demo/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RootSpanRecorder is a span processor that remembers the span context of every root
+// span started in the run, so a run summary can link to all of them.
+type RootSpanRecorder struct {
+	mu    sync.Mutex
+	roots []rootSpan
+}
+
+type rootSpan struct {
+	name string
+	sc   trace.SpanContext
+}
+
+var _ sdktrace.SpanProcessor = (*RootSpanRecorder)(nil)
+
+// NewRootSpanRecorder creates an empty recorder
+func NewRootSpanRecorder() *RootSpanRecorder {
+	return &RootSpanRecorder{}
+}
+
+// OnStart records s if it starts a new trace
+func (r *RootSpanRecorder) OnStart(_ context.Context, s sdktrace.ReadWriteSpan) {
+	if s.Parent().IsValid() {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.roots = append(r.roots, rootSpan{name: s.Name(), sc: s.SpanContext()})
+}
+
+func (r *RootSpanRecorder) OnEnd(sdktrace.ReadOnlySpan)      {}
+func (r *RootSpanRecorder) Shutdown(context.Context) error   { return nil }
+func (r *RootSpanRecorder) ForceFlush(context.Context) error { return nil }
+
+func (r *RootSpanRecorder) snapshot() []rootSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]rootSpan(nil), r.roots...)
+}
+
+// EmitRunSummary emits a synthetic RunSummary span (new trace) linking to every root span
+// recorded so far, acting as an index of the demo run in SigNoz. Links beyond
+// RunSummaryMaxLinks per span are spread over RunSummaryPage child spans so none are
+// dropped by the SDK link limit.
+func EmitRunSummary(recorder *RootSpanRecorder, attrs ...attribute.KeyValue) {
+	roots := recorder.snapshot()
+	tracer := otel.Tracer("run-summary")
+
+	pages := (len(roots) + RunSummaryMaxLinks - 1) / RunSummaryMaxLinks
+	attrs = append(attrs,
+		attribute.Int("run.root_spans", len(roots)),
+		attribute.Int("run.summary_pages", pages),
+	)
+
+	first := roots
+	if len(first) > RunSummaryMaxLinks {
+		first = first[:RunSummaryMaxLinks]
+	}
+	ctx, summary := tracer.Start(context.Background(), "RunSummary",
+		trace.WithNewRoot(),
+		trace.WithLinks(rootLinks(first, 0)...),
+		trace.WithAttributes(attrs...),
+	)
+
+	for page := 1; page < pages; page++ {
+		start := page * RunSummaryMaxLinks
+		end := min(start+RunSummaryMaxLinks, len(roots))
+		_, pageSpan := tracer.Start(ctx, "RunSummaryPage",
+			trace.WithLinks(rootLinks(roots[start:end], start)...),
+			trace.WithAttributes(attribute.Int("run.summary_page", page)),
+		)
+		telemetry.EndWithStatus(pageSpan, nil)
+	}
+	telemetry.EndWithStatus(summary, nil)
+
+	log.Printf("Run summary emitted (trace=%s root_spans=%d)", summary.SpanContext().TraceID(), len(roots))
+}
+
+func rootLinks(roots []rootSpan, offset int) []trace.Link {
+	links := make([]trace.Link, 0, len(roots))
+	for i, r := range roots {
+		links = append(links, trace.Link{
+			SpanContext: r.sc,
+			Attributes: []attribute.KeyValue{
+				attribute.String("link.type", "run_root_span"),
+				attribute.String("span.name", r.name),
+				attribute.Int("run.root_index", offset+i),
+			},
+		})
+	}
+	return links
+}
@@ -0,0 +1,126 @@
+package scenario
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// GenerateOptions shapes synthetic traces
+type GenerateOptions struct {
+	Traces       int           // number of traces to generate
+	Depth        int           // levels below the root span
+	Fanout       int           // children per span
+	LinksPerSpan int           // links on every non-root span
+	LinkPool     int           // recent span contexts link targets are drawn from (across traces)
+	SpanDuration time.Duration // duration of leaf spans; parents cover their children
+	Rate         float64       // traces per second (0 = as fast as possible)
+}
+
+// DefaultGenerateOptions returns a small, link-dense shape
+func DefaultGenerateOptions() GenerateOptions {
+	return GenerateOptions{
+		Traces:       10,
+		Depth:        2,
+		Fanout:       3,
+		LinksPerSpan: 2,
+		LinkPool:     256,
+		SpanDuration: 10 * time.Millisecond,
+	}
+}
+
+// Generate produces synthetic traces for load-testing link ingestion and rendering. Each
+// trace is a tree of Fanout^level spans per level; every non-root span links to
+// LinksPerSpan span contexts drawn at random from the most recent LinkPool spans, which
+// includes spans of earlier traces, so the link graph crosses trace boundaries.
+// Timestamps are computed rather than slept.
+func Generate(ctx context.Context, tracer trace.Tracer, opts GenerateOptions) Result {
+	g := &generator{tracer: tracer, opts: opts}
+
+	var interval time.Duration
+	if opts.Rate > 0 {
+		interval = time.Duration(float64(time.Second) / opts.Rate)
+	}
+	next := time.Now()
+
+	for i := 0; i < opts.Traces && ctx.Err() == nil; i++ {
+		if interval > 0 {
+			select {
+			case <-time.After(time.Until(next)):
+			case <-ctx.Done():
+				return g.result
+			}
+			next = next.Add(interval)
+		}
+		g.trace(ctx, i)
+	}
+	return g.result
+}
+
+type generator struct {
+	tracer trace.Tracer
+	opts   GenerateOptions
+	pool   []trace.SpanContext
+	result Result
+}
+
+func (g *generator) trace(ctx context.Context, index int) {
+	start := time.Now()
+	g.span(ctx, start, 0, []attribute.KeyValue{attribute.Int("synthetic.trace_index", index)})
+	g.result.Traces++
+}
+
+// span generates one span and its subtree, returning the subtree's end time
+func (g *generator) span(ctx context.Context, start time.Time, level int, attrs []attribute.KeyValue) time.Time {
+	opts := []trace.SpanStartOption{
+		trace.WithTimestamp(start),
+		trace.WithAttributes(append(attrs, attribute.Int("synthetic.level", level))...),
+	}
+	if level == 0 {
+		opts = append(opts, trace.WithNewRoot())
+	} else if links := g.links(); len(links) > 0 {
+		opts = append(opts, trace.WithLinks(links...))
+		g.result.Links += len(links)
+	}
+
+	ctx, span := g.tracer.Start(ctx, "SyntheticSpan", opts...)
+	g.result.Spans++
+
+	end := start.Add(g.opts.SpanDuration)
+	if level < g.opts.Depth {
+		childStart := start
+		for c := 0; c < g.opts.Fanout; c++ {
+			childStart = g.span(ctx, childStart, level+1, []attribute.KeyValue{attribute.Int("synthetic.child_index", c)})
+		}
+		end = later(end, childStart)
+	}
+	span.End(trace.WithTimestamp(end))
+
+	g.remember(span.SpanContext())
+	return end
+}
+
+func (g *generator) links() []trace.Link {
+	n := min(g.opts.LinksPerSpan, len(g.pool))
+	links := make([]trace.Link, 0, n)
+	for _, i := range rand.Perm(len(g.pool))[:n] {
+		links = append(links, trace.Link{
+			SpanContext: g.pool[i],
+			Attributes:  []attribute.KeyValue{attribute.String("link.type", "synthetic")},
+		})
+	}
+	return links
+}
+
+func (g *generator) remember(sc trace.SpanContext) {
+	if g.opts.LinkPool <= 0 {
+		return
+	}
+	if len(g.pool) == g.opts.LinkPool {
+		g.pool = g.pool[1:]
+	}
+	g.pool = append(g.pool, sc)
+}
@@ -0,0 +1,279 @@
+// Package scenario generates spans and links from a YAML description of a link topology,
+// so an architecture's async shape can be prototyped without writing Go.
+//
+// A scenario is a list of nodes. Each node becomes one span (or count spans) that is
+// either a child of an earlier node (parent), the root of a new trace (new_trace), or
+// both unset, a root of the scenario trace. Links always reference earlier nodes:
+// "backward" links are set on the node's span when it starts, "forward" links are
+// added to the (still open) target span via AddLink. Span timestamps are computed from
+// delays and durations rather than slept, so a scenario runs instantly.
+package scenario
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gopkg.in/yaml.v3"
+)
+
+// Link directions
+const (
+	Backward = "backward"
+	Forward  = "forward"
+)
+
+// Scenario is the top-level YAML document
+type Scenario struct {
+	Name  string `yaml:"name"`
+	Nodes []Node `yaml:"nodes"`
+}
+
+// Node describes one span (or count sibling spans) in the topology
+type Node struct {
+	Name        string            `yaml:"name"`
+	Kind        string            `yaml:"kind"`         // internal (default), server, client, producer, consumer
+	Parent      string            `yaml:"parent"`       // earlier node this span is a child of
+	NewTrace    bool              `yaml:"new_trace"`    // start a new trace instead of joining the scenario trace
+	Count       int               `yaml:"count"`        // number of sibling spans (default 1)
+	DelayMs     int64             `yaml:"delay_ms"`     // start delay after the parent start / latest link target end
+	DurationMs  int64             `yaml:"duration_ms"`  // span duration
+	FailureRate float64           `yaml:"failure_rate"` // 0..1 chance each span ends with an error
+	Attributes  map[string]string `yaml:"attributes"`
+	Links       []Link            `yaml:"links"`
+}
+
+// Link describes a link between a node and an earlier node
+type Link struct {
+	To        string `yaml:"to"`
+	Type      string `yaml:"type"`      // recorded as link.type (default: the direction)
+	Direction string `yaml:"direction"` // backward (default) or forward
+}
+
+// Result summarizes what a run generated
+type Result struct {
+	Traces   int
+	Spans    int
+	Links    int
+	Failures int
+}
+
+// Load reads and validates a scenario file
+func Load(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %w", err)
+	}
+
+	var s Scenario
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to decode scenario: %w", err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Validate checks that names are unique and every reference points at an earlier node
+func (s *Scenario) Validate() error {
+	if len(s.Nodes) == 0 {
+		return errors.New("scenario has no nodes")
+	}
+
+	seen := make(map[string]bool, len(s.Nodes))
+	for i, n := range s.Nodes {
+		if n.Name == "" {
+			return fmt.Errorf("node %d: name is required", i+1)
+		}
+		if seen[n.Name] {
+			return fmt.Errorf("node %q: duplicate name", n.Name)
+		}
+		if _, err := spanKind(n.Kind); err != nil {
+			return fmt.Errorf("node %q: %w", n.Name, err)
+		}
+		if n.Parent != "" && !seen[n.Parent] {
+			return fmt.Errorf("node %q: parent %q must be declared earlier", n.Name, n.Parent)
+		}
+		if n.Parent != "" && n.NewTrace {
+			return fmt.Errorf("node %q: parent and new_trace are mutually exclusive", n.Name)
+		}
+		if n.Count < 0 || n.DelayMs < 0 || n.DurationMs < 0 {
+			return fmt.Errorf("node %q: count, delay_ms and duration_ms must not be negative", n.Name)
+		}
+		if n.FailureRate < 0 || n.FailureRate > 1 {
+			return fmt.Errorf("node %q: failure_rate must be between 0 and 1", n.Name)
+		}
+		for _, l := range n.Links {
+			if !seen[l.To] {
+				return fmt.Errorf("node %q: link target %q must be declared earlier", n.Name, l.To)
+			}
+			if l.Direction != "" && l.Direction != Backward && l.Direction != Forward {
+				return fmt.Errorf("node %q: link direction %q (use %s or %s)", n.Name, l.Direction, Backward, Forward)
+			}
+		}
+		seen[n.Name] = true
+	}
+	return nil
+}
+
+// instance is one generated span
+type instance struct {
+	ctx   context.Context
+	span  trace.Span
+	start time.Time
+	end   time.Time
+}
+
+// Run generates the scenario's spans with tracer. All spans are started in declaration
+// order, forward links are added, then every span is ended at its computed end time.
+func (s *Scenario) Run(ctx context.Context, tracer trace.Tracer) Result {
+	var result Result
+	origin := time.Now()
+	traces := make(map[trace.TraceID]struct{})
+	nodes := make(map[string][]*instance, len(s.Nodes))
+	var all []*instance
+
+	ctx, root := tracer.Start(ctx, "Scenario",
+		trace.WithTimestamp(origin),
+		trace.WithAttributes(attribute.String("scenario.name", s.Name)),
+	)
+
+	for _, n := range s.Nodes {
+		kind, _ := spanKind(n.Kind)
+		count := max(n.Count, 1)
+
+		var backward []trace.Link
+		start := origin
+		for _, l := range n.Links {
+			if l.Direction == Forward {
+				continue
+			}
+			for _, target := range nodes[l.To] {
+				backward = append(backward, trace.Link{
+					SpanContext: target.span.SpanContext(),
+					Attributes:  linkAttributes(l, Backward),
+				})
+				start = later(start, target.end)
+			}
+		}
+
+		parents := []*instance{{ctx: ctx, start: origin}}
+		if n.Parent != "" {
+			parents = nodes[n.Parent]
+		}
+
+		for i := 0; i < count; i++ {
+			parent := parents[i%len(parents)]
+			spanStart := later(start, parent.start).Add(time.Duration(n.DelayMs) * time.Millisecond)
+
+			attrs := []attribute.KeyValue{
+				attribute.String("scenario.name", s.Name),
+				attribute.String("scenario.node", n.Name),
+				attribute.Int("scenario.instance", i),
+			}
+			for k, v := range n.Attributes {
+				attrs = append(attrs, attribute.String(k, v))
+			}
+			opts := []trace.SpanStartOption{
+				trace.WithSpanKind(kind),
+				trace.WithTimestamp(spanStart),
+				trace.WithLinks(backward...),
+				trace.WithAttributes(attrs...),
+			}
+			if n.NewTrace {
+				opts = append(opts, trace.WithNewRoot())
+			}
+
+			spanCtx, span := tracer.Start(parent.ctx, n.Name, opts...)
+			inst := &instance{
+				ctx:   spanCtx,
+				span:  span,
+				start: spanStart,
+				end:   spanStart.Add(time.Duration(n.DurationMs) * time.Millisecond),
+			}
+			nodes[n.Name] = append(nodes[n.Name], inst)
+			all = append(all, inst)
+			traces[span.SpanContext().TraceID()] = struct{}{}
+			result.Spans++
+			result.Links += len(backward)
+
+			if n.FailureRate > 0 && rand.Float64() < n.FailureRate {
+				span.RecordError(fmt.Errorf("simulated failure in %s", n.Name), trace.WithTimestamp(inst.end))
+				span.SetStatus(codes.Error, "simulated failure")
+				result.Failures++
+			}
+
+			for _, l := range n.Links {
+				if l.Direction != Forward {
+					continue
+				}
+				for _, target := range nodes[l.To] {
+					target.span.AddLink(trace.Link{
+						SpanContext: span.SpanContext(),
+						Attributes:  linkAttributes(l, Forward),
+					})
+					result.Links++
+				}
+			}
+		}
+	}
+
+	scenarioEnd := origin
+	for _, inst := range all {
+		inst.span.End(trace.WithTimestamp(inst.end))
+		scenarioEnd = later(scenarioEnd, inst.end)
+	}
+	root.SetAttributes(
+		attribute.Int("scenario.spans", result.Spans),
+		attribute.Int("scenario.links", result.Links),
+		attribute.Int("scenario.failures", result.Failures),
+	)
+	root.End(trace.WithTimestamp(scenarioEnd))
+	traces[root.SpanContext().TraceID()] = struct{}{}
+
+	result.Traces = len(traces)
+	return result
+}
+
+func linkAttributes(l Link, direction string) []attribute.KeyValue {
+	linkType := l.Type
+	if linkType == "" {
+		linkType = direction
+	}
+	return []attribute.KeyValue{
+		attribute.String("link.type", linkType),
+		attribute.String("link.direction", direction),
+		attribute.String("link.target_node", l.To),
+	}
+}
+
+func spanKind(kind string) (trace.SpanKind, error) {
+	switch kind {
+	case "", "internal":
+		return trace.SpanKindInternal, nil
+	case "server":
+		return trace.SpanKindServer, nil
+	case "client":
+		return trace.SpanKindClient, nil
+	case "producer":
+		return trace.SpanKindProducer, nil
+	case "consumer":
+		return trace.SpanKindConsumer, nil
+	default:
+		return trace.SpanKindUnspecified, fmt.Errorf("unknown span kind %q", kind)
+	}
+}
+
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,162 @@
+// Package spanbuild creates spans at explicit times on a virtual timeline. Examples built
+// on it produce the same trace structure (names, parents, links, timestamps relative to
+// the start) on every run without sleeping, so their output can be compared against
+// golden files, e.g. through an in-memory exporter and a timeline started at a fixed time.
+package spanbuild
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Timeline is a virtual clock spans are placed on; it is safe for concurrent use, but
+// concurrent work gets deterministic times only on its own Fork
+type Timeline struct {
+	tracer trace.Tracer
+
+	mu  sync.Mutex
+	now time.Time
+}
+
+// New returns a timeline starting at start whose spans are created with tracer
+func New(tracer trace.Tracer, start time.Time) *Timeline {
+	return &Timeline{tracer: tracer, now: start}
+}
+
+// Now returns the current time of the timeline
+func (t *Timeline) Now() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.now
+}
+
+// Advance moves the timeline forward by d, standing in for work that takes d, and
+// returns the new time
+func (t *Timeline) Advance(d time.Duration) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.now = t.now.Add(d)
+	return t.now
+}
+
+// advanceTo moves the timeline to at unless it is already later
+func (t *Timeline) advanceTo(at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if at.After(t.now) {
+		t.now = at
+	}
+}
+
+// Fork returns a timeline starting at t's current time that advances on its own, for a
+// lane of concurrent work (a worker, a shard)
+func (t *Timeline) Fork() *Timeline {
+	return New(t.tracer, t.Now())
+}
+
+// Join moves t to the latest time of forks, as when waiting for all of them
+func (t *Timeline) Join(forks ...*Timeline) {
+	for _, f := range forks {
+		t.advanceTo(f.Now())
+	}
+}
+
+// Span begins describing a span named name on the timeline
+func (t *Timeline) Span(name string) *Builder {
+	return &Builder{tl: t, name: name}
+}
+
+// Builder describes one span; the zero start means the timeline's time when the span is
+// created
+type Builder struct {
+	tl       *Timeline
+	name     string
+	start    time.Time
+	duration time.Duration
+	opts     []trace.SpanStartOption
+	links    []trace.Link
+}
+
+// Kind sets the span kind
+func (b *Builder) Kind(kind trace.SpanKind) *Builder {
+	b.opts = append(b.opts, trace.WithSpanKind(kind))
+	return b
+}
+
+// NewRoot makes the span the root of a new trace, ignoring the parent in ctx
+func (b *Builder) NewRoot() *Builder {
+	b.opts = append(b.opts, trace.WithNewRoot())
+	return b
+}
+
+// Attrs adds attributes to the span
+func (b *Builder) Attrs(attrs ...attribute.KeyValue) *Builder {
+	b.opts = append(b.opts, trace.WithAttributes(attrs...))
+	return b
+}
+
+// Link adds a link to spanCtx, skipped if it is invalid
+func (b *Builder) Link(spanCtx trace.SpanContext, attrs ...attribute.KeyValue) *Builder {
+	if spanCtx.IsValid() {
+		b.links = append(b.links, trace.Link{SpanContext: spanCtx, Attributes: attrs})
+	}
+	return b
+}
+
+// Links adds links to the span
+func (b *Builder) Links(links ...trace.Link) *Builder {
+	b.links = append(b.links, links...)
+	return b
+}
+
+// At starts the span at start instead of the timeline's time
+func (b *Builder) At(start time.Time) *Builder {
+	b.start = start
+	return b
+}
+
+// Lasting sets how long the span takes: its duration when emitted, its minimum duration
+// when started
+func (b *Builder) Lasting(d time.Duration) *Builder {
+	b.duration = d
+	return b
+}
+
+// Emit creates the span, ends it after its duration and moves the timeline to its end.
+// It returns the span's context, for later links.
+func (b *Builder) Emit(ctx context.Context) trace.SpanContext {
+	_, span := b.Start(ctx)
+	span.End()
+	return span.SpanContext()
+}
+
+// Start creates the span and returns it open, with ctx carrying it for child spans. The
+// timeline does not move until End.
+func (b *Builder) Start(ctx context.Context) (context.Context, *Span) {
+	start := b.start
+	if start.IsZero() {
+		start = b.tl.Now()
+	}
+	opts := append(append([]trace.SpanStartOption(nil), b.opts...), trace.WithTimestamp(start), trace.WithLinks(b.links...))
+	ctx, span := b.tl.tracer.Start(ctx, b.name, opts...)
+	return ctx, &Span{Span: span, tl: b.tl, minEnd: start.Add(b.duration)}
+}
+
+// Span is a span started by a Builder. End ends it at the timeline's time (children
+// advanced it) or after its minimum duration, whichever is later, and moves the timeline
+// there; the other methods act on the underlying span.
+type Span struct {
+	trace.Span
+	tl     *Timeline
+	minEnd time.Time
+}
+
+// End ends the span on the timeline
+func (s *Span) End(opts ...trace.SpanEndOption) {
+	s.tl.advanceTo(s.minEnd)
+	s.Span.End(append(opts, trace.WithTimestamp(s.tl.Now()))...)
+}
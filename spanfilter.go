@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"span-links-signoz-demo/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SpanDropFilter wraps an exporting span processor and drops spans by name before they
+// reach it, a cost-control technique for link-heavy pipelines with noisy child spans.
+// Dropping a span does not break the trace: its children are reparented onto its nearest
+// kept ancestor, its links are handed up to that ancestor, and links other spans have to
+// it are retargeted there. Processors running next to the filter (run summary, link
+// index) still see every span. Children that end after their kept ancestor are exported
+// with their original (missing) parent.
+type SpanDropFilter struct {
+	next  sdktrace.SpanProcessor
+	names map[string]bool
+
+	mu       sync.Mutex
+	dropped  map[trace.SpanID]trace.SpanContext // live dropped span -> kept ancestor
+	byParent map[trace.SpanID][]trace.SpanID    // kept ancestor -> its dropped descendants
+	handUp   map[trace.SpanID][]sdktrace.Link   // kept ancestor -> links of dropped descendants
+
+	droppedSpans metric.Int64Counter
+}
+
+var _ sdktrace.SpanProcessor = (*SpanDropFilter)(nil)
+
+// NewSpanDropFilter drops spans named in names before they reach next
+func NewSpanDropFilter(next sdktrace.SpanProcessor, names []string) *SpanDropFilter {
+	f := &SpanDropFilter{
+		next:     next,
+		names:    make(map[string]bool, len(names)),
+		dropped:  make(map[trace.SpanID]trace.SpanContext),
+		byParent: make(map[trace.SpanID][]trace.SpanID),
+		handUp:   make(map[trace.SpanID][]sdktrace.Link),
+	}
+	for _, name := range names {
+		f.names[name] = true
+	}
+	counter, err := otel.Meter("span-filter").Int64Counter(telemetry.MetricSpansDropped,
+		metric.WithDescription("Spans dropped by name before export"),
+		metric.WithUnit("{span}"),
+	)
+	if err != nil {
+		log.Printf("Failed to create dropped spans counter: %v", err)
+	}
+	f.droppedSpans = counter
+	return f
+}
+
+// OnStart remembers where the children of a span that will be dropped belong
+func (f *SpanDropFilter) OnStart(ctx context.Context, s sdktrace.ReadWriteSpan) {
+	if !f.names[s.Name()] {
+		f.next.OnStart(ctx, s)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ancestor := s.Parent()
+	if a, ok := f.dropped[ancestor.SpanID()]; ok {
+		ancestor = a // nested dropped spans share the kept ancestor
+	}
+	f.dropped[s.SpanContext().SpanID()] = ancestor
+	f.byParent[ancestor.SpanID()] = append(f.byParent[ancestor.SpanID()], s.SpanContext().SpanID())
+}
+
+// OnEnd drops s if its name is filtered, otherwise exports it with its parent and links
+// adjusted for dropped spans
+func (f *SpanDropFilter) OnEnd(s sdktrace.ReadOnlySpan) {
+	id := s.SpanContext().SpanID()
+
+	f.mu.Lock()
+	if f.names[s.Name()] {
+		// Entries of spans whose ancestor already ended are gone; their links go with them
+		if ancestor, ok := f.dropped[id]; ok && len(s.Links()) > 0 {
+			f.handUp[ancestor.SpanID()] = append(f.handUp[ancestor.SpanID()], f.retarget(s.Links())...)
+		}
+		f.mu.Unlock()
+		if f.droppedSpans != nil {
+			f.droppedSpans.Add(context.Background(), 1, metric.WithAttributes(attribute.String("span.name", s.Name())))
+		}
+		return
+	}
+
+	if len(f.dropped) == 0 {
+		f.mu.Unlock()
+		f.next.OnEnd(s)
+		return
+	}
+	parent, reparented := f.dropped[s.Parent().SpanID()]
+	if !reparented {
+		parent = s.Parent()
+	}
+	links := append(f.retarget(s.Links()), f.handUp[id]...)
+	delete(f.handUp, id)
+	for _, d := range f.byParent[id] {
+		delete(f.dropped, d)
+	}
+	delete(f.byParent, id)
+	f.mu.Unlock()
+
+	f.next.OnEnd(filteredSpan{ReadOnlySpan: s, parent: parent, links: links})
+}
+
+// retarget points links at dropped spans to their kept ancestor; f.mu must be held
+func (f *SpanDropFilter) retarget(links []sdktrace.Link) []sdktrace.Link {
+	out := make([]sdktrace.Link, 0, len(links))
+	for _, l := range links {
+		if ancestor, ok := f.dropped[l.SpanContext.SpanID()]; ok && ancestor.IsValid() {
+			l.Attributes = append(append([]attribute.KeyValue(nil), l.Attributes...),
+				attribute.String("link.retargeted_from", l.SpanContext.SpanID().String()))
+			l.SpanContext = ancestor
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+func (f *SpanDropFilter) Shutdown(ctx context.Context) error   { return f.next.Shutdown(ctx) }
+func (f *SpanDropFilter) ForceFlush(ctx context.Context) error { return f.next.ForceFlush(ctx) }
+
+// filteredSpan is an ended span with its parent and links adjusted for dropped spans
+type filteredSpan struct {
+	sdktrace.ReadOnlySpan
+	parent trace.SpanContext
+	links  []sdktrace.Link
+}
+
+func (s filteredSpan) Parent() trace.SpanContext { return s.parent }
+func (s filteredSpan) Links() []sdktrace.Link    { return s.links }
@@ -0,0 +1,150 @@
+// Package spanstore keeps the span contexts of recently finished work, keyed by entity ID
+// (order, customer, shard), for aggregators that look up their link targets later. Memory
+// is bounded twice: the store holds at most a fixed number of entries, overwriting the
+// oldest, and entries older than the TTL are no longer returned.
+package spanstore
+
+import (
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Entry is one recorded span context
+type Entry struct {
+	Key         string // entity ID the span belongs to
+	SpanContext trace.SpanContext
+	Attributes  []attribute.KeyValue // recorded with the span, added to its link
+	Recorded    time.Time
+}
+
+// Link returns a link to the entry's span with its attributes followed by attrs
+func (e Entry) Link(attrs ...attribute.KeyValue) trace.Link {
+	return trace.Link{
+		SpanContext: e.SpanContext,
+		Attributes:  append(append([]attribute.KeyValue(nil), e.Attributes...), attrs...),
+	}
+}
+
+// Store is a fixed-size ring of entries with a TTL; it is safe for concurrent use. Lookups
+// scan the ring, so keep the capacity in the hundreds or low thousands.
+type Store struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	ring    []Entry
+	next    int
+	size    int
+	evicted int
+}
+
+// New returns a store of at most capacity entries, each returned for ttl after it was
+// recorded (ttl 0 keeps entries until they are overwritten)
+func New(capacity int, ttl time.Duration) *Store {
+	return NewWithClock(capacity, ttl, time.Now)
+}
+
+// NewWithClock is New reading the time from now, for fake clocks
+func NewWithClock(capacity int, ttl time.Duration, now func() time.Time) *Store {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Store{ttl: ttl, now: now, ring: make([]Entry, capacity)}
+}
+
+// Add records spanCtx under key, reporting false (and recording nothing) if it is invalid.
+// When the store is full the oldest entry is overwritten; if it had not expired yet it
+// counts as evicted.
+func (s *Store) Add(key string, spanCtx trace.SpanContext, attrs ...attribute.KeyValue) bool {
+	if !spanCtx.IsValid() {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if s.size == len(s.ring) && s.live(s.ring[s.next], now) {
+		s.evicted++
+	}
+	s.ring[s.next] = Entry{Key: key, SpanContext: spanCtx, Attributes: attrs, Recorded: now}
+	s.next = (s.next + 1) % len(s.ring)
+	if s.size < len(s.ring) {
+		s.size++
+	}
+	return true
+}
+
+// Lookup returns the live entries recorded under key, oldest first
+func (s *Store) Lookup(key string) []Entry {
+	return s.collect(func(e Entry) bool { return e.Key == key }, false)
+}
+
+// Latest returns the most recent live entry recorded under key
+func (s *Store) Latest(key string) (Entry, bool) {
+	entries := s.Lookup(key)
+	if len(entries) == 0 {
+		return Entry{}, false
+	}
+	return entries[len(entries)-1], true
+}
+
+// Between returns the live entries recorded in [from, to), oldest first
+func (s *Store) Between(from, to time.Time) []Entry {
+	return s.collect(recordedIn(from, to), false)
+}
+
+// Take is Lookup that also removes the returned entries, for aggregators that link each
+// span once. Removed entries no longer count as evicted when overwritten.
+func (s *Store) Take(key string) []Entry {
+	return s.collect(func(e Entry) bool { return e.Key == key }, true)
+}
+
+// TakeBetween is Between that also removes the returned entries
+func (s *Store) TakeBetween(from, to time.Time) []Entry {
+	return s.collect(recordedIn(from, to), true)
+}
+
+// Len returns the number of live entries
+func (s *Store) Len() int {
+	return len(s.collect(func(Entry) bool { return true }, false))
+}
+
+// Evicted returns how many entries have been overwritten before their TTL expired: link
+// targets lost to the capacity bound
+func (s *Store) Evicted() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.evicted
+}
+
+// collect returns the live entries matching match, oldest first, removing them if take
+func (s *Store) collect(match func(Entry) bool, take bool) []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	var out []Entry
+	for i := 0; i < s.size; i++ {
+		idx := (s.next - s.size + i + len(s.ring)) % len(s.ring)
+		if e := s.ring[idx]; s.live(e, now) && match(e) {
+			out = append(out, e)
+			if take {
+				s.ring[idx] = Entry{} // invalid span context: never live again
+			}
+		}
+	}
+	return out
+}
+
+// recordedIn matches entries recorded in [from, to)
+func recordedIn(from, to time.Time) func(Entry) bool {
+	return func(e Entry) bool {
+		return !e.Recorded.Before(from) && e.Recorded.Before(to)
+	}
+}
+
+// live reports whether e has not been taken and has not expired at now
+func (s *Store) live(e Entry, now time.Time) bool {
+	return e.SpanContext.IsValid() && (s.ttl <= 0 || now.Sub(e.Recorded) < s.ttl)
+}
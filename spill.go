@@ -0,0 +1,548 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"span-links-signoz-demo/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SpillExporter wraps a span exporter so that batches it fails to export (collector
+// outage, network partition) are written to files in a spill directory instead of being
+// dropped. A background loop re-exports spilled batches, oldest first, every
+// SpillRetryInterval and once more at shutdown; the telemetry.spans.spilled and
+// telemetry.spans.recovered counters show the outage and the recovery.
+type SpillExporter struct {
+	next sdktrace.SpanExporter
+	dir  string
+
+	spilled   metric.Int64Counter
+	recovered metric.Int64Counter
+
+	seq       atomic.Int64
+	recoverMu sync.Mutex // one recovery pass at a time
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+var _ sdktrace.SpanExporter = (*SpillExporter)(nil)
+
+// NewSpillExporter wraps next, spilling failed batches to dir (created if missing)
+func NewSpillExporter(next sdktrace.SpanExporter, dir string) (*SpillExporter, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create spill directory: %w", err)
+	}
+
+	meter := otel.Meter("span-spill")
+	spilled, err := meter.Int64Counter(telemetry.MetricSpansSpilled,
+		metric.WithDescription("Spans written to disk after a failed export"),
+		metric.WithUnit("{span}"),
+	)
+	if err != nil {
+		log.Printf("Failed to create spilled spans counter: %v", err)
+	}
+	recovered, err := meter.Int64Counter(telemetry.MetricSpansRecovered,
+		metric.WithDescription("Spilled spans exported successfully on a later attempt"),
+		metric.WithUnit("{span}"),
+	)
+	if err != nil {
+		log.Printf("Failed to create recovered spans counter: %v", err)
+	}
+
+	e := &SpillExporter{
+		next:      next,
+		dir:       dir,
+		spilled:   spilled,
+		recovered: recovered,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go e.retryLoop()
+	return e, nil
+}
+
+// ExportSpans exports spans, spilling them to disk if the wrapped exporter fails
+func (e *SpillExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	err := e.next.ExportSpans(ctx, spans)
+	if err == nil {
+		return nil
+	}
+
+	path, spillErr := e.spill(spans)
+	if spillErr != nil {
+		return errors.Join(err, spillErr)
+	}
+	if e.spilled != nil {
+		e.spilled.Add(context.Background(), int64(len(spans)))
+	}
+	log.Printf("Span export failed, spilled %d spans to %s: %v", len(spans), path, err)
+	return nil
+}
+
+// Shutdown stops the retry loop, makes a last attempt to re-export spilled batches and
+// shuts the wrapped exporter down
+func (e *SpillExporter) Shutdown(ctx context.Context) error {
+	close(e.stop)
+	<-e.done
+	e.recover(ctx)
+	return e.next.Shutdown(ctx)
+}
+
+// retryLoop re-exports spilled batches every SpillRetryInterval until shutdown
+func (e *SpillExporter) retryLoop() {
+	defer close(e.done)
+	ticker := time.NewTicker(SpillRetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), DefaultExportTimeout)
+			e.recover(ctx)
+			cancel()
+		}
+	}
+}
+
+// recover re-exports spill files oldest first, stopping at the first failure (the
+// backend is presumably still down). Files that cannot be read are renamed to *.bad so
+// later passes skip them.
+func (e *SpillExporter) recover(ctx context.Context) {
+	e.recoverMu.Lock()
+	defer e.recoverMu.Unlock()
+
+	files, err := filepath.Glob(filepath.Join(e.dir, "spill-*.jsonl"))
+	if err != nil || len(files) == 0 {
+		return
+	}
+	sort.Strings(files)
+
+	for _, path := range files {
+		spans, err := readSpillFile(path)
+		if err != nil {
+			bad := path + ".bad"
+			if renameErr := os.Rename(path, bad); renameErr != nil {
+				log.Printf("Skipping unreadable spill file %s: %v (failed to set it aside: %v)", path, err, renameErr)
+				continue
+			}
+			log.Printf("Set aside unreadable spill file as %s: %v", bad, err)
+			continue
+		}
+		if err := e.next.ExportSpans(ctx, spans); err != nil {
+			return
+		}
+		if err := os.Remove(path); err != nil {
+			log.Printf("Failed to remove recovered spill file %s: %v", path, err)
+		}
+		if e.recovered != nil {
+			e.recovered.Add(context.Background(), int64(len(spans)))
+		}
+		log.Printf("Recovered %d spilled spans from %s", len(spans), path)
+	}
+}
+
+// spill writes spans to a new spill file, one JSON span per line
+func (e *SpillExporter) spill(spans []sdktrace.ReadOnlySpan) (string, error) {
+	name := fmt.Sprintf("spill-%020d-%06d.jsonl", time.Now().UnixNano(), e.seq.Add(1))
+	path := filepath.Join(e.dir, name)
+	tmp := path + ".tmp"
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return "", fmt.Errorf("failed to create spill file: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, s := range spans {
+		if err := enc.Encode(newSpilledSpan(s)); err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return "", fmt.Errorf("failed to encode spilled span: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to write spill file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to write spill file: %w", err)
+	}
+	// Rename last so the retry loop never picks up a half-written file
+	if err := os.Rename(tmp, path); err != nil {
+		return "", fmt.Errorf("failed to finalize spill file: %w", err)
+	}
+	return path, nil
+}
+
+// readSpillFile decodes the spans of a spill file
+func readSpillFile(path string) ([]sdktrace.ReadOnlySpan, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var spans []sdktrace.ReadOnlySpan
+	dec := json.NewDecoder(f)
+	for dec.More() {
+		var s spilledSpan
+		if err := dec.Decode(&s); err != nil {
+			return nil, err
+		}
+		ro, err := s.readOnly()
+		if err != nil {
+			return nil, err
+		}
+		spans = append(spans, ro)
+	}
+	return spans, nil
+}
+
+// spilledSpan is the on-disk form of a finished span
+type spilledSpan struct {
+	Name         string         `json:"name"`
+	SpanContext  spilledSpanCtx `json:"span_context"`
+	Parent       spilledSpanCtx `json:"parent"`
+	Kind         int            `json:"kind"`
+	Start        time.Time      `json:"start"`
+	End          time.Time      `json:"end"`
+	Attributes   []spilledAttr  `json:"attributes,omitempty"`
+	Events       []spilledEvent `json:"events,omitempty"`
+	Links        []spilledLink  `json:"links,omitempty"`
+	StatusCode   uint32         `json:"status_code"`
+	StatusDesc   string         `json:"status_description,omitempty"`
+	DroppedAttrs int            `json:"dropped_attributes,omitempty"`
+	DroppedEvts  int            `json:"dropped_events,omitempty"`
+	DroppedLinks int            `json:"dropped_links,omitempty"`
+	Children     int            `json:"child_span_count,omitempty"`
+	Resource     []spilledAttr  `json:"resource,omitempty"`
+	ResourceURL  string         `json:"resource_schema_url,omitempty"`
+	Scope        spilledScope   `json:"scope"`
+}
+
+type spilledSpanCtx struct {
+	TraceID    string `json:"trace_id,omitempty"`
+	SpanID     string `json:"span_id,omitempty"`
+	TraceFlags byte   `json:"trace_flags,omitempty"`
+	TraceState string `json:"trace_state,omitempty"`
+	Remote     bool   `json:"remote,omitempty"`
+}
+
+type spilledAttr struct {
+	Key   string          `json:"k"`
+	Type  string          `json:"t"`
+	Value json.RawMessage `json:"v"`
+}
+
+type spilledEvent struct {
+	Name       string        `json:"name"`
+	Time       time.Time     `json:"time"`
+	Attributes []spilledAttr `json:"attributes,omitempty"`
+	Dropped    int           `json:"dropped_attributes,omitempty"`
+}
+
+type spilledLink struct {
+	SpanContext spilledSpanCtx `json:"span_context"`
+	Attributes  []spilledAttr  `json:"attributes,omitempty"`
+	Dropped     int            `json:"dropped_attributes,omitempty"`
+}
+
+type spilledScope struct {
+	Name      string `json:"name"`
+	Version   string `json:"version,omitempty"`
+	SchemaURL string `json:"schema_url,omitempty"`
+}
+
+func newSpilledSpan(s sdktrace.ReadOnlySpan) spilledSpan {
+	out := spilledSpan{
+		Name:         s.Name(),
+		SpanContext:  newSpilledSpanCtx(s.SpanContext()),
+		Parent:       newSpilledSpanCtx(s.Parent()),
+		Kind:         int(s.SpanKind()),
+		Start:        s.StartTime(),
+		End:          s.EndTime(),
+		Attributes:   newSpilledAttrs(s.Attributes()),
+		StatusCode:   uint32(s.Status().Code),
+		StatusDesc:   s.Status().Description,
+		DroppedAttrs: s.DroppedAttributes(),
+		DroppedEvts:  s.DroppedEvents(),
+		DroppedLinks: s.DroppedLinks(),
+		Children:     s.ChildSpanCount(),
+		Scope: spilledScope{
+			Name:      s.InstrumentationScope().Name,
+			Version:   s.InstrumentationScope().Version,
+			SchemaURL: s.InstrumentationScope().SchemaURL,
+		},
+	}
+	for _, ev := range s.Events() {
+		out.Events = append(out.Events, spilledEvent{
+			Name:       ev.Name,
+			Time:       ev.Time,
+			Attributes: newSpilledAttrs(ev.Attributes),
+			Dropped:    ev.DroppedAttributeCount,
+		})
+	}
+	for _, l := range s.Links() {
+		out.Links = append(out.Links, spilledLink{
+			SpanContext: newSpilledSpanCtx(l.SpanContext),
+			Attributes:  newSpilledAttrs(l.Attributes),
+			Dropped:     l.DroppedAttributeCount,
+		})
+	}
+	if res := s.Resource(); res != nil {
+		out.Resource = newSpilledAttrs(res.Attributes())
+		out.ResourceURL = res.SchemaURL()
+	}
+	return out
+}
+
+// readOnly rebuilds the span for re-export
+func (s spilledSpan) readOnly() (sdktrace.ReadOnlySpan, error) {
+	sc, err := s.SpanContext.spanContext()
+	if err != nil {
+		return nil, err
+	}
+	parent, err := s.Parent.spanContext()
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := decodeSpilledAttrs(s.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	resAttrs, err := decodeSpilledAttrs(s.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	span := &spilledReadOnlySpan{
+		name:         s.Name,
+		spanContext:  sc,
+		parent:       parent,
+		kind:         trace.SpanKind(s.Kind),
+		start:        s.Start,
+		end:          s.End,
+		attributes:   attrs,
+		status:       sdktrace.Status{Code: codes.Code(s.StatusCode), Description: s.StatusDesc},
+		droppedAttrs: s.DroppedAttrs,
+		droppedEvts:  s.DroppedEvts,
+		droppedLinks: s.DroppedLinks,
+		children:     s.Children,
+		resource:     resource.NewWithAttributes(s.ResourceURL, resAttrs...),
+		scope: instrumentation.Scope{
+			Name:      s.Scope.Name,
+			Version:   s.Scope.Version,
+			SchemaURL: s.Scope.SchemaURL,
+		},
+	}
+	for _, ev := range s.Events {
+		evAttrs, err := decodeSpilledAttrs(ev.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		span.events = append(span.events, sdktrace.Event{
+			Name:                  ev.Name,
+			Time:                  ev.Time,
+			Attributes:            evAttrs,
+			DroppedAttributeCount: ev.Dropped,
+		})
+	}
+	for _, l := range s.Links {
+		linkSC, err := l.SpanContext.spanContext()
+		if err != nil {
+			return nil, err
+		}
+		linkAttrs, err := decodeSpilledAttrs(l.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		span.links = append(span.links, sdktrace.Link{
+			SpanContext:           linkSC,
+			Attributes:            linkAttrs,
+			DroppedAttributeCount: l.Dropped,
+		})
+	}
+	return span, nil
+}
+
+// spilledReadOnlySpan is a span rebuilt from a spill file. ReadOnlySpan can only be
+// implemented by embedding it; every method is overridden, so the nil embedded interface
+// is never called.
+type spilledReadOnlySpan struct {
+	sdktrace.ReadOnlySpan
+
+	name         string
+	spanContext  trace.SpanContext
+	parent       trace.SpanContext
+	kind         trace.SpanKind
+	start, end   time.Time
+	attributes   []attribute.KeyValue
+	events       []sdktrace.Event
+	links        []sdktrace.Link
+	status       sdktrace.Status
+	droppedAttrs int
+	droppedEvts  int
+	droppedLinks int
+	children     int
+	resource     *resource.Resource
+	scope        instrumentation.Scope
+}
+
+func (s *spilledReadOnlySpan) Name() string                                { return s.name }
+func (s *spilledReadOnlySpan) SpanContext() trace.SpanContext              { return s.spanContext }
+func (s *spilledReadOnlySpan) Parent() trace.SpanContext                   { return s.parent }
+func (s *spilledReadOnlySpan) SpanKind() trace.SpanKind                    { return s.kind }
+func (s *spilledReadOnlySpan) StartTime() time.Time                        { return s.start }
+func (s *spilledReadOnlySpan) EndTime() time.Time                          { return s.end }
+func (s *spilledReadOnlySpan) Attributes() []attribute.KeyValue            { return s.attributes }
+func (s *spilledReadOnlySpan) Links() []sdktrace.Link                      { return s.links }
+func (s *spilledReadOnlySpan) Events() []sdktrace.Event                    { return s.events }
+func (s *spilledReadOnlySpan) Status() sdktrace.Status                     { return s.status }
+func (s *spilledReadOnlySpan) Resource() *resource.Resource                { return s.resource }
+func (s *spilledReadOnlySpan) DroppedAttributes() int                      { return s.droppedAttrs }
+func (s *spilledReadOnlySpan) DroppedLinks() int                           { return s.droppedLinks }
+func (s *spilledReadOnlySpan) DroppedEvents() int                          { return s.droppedEvts }
+func (s *spilledReadOnlySpan) ChildSpanCount() int                         { return s.children }
+func (s *spilledReadOnlySpan) InstrumentationScope() instrumentation.Scope { return s.scope }
+
+//nolint:staticcheck // required by ReadOnlySpan for backwards compatibility
+func (s *spilledReadOnlySpan) InstrumentationLibrary() instrumentation.Library { return s.scope }
+
+func newSpilledSpanCtx(sc trace.SpanContext) spilledSpanCtx {
+	if !sc.IsValid() {
+		return spilledSpanCtx{}
+	}
+	return spilledSpanCtx{
+		TraceID:    sc.TraceID().String(),
+		SpanID:     sc.SpanID().String(),
+		TraceFlags: byte(sc.TraceFlags()),
+		TraceState: sc.TraceState().String(),
+		Remote:     sc.IsRemote(),
+	}
+}
+
+func (c spilledSpanCtx) spanContext() (trace.SpanContext, error) {
+	if c.TraceID == "" {
+		return trace.SpanContext{}, nil
+	}
+	traceID, err := trace.TraceIDFromHex(c.TraceID)
+	if err != nil {
+		return trace.SpanContext{}, fmt.Errorf("invalid spilled trace ID: %w", err)
+	}
+	spanID, err := trace.SpanIDFromHex(c.SpanID)
+	if err != nil {
+		return trace.SpanContext{}, fmt.Errorf("invalid spilled span ID: %w", err)
+	}
+	state, err := trace.ParseTraceState(c.TraceState)
+	if err != nil {
+		return trace.SpanContext{}, fmt.Errorf("invalid spilled trace state: %w", err)
+	}
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.TraceFlags(c.TraceFlags),
+		TraceState: state,
+		Remote:     c.Remote,
+	}), nil
+}
+
+func newSpilledAttrs(kvs []attribute.KeyValue) []spilledAttr {
+	out := make([]spilledAttr, 0, len(kvs))
+	for _, kv := range kvs {
+		v, err := json.Marshal(kv.Value.AsInterface())
+		if err != nil {
+			continue
+		}
+		out = append(out, spilledAttr{Key: string(kv.Key), Type: kv.Value.Type().String(), Value: v})
+	}
+	return out
+}
+
+func decodeSpilledAttrs(in []spilledAttr) ([]attribute.KeyValue, error) {
+	out := make([]attribute.KeyValue, 0, len(in))
+	for _, a := range in {
+		kv, err := a.keyValue()
+		if err != nil {
+			return nil, fmt.Errorf("invalid spilled attribute %s: %w", a.Key, err)
+		}
+		out = append(out, kv)
+	}
+	return out, nil
+}
+
+func (a spilledAttr) keyValue() (attribute.KeyValue, error) {
+	key := attribute.Key(a.Key)
+	switch a.Type {
+	case "BOOL":
+		var v bool
+		err := json.Unmarshal(a.Value, &v)
+		return key.Bool(v), err
+	case "INT64":
+		var v int64
+		err := json.Unmarshal(a.Value, &v)
+		return key.Int64(v), err
+	case "FLOAT64":
+		var v float64
+		err := json.Unmarshal(a.Value, &v)
+		return key.Float64(v), err
+	case "STRING":
+		var v string
+		err := json.Unmarshal(a.Value, &v)
+		return key.String(v), err
+	case "BOOLSLICE":
+		var v []bool
+		err := json.Unmarshal(a.Value, &v)
+		return key.BoolSlice(v), err
+	case "INT64SLICE":
+		var v []int64
+		err := json.Unmarshal(a.Value, &v)
+		return key.Int64Slice(v), err
+	case "FLOAT64SLICE":
+		var v []float64
+		err := json.Unmarshal(a.Value, &v)
+		return key.Float64Slice(v), err
+	case "STRINGSLICE":
+		var v []string
+		err := json.Unmarshal(a.Value, &v)
+		return key.StringSlice(v), err
+	default:
+		return attribute.KeyValue{}, fmt.Errorf("unsupported type %q", a.Type)
+	}
+}
+
+// outageExporter simulates a collector outage: every export during the first outage
+// window after startup fails without reaching the wrapped exporter
+type outageExporter struct {
+	sdktrace.SpanExporter
+	until time.Time
+}
+
+// ExportSpans fails while the outage lasts
+func (o outageExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if time.Now().Before(o.until) {
+		return fmt.Errorf("simulated collector outage (%s left)", time.Until(o.until).Round(time.Millisecond))
+	}
+	return o.SpanExporter.ExportSpans(ctx, spans)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// flakyExporter fails every export while down is set and keeps the spans it accepts
+type flakyExporter struct {
+	down  atomic.Bool
+	mu    sync.Mutex
+	spans []sdktrace.ReadOnlySpan
+}
+
+func (f *flakyExporter) ExportSpans(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if f.down.Load() {
+		return errors.New("collector unavailable")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.spans = append(f.spans, spans...)
+	return nil
+}
+
+func (f *flakyExporter) Shutdown(context.Context) error { return nil }
+
+func (f *flakyExporter) exported() []sdktrace.ReadOnlySpan {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]sdktrace.ReadOnlySpan(nil), f.spans...)
+}
+
+// TestSpillRoundTrip spills a batch while the exporter is down and checks the recovered
+// spans match the originals: IDs, parent, kind, timing, attributes, links, events, status
+func TestSpillRoundTrip(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := tp.Tracer("spill-test")
+	ctx := context.Background()
+
+	pubCtx, publish := tracer.Start(ctx, "PublishOrder", trace.WithSpanKind(trace.SpanKindProducer))
+	_, validate := tracer.Start(pubCtx, "ValidateOrder")
+	validate.End()
+	publish.End()
+	_, process := tracer.Start(ctx, "ProcessOrder",
+		trace.WithNewRoot(),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithLinks(trace.Link{
+			SpanContext: publish.SpanContext(),
+			Attributes:  []attribute.KeyValue{attribute.String("link.type", "queue_consumption")},
+		}),
+		trace.WithAttributes(
+			attribute.String("order.id", "ORDER-1"),
+			attribute.Int64("messaging.consumer.lag_ms", 42),
+			attribute.Float64("order.amount", 12.5),
+			attribute.Bool("backfill", false),
+			attribute.StringSlice("tags", []string{"a", "b"}),
+		),
+	)
+	process.AddEvent("step.retry", trace.WithAttributes(attribute.Int("retry.attempt", 1)))
+	process.SetStatus(codes.Error, "payment declined")
+	process.End()
+	original := recorder.Ended()
+
+	next := &flakyExporter{}
+	next.down.Store(true)
+	dir := t.TempDir()
+	spill, err := NewSpillExporter(next, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spill.Shutdown(ctx)
+
+	if err := spill.ExportSpans(ctx, original); err != nil {
+		t.Fatalf("export during the outage returned %v, want the batch spilled", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "spill-*.jsonl"))
+	if len(files) != 1 {
+		t.Fatalf("got %d spill files, want 1", len(files))
+	}
+
+	// Still down: the file stays
+	spill.recover(ctx)
+	if len(next.exported()) != 0 {
+		t.Fatal("spans exported while the exporter was down")
+	}
+
+	next.down.Store(false)
+	spill.recover(ctx)
+	recovered := next.exported()
+	if len(recovered) != len(original) {
+		t.Fatalf("recovered %d spans, want %d", len(recovered), len(original))
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "spill-*")); len(files) != 0 {
+		t.Errorf("spill files left after recovery: %v", files)
+	}
+
+	for i, want := range original {
+		got := recovered[i]
+		if got.Name() != want.Name() {
+			t.Errorf("span %d: name %q, want %q", i, got.Name(), want.Name())
+			continue
+		}
+		if !got.SpanContext().Equal(want.SpanContext()) || !got.Parent().Equal(want.Parent()) {
+			t.Errorf("%s: context %v parent %v, want %v parent %v", want.Name(), got.SpanContext(), got.Parent(), want.SpanContext(), want.Parent())
+		}
+		if got.SpanKind() != want.SpanKind() {
+			t.Errorf("%s: kind %v, want %v", want.Name(), got.SpanKind(), want.SpanKind())
+		}
+		if !got.StartTime().Equal(want.StartTime()) || !got.EndTime().Equal(want.EndTime()) {
+			t.Errorf("%s: timing changed", want.Name())
+		}
+		if !sameAttrs(got.Attributes(), want.Attributes()) {
+			t.Errorf("%s: attributes %v, want %v", want.Name(), got.Attributes(), want.Attributes())
+		}
+		if got.Status() != want.Status() {
+			t.Errorf("%s: status %v, want %v", want.Name(), got.Status(), want.Status())
+		}
+		if got.InstrumentationScope() != want.InstrumentationScope() {
+			t.Errorf("%s: scope %v, want %v", want.Name(), got.InstrumentationScope(), want.InstrumentationScope())
+		}
+		if !got.Resource().Equal(want.Resource()) {
+			t.Errorf("%s: resource %v, want %v", want.Name(), got.Resource(), want.Resource())
+		}
+		if len(got.Links()) != len(want.Links()) {
+			t.Errorf("%s: %d links, want %d", want.Name(), len(got.Links()), len(want.Links()))
+		}
+		for j, l := range want.Links() {
+			if j >= len(got.Links()) {
+				break
+			}
+			if !got.Links()[j].SpanContext.Equal(l.SpanContext) || !sameAttrs(got.Links()[j].Attributes, l.Attributes) {
+				t.Errorf("%s: link %d %+v, want %+v", want.Name(), j, got.Links()[j], l)
+			}
+		}
+		if len(got.Events()) != len(want.Events()) {
+			t.Errorf("%s: %d events, want %d", want.Name(), len(got.Events()), len(want.Events()))
+		}
+		for j, e := range want.Events() {
+			if j >= len(got.Events()) {
+				break
+			}
+			g := got.Events()[j]
+			if g.Name != e.Name || !g.Time.Equal(e.Time) || !sameAttrs(g.Attributes, e.Attributes) {
+				t.Errorf("%s: event %d %+v, want %+v", want.Name(), j, g, e)
+			}
+		}
+	}
+}
+
+// TestSpillCorruptFileSetAside checks an unreadable spill file is renamed to *.bad instead
+// of being retried on every pass, and does not hold up the files after it
+func TestSpillCorruptFileSetAside(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	_, span := tp.Tracer("spill-test").Start(context.Background(), "PublishOrder")
+	span.End()
+
+	next := &flakyExporter{}
+	next.down.Store(true)
+	dir := t.TempDir()
+	spill, err := NewSpillExporter(next, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spill.Shutdown(context.Background())
+
+	corrupt := filepath.Join(dir, "spill-00000000000000000000-000000.jsonl")
+	if err := os.WriteFile(corrupt, []byte("{not json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := spill.ExportSpans(context.Background(), recorder.Ended()); err != nil {
+		t.Fatal(err)
+	}
+
+	next.down.Store(false)
+	spill.recover(context.Background())
+	if got := len(next.exported()); got != 1 {
+		t.Errorf("recovered %d spans, want the 1 valid one", got)
+	}
+	if _, err := os.Stat(corrupt + ".bad"); err != nil {
+		t.Errorf("corrupt spill file not set aside: %v", err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "spill-*.jsonl")); len(files) != 0 {
+		t.Errorf("spill files left for the next pass: %v", files)
+	}
+}
+
+// sameAttrs compares attribute lists as sets
+func sameAttrs(a, b []attribute.KeyValue) bool {
+	as, bs := attribute.NewSet(a...), attribute.NewSet(b...)
+	return as.Equals(&bs)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"span-links-signoz-demo/pkg/worker"
+	"span-links-signoz-demo/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// RunStats holds live counters for the current run (dashboard, admin endpoints)
+type RunStats struct {
+	published  atomic.Int64
+	processed  atomic.Int64
+	failed     atomic.Int64
+	rolledBack atomic.Int64
+	corrupted  atomic.Int64
+	parseFails atomic.Int64
+
+	mu        sync.Mutex
+	recent    []worker.TraceSummary
+	perWorker map[string]int64
+}
+
+// NewRunStats creates empty run stats
+func NewRunStats() *RunStats {
+	return &RunStats{
+		perWorker: make(map[string]int64),
+	}
+}
+
+// IncPublished counts a published order
+func (s *RunStats) IncPublished() { s.published.Add(1) }
+
+// AddRolledBack counts n published orders withdrawn by a rolled-back batch
+func (s *RunStats) AddRolledBack(n int) { s.rolledBack.Add(int64(n)) }
+
+// IncTraceParentCorrupted counts a message published with a corrupted traceparent
+func (s *RunStats) IncTraceParentCorrupted() { s.corrupted.Add(1) }
+
+// IncLinkParseFailed counts a delivery whose producer context could not be parsed
+func (s *RunStats) IncLinkParseFailed() { s.parseFails.Add(1) }
+
+// IncProcessed counts a successfully processed order
+func (s *RunStats) IncProcessed(workerID string) {
+	s.processed.Add(1)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.perWorker[workerID]++
+}
+
+// IncFailed counts an order whose processing failed
+func (s *RunStats) IncFailed() { s.failed.Add(1) }
+
+// Published returns the number of published orders
+func (s *RunStats) Published() int64 { return s.published.Load() }
+
+// Processed returns the number of successfully processed orders
+func (s *RunStats) Processed() int64 { return s.processed.Load() }
+
+// Failed returns the number of failed orders
+func (s *RunStats) Failed() int64 { return s.failed.Load() }
+
+// RolledBack returns the number of published orders withdrawn by rolled-back batches
+func (s *RunStats) RolledBack() int64 { return s.rolledBack.Load() }
+
+// TraceParentCorrupted returns the number of messages published with a corrupted traceparent
+func (s *RunStats) TraceParentCorrupted() int64 { return s.corrupted.Load() }
+
+// LinkParseFailed returns the number of deliveries whose producer context could not be parsed
+func (s *RunStats) LinkParseFailed() int64 { return s.parseFails.Load() }
+
+// CorruptionRate returns the fraction of published messages whose traceparent was corrupted
+func (s *RunStats) CorruptionRate() float64 {
+	published := s.Published()
+	if published == 0 {
+		return 0
+	}
+	return float64(s.TraceParentCorrupted()) / float64(published)
+}
+
+// Outstanding returns the number of published orders not yet processed, failed, or
+// rolled back, including orders a dispatcher has taken off the queue but not yet handed
+// to a worker
+func (s *RunStats) Outstanding() int64 {
+	return s.Published() - s.Processed() - s.Failed() - s.RolledBack()
+}
+
+// RecordTrace remembers a processed order's trace, keeping the most recent RecentTraceCapacity
+func (s *RunStats) RecordTrace(summary worker.TraceSummary) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.recent = append(s.recent, summary)
+	if len(s.recent) > RecentTraceCapacity {
+		s.recent = s.recent[len(s.recent)-RecentTraceCapacity:]
+	}
+}
+
+// RecentTraces returns the most recent trace summaries, newest last
+func (s *RunStats) RecentTraces() []worker.TraceSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]worker.TraceSummary(nil), s.recent...)
+}
+
+// ProcessedByWorker returns successfully processed order counts per worker
+func (s *RunStats) ProcessedByWorker() map[string]int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]int64, len(s.perWorker))
+	for id, n := range s.perWorker {
+		counts[id] = n
+	}
+	return counts
+}
+
+// registerQueueDepthGauge reports depth as the orders.queue.depth gauge
+func registerQueueDepthGauge(depth func() int) {
+	_, err := otel.Meter("run-stats").Int64ObservableGauge(telemetry.MetricOrdersQueueDepth,
+		metric.WithDescription("Orders waiting to be handed to a worker"),
+		metric.WithUnit("{order}"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(int64(depth()))
+			return nil
+		}),
+	)
+	if err != nil {
+		log.Printf("Failed to create queue depth gauge: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"span-links-signoz-demo/pkg/queue"
+	"span-links-signoz-demo/telemetry"
+
+	"github.com/XSAM/otelsql"
+	_ "github.com/mattn/go-sqlite3"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultOrderDBPath is a shared in-memory SQLite database, discarded at exit
+const DefaultOrderDBPath = "file:orders?mode=memory&cache=shared"
+
+// OrderStore persists processed orders in SQLite through an otelsql-instrumented
+// database/sql handle, so every statement shows up as a DB span in the consumer trace
+type OrderStore struct {
+	db *sql.DB
+}
+
+// OpenOrderStore opens (creating if needed) the orders database at dsn
+func OpenOrderStore(ctx context.Context, dsn string) (*OrderStore, error) {
+	db, err := otelsql.Open("sqlite3", dsn,
+		otelsql.WithAttributes(telemetry.DBSystemSqlite),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitRows:             true,
+			OmitConnectorConnect: true,
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open order database: %w", err)
+	}
+	// SQLite allows one writer; serialising connections avoids "database is locked"
+	db.SetMaxOpenConns(1)
+
+	const schema = `CREATE TABLE IF NOT EXISTS orders (
+		id           TEXT PRIMARY KEY,
+		customer_id  TEXT NOT NULL,
+		amount       REAL NOT NULL,
+		worker_id    TEXT NOT NULL,
+		trace_id     TEXT NOT NULL,
+		processed_at TIMESTAMP NOT NULL
+	)`
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create orders table: %w", err)
+	}
+	return &OrderStore{db: db}, nil
+}
+
+// Close closes the database
+func (s *OrderStore) Close() error {
+	return s.db.Close()
+}
+
+// SaveOrder upserts a processed order along with the trace that processed it
+func (s *OrderStore) SaveOrder(ctx context.Context, order queue.Order, workerID string) error {
+	traceID := trace.SpanContextFromContext(ctx).TraceID().String()
+	_, err := s.db.ExecContext(context.WithoutCancel(ctx),
+		`INSERT INTO orders (id, customer_id, amount, worker_id, trace_id, processed_at)
+		 VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(id) DO UPDATE SET worker_id = excluded.worker_id, trace_id = excluded.trace_id, processed_at = excluded.processed_at`,
+		order.ID, order.CustomerID, order.Amount, workerID, traceID, time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save order %s: %w", order.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package telemetry
+
+// Names of the metrics the demo emits. Instruments are created with these constants and
+// the generated dashboards and alert rules (spanlinks gen-dashboard) query them from
+// Metrics, so a renamed metric cannot silently leave a panel empty.
+const (
+	MetricOrdersPublished          = "orders.published"
+	MetricOrdersProcessed          = "orders.processed"
+	MetricOrdersQueueDepth         = "orders.queue.depth"
+	MetricOrdersProcessingDuration = "orders.processing.duration"
+	MetricOrdersConsumerLag        = "orders.consumer.lag"
+	MetricOrdersSLOBreaches        = "orders.slo.breaches"
+	MetricSpanLinks                = "span.links"
+	MetricSpanLinkedTraces         = "span.links.linked_traces"
+	MetricLinkAttributeSuppressed  = "link.attribute.suppressed"
+	MetricSpansSpilled             = "telemetry.spans.spilled"
+	MetricSpansRecovered           = "telemetry.spans.recovered"
+	MetricSpansDropped             = "telemetry.spans.dropped"
+	MetricErrorBudgetSuccessRate   = "run.error_budget.success_rate"
+	MetricErrorBudgetConsumed      = "run.error_budget.consumed"
+	MetricQueuePublished           = "queue.messages.published"
+	MetricQueueConsumed            = "queue.messages.consumed"
+	MetricQueueAcked               = "queue.messages.acked"
+	MetricQueuePublishToConsume    = "queue.publish_to_consume.duration"
+)
+
+// OutcomeKey is the attribute orders.processed and orders.processing.duration are split
+// by: OutcomeSuccess or OutcomeFailure
+const OutcomeKey = "order.outcome"
+
+// Order outcomes
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// MetricKind is the instrument kind of a metric
+type MetricKind string
+
+// Metric kinds
+const (
+	Counter   MetricKind = "counter"   // monotonic sum
+	Histogram MetricKind = "histogram" // explicit-bucket histogram
+	Gauge     MetricKind = "gauge"     // last value
+)
+
+// Metric describes one emitted metric
+type Metric struct {
+	Name        string
+	Kind        MetricKind
+	Unit        string
+	Description string
+	Attributes  []string // attributes worth grouping by
+}
+
+// Metrics lists every metric the demo emits
+var Metrics = []Metric{
+	{MetricOrdersPublished, Counter, "{order}", "Orders published to the queue", []string{"messaging.destination.name"}},
+	{MetricOrdersProcessed, Counter, "{order}", "Orders processed, by outcome", []string{OutcomeKey, "worker.id"}},
+	{MetricOrdersQueueDepth, Gauge, "{order}", "Orders waiting to be handed to a worker", nil},
+	{MetricOrdersProcessingDuration, Histogram, "ms", "Time a worker spent processing an order, by outcome", []string{OutcomeKey}},
+	{MetricOrdersConsumerLag, Histogram, "ms", "Time between an order being created and a worker picking it up", []string{"messaging.destination.name"}},
+	{MetricOrdersSLOBreaches, Counter, "{order}", "Orders whose publish-to-processed latency exceeded the latency budget", nil},
+	{MetricSpanLinks, Counter, "{link}", "Span links recorded, by span name and link type", []string{"span.name", "link.type"}},
+	{MetricSpanLinkedTraces, Histogram, "{trace}", "Distinct traces, other than its own, that a span links to", []string{"span.name"}},
+	{MetricLinkAttributeSuppressed, Counter, "", "Link attribute values replaced or dropped by the cardinality guard", []string{"attribute.key"}},
+	{MetricSpansSpilled, Counter, "{span}", "Spans written to disk after a failed export", nil},
+	{MetricSpansRecovered, Counter, "{span}", "Spilled spans exported successfully on a later attempt", nil},
+	{MetricSpansDropped, Counter, "{span}", "Spans dropped by name before export", []string{"span.name"}},
+	{MetricErrorBudgetSuccessRate, Gauge, "", "Share of the run's orders processed successfully", nil},
+	{MetricErrorBudgetConsumed, Gauge, "", "Share of the run's error budget used up by failed orders", nil},
+	{MetricQueuePublished, Counter, "{message}", "Messages published, by topic and link direction", []string{"messaging.destination.name", "link.direction"}},
+	{MetricQueueConsumed, Counter, "{message}", "Messages handed to consumers, by topic and link direction", []string{"messaging.destination.name", "link.direction"}},
+	{MetricQueueAcked, Counter, "{message}", "Messages acknowledged by consumers, by topic and link direction", []string{"messaging.destination.name", "link.direction"}},
+	{MetricQueuePublishToConsume, Histogram, "ms", "Time from a message being published to a consumer taking it, by topic and link direction", []string{"messaging.destination.name", "link.direction"}},
+}
+
+// LookupMetric returns the description of the metric called name
+func LookupMetric(name string) (Metric, bool) {
+	for _, m := range Metrics {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return Metric{}, false
+}
@@ -0,0 +1,45 @@
+package telemetry
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// EndWithStatus ends span with its status set from err: Ok when err is nil, else Error
+// with err recorded as an exception event. Every span of the pipeline ends through it,
+// so an error filter on a link query in SigNoz tells failed spans from successful ones
+// instead of from spans that never said.
+func EndWithStatus(span trace.Span, err error, opts ...trace.SpanEndOption) {
+	SetStatus(span, err)
+	span.End(opts...)
+}
+
+// SetStatus sets span's status from err like EndWithStatus, for spans that end elsewhere
+func SetStatus(span trace.Span, err error) {
+	if err != nil {
+		if rec, ok := err.(recordedError); !ok || rec.spanID != span.SpanContext().SpanID() {
+			span.RecordError(err)
+		}
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+	span.SetStatus(codes.Ok, "")
+}
+
+// recordedError is an error RecordError already recorded on span
+type recordedError struct {
+	error
+	spanID trace.SpanID
+}
+
+func (e recordedError) Unwrap() error { return e.error }
+
+// RecordError records err on span as an exception event with extra attributes (e.g.
+// exception.stacktrace) and returns it marked as recorded, so ending the span with it
+// through EndWithStatus sets the Error status without a second exception event (other
+// spans ended with it still record it)
+func RecordError(span trace.Span, err error, attrs ...attribute.KeyValue) error {
+	span.RecordError(err, trace.WithAttributes(attrs...))
+	return recordedError{error: err, spanID: span.SpanContext().SpanID()}
+}
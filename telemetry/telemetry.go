@@ -0,0 +1,73 @@
+// Package telemetry is the one place the demo's programs take semantic conventions from.
+// The semconv version is pinned here and the schema URL resources are stamped with can be
+// overridden, so upgrading conventions (or trying a newer schema against a backend) does
+// not mean touching every runner.
+package telemetry
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// SemconvVersion is the semantic conventions version of the attribute helpers below
+const SemconvVersion = "1.21.0"
+
+// SchemaURLEnv overrides the schema URL of every resource built by ServiceResource
+const SchemaURLEnv = "OTEL_SEMCONV_SCHEMA_URL"
+
+// ServiceVersion is the service.version every demo program reports
+const ServiceVersion = "1.0.0"
+
+// warnSchemaOnce logs a schema URL override that does not match SemconvVersion once per
+// process rather than once per resource
+var warnSchemaOnce sync.Once
+
+// SchemaURL returns the schema URL resources are stamped with: OTEL_SEMCONV_SCHEMA_URL
+// when set, else the one matching SemconvVersion. An override naming another version is
+// used as-is, with a warning: the attributes still follow SemconvVersion, so a backend
+// applying that schema's migrations may rename them wrongly.
+func SchemaURL() string {
+	url := os.Getenv(SchemaURLEnv)
+	if url == "" {
+		return semconv.SchemaURL
+	}
+	if url != semconv.SchemaURL {
+		warnSchemaOnce.Do(func() {
+			log.Printf("Warning: %s=%s does not match the pinned semantic conventions (%s); attributes still follow v%s",
+				SchemaURLEnv, url, semconv.SchemaURL, SemconvVersion)
+		})
+	}
+	return url
+}
+
+// ServiceResource describes a demo program: service.name, service.version and
+// environment=demo plus attrs, under SchemaURL
+func ServiceResource(ctx context.Context, serviceName string, attrs ...attribute.KeyValue) (*resource.Resource, error) {
+	return resource.New(ctx,
+		resource.WithSchemaURL(SchemaURL()),
+		resource.WithAttributes(append([]attribute.KeyValue{
+			semconv.ServiceName(serviceName),
+			semconv.ServiceVersion(ServiceVersion),
+			attribute.String("environment", "demo"),
+		}, attrs...)...),
+	)
+}
+
+// ServiceName returns the service.name attribute
+func ServiceName(name string) attribute.KeyValue {
+	return semconv.ServiceName(name)
+}
+
+// ServiceInstanceID returns the service.instance.id attribute
+func ServiceInstanceID(id string) attribute.KeyValue {
+	return semconv.ServiceInstanceID(id)
+}
+
+// DBSystemSqlite is the db.system attribute for SQLite
+var DBSystemSqlite = semconv.DBSystemSqlite
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tenantKey is the span attribute naming the simulated tenant a span belongs to
+const tenantKey = attribute.Key("tenant.id")
+
+// tenantExport is where one tenant's spans are sent
+type tenantExport struct {
+	endpoint string            // "" exports to the default endpoint
+	headers  map[string]string // override the default headers (e.g. signoz-ingestion-key)
+}
+
+// TenantRouter sends each span to the export processor of its tenant, as one process
+// serving several SigNoz Cloud tenants with their own ingestion keys would. Producers put
+// the tenant on the batch span and workers on the consumer span (from the tenant-id
+// header); every span started under a span with a tenant inherits it. Spans without a
+// tenant go to the default processor.
+type TenantRouter struct {
+	tenants  map[string]sdktrace.SpanProcessor
+	fallback sdktrace.SpanProcessor
+
+	mu   sync.Mutex
+	live map[trace.SpanID]string // tenant of spans that have started but not ended
+}
+
+var _ sdktrace.SpanProcessor = (*TenantRouter)(nil)
+
+// NewTenantRouter routes the spans of each tenant to its processor and all others to fallback
+func NewTenantRouter(tenants map[string]sdktrace.SpanProcessor, fallback sdktrace.SpanProcessor) *TenantRouter {
+	return &TenantRouter{
+		tenants:  tenants,
+		fallback: fallback,
+		live:     make(map[trace.SpanID]string),
+	}
+}
+
+// OnStart gives a span without a tenant the tenant of its parent and hands the span to
+// that tenant's processor, so processors that track span starts (a SpanDropFilter
+// reparenting the children of dropped spans) see the spans they will be given at the end
+func (r *TenantRouter) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	tenant := spanTenant(s)
+
+	r.mu.Lock()
+	if tenant == "" {
+		tenant = r.live[s.Parent().SpanID()]
+		if tenant != "" {
+			s.SetAttributes(tenantKey.String(tenant))
+		}
+	}
+	if tenant != "" {
+		r.live[s.SpanContext().SpanID()] = tenant
+	}
+	r.mu.Unlock()
+
+	r.processor(tenant).OnStart(parent, s)
+}
+
+// OnEnd hands the span to its tenant's processor
+func (r *TenantRouter) OnEnd(s sdktrace.ReadOnlySpan) {
+	r.mu.Lock()
+	delete(r.live, s.SpanContext().SpanID())
+	r.mu.Unlock()
+
+	r.processor(spanTenant(s)).OnEnd(s)
+}
+
+// processor returns the processor of tenant, the fallback for no or an unknown tenant
+func (r *TenantRouter) processor(tenant string) sdktrace.SpanProcessor {
+	if p, ok := r.tenants[tenant]; ok {
+		return p
+	}
+	return r.fallback
+}
+
+// Shutdown shuts down every tenant's processor and the fallback
+func (r *TenantRouter) Shutdown(ctx context.Context) error {
+	errs := []error{r.fallback.Shutdown(ctx)}
+	for _, p := range r.tenants {
+		errs = append(errs, p.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// ForceFlush flushes every tenant's processor and the fallback
+func (r *TenantRouter) ForceFlush(ctx context.Context) error {
+	errs := []error{r.fallback.ForceFlush(ctx)}
+	for _, p := range r.tenants {
+		errs = append(errs, p.ForceFlush(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func spanTenant(s sdktrace.ReadOnlySpan) string {
+	for _, kv := range s.Attributes() {
+		if kv.Key == tenantKey {
+			return kv.Value.AsString()
+		}
+	}
+	return ""
+}
+
+// tenantExportsFromEnv reads TENANT_OTLP_HEADERS ("acme=signoz-ingestion-key=KEY1;globex=
+// signoz-ingestion-key=KEY2", headers in OTEL_EXPORTER_OTLP_HEADERS format) and the
+// optional TENANT_OTLP_ENDPOINTS ("acme=https://ingest.eu.signoz.cloud:443;..."). A tenant
+// needs an entry in at least one of them.
+func tenantExportsFromEnv() map[string]tenantExport {
+	tenants := make(map[string]tenantExport)
+	for tenant, headers := range parseTenantMap("TENANT_OTLP_HEADERS") {
+		tenants[tenant] = tenantExport{headers: parseHeaders(headers)}
+	}
+	for tenant, endpoint := range parseTenantMap("TENANT_OTLP_ENDPOINTS") {
+		export := tenants[tenant]
+		export.endpoint = endpoint
+		tenants[tenant] = export
+	}
+	return tenants
+}
+
+// parseTenantMap reads a "tenant=value;tenant=value" variable
+func parseTenantMap(name string) map[string]string {
+	m := make(map[string]string)
+	for _, entry := range strings.Split(os.Getenv(name), ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		tenant, value, ok := strings.Cut(entry, "=")
+		tenant = strings.TrimSpace(tenant)
+		if !ok || tenant == "" {
+			log.Printf("Ignoring invalid %s entry %q", name, entry)
+			continue
+		}
+		m[tenant] = strings.TrimSpace(value)
+	}
+	return m
+}
+
+// tenantsFromEnv returns the configured tenant names in order
+func tenantsFromEnv() []string {
+	exports := tenantExportsFromEnv()
+	tenants := make([]string, 0, len(exports))
+	for tenant := range exports {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+	return tenants
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"span-links-signoz-demo/pkg/producer"
+	"span-links-signoz-demo/pkg/queue"
+	"span-links-signoz-demo/pkg/worker"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gopkg.in/yaml.v3"
+)
+
+// waitForDrain waits until every order published under stats was processed or failed,
+// reporting false if timeout passed first (or ctx ended)
+func waitForDrain(ctx context.Context, stats *RunStats, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	for stats.Outstanding() > 0 {
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-deadline:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+// Topology describes a simulated mesh of producer and consumer instances over several
+// queues, all running in one process
+type Topology struct {
+	Producers  int `yaml:"producers"`   // producer instances
+	Consumers  int `yaml:"consumers"`   // consumer instances; each consumes one queue
+	Queues     int `yaml:"queues"`      // queues (topics orders.q1..qK)
+	Batches    int `yaml:"batches"`     // batches each producer publishes
+	BatchSize  int `yaml:"batch_size"`  // orders per batch
+	IntervalMs int `yaml:"interval_ms"` // pause between a producer's batches
+}
+
+// LoadTopology reads a topology file, filling in defaults for omitted fields
+func LoadTopology(path string) (Topology, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Topology{}, fmt.Errorf("failed to read topology: %w", err)
+	}
+	t := Topology{Producers: 2, Consumers: 3, Queues: 2, Batches: 2, BatchSize: 5, IntervalMs: 500}
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return Topology{}, fmt.Errorf("failed to decode topology: %w", err)
+	}
+	switch {
+	case t.Producers < 1 || t.Consumers < 1 || t.Queues < 1:
+		return Topology{}, errors.New("topology needs at least one producer, consumer and queue")
+	case t.Consumers < t.Queues:
+		return Topology{}, fmt.Errorf("topology has %d queues but only %d consumers; every queue needs a consumer", t.Queues, t.Consumers)
+	case t.Batches < 1 || t.BatchSize < 1:
+		return Topology{}, errors.New("topology batches and batch_size must be at least 1")
+	}
+	return t, nil
+}
+
+// topologyQueue names queue k (0-based)
+func topologyQueue(k int) string {
+	return fmt.Sprintf("orders.q%d", k+1)
+}
+
+// runTopology runs the simulated mesh: every producer and consumer instance gets its own
+// tracer provider (service.name producer-service/worker-service, service.instance.id
+// producer-N/worker-N). Producer N publishes its b-th batch to queue (N+b) mod K, so each
+// producer feeds several queues; consumer M consumes queue M mod K. Consumer spans link
+// back to whichever producer instance published the order, giving SigNoz's service map a
+// realistic many-to-many mesh of linked traces.
+func runTopology(ctx context.Context, providers *TelemetryProviders, topo Topology) error {
+	orders := queue.New()
+	stats := NewRunStats()
+	log.Printf("Topology mode (producers=%d consumers=%d queues=%d batches=%d batch_size=%d)",
+		topo.Producers, topo.Consumers, topo.Queues, topo.Batches, topo.BatchSize)
+
+	consumeCtx, stopConsumers := context.WithCancel(ctx)
+	defer stopConsumers()
+
+	var consumers sync.WaitGroup
+	for m := 0; m < topo.Consumers; m++ {
+		tp, err := NewInstanceTracerProvider(providers, "worker-service", fmt.Sprintf("worker-%d", m+1))
+		if err != nil {
+			return err
+		}
+		consumer := worker.New(orders,
+			worker.WithTracer(tp.Tracer("worker-service")),
+			worker.WithRunStats(stats),
+		)
+
+		consumers.Add(1)
+		go func(workerID, topic string) {
+			defer consumers.Done()
+			consumer.ProcessOrders(consumeCtx, workerID, topic)
+		}(fmt.Sprintf("Worker-%d", m+1), topologyQueue(m%topo.Queues))
+	}
+
+	var producers sync.WaitGroup
+	for n := 0; n < topo.Producers; n++ {
+		tp, err := NewInstanceTracerProvider(providers, "producer-service", fmt.Sprintf("producer-%d", n+1))
+		if err != nil {
+			return err
+		}
+		// One publisher per queue, all sharing the instance's tracer
+		publishers := make([]*producer.Service, topo.Queues)
+		for k := range publishers {
+			publishers[k] = producer.New(orders,
+				producer.WithTracer(tp.Tracer("producer-service")),
+				producer.WithTopic(topologyQueue(k)),
+			)
+			publishers[k].SetRunStats(stats)
+		}
+
+		producers.Add(1)
+		go func(n int) {
+			defer producers.Done()
+			for b := 0; b < topo.Batches; b++ {
+				if b > 0 {
+					select {
+					case <-time.After(time.Duration(topo.IntervalMs) * time.Millisecond):
+					case <-ctx.Done():
+						return
+					}
+				}
+				k := (n + b) % topo.Queues
+				if _, err := publishers[k].PublishOrderBatch(ctx, topo.BatchSize); err != nil {
+					log.Printf("Producer-%d failed to publish to %s: %v", n+1, topologyQueue(k), err)
+				}
+			}
+		}(n)
+	}
+	producers.Wait()
+
+	// Let consumers drain everything that was published
+	if !waitForDrain(ctx, stats, DrainTimeout) {
+		log.Printf("Topology run timed out with %d orders outstanding", stats.Outstanding())
+	}
+	stopConsumers()
+	consumers.Wait()
+
+	log.Printf("Topology run completed (published=%d processed=%d failed=%d)", stats.Published(), stats.Processed(), stats.Failed())
+	EmitRunSummary(providers.RootSpans,
+		runIDKey.String(runID),
+		attribute.String("run.scenario", "topology"),
+		attribute.Int("run.topology.producers", topo.Producers),
+		attribute.Int("run.topology.consumers", topo.Consumers),
+		attribute.Int("run.topology.queues", topo.Queues),
+		attribute.Int64("run.orders.published", stats.Published()),
+		attribute.Int64("run.orders.processed", stats.Processed()),
+		attribute.Int64("run.orders.failed", stats.Failed()),
+	)
+	return nil
+}
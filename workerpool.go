@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"span-links-signoz-demo/pkg/queue"
+	"span-links-signoz-demo/pkg/worker"
+)
+
+// WorkerPool runs a resizable set of workers reading the queue directly (the default
+// dispatch mode). Workers are numbered in start order and never reuse an ID, so a retired
+// worker still finishing its last order never shares a worker.id with a new one. Retiring
+// a worker lets it finish the order it holds; it only stops taking new ones.
+type WorkerPool struct {
+	ctx      context.Context
+	wg       *sync.WaitGroup
+	consumer *worker.Service
+	consume  func(ctx context.Context, workerID int) (queue.Order, error)
+
+	mu      sync.Mutex
+	retire  []context.CancelFunc // running workers, oldest first
+	started int
+}
+
+// NewWorkerPool creates an empty pool whose workers run until ctx is done (tracked by wg)
+// and take orders from consume
+func NewWorkerPool(ctx context.Context, wg *sync.WaitGroup, consumer *worker.Service, consume func(ctx context.Context, workerID int) (queue.Order, error)) *WorkerPool {
+	return &WorkerPool{ctx: ctx, wg: wg, consumer: consumer, consume: consume}
+}
+
+// Size returns the number of workers taking orders
+func (p *WorkerPool) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.retire)
+}
+
+// Resize starts or retires workers until n are taking orders; the newest are retired first
+func (p *WorkerPool) Resize(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(p.retire) < n {
+		p.start()
+	}
+	for len(p.retire) > n {
+		last := len(p.retire) - 1
+		p.retire[last]()
+		p.retire = p.retire[:last]
+	}
+}
+
+// start runs one more worker; p.mu must be held
+func (p *WorkerPool) start() {
+	p.started++
+	id := p.started
+	workerID := fmt.Sprintf("Worker-%d", id)
+
+	// Retiring cancels only the wait for the next order; processing runs on loopCtx, which
+	// is stopped once the worker comes back for more
+	loopCtx, stop := context.WithCancel(p.ctx)
+	consumeCtx, retire := context.WithCancel(loopCtx)
+	p.retire = append(p.retire, retire)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer stop()
+		p.consumer.ProcessFrom(loopCtx, workerID, func(context.Context) (queue.Order, error) {
+			if consumeCtx.Err() == nil {
+				order, err := p.consume(consumeCtx, id)
+				if consumeCtx.Err() == nil {
+					return order, err
+				}
+				if err == nil {
+					return order, nil // taken just as the worker was retired
+				}
+			}
+			stop()
+			if p.ctx.Err() == nil {
+				log.Printf("Worker retired (worker=%s)", workerID)
+			}
+			return queue.Order{}, consumeCtx.Err()
+		})
+	}()
+}